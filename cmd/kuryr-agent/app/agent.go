@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -9,19 +10,27 @@ import (
 	"k8s.io/apiserver/pkg/server/healthz"
 	"k8s.io/apiserver/pkg/server/mux"
 	"k8s.io/apiserver/pkg/server/routes"
+	"k8s.io/client-go/informers"
 	"projectkuryr/kuryr/pkg/agent/interfacestore"
 
 	"k8s.io/component-base/metrics/legacyregistry"
 	"k8s.io/klog"
 	"net/http"
+	"projectkuryr/kuryr/pkg/agent/apiserver"
 	"projectkuryr/kuryr/pkg/agent/cniserver"
+	"projectkuryr/kuryr/pkg/agent/cnistate"
+	"projectkuryr/kuryr/pkg/agent/config"
 	"projectkuryr/kuryr/pkg/agent/openflow"
 	ofconfig "projectkuryr/kuryr/pkg/ovs/openflow"
 	kuryrinformers "projectkuryr/kuryr/pkg/client/informers/externalversions"
 	"projectkuryr/kuryr/pkg/healthcheck"
 	"projectkuryr/kuryr/pkg/k8s"
 	"projectkuryr/kuryr/pkg/ovs/ovsconfig"
+	"projectkuryr/kuryr/pkg/ovs/ovsctl"
+	k8sproxy "projectkuryr/kuryr/pkg/proxy"
 	"projectkuryr/kuryr/pkg/signals"
+	"projectkuryr/kuryr/pkg/utils/env"
+	"projectkuryr/kuryr/pkg/utils/tracing"
 	"projectkuryr/kuryr/pkg/version"
 	"time"
 )
@@ -112,14 +121,21 @@ func serveMetrics(bindAddress, kuryrAgentMetrics string, enableProfiling bool, e
 
 func run(o *Options) error {
 	klog.Infof("Starting Kuryr agent (version %s)", version.GetFullVersion())
+	if o.config.EnableTracing {
+		shutdownTracing, err := tracing.Init("kuryr-agent", o.config.TracingCollectorEndpoint)
+		if err != nil {
+			return fmt.Errorf("error initializing tracing: %v", err)
+		}
+		defer shutdownTracing(context.TODO())
+	}
 	// Create K8s Clientset, CRD Clientset and SharedInformerFactory for the given config.
 	//k8sClient, _, crdClient, err := k8s.CreateClients(o.config.ClientConnection, o.config.KubeAPIServerOverride)
-	_, _, crdClient, err := k8s.CreateClientsCrd(o.config.ClientConnection, "")
+	k8sClient, _, crdClient, err := k8s.CreateClientsCrd(o.config.ClientConnection, "")
 	if err != nil {
 		return fmt.Errorf("error creating k8s clients: %v", err)
 	}
 
-	//informerFactory := informers.NewSharedInformerFactory(client, informerDefaultResync)
+	informerFactory := informers.NewSharedInformerFactory(k8sClient, informerDefaultResync)
 	//podInformer := informerFactory.Core().V1().Pods()
 	crdInformerFactory := kuryrinformers.NewSharedInformerFactory(crdClient, informerDefaultResync)
 	kpInformer := crdInformerFactory.Openstack().V1alpha1().KuryrPorts()
@@ -162,25 +178,60 @@ func run(o *Options) error {
 		klog.Infof("ofport: %+v\n", ofport)
 	}
 
+	nodeName, err := env.GetNodeName()
+	if err != nil {
+		return fmt.Errorf("error getting Node name: %v", err)
+	}
 	cniServer := cniserver.New(
 		o.config.CNISocket,
 		o.config.HostProcPathPrefix,
+		k8sClient,
 		crdClient,
 		kpInformer,
+		o.config.CNIChaining,
 		nil,
 		nil,
-		nil)
+		&config.NodeConfig{Name: nodeName})
 
-	err = cniServer.InitializeCniServer(ovsBridgeClient)
+	// The attachments of the Pods are saved by the CNI server and read by the agent API.
+	stateStore, err := cnistate.NewStore(o.config.CNIStateDir)
+	if err != nil {
+		return fmt.Errorf("error creating CNI state store: %v", err)
+	}
+	// The taps are sent to the Service pipeline of kuryrProxy by the CNI server.
+	var serviceOFClient openflow.Client
+	if o.config.EnableKuryrProxy {
+		serviceOFClient = ofClient
+	}
+	err = cniServer.InitializeCniServer(ovsBridgeClient, serviceOFClient, ifaceStore, stateStore)
 	if err != nil {
 		return fmt.Errorf("error initializing CNI server: %v", err)
 	}
+
+	apiToken, err := apiserver.LoadOrCreateToken(o.config.APITokenFile)
+	if err != nil {
+		return fmt.Errorf("error loading agent API token: %v", err)
+	}
+	apiServer := apiserver.New(o.config.APIBindAddress, apiToken, ifaceStore, stateStore, ovsBridgeClient, ovsctl.NewClient(o.config.OVSBridge))
+
+	var proxier k8sproxy.Provider
+	if o.config.EnableKuryrProxy {
+		proxier, err = newServiceProxy(o, k8sClient, informerFactory, ofClient, ovsBridgeClient)
+		if err != nil {
+			return fmt.Errorf("error creating kuryrProxy: %v", err)
+		}
+	}
 	// set up signal capture: the first SIGTERM / SIGINT signal is handled gracefully and will
 	// cause the stopCh channel to be closed; if another signal is received before the program
 	// exits, we will force exit.
 	stopCh := signals.RegisterSignalHandlers()
 	crdInformerFactory.Start(stopCh)
 	go cniServer.Run(stopCh)
+	go apiServer.Run(stopCh)
+	if proxier != nil {
+		go proxier.Run(stopCh)
+	}
+	informerFactory.Start(stopCh)
 
 	<-stopCh
 	klog.Info("Stopping Kuryr agent")
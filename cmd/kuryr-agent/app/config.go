@@ -20,6 +20,25 @@ type AgentConfig struct {
 	BindAddressHardFail bool
 
 	CNISocket string `yaml:"cniSocket,omitempty"`
+	// Whether kuryr runs as a chained CNI plugin after another primary CNI. kuryr then adds the VIF
+	// named by CNI_IFNAME to the prevResult of the chain. Defaults to false.
+	CNIChaining bool `yaml:"cniChaining,omitempty"`
+	// Directory where kuryr-agent saves the attachments of the Pods, so that they survive its
+	// restarts. Defaults to /var/lib/kuryr/cni.
+	CNIStateDir string `yaml:"cniStateDir,omitempty"`
+	// IP address and port of the node-local HTTP API of kuryr-agent, used to introspect the
+	// attachments of the Pods. Defaults to 127.0.0.1:10351.
+	APIBindAddress string `yaml:"apiBindAddress,omitempty"`
+	// File of the bearer token of the node-local HTTP API, a random token is generated if the file
+	// doesn't exist. Defaults to /var/run/kuryr/agent-api-token.
+	APITokenFile string `yaml:"apiTokenFile,omitempty"`
+	// Whether or not to record the OpenTelemetry spans of the networking of the Pods and export them
+	// to Jaeger. The spans of a Pod are in the trace of its UID. Defaults to false.
+	EnableTracing bool `yaml:"enableTracing,omitempty"`
+	// Endpoint of the Jaeger collector the spans are exported to, e.g.
+	// http://jaeger-collector:14268/api/traces. Defaults to the OTEL_EXPORTER_JAEGER_ENDPOINT
+	// environment variable, or else to http://localhost:14268/api/traces.
+	TracingCollectorEndpoint string `yaml:"tracingCollectorEndpoint,omitempty"`
 
 	// Name of the OpenVSwitch bridge kuryr-agent will create and use.
 	// Make sure it doesn't conflict with your existing OpenVSwitch bridges.
@@ -81,6 +100,26 @@ type AgentConfig struct {
 	// --service-cluster-ip-range. When kuryrProxy is enabled, this parameter is not needed.
 	// No default value for this field.
 	ServiceCIDRv6 string `yaml:"serviceCIDRv6,omitempty"`
+	// Whether or not to enable kuryrProxy, which load-balances the ClusterIP Services with OVS groups
+	// and flows on the OVS bridge. It is meant for the clusters where Octavia is not available.
+	// Defaults to false.
+	EnableKuryrProxy bool `yaml:"enableKuryrProxy,omitempty"`
+	// CIDR of the Neutron IPv4 subnet of the Pods, used by kuryrProxy to load-balance the IPv4
+	// Services. Defaults to the IPv4 CIDR in the PodCIDRs of the Node, kuryrProxy needs either.
+	PodCIDR string `yaml:"podCIDR,omitempty"`
+	// CIDR of the Neutron IPv6 subnet of the Pods, used by kuryrProxy to load-balance the IPv6
+	// Services. Defaults to the IPv6 CIDR in the PodCIDRs of the Node.
+	PodCIDRv6 string `yaml:"podCIDRv6,omitempty"`
+	// Whether or not kuryrProxy reads the Endpoints of the Services from the EndpointSlice API instead
+	// of the Endpoints API. It is ignored when kuryrProxy is not enabled.
+	// Defaults to false.
+	EnableEndpointSlice bool `yaml:"enableEndpointSlice,omitempty"`
+	// Whether or not kuryrProxy handles the NodePort of the Services. The NodePort traffic received by
+	// the Node is DNAT'd with iptables, and the health check node port of the Services with
+	// externalTrafficPolicy Local is served by kuryr-agent. Leave it disabled when kube-proxy runs
+	// in the cluster. It is ignored when kuryrProxy is not enabled.
+	// Defaults to false.
+	EnableNodePort bool `yaml:"enableNodePort,omitempty"`
 	// Whether or not to enable IPSec (ESP) encryption for Pod traffic across Nodes. IPSec encryption
 	// is supported only for the GRE tunnel type. kuryr uses Preshared Key (PSK) for IKE
 	// authentication. When IPSec tunnel is enabled, the PSK value must be passed to kuryr Agent
@@ -7,6 +7,9 @@ import (
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
 	"k8s.io/klog"
+	"net"
+	"projectkuryr/kuryr/pkg/agent/apiserver"
+	"projectkuryr/kuryr/pkg/agent/cnistate"
 	"projectkuryr/kuryr/pkg/agent/config"
 	"projectkuryr/kuryr/pkg/ovs/ovsconfig"
 	"projectkuryr/kuryr/pkg/cni"
@@ -80,6 +83,15 @@ func (o *Options) setDefaults() {
 	if o.config.CNISocket == "" {
 		o.config.CNISocket = cni.KuryrCNISocketAddr
 	}
+	if o.config.CNIStateDir == "" {
+		o.config.CNIStateDir = cnistate.DefaultDir
+	}
+	if o.config.APIBindAddress == "" {
+		o.config.APIBindAddress = apiserver.DefaultBindAddress
+	}
+	if o.config.APITokenFile == "" {
+		o.config.APITokenFile = apiserver.DefaultTokenFile
+	}
 	if o.config.OVSBridge == "" {
 		o.config.OVSBridge = defaultOVSBridge
 	}
@@ -133,6 +145,9 @@ func (o *Options) validate(args []string) error {
 	}
 
 	// 检查 o.config.HealthzBindAddress 和 o.config.MetricsBindAddress 是否符合ipPort
+	if _, _, err := net.SplitHostPort(o.config.APIBindAddress); err != nil {
+		return fmt.Errorf("apiBindAddress %s is invalid: %v", o.config.APIBindAddress, err)
+	}
 	return nil
 }
 
@@ -0,0 +1,209 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+
+	"projectkuryr/kuryr/pkg/agent/config"
+	"projectkuryr/kuryr/pkg/agent/controller/noderoute"
+	"projectkuryr/kuryr/pkg/agent/openflow"
+	"projectkuryr/kuryr/pkg/agent/openflow/cookie"
+	agentproxy "projectkuryr/kuryr/pkg/agent/proxy"
+	"projectkuryr/kuryr/pkg/agent/types"
+	"projectkuryr/kuryr/pkg/agent/util"
+	"projectkuryr/kuryr/pkg/agent/util/iptables"
+	"projectkuryr/kuryr/pkg/healthcheck"
+	"projectkuryr/kuryr/pkg/ovs/ovsconfig"
+	k8sproxy "projectkuryr/kuryr/pkg/proxy"
+	"projectkuryr/kuryr/pkg/utils/env"
+)
+
+const (
+	// proxyRoundNumKey is the external ID of the OVS bridge holding the round number of the
+	// flows of kuryrProxy.
+	proxyRoundNumKey        = "kuryrProxyRoundNum"
+	initialRoundNum         = 1
+	maxRetryForRoundNumSave = 5
+)
+
+// newServiceProxy installs the Service pipeline of kuryrProxy on the OVS bridge and creates the
+// proxier serving the ClusterIP Services from OVS, and their NodePort from iptables when
+// enabled. The flows of the previous run of the agent are deleted once the proxier and the CNI
+// server had the time to install them again.
+func newServiceProxy(o *Options, k8sClient clientset.Interface, informerFactory informers.SharedInformerFactory, ofClient openflow.Client, ovsBridgeClient ovsconfig.OVSBridgeClient) (k8sproxy.Provider, error) {
+	nodeName, err := env.GetNodeName()
+	if err != nil {
+		return nil, err
+	}
+	node, err := k8sClient.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Node %s: %v", nodeName, err)
+	}
+	nodeIP, err := noderoute.GetNodeAddr(node)
+	if err != nil {
+		return nil, err
+	}
+	nodeAddr, _, err := util.GetIPNetDeviceFromIP(nodeIP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get local IPNet: %v", err)
+	}
+	nodeConfig := &config.NodeConfig{
+		Name:       nodeName,
+		OVSBridge:  o.config.OVSBridge,
+		NodeIPAddr: nodeAddr,
+	}
+	if err := setPodCIDRs(nodeConfig, o, node); err != nil {
+		return nil, err
+	}
+
+	roundInfo := getRoundInfo(ovsBridgeClient)
+	connCh, err := ofClient.InitializeServicePipeline(roundInfo, nodeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize the Service pipeline: %v", err)
+	}
+	go func() {
+		// Like the agent does for the Antrea pipeline, give the time to the proxier and to the CNI
+		// server to install their flows with the new round number before deleting the stale ones.
+		time.Sleep(10 * time.Second)
+		klog.Info("Deleting stale flows from previous round if any")
+		if err := ofClient.DeleteStaleFlows(); err != nil {
+			klog.Errorf("Error when deleting stale flows from previous round: %v", err)
+			return
+		}
+		persistRoundNum(roundInfo.RoundNum, ovsBridgeClient, 1*time.Second, maxRetryForRoundNumSave)
+	}()
+	go func() {
+		for {
+			if _, ok := <-connCh; !ok {
+				return
+			}
+			klog.Info("Replaying OF flows to OVS bridge")
+			ofClient.ReplayFlows()
+			klog.Info("Flow replay completed")
+		}
+	}()
+
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartLogging(klog.Infof)
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: k8sClient.CoreV1().Events("")})
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "kuryr-agent-proxy", Host: nodeName})
+
+	isIPv6 := nodeIP.To4() == nil
+	var ipt agentproxy.IPTablesClient
+	var serviceHealthServer healthcheck.ServiceHealthServer
+	if o.config.EnableNodePort {
+		iptClient, err := iptables.New(!isIPv6, isIPv6)
+		if err != nil {
+			return nil, fmt.Errorf("error creating IPTables instance: %v", err)
+		}
+		ipt = iptClient
+		serviceHealthServer = healthcheck.NewServiceHealthServer(nodeName, recorder)
+	}
+	return agentproxy.NewProxier(nodeName, informerFactory, ofClient, recorder, isIPv6, o.config.EnableEndpointSlice, ipt, serviceHealthServer), nil
+}
+
+// setPodCIDRs sets the Pod CIDRs of nodeConfig from the configuration of the agent, or else from
+// the PodCIDRs of the Node. They tell kuryrProxy the IP families of the Pods.
+func setPodCIDRs(nodeConfig *config.NodeConfig, o *Options, node *corev1.Node) error {
+	podCIDRs := []string{o.config.PodCIDR, o.config.PodCIDRv6}
+	if o.config.PodCIDR == "" && o.config.PodCIDRv6 == "" {
+		podCIDRs = node.Spec.PodCIDRs
+	}
+	for _, podCIDR := range podCIDRs {
+		if podCIDR == "" {
+			continue
+		}
+		_, subnet, err := net.ParseCIDR(podCIDR)
+		if err != nil {
+			return fmt.Errorf("failed to parse Pod CIDR %s: %v", podCIDR, err)
+		}
+		if subnet.IP.To4() != nil {
+			nodeConfig.PodIPv4CIDR = subnet
+		} else {
+			nodeConfig.PodIPv6CIDR = subnet
+		}
+	}
+	if nodeConfig.PodIPv4CIDR == nil && nodeConfig.PodIPv6CIDR == nil {
+		return fmt.Errorf("no Pod CIDR configured and no PodCIDRs for Node %s, kuryrProxy needs one", nodeConfig.Name)
+	}
+	return nil
+}
+
+// persistRoundNum will save the provided round number to OVSDB as an external ID. To account for
+// transient failures, this (synchronous) function includes a retry mechanism.
+func persistRoundNum(num uint64, bridgeClient ovsconfig.OVSBridgeClient, interval time.Duration, maxRetries int) {
+	klog.Infof("Persisting round number %d to OVSDB", num)
+	for retry := 0; retry <= maxRetries; retry++ {
+		err := saveRoundNum(num, bridgeClient)
+		if err == nil {
+			klog.Infof("Round number %d was persisted to OVSDB", num)
+			return // success
+		}
+		klog.Errorf("Error when writing round number to OVSDB: %v", err)
+		time.Sleep(interval)
+	}
+	klog.Errorf("Unable to persist round number %d to OVSDB after %d tries", num, maxRetries+1)
+}
+
+func getLastRoundNum(bridgeClient ovsconfig.OVSBridgeClient) (uint64, error) {
+	extIDs, ovsCfgErr := bridgeClient.GetExternalIDs()
+	if ovsCfgErr != nil {
+		return 0, fmt.Errorf("error getting external IDs: %w", ovsCfgErr)
+	}
+	roundNumValue, exists := extIDs[proxyRoundNumKey]
+	if !exists {
+		return 0, fmt.Errorf("no round number found in OVSDB")
+	}
+	num, err := strconv.ParseUint(roundNumValue, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing last round number %v: %w", roundNumValue, err)
+	}
+	return num, nil
+}
+
+func saveRoundNum(num uint64, bridgeClient ovsconfig.OVSBridgeClient) error {
+	extIDs, ovsCfgErr := bridgeClient.GetExternalIDs()
+	if ovsCfgErr != nil {
+		return fmt.Errorf("error getting external IDs: %w", ovsCfgErr)
+	}
+	updatedExtIDs := make(map[string]interface{})
+	for k, v := range extIDs {
+		updatedExtIDs[k] = v
+	}
+	updatedExtIDs[proxyRoundNumKey] = fmt.Sprint(num)
+	return bridgeClient.SetExternalIDs(updatedExtIDs)
+}
+
+func getRoundInfo(bridgeClient ovsconfig.OVSBridgeClient) types.RoundInfo {
+	roundInfo := types.RoundInfo{}
+	num, err := getLastRoundNum(bridgeClient)
+	if err != nil {
+		klog.Infof("No round number found in OVSDB, using %v", initialRoundNum)
+		// We use a fixed value instead of a randomly-generated value to ensure that stale
+		// flows can be properly deleted in case of multiple rapid restarts when the agent
+		// is first deployed to a Node.
+		num = initialRoundNum
+	} else {
+		roundInfo.PrevRoundNum = new(uint64)
+		*roundInfo.PrevRoundNum = num
+		num++
+	}
+
+	num %= 1 << cookie.BitwidthRound
+	klog.Infof("Using round number %d", num)
+	roundInfo.RoundNum = num
+
+	return roundInfo
+}
@@ -3,17 +3,59 @@ package main
 import (
 	"fmt"
 	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/containernetworking/cni/pkg/types"
 	cniversion "github.com/containernetworking/cni/pkg/version"
+	"io/ioutil"
+	"log"
+	"os"
+	cnipb "projectkuryr/kuryr/pkg/apis/cni/v1alpha1"
 	"projectkuryr/kuryr/pkg/cni"
 	"projectkuryr/kuryr/pkg/version"
 )
 
 func main() {
-	skel.PluginMain(
-		cni.ActionAdd.Request,
-		cni.ActionCheck.Request,
-		cni.ActionDel.Request,
-		cniversion.All,
-		fmt.Sprintf("Kuryr CNI %s", version.GetFullVersionWithRuntimeInfo()),
-	)
+	// skel only knows the commands of the CNI spec up to 0.4.0, the STATUS and GC commands added
+	// in 1.1.0 are dispatched here.
+	switch os.Getenv("CNI_COMMAND") {
+	case "STATUS":
+		runCommand(cni.ActionStatus)
+	case "GC":
+		runCommand(cni.ActionGC)
+	default:
+		skel.PluginMain(
+			cni.ActionAdd.Request,
+			cni.ActionCheck.Request,
+			cni.ActionDel.Request,
+			cniversion.All,
+			fmt.Sprintf("Kuryr CNI %s", version.GetFullVersionWithRuntimeInfo()),
+		)
+	}
+}
+
+// runCommand runs a command which only takes the network configuration, like skel.PluginMain
+// does for the other commands.
+func runCommand(action cni.Action) {
+	stdinData, err := ioutil.ReadAll(os.Stdin)
+	if err == nil {
+		err = action.Request(&skel.CmdArgs{
+			Path:      os.Getenv("CNI_PATH"),
+			StdinData: stdinData,
+		})
+	} else {
+		err = &types.Error{
+			Code: uint(cnipb.ErrorCode_IO_FAILURE),
+			Msg:  fmt.Sprintf("error reading from stdin: %v", err),
+		}
+	}
+	if err == nil {
+		return
+	}
+	cniErr, ok := err.(*types.Error)
+	if !ok {
+		cniErr = &types.Error{Code: uint(cnipb.ErrorCode_UNKNOWN), Msg: err.Error()}
+	}
+	if err := cniErr.Print(); err != nil {
+		log.Print("Error writing error JSON to stdout: ", err)
+	}
+	os.Exit(1)
 }
@@ -1,6 +1,8 @@
 package app
 
 import (
+	"time"
+
 	componentbaseconfig "k8s.io/component-base/config"
 )
 
@@ -16,6 +18,19 @@ type ControllerConfig struct {
 	// Defaults to true.
 	EnablePrometheusMetrics bool `yaml:"enablePrometheusMetrics,omitempty"`
 
+	// PodDrainTimeout bounds how long a terminating pod keeps its Neutron port
+	// and its load balancer members (with weight 0) while its containers are
+	// still running.
+	// Defaults to 30s.
+	PodDrainTimeout time.Duration `yaml:"podDrainTimeout,omitempty"`
+	// Whether or not to record the OpenTelemetry spans of the networking of the Pods and export them
+	// to Jaeger. The spans of a Pod are in the trace of its UID. Defaults to false.
+	EnableTracing bool `yaml:"enableTracing,omitempty"`
+	// Endpoint of the Jaeger collector the spans are exported to, e.g.
+	// http://jaeger-collector:14268/api/traces. Defaults to the OTEL_EXPORTER_JAEGER_ENDPOINT
+	// environment variable, or else to http://localhost:14268/api/traces.
+	TracingCollectorEndpoint string `yaml:"tracingCollectorEndpoint,omitempty"`
+
 	ServiceCIDR   string    `yaml:"serviceCIDR,omitempty"`
 	ServiceCIDRv6 string    `yaml:"serviceCIDRv6,omitempty"`
 	Openstack     Openstack `yaml:"openstack"`
@@ -41,12 +56,17 @@ type NetworkResources struct {
 	PodSubnetId string `yaml:"podSubnet,omitempty"`
 	PodSubnetPool string `yaml:"podSubnetPool,omitempty"` // 配置了 subnet 就不需要改字段
 	PodSubnetCIDR string `yaml:"podSubnetCIDR,omitempty"` // 不需要用户配置，通过subnet反查
+	// 可选的 IPv6 subnet，必须与 podSubnet 在同一个 network，配置后 pod 为双栈
+	PodIPv6SubnetId string `yaml:"podIPv6Subnet,omitempty"`
+	PodIPv6SubnetCIDR string `yaml:"podIPv6SubnetCIDR,omitempty"` // 不需要用户配置，通过subnet反查
 	PodSgIds []string `yaml:"podSg,omitempty"`  // 如果没有配置，kns中sgs为空，使用默认安全组
 	PodRouterId string `yaml:"podRouter,omitempty"`      //
 	ProjectId string `yaml:"projectId,omitempty"`
 
 	SvcSubnetCIDR  string `yaml:"svcSubnetCIDR,omitempty"`
 	SvcSubnetId string `yaml:"svcSubnet,omitempty"`
+	// 分配 floating IP 的外部网络，未配置时只支持用户预先分配的 floating IP
+	ExternalNetId string `yaml:"externalNet,omitempty"`
 	OvsBridge string `yaml:"ovsBridge,omitempty"`
 	LinkIface string `yaml:"linkIface,omitempty"`
 }
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -20,6 +21,7 @@ import (
 	"k8s.io/klog"
 
 	"projectkuryr/kuryr/pkg/k8s"
+	"projectkuryr/kuryr/pkg/utils/tracing"
 	"projectkuryr/kuryr/pkg/version"
 
 	//kuryrv1alpha1 "projectkuryr/kuryr/pkg/apis/openstack/v1alpha1"
@@ -136,6 +138,13 @@ func GetHostname(hostnameOverride string) (string, error) {
 // run starts Kuryr Controller with the given options and waits for termination signal.
 func run(o *Options) error {
 	klog.Infof("Starting Kuryr Controller (version %s)", version.GetFullVersion())
+	if o.config.EnableTracing {
+		shutdownTracing, err := tracing.Init("kuryr-controller", o.config.TracingCollectorEndpoint)
+		if err != nil {
+			return fmt.Errorf("error initializing tracing: %v", err)
+		}
+		defer shutdownTracing(context.TODO())
+	}
 
 	LearnIndexer()
 
@@ -156,9 +165,11 @@ func run(o *Options) error {
 	nsInformer := informerFactory.Core().V1().Namespaces()
 	podInformer := informerFactory.Core().V1().Pods()
 	//networkPolicyInformer := informerFactory.Networking().V1().NetworkPolicies()
-	//serviceInformer := informerFactory.Core().V1().Services()
+	serviceInformer := informerFactory.Core().V1().Services()
+	endpointsInformer := informerFactory.Core().V1().Endpoints()
 	knsInformer := crdInformerFactory.Openstack().V1alpha1().KuryrNetworks()
 	kpInformer := crdInformerFactory.Openstack().V1alpha1().KuryrPorts()
+	klbInformer := crdInformerFactory.Openstack().V1alpha1().KuryrLoadBalancers()
 
 	// Add kuryr-controller types to the default Kubernetes Scheme so Events can be logged for sample-controller types.
 	utilruntime.Must(kuryrscheme.AddToScheme(scheme.Scheme)) //???????????????????????????????????????
@@ -188,11 +199,23 @@ func run(o *Options) error {
 		kpInformer,
 		recorder)
 
+	svcController := NewSvcController(o.config,
+		client,
+		crdClient,
+		osClient,
+		serviceInformer,
+		endpointsInformer,
+		podInformer,
+		knsInformer,
+		klbInformer,
+		recorder)
+
 	stopCh := wait.NeverStop
 	informerFactory.Start(stopCh)
 	crdInformerFactory.Start(stopCh)
 
 	go nsController.Run(1, stopCh)
+	go svcController.Run(1, stopCh)
 
 	<-stopCh
 	klog.Info("Stopping Kuryr controller")
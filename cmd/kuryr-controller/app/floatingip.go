@@ -0,0 +1,108 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/floatingips"
+	"k8s.io/klog"
+
+	kuryrv1alpha1 "projectkuryr/kuryr/pkg/apis/openstack/v1alpha1"
+	"projectkuryr/kuryr/pkg/openstack/openstackConfig"
+)
+
+// floatingIPDescription tags the floating IPs allocated by kuryr so that they
+// can be told apart from the ones pre-allocated by users.
+func floatingIPDescription(kind, namespace, name string) string {
+	return fmt.Sprintf("kuryr %s %s/%s", kind, namespace, name)
+}
+
+// allocateFloatingIP associates a floating IP with portID. A non-empty address
+// selects a floating IP pre-allocated by the user, otherwise a new one is
+// allocated from externalNetID. A floating IP already associated with the port
+// is adopted, so that retrying after a failed status update does not leak it.
+func allocateFloatingIP(osClient openstackConfig.Interface, externalNetID, address, portID, projectID, description string) (*kuryrv1alpha1.FloatingIPInfo, error) {
+	associated, err := osClient.ListFloatingIPs(floatingips.ListOpts{PortID: portID})
+	if err != nil {
+		return nil, err
+	}
+	if len(associated) > 0 {
+		fip := associated[0]
+		if address != "" && fip.FloatingIP != address {
+			return nil, fmt.Errorf("port %s already has floating IP %s associated", portID, fip.FloatingIP)
+		}
+		allocMethod := kuryrv1alpha1.FloatingIPAllocMethodUser
+		if address == "" && fip.Description == description {
+			allocMethod = kuryrv1alpha1.FloatingIPAllocMethodPool
+		}
+		klog.Infof("Adopt floating IP %s(%s) associated with port %s", fip.FloatingIP, fip.ID, portID)
+		return newFloatingIPInfo(&fip, allocMethod), nil
+	}
+
+	if address != "" {
+		fips, err := osClient.ListFloatingIPs(floatingips.ListOpts{FloatingIP: address})
+		if err != nil {
+			return nil, err
+		}
+		if len(fips) == 0 {
+			return nil, fmt.Errorf("floating IP %s not found", address)
+		}
+		if fips[0].PortID != "" {
+			return nil, fmt.Errorf("floating IP %s is already associated with port %s", address, fips[0].PortID)
+		}
+		fip, err := osClient.UpdateFloatingIP(fips[0].ID, floatingips.UpdateOpts{PortID: &portID})
+		if err != nil {
+			return nil, err
+		}
+		klog.Infof("Associate floating IP %s(%s) with port %s", fip.FloatingIP, fip.ID, portID)
+		return newFloatingIPInfo(fip, kuryrv1alpha1.FloatingIPAllocMethodUser), nil
+	}
+
+	if externalNetID == "" {
+		return nil, fmt.Errorf("no external network configured to allocate a floating IP from")
+	}
+	fip, err := osClient.CreateFloatingIP(floatingips.CreateOpts{
+		FloatingNetworkID: externalNetID,
+		PortID:            portID,
+		ProjectID:         projectID,
+		Description:       description,
+	})
+	if err != nil {
+		return nil, err
+	}
+	klog.Infof("Allocate floating IP %s(%s) for port %s", fip.FloatingIP, fip.ID, portID)
+	return newFloatingIPInfo(fip, kuryrv1alpha1.FloatingIPAllocMethodPool), nil
+}
+
+// releaseFloatingIP deletes a floating IP allocated by kuryr and only
+// disassociates one pre-allocated by the user. It is a no-op for nil.
+func releaseFloatingIP(osClient openstackConfig.Interface, info *kuryrv1alpha1.FloatingIPInfo) error {
+	if info == nil {
+		return nil
+	}
+	var err error
+	if info.AllocMethod == kuryrv1alpha1.FloatingIPAllocMethodPool {
+		err = osClient.DeleteFloatingIP(info.ID)
+	} else {
+		_, err = osClient.UpdateFloatingIP(info.ID, floatingips.UpdateOpts{PortID: new(string)})
+	}
+	if err != nil && !isOpenstackNotFound(err) {
+		return err
+	}
+	klog.Infof("Release floating IP %s(%s) from port %s", info.Address, info.ID, info.PortID)
+	return nil
+}
+
+func newFloatingIPInfo(fip *floatingips.FloatingIP, allocMethod string) *kuryrv1alpha1.FloatingIPInfo {
+	return &kuryrv1alpha1.FloatingIPInfo{
+		ID:          fip.ID,
+		Address:     fip.FloatingIP,
+		PortID:      fip.PortID,
+		AllocMethod: allocMethod,
+	}
+}
+
+func isOpenstackNotFound(err error) bool {
+	_, ok := err.(gophercloud.ErrDefault404)
+	return ok
+}
@@ -6,9 +6,11 @@ import (
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/portsbinding"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	v1 "k8s.io/client-go/informers/core/v1"
@@ -23,7 +25,10 @@ import (
 	kuryrinformers "projectkuryr/kuryr/pkg/client/informers/externalversions/openstack/v1alpha1"
 	kuryrlisters "projectkuryr/kuryr/pkg/client/listers/openstack/v1alpha1"
 	"projectkuryr/kuryr/pkg/openstack/openstackConfig"
+	"projectkuryr/kuryr/pkg/utils/tracing"
 	"reflect"
+	"regexp"
+	"strconv"
 	"time"
 )
 
@@ -47,7 +52,7 @@ type NsController struct {
 	kubeclientset 		kubernetes.Interface
 	// a clientset for our own API group
 	crdclientset 		kuryrclientset.Interface
-	osClient	 		*openstackConfig.OSClient
+	osClient	 		openstackConfig.Interface
 
 	nsLister 		 	corelisters.NamespaceLister
 	nsSynced 		 	cache.InformerSynced
@@ -80,7 +85,7 @@ func NewNsController(
 	config *ControllerConfig,
 	kubeClientset kubernetes.Interface,
 	crdClientset kuryrclientset.Interface,
-	osClient 	*openstackConfig.OSClient,
+	osClient 	openstackConfig.Interface,
 	nsInformer v1.NamespaceInformer,
 	knsInformer kuryrinformers.KuryrNetworkInformer,
 	podInformer 	v1.PodInformer,
@@ -150,7 +155,7 @@ func NewNsController(
 				return
 			}
 			if isFinalize(newKp) && containsString(newKp.Finalizers, FinalizerKuryrPort ) {
-				c.kpOnFinalize(newKp)
+				c.enqueueKp(newKp)
 			}
 		},
 	})
@@ -236,6 +241,7 @@ func (c *NsController) Run(threadiness int, stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
 	defer c.nsQueue.ShutDown()
 	defer c.podQueue.ShutDown()
+	defer c.internalKuryrPortQueue.ShutDown()
 
 	// Start the informer factories to begin populating the informer caches
 	klog.Info("Starting Namespaces & Pod controller")
@@ -250,6 +256,7 @@ func (c *NsController) Run(threadiness int, stopCh <-chan struct{}) {
 	for i := 0; i < threadiness; i++ {
 		go wait.Until(c.runWorker4Ns, time.Second, stopCh)
 		go wait.Until(c.runWorker4Pod, time.Second, stopCh)
+		go wait.Until(c.runWorker4Kp, time.Second, stopCh)
 	}
 
 	klog.Info("Started workers")
@@ -267,6 +274,58 @@ func (c *NsController) runWorker4Ns() {
 	}
 }
 
+func (c *NsController) runWorker4Kp() {
+	for c.processNextKpWorkItem() {
+	}
+}
+
+func (c *NsController) enqueueKp(kp *kuryrv1alpha1.KuryrPort) {
+	key, err := cache.MetaNamespaceKeyFunc(kp)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.internalKuryrPortQueue.Add(key)
+}
+
+// processNextKpWorkItem finalizes a KuryrPort, it is requeued until all of its
+// OpenStack resources are released.
+func (c *NsController) processNextKpWorkItem() bool {
+	key, shutdown := c.internalKuryrPortQueue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.internalKuryrPortQueue.Done(key)
+
+	if err := c.syncKuryrPort(key.(string)); err != nil {
+		// Put the item back on the workqueue to handle any transient errors.
+		c.internalKuryrPortQueue.AddRateLimited(key)
+		klog.Errorf("Failed to sync KuryrPort %s: %v", key, err)
+		return true
+	}
+	c.internalKuryrPortQueue.Forget(key)
+	return true
+}
+
+func (c *NsController) syncKuryrPort(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+	kp, err := c.kpLister.KuryrPorts(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if !isFinalize(kp) || !containsString(kp.Finalizers, FinalizerKuryrPort) {
+		return nil
+	}
+	return c.kpOnFinalize(kp)
+}
+
 func (c *NsController) processNextNamespaceWorkItem() bool {
 	key, shutdown := c.nsQueue.Get()
 	if shutdown {
@@ -424,6 +483,16 @@ func (c *NsController) kpSyncFromPod(pod *corev1.Pod) error{
 
 	if isFinalize(pod) {
 		if containsString(pod.Finalizers, FinalizerPod){
+			// 容器还在运行时保留 port，让负载均衡先摘除流量
+			if remaining := podDrainRemaining(pod, c.config.PodDrainTimeout, time.Now()); remaining > 0 {
+				klog.Infof("\tPod(%s/%s) is draining, deferring the KuryrPort removal for %v", pod.GetNamespace(), pod.GetName(), remaining)
+				key, err := cache.MetaNamespaceKeyFunc(pod)
+				if err != nil {
+					return err
+				}
+				c.podQueue.AddAfter(key, remaining)
+				return nil
+			}
 			klog.Infof("\tPod(%s/%s) is Finalize, removing the KuryrPort", pod.GetNamespace(), pod.GetName())
 			return c.PodOnFinalize(pod)
 		}
@@ -434,13 +503,87 @@ func (c *NsController) kpSyncFromPod(pod *corev1.Pod) error{
 	kp, err := c.kpLister.KuryrPorts(pod.Namespace).Get(pod.Name)
 	if err != nil {
 		if errors.IsNotFound(err) {
-			return c.newKuryrPort(pod)
+			if err := c.newKuryrPort(pod); err != nil {
+				return err
+			}
+			// port 刚创建，通常还不是 ACTIVE，稍后检查 readiness gate
+			if hasNetworkReadyGate(pod) {
+				key, err := cache.MetaNamespaceKeyFunc(pod)
+				if err != nil {
+					return err
+				}
+				c.podQueue.AddAfter(key, networkReadyPollInterval)
+			}
+			return nil
 		}
 	}else{
 		klog.Infof("\tGot KuryrPort:(%s/%s)\n", kp.GetNamespace(), kp.GetName())
 		// 是否需要更新 labels？
+		if err := c.syncPodFloatingIP(pod, kp); err != nil {
+			return err
+		}
+		return c.syncPodNetworkReady(pod, kp)
+	}
+
+	return nil
+}
+
+// syncPodFloatingIP associates or releases the floating IP of the default VIF
+// according to the AnnotationPodFloatingIP annotation of the pod.
+func (c *NsController) syncPodFloatingIP(pod *corev1.Pod, kp *kuryrv1alpha1.KuryrPort) error {
+	want := pod.Annotations[AnnotationPodFloatingIP]
+	cur := kp.Status.FloatingIP
+	if cur != nil {
+		if want == "true" && cur.AllocMethod == kuryrv1alpha1.FloatingIPAllocMethodPool {
+			return nil
+		}
+		if want != "" && want != "true" && cur.AllocMethod == kuryrv1alpha1.FloatingIPAllocMethodUser && cur.Address == want {
+			return nil
+		}
+	} else if want == "" {
+		return nil
+	}
+
+	kp = kp.DeepCopy()
+	if cur != nil {
+		if err := releaseFloatingIP(c.osClient, cur); err != nil {
+			return err
+		}
+		kp.Status.FloatingIP = nil
+		if want == "" {
+			return c.updateKp(kp)
+		}
 	}
 
+	if len(kp.Status.Vifs) == 0 {
+		return nil
+	}
+	portID := kp.Status.Vifs[0].Vif.ID
+	for _, vif := range kp.Status.Vifs {
+		if vif.IsDefault {
+			portID = vif.Vif.ID
+		}
+	}
+	address := want
+	if want == "true" {
+		address = ""
+	}
+	fip, err := allocateFloatingIP(c.osClient, c.config.Openstack.ExternalNetId, address, portID, kp.Status.ProjectId,
+		floatingIPDescription("pod", pod.Namespace, pod.Name))
+	if err != nil {
+		c.recorder.Eventf(pod, corev1.EventTypeWarning, FailedSynced, "Failed to associate floating IP: %v", err)
+		return err
+	}
+	kp.Status.FloatingIP = fip
+	if err := c.updateKp(kp); err != nil {
+		// 记录失败的 floating IP 不会在删除时释放
+		if releaseErr := releaseFloatingIP(c.osClient, fip); releaseErr != nil {
+			klog.Errorf("\tRelease floating IP %s of pod(%s/%s) Error: %v", fip.Address, pod.Namespace, pod.Name, releaseErr)
+			return utilerrors.NewAggregate([]error{err, releaseErr})
+		}
+		return err
+	}
+	klog.Infof("\tPod(%s/%s) floating IP %s", pod.Namespace, pod.Name, fip.Address)
 	return nil
 }
 
@@ -466,6 +609,42 @@ func isPodCompleted(pod *corev1.Pod) bool {
 	return false
 }
 
+// podContainersExited returns true once no container of the pod is running.
+func podContainersExited(pod *corev1.Pod) bool {
+	if isPodCompleted(pod) {
+		return true
+	}
+	for _, status := range pod.Status.InitContainerStatuses {
+		if status.State.Running != nil {
+			return false
+		}
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Running != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// podDrainRemaining returns how long the network of a terminating pod must be
+// kept, until its containers exit or drainTimeout has passed since the
+// deletion was requested.
+func podDrainRemaining(pod *corev1.Pod, drainTimeout time.Duration, now time.Time) time.Duration {
+	if pod.DeletionTimestamp == nil || podContainersExited(pod) {
+		return 0
+	}
+	// DeletionTimestamp 是宽限期结束的时间，往前推得到删除开始的时间
+	start := pod.DeletionTimestamp.Time
+	if pod.DeletionGracePeriodSeconds != nil {
+		start = start.Add(-time.Duration(*pod.DeletionGracePeriodSeconds) * time.Second)
+	}
+	if remaining := start.Add(drainTimeout).Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
 func (c *NsController) knsSyncFromNs(ns *corev1.Namespace) error{
 	/*
 		1. 查询当前是否有kns，没有创建
@@ -507,7 +686,61 @@ func (c *NsController) knsSyncFromNs(ns *corev1.Namespace) error{
 	return nil
 }
 
-func (c *NsController) newKuryrPort(pod *corev1.Pod) error{
+// pciSlotRegexp matches a PCI address, e.g. 0000:03:10.2.
+var pciSlotRegexp = regexp.MustCompile(`^[0-9a-fA-F]{4}:[0-9a-fA-F]{2}:[0-9a-fA-F]{2}\.[0-7]$`)
+
+// podPortBinding returns the vnic_type and the binding:profile of the Neutron port of the pod. A
+// pod gets a SR-IOV VF with a direct port carrying the PCI slot of the VF when it asks for it with
+// the vnicType and pciSlot annotations, and a tap with a normal port otherwise.
+func podPortBinding(annotations map[string]string) (string, map[string]interface{}, error) {
+	switch vnicType := annotations[AnnotationPodVNICType]; vnicType {
+	case "", kuryrv1alpha1.VNICTypeNormal:
+		return kuryrv1alpha1.VNICTypeNormal, nil, nil
+	case kuryrv1alpha1.VNICTypeDirect:
+		pciSlot := annotations[AnnotationPodPCISlot]
+		if !pciSlotRegexp.MatchString(pciSlot) {
+			return "", nil, fmt.Errorf("invalid %s annotation %q for vnic type %s", AnnotationPodPCISlot, pciSlot, vnicType)
+		}
+		return vnicType, map[string]interface{}{"pci_slot": pciSlot}, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported vnic type %q", vnicType)
+	}
+}
+
+// vifVlan returns the VLAN set by Neutron in the binding:vif_details of a direct port on a VLAN
+// network, 0 for the other ports.
+func vifVlan(vifDetails map[string]interface{}) int {
+	switch vlan := vifDetails["vlan"].(type) {
+	case string:
+		id, _ := strconv.Atoi(vlan)
+		return id
+	case float64:
+		return int(vlan)
+	}
+	return 0
+}
+
+// podSecurityGroups returns the security groups of the port of a pod, the AnnotationPodSg annotation
+// of the pod or else the security groups of its namespace. None means the default security group
+// of the project.
+func podSecurityGroups(annotations map[string]string, kns *kuryrv1alpha1.KuryrNetwork) []string {
+	if "" != annotations[AnnotationPodSg] {
+		return []string{annotations[AnnotationPodSg]}
+	}
+	if len(kns.Status.PodSgs) > 0 {
+		klog.Infof("Get sgs from kns: %v", kns.Status.PodSgs)
+		return kns.Status.PodSgs
+	}
+	return nil
+}
+
+// newKuryrPort creates the Neutron port and the KuryrPort of the Pod. Its span and the span of the
+// Neutron port are in the trace of the Pod UID, like the spans of the CNI requests of kuryr-agent.
+func (c *NsController) newKuryrPort(pod *corev1.Pod) (err error) {
+	ctx, span := tracing.Start(context.TODO(), string(pod.UID), "controller.newKuryrPort")
+	span.SetAttribute("pod", pod.Namespace+"/"+pod.Name)
+	defer func() { span.End(err) }()
+
 	kns, err := c.knsLister.KuryrNetworks(pod.Namespace).Get(pod.Namespace)
 	if err != nil {
 		klog.Errorf("get kns(%s/%s) failed %v", pod.Namespace, pod.Namespace, err)
@@ -527,14 +760,7 @@ func (c *NsController) newKuryrPort(pod *corev1.Pod) error{
 		fixedIP.IPAddress = annotations[AnnotationPodFixedIP]
 	}
 
-	var podSgs []string
-
-	if "" != annotations[AnnotationPodSg] {
-		podSgs = []string{annotations[AnnotationPodSg]}
-	}else if len(kns.Status.PodSgs) > 0 {
-		klog.Infof("Get sgs from kns: %v", kns.Status.PodSgs)
-		podSgs = kns.Status.PodSgs
-	}
+	podSgs := podSecurityGroups(annotations, kns)
 
 	podIfNameIsDefault := true
 	podIfName := c.config.Openstack.LinkIface
@@ -543,24 +769,34 @@ func (c *NsController) newKuryrPort(pod *corev1.Pod) error{
 		podIfNameIsDefault = false
 	}
 
+	// 双栈：同时在 IPv6 subnet 上申请地址，SLAAC subnet 的地址由 Neutron 根据 MAC 生成
+	fixedIPs := []ports.IP{fixedIP}
+	if kns.Status.PodIPv6SubnetId != "" {
+		fixedIPs = append(fixedIPs, ports.IP{SubnetID: kns.Status.PodIPv6SubnetId})
+	}
+
 	portCreateOpts := &ports.CreateOpts{
 		ProjectID: 		podProject,
 		NetworkID: 		podNetwork,
 		DeviceOwner:	OpenstackPortDeviceOwner,
 		AdminStateUp: gophercloud.Enabled,
-		FixedIPs:     []ports.IP{fixedIP},
+		FixedIPs:     fixedIPs,
 		//SecurityGroups: &[]string{},
 	}
 	if len(podSgs) > 0 {
 		klog.Infof("Create port with sgS: %v", podSgs)
 		portCreateOpts.SecurityGroups = &podSgs
 	}
-	//profile := map[string]interface{}{"foo": "bar"}
+	vnicType, profile, err := podPortBinding(annotations)
+	if err != nil {
+		klog.Errorf("Invalid port binding of pod(%s/%s): %v", pod.Namespace, pod.Name, err)
+		return err
+	}
 	createOpts := portsbinding.CreateOptsExt{
 		CreateOptsBuilder: portCreateOpts,
 		HostID:            pod.Spec.NodeName,
-		VNICType:		   "normal",
-		//Profile:           profile,
+		VNICType:		   vnicType,
+		Profile:           profile,
 	}
 
 	netExt, err :=  c.osClient.GetNetwork(portCreateOpts.NetworkID)
@@ -569,22 +805,35 @@ func (c *NsController) newKuryrPort(pod *corev1.Pod) error{
 		return err
 	}
 
-	subnet, err := c.osClient.GetSubnet(fixedIP.SubnetID)
-	if err != nil {
-		klog.Errorf("Get subnet(%v) Error: %v\n", fixedIP.SubnetID, err)
+	knownSubnets := map[string]*subnets.Subnet{}
+	for _, ip := range fixedIPs {
+		subnet, err := c.osClient.GetSubnet(ip.SubnetID)
+		if err != nil {
+			klog.Errorf("Get subnet(%v) Error: %v\n", ip.SubnetID, err)
 
-		return err
+			return err
+		}
+		knownSubnets[ip.SubnetID] = subnet
 	}
 
+	_, portSpan := tracing.Start(ctx, "", "controller.createPort")
+	portSpan.SetAttribute("network", portCreateOpts.NetworkID)
+	portSpan.SetAttribute("vnicType", vnicType)
 	portExt, err := c.osClient.CreatePort(createOpts)
+	if err == nil {
+		portSpan.SetAttribute("vif", portExt.ID)
+	}
+	portSpan.End(err)
 	if err != nil{
 		klog.Errorf("Create port (%v) Error: %v\n", createOpts, err)
 		return err
 	}
 
-	var ips []kuryrv1alpha1.IP
-	for _, ip := range  portExt.FixedIPs {
-		ips = append(ips, kuryrv1alpha1.IP{IPAddress: ip.IPAddress, SubnetID: ip.SubnetID})
+	vifSubnets, err := newVifSubnets(c.osClient, portExt.FixedIPs, knownSubnets)
+	if err != nil {
+		c.osClient.DeletePort(portExt.ID)
+		klog.Errorf("Get subnets of port(%s) Error: %v\n", portExt.ID, err)
+		return err
 	}
 
 	vif := kuryrv1alpha1.KuryrVif{
@@ -597,18 +846,15 @@ func (c *NsController) newKuryrPort(pod *corev1.Pod) error{
 			ID:             portExt.ID,
 			MACAddress:     portExt.MACAddress,
 			Plugin:         portExt.VIFType,
+			VNICType:       vnicType,
+			PCISlot:        annotations[AnnotationPodPCISlot],
+			Vlan:           vifVlan(portExt.VIFDetails),
 			//VIFType: portExt.VIFType,
 			SecurityGroups: portExt.SecurityGroups,
 			Network: kuryrv1alpha1.Network{
 				ID:  portExt.NetworkID,
 				MTU: netExt.MTU,
-				Subnets: []kuryrv1alpha1.Subnet{{
-					//ID:      subnet.ID,
-					Cidr:    subnet.CIDR,
-					Gateway: subnet.GatewayIP,
-					DNS:     subnet.DNSNameservers,
-					Ips: ips,
-				}},
+				Subnets: vifSubnets,
 			},
 		},
 	}
@@ -648,8 +894,62 @@ func (c *NsController) newKuryrPort(pod *corev1.Pod) error{
 	return err
 }
 
-func (c *NsController) kpOnFinalize(kp *kuryrv1alpha1.KuryrPort) {
+// newVifSubnet returns the subnet of a VIF with the IP version, host routes and
+// DNS nameservers of the Neutron subnet.
+func newVifSubnet(subnet *subnets.Subnet, ips []kuryrv1alpha1.IP) kuryrv1alpha1.Subnet {
+	var routes []kuryrv1alpha1.Route
+	for _, route := range subnet.HostRoutes {
+		routes = append(routes, kuryrv1alpha1.Route{Cidr: route.DestinationCIDR, Gateway: route.NextHop})
+	}
+	return kuryrv1alpha1.Subnet{
+		//ID:      subnet.ID,
+		Routes:          routes,
+		Ips:             ips,
+		IPVersion:       subnet.IPVersion,
+		IPv6AddressMode: subnet.IPv6AddressMode,
+		Cidr:            subnet.CIDR,
+		Gateway:         subnet.GatewayIP,
+		DNS:             subnet.DNSNameservers,
+	}
+}
+
+// newVifSubnets groups the fixed IPs of a port by subnet, in the order of the
+// fixed IPs. The subnets missing from knownSubnets are fetched from Neutron,
+// which adds the addresses of the SLAAC subnets of the network to the ports.
+func newVifSubnets(osClient openstackConfig.Interface, fixedIPs []ports.IP, knownSubnets map[string]*subnets.Subnet) ([]kuryrv1alpha1.Subnet, error) {
+	var vifSubnets []kuryrv1alpha1.Subnet
+	index := map[string]int{}
+	for _, fixedIP := range fixedIPs {
+		i, ok := index[fixedIP.SubnetID]
+		if !ok {
+			subnet, found := knownSubnets[fixedIP.SubnetID]
+			if !found {
+				var err error
+				if subnet, err = osClient.GetSubnet(fixedIP.SubnetID); err != nil {
+					return nil, err
+				}
+			}
+			i = len(vifSubnets)
+			index[fixedIP.SubnetID] = i
+			vifSubnets = append(vifSubnets, newVifSubnet(subnet, nil))
+		}
+		vifSubnets[i].Ips = append(vifSubnets[i].Ips, kuryrv1alpha1.IP{IPAddress: fixedIP.IPAddress, SubnetID: fixedIP.SubnetID})
+	}
+	return vifSubnets, nil
+}
+
+// kpOnFinalize releases the floating IP and the ports of a deleted KuryrPort,
+// then removes its finalizer. An error is returned when the floating IP can't
+// be released, the finalizer is kept so that it is released on retry.
+func (c *NsController) kpOnFinalize(kp *kuryrv1alpha1.KuryrPort) error {
 	klog.Infof("\tFinalizer KuryrPort(%s)\n", kp.GetName())
+	kp = kp.DeepCopy()
+
+	// Release the floating IP before its port.
+	if err := releaseFloatingIP(c.osClient, kp.Status.FloatingIP); err != nil {
+		klog.Errorf("\tRelease floating IP of KuryrPort(%s) Error: %v", kp.GetName(), err)
+		return err
+	}
 
 	// Release ports
 	for _, vif := range kp.Status.Vifs {
@@ -668,8 +968,11 @@ func (c *NsController) kpOnFinalize(kp *kuryrv1alpha1.KuryrPort) {
 
 	// Remove finalizer from KuryrPort.
 	kp.Finalizers = removeString(kp.Finalizers, FinalizerKuryrPort)
-	c.updateKp(kp)
+	if err := c.updateKp(kp); err != nil {
+		return err
+	}
 	klog.Infof("\tRemove KuryrPort Finalizers.")
+	return nil
 }
 
 //func (c *NsController) delKuryrPort(pod *corev1.Pod) error{
@@ -763,6 +1066,8 @@ func (c *NsController) newKuryrNetwork(ns *corev1.Namespace) (*kuryrv1alpha1.Kur
 			PodSgs: c.config.Openstack.PodSgIds,
 			PodSubnetId : c.config.Openstack.PodSubnetId,
 			PodSubnetCIDR: c.config.Openstack.PodSubnetCIDR,
+			PodIPv6SubnetId: c.config.Openstack.PodIPv6SubnetId,
+			PodIPv6SubnetCIDR: c.config.Openstack.PodIPv6SubnetCIDR,
 			PodNetId : c.config.Openstack.PodNetId,
 			SvcSubnetId: c.config.Openstack.SvcSubnetId,
 			SvcSubnetCIDR: c.config.Openstack.SvcSubnetCIDR,
@@ -785,6 +1090,21 @@ func (c *NsController) newKuryrNetwork(ns *corev1.Namespace) (*kuryrv1alpha1.Kur
 		kns.Status.PodSubnetCIDR = subnet.CIDR
 		kns.Status.PodNetId = subnet.NetworkID
 		//kns.Status.SvcSubnetCIDR 同一个环境中CIDR是相同的
+
+		kns.Status.PodIPv6SubnetId = ""
+		kns.Status.PodIPv6SubnetCIDR = ""
+		if ipv6SubnetId := annotations[AnnotationPodIPv6Subnet]; ipv6SubnetId != "" {
+			ipv6Subnet, err := c.osClient.GetSubnet(ipv6SubnetId)
+			if err == nil {
+				err = validatePodIPv6Subnet(ipv6Subnet, subnet.NetworkID)
+			}
+			if err != nil {
+				klog.Errorf("Get IPv6 subnet by id(%s) Failed. %v\n", ipv6SubnetId, err)
+				return nil, err
+			}
+			kns.Status.PodIPv6SubnetId = ipv6SubnetId
+			kns.Status.PodIPv6SubnetCIDR = ipv6Subnet.CIDR
+		}
 	}
 
 	return &kns, nil
@@ -0,0 +1,352 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/acceptance/openstack/networking/v2/extensions/mtu"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/portsbinding"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+
+	kuryrv1alpha1 "projectkuryr/kuryr/pkg/apis/openstack/v1alpha1"
+	crdfake "projectkuryr/kuryr/pkg/client/clientset/versioned/fake"
+	kuryrinformers "projectkuryr/kuryr/pkg/client/informers/externalversions"
+	"projectkuryr/kuryr/pkg/openstack/openstackConfig/fake"
+)
+
+func TestPodDrainRemaining(t *testing.T) {
+	now := time.Now()
+	grace := int64(30)
+	terminating := func(running bool, requested time.Time) *corev1.Pod {
+		pod := newTestPod("10.1.0.5", running)
+		deletion := metav1.NewTime(requested.Add(time.Duration(grace) * time.Second))
+		pod.DeletionTimestamp = &deletion
+		pod.DeletionGracePeriodSeconds = &grace
+		return pod
+	}
+	tests := []struct {
+		name     string
+		pod      *corev1.Pod
+		expected time.Duration
+	}{
+		{name: "not terminating", pod: newTestPod("10.1.0.5", true), expected: 0},
+		{name: "containers running", pod: terminating(true, now.Add(-10*time.Second)), expected: 50 * time.Second},
+		{name: "containers exited", pod: terminating(false, now.Add(-10*time.Second)), expected: 0},
+		{name: "drain timeout passed", pod: terminating(true, now.Add(-2*time.Minute)), expected: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, podDrainRemaining(tt.pod, time.Minute, now))
+		})
+	}
+}
+
+func TestNewVifSubnet(t *testing.T) {
+	ips := []kuryrv1alpha1.IP{{SubnetID: "subnet-6", IPAddress: "fd00:10::5"}}
+	subnet := &subnets.Subnet{
+		ID:              "subnet-6",
+		IPVersion:       6,
+		IPv6AddressMode: "slaac",
+		CIDR:            "fd00:10::/64",
+		GatewayIP:       "fd00:10::1",
+		DNSNameservers:  []string{"fd00::53"},
+		HostRoutes:      []subnets.HostRoute{{DestinationCIDR: "fd00:20::/64", NextHop: "fd00:10::fe"}},
+	}
+	expected := kuryrv1alpha1.Subnet{
+		Routes:          []kuryrv1alpha1.Route{{Cidr: "fd00:20::/64", Gateway: "fd00:10::fe"}},
+		Ips:             ips,
+		IPVersion:       6,
+		IPv6AddressMode: "slaac",
+		Cidr:            "fd00:10::/64",
+		Gateway:         "fd00:10::1",
+		DNS:             []string{"fd00::53"},
+	}
+	assert.Equal(t, expected, newVifSubnet(subnet, ips))
+}
+
+func TestNewVifSubnets(t *testing.T) {
+	osClient := fake.NewOSClient()
+	osClient.AddSubnet(subnets.Subnet{ID: "subnet-slaac", IPVersion: 6, IPv6AddressMode: "slaac", CIDR: "fd00:30::/64"})
+	knownSubnets := map[string]*subnets.Subnet{
+		"subnet-4": {ID: "subnet-4", IPVersion: 4, CIDR: "10.1.0.0/24", GatewayIP: "10.1.0.1"},
+		"subnet-6": {ID: "subnet-6", IPVersion: 6, IPv6AddressMode: "dhcpv6-stateful", CIDR: "fd00:10::/64", GatewayIP: "fd00:10::1"},
+	}
+	fixedIPs := []ports.IP{
+		{SubnetID: "subnet-4", IPAddress: "10.1.0.5"},
+		{SubnetID: "subnet-6", IPAddress: "fd00:10::5"},
+		{SubnetID: "subnet-4", IPAddress: "10.1.0.6"},
+		// Added by Neutron for the SLAAC subnet of the network.
+		{SubnetID: "subnet-slaac", IPAddress: "fd00:30::f816:3eff:fe00:1"},
+	}
+
+	vifSubnets, err := newVifSubnets(osClient, fixedIPs, knownSubnets)
+	assert.NoError(t, err)
+	assert.Len(t, vifSubnets, 3)
+	assert.Equal(t, "10.1.0.0/24", vifSubnets[0].Cidr)
+	assert.Equal(t, []kuryrv1alpha1.IP{{SubnetID: "subnet-4", IPAddress: "10.1.0.5"}, {SubnetID: "subnet-4", IPAddress: "10.1.0.6"}}, vifSubnets[0].Ips)
+	assert.Equal(t, 6, vifSubnets[1].IPVersion)
+	assert.Equal(t, "dhcpv6-stateful", vifSubnets[1].IPv6AddressMode)
+	assert.Equal(t, "fd00:10::1", vifSubnets[1].Gateway)
+	assert.Equal(t, "slaac", vifSubnets[2].IPv6AddressMode)
+	assert.Equal(t, []kuryrv1alpha1.IP{{SubnetID: "subnet-slaac", IPAddress: "fd00:30::f816:3eff:fe00:1"}}, vifSubnets[2].Ips)
+
+	_, err = newVifSubnets(osClient, []ports.IP{{SubnetID: "unknown"}}, nil)
+	assert.Error(t, err)
+}
+
+func TestValidatePodIPv6Subnet(t *testing.T) {
+	assert.NoError(t, validatePodIPv6Subnet(&subnets.Subnet{ID: "v6", IPVersion: 6, NetworkID: "pod-net"}, "pod-net"))
+	assert.Error(t, validatePodIPv6Subnet(&subnets.Subnet{ID: "v4", IPVersion: 4, NetworkID: "pod-net"}, "pod-net"))
+	assert.Error(t, validatePodIPv6Subnet(&subnets.Subnet{ID: "v6", IPVersion: 6, NetworkID: "other-net"}, "pod-net"))
+}
+
+func TestPodPortBinding(t *testing.T) {
+	tests := []struct {
+		name         string
+		annotations  map[string]string
+		wantVNICType string
+		wantProfile  map[string]interface{}
+		wantErr      bool
+	}{
+		{name: "default", wantVNICType: kuryrv1alpha1.VNICTypeNormal},
+		{name: "normal", annotations: map[string]string{AnnotationPodVNICType: "normal"}, wantVNICType: kuryrv1alpha1.VNICTypeNormal},
+		{
+			name:         "direct",
+			annotations:  map[string]string{AnnotationPodVNICType: "direct", AnnotationPodPCISlot: "0000:03:10.2"},
+			wantVNICType: kuryrv1alpha1.VNICTypeDirect,
+			wantProfile:  map[string]interface{}{"pci_slot": "0000:03:10.2"},
+		},
+		{name: "direct without PCI slot", annotations: map[string]string{AnnotationPodVNICType: "direct"}, wantErr: true},
+		{name: "direct with invalid PCI slot", annotations: map[string]string{AnnotationPodVNICType: "direct", AnnotationPodPCISlot: "03:10.2"}, wantErr: true},
+		{name: "unsupported", annotations: map[string]string{AnnotationPodVNICType: "macvtap"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vnicType, profile, err := podPortBinding(tt.annotations)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantVNICType, vnicType)
+			assert.Equal(t, tt.wantProfile, profile)
+		})
+	}
+}
+
+func TestVifVlan(t *testing.T) {
+	assert.Equal(t, 0, vifVlan(nil))
+	assert.Equal(t, 100, vifVlan(map[string]interface{}{"vlan": "100"}))
+	assert.Equal(t, 200, vifVlan(map[string]interface{}{"vlan": float64(200)}))
+}
+
+// newTestNsController returns a NsController whose KuryrNetwork lister has the
+// KuryrNetwork of the namespace of the pod, with sg-1 as security group.
+func newTestNsController(t *testing.T, config *ControllerConfig, osClient *fake.OSClient, pod *corev1.Pod,
+	kp *kuryrv1alpha1.KuryrPort) (*NsController, *k8sfake.Clientset, *crdfake.Clientset) {
+	kubeClient := k8sfake.NewSimpleClientset(pod)
+	crdClient := crdfake.NewSimpleClientset(kp)
+	informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+	crdInformerFactory := kuryrinformers.NewSharedInformerFactory(crdClient, 0)
+	knsInformer := crdInformerFactory.Openstack().V1alpha1().KuryrNetworks()
+	require.NoError(t, knsInformer.Informer().GetIndexer().Add(&kuryrv1alpha1.KuryrNetwork{
+		ObjectMeta: metav1.ObjectMeta{Name: pod.Namespace, Namespace: pod.Namespace},
+		Status:     kuryrv1alpha1.KuryrNetworkStatus{PodSgs: []string{"sg-1"}},
+	}))
+	c := NewNsController(config, kubeClient, crdClient, osClient,
+		informerFactory.Core().V1().Namespaces(), knsInformer, informerFactory.Core().V1().Pods(),
+		crdInformerFactory.Openstack().V1alpha1().KuryrPorts(), record.NewFakeRecorder(100))
+	return c, kubeClient, crdClient
+}
+
+func TestSyncPodFloatingIP(t *testing.T) {
+	tests := []struct {
+		name string
+		// annotation is the AnnotationPodFloatingIP annotation of the pod.
+		annotation  string
+		updateErr   bool
+		deleteErr   bool
+		expectErr   bool
+		expectFIPs  int
+		expectState bool
+	}{
+		{name: "pool floating IP", annotation: "true", expectFIPs: 1, expectState: true},
+		{name: "no annotation", expectFIPs: 0},
+		{name: "KuryrPort update failure releases the floating IP", annotation: "true", updateErr: true, expectErr: true, expectFIPs: 0},
+		{name: "release failure is returned", annotation: "true", updateErr: true, deleteErr: true, expectErr: true, expectFIPs: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			osClient := fake.NewOSClient()
+			for _, n := range []struct{ net, subnet, cidr string }{
+				{"pod-net", "pod-subnet", "10.1.0.0/24"},
+				{"ext-net", "ext-subnet", "172.24.4.0/24"},
+			} {
+				osClient.AddNetwork(mtu.NetworkMTU{Network: networks.Network{ID: n.net}})
+				osClient.AddSubnet(subnets.Subnet{ID: n.subnet, NetworkID: n.net, CIDR: n.cidr})
+			}
+			port, err := osClient.CreatePort(portsbinding.CreateOptsExt{CreateOptsBuilder: ports.CreateOpts{NetworkID: "pod-net"}})
+			require.NoError(t, err)
+
+			pod := newTestPod("10.1.0.5", true)
+			if tt.annotation != "" {
+				pod.Annotations = map[string]string{AnnotationPodFloatingIP: tt.annotation}
+			}
+			kp := &kuryrv1alpha1.KuryrPort{
+				ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+				Status: kuryrv1alpha1.KuryrPortStatus{Vifs: []kuryrv1alpha1.KuryrVif{
+					{IsDefault: true, Vif: kuryrv1alpha1.VIF{ID: port.ID}},
+				}},
+			}
+			config := &ControllerConfig{}
+			config.Openstack.ExternalNetId = "ext-net"
+			c, _, crdClient := newTestNsController(t, config, osClient, pod, kp)
+			if tt.updateErr {
+				crdClient.PrependReactor("update", "kuryrports", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("update failed")
+				})
+			}
+			if tt.deleteErr {
+				osClient.InjectError(fake.OpDeleteFloatingIP, errors.New("delete failed"))
+			}
+
+			err = c.syncPodFloatingIP(pod, kp)
+			if tt.expectErr {
+				require.Error(t, err)
+				if tt.deleteErr {
+					assert.Contains(t, err.Error(), "delete failed")
+				}
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Len(t, osClient.FloatingIPs(), tt.expectFIPs)
+			kp, err = crdClient.OpenstackV1alpha1().KuryrPorts(kp.Namespace).Get(context.TODO(), kp.Name, metav1.GetOptions{})
+			require.NoError(t, err)
+			if tt.expectState {
+				require.NotNil(t, kp.Status.FloatingIP)
+				assert.Equal(t, kuryrv1alpha1.FloatingIPAllocMethodPool, kp.Status.FloatingIP.AllocMethod)
+				assert.Equal(t, port.ID, kp.Status.FloatingIP.PortID)
+			} else {
+				assert.Nil(t, kp.Status.FloatingIP)
+			}
+		})
+	}
+}
+
+func TestKpOnFinalize(t *testing.T) {
+	osClient := fake.NewOSClient()
+	for _, n := range []struct{ net, subnet, cidr string }{
+		{"pod-net", "pod-subnet", "10.1.0.0/24"},
+		{"ext-net", "ext-subnet", "172.24.4.0/24"},
+	} {
+		osClient.AddNetwork(mtu.NetworkMTU{Network: networks.Network{ID: n.net}})
+		osClient.AddSubnet(subnets.Subnet{ID: n.subnet, NetworkID: n.net, CIDR: n.cidr})
+	}
+	port, err := osClient.CreatePort(portsbinding.CreateOptsExt{CreateOptsBuilder: ports.CreateOpts{NetworkID: "pod-net"}})
+	require.NoError(t, err)
+	fip, err := allocateFloatingIP(osClient, "ext-net", "", port.ID, "", floatingIPDescription("pod", "default", "pod1"))
+	require.NoError(t, err)
+
+	pod := newTestPod("10.1.0.5", false)
+	pod.Finalizers = []string{FinalizerPod}
+	now := metav1.Now()
+	kp := &kuryrv1alpha1.KuryrPort{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              pod.Name,
+			Namespace:         pod.Namespace,
+			Finalizers:        []string{FinalizerKuryrPort},
+			DeletionTimestamp: &now,
+		},
+		Status: kuryrv1alpha1.KuryrPortStatus{
+			Vifs:       []kuryrv1alpha1.KuryrVif{{IsDefault: true, Vif: kuryrv1alpha1.VIF{ID: port.ID}}},
+			FloatingIP: fip,
+		},
+	}
+	c, _, crdClient := newTestNsController(t, &ControllerConfig{}, osClient, pod, kp)
+	defer c.internalKuryrPortQueue.ShutDown()
+	getKp := func() *kuryrv1alpha1.KuryrPort {
+		kp, err := crdClient.OpenstackV1alpha1().KuryrPorts(kp.Namespace).Get(context.TODO(), kp.Name, metav1.GetOptions{})
+		require.NoError(t, err)
+		return kp
+	}
+
+	// The KuryrPort keeps its finalizer and its port while the floating IP
+	// can't be released.
+	osClient.InjectError(fake.OpDeleteFloatingIP, errors.New("delete failed"))
+	err = c.kpOnFinalize(kp)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "delete failed")
+	assert.Len(t, osClient.FloatingIPs(), 1)
+	_, err = osClient.GetPort(port.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{FinalizerKuryrPort}, getKp().Finalizers)
+
+	require.NoError(t, c.kpOnFinalize(kp))
+	assert.Empty(t, osClient.FloatingIPs())
+	_, err = osClient.GetPort(port.ID)
+	assert.Error(t, err)
+	assert.Empty(t, getKp().Finalizers)
+}
+
+func TestSyncPodNetworkReady(t *testing.T) {
+	osClient := fake.NewOSClient()
+	osClient.AddNetwork(mtu.NetworkMTU{Network: networks.Network{ID: "pod-net"}})
+	osClient.AddSubnet(subnets.Subnet{ID: "pod-subnet", NetworkID: "pod-net", CIDR: "10.1.0.0/24", GatewayIP: "10.1.0.1"})
+	port, err := osClient.CreatePort(portsbinding.CreateOptsExt{
+		CreateOptsBuilder: ports.CreateOpts{NetworkID: "pod-net", SecurityGroups: &[]string{"sg-1"}},
+	})
+	require.NoError(t, err)
+
+	pod := newTestPod("10.1.0.5", true)
+	pod.Spec.ReadinessGates = []corev1.PodReadinessGate{{ConditionType: ConditionNetworkReady}}
+	pod.Annotations = map[string]string{AnnotationPodSg: "sg-2"}
+	kp := &kuryrv1alpha1.KuryrPort{
+		ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		Status: kuryrv1alpha1.KuryrPortStatus{Vifs: []kuryrv1alpha1.KuryrVif{
+			{IsDefault: true, Vif: kuryrv1alpha1.VIF{ID: port.ID, Status: port.Status, SecurityGroups: port.SecurityGroups}},
+		}},
+	}
+	c, kubeClient, crdClient := newTestNsController(t, &ControllerConfig{}, osClient, pod, kp)
+
+	sync := func(expectedStatus corev1.ConditionStatus, expectedReason string) {
+		pod, err := kubeClient.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
+		require.NoError(t, err)
+		kp, err := crdClient.OpenstackV1alpha1().KuryrPorts(kp.Namespace).Get(context.TODO(), kp.Name, metav1.GetOptions{})
+		require.NoError(t, err)
+		require.NoError(t, c.syncPodNetworkReady(pod, kp))
+
+		pod, err = kubeClient.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
+		require.NoError(t, err)
+		cond := getPodCondition(pod, ConditionNetworkReady)
+		require.NotNil(t, cond)
+		assert.Equal(t, expectedStatus, cond.Status)
+		assert.Equal(t, expectedReason, cond.Reason)
+	}
+
+	sync(corev1.ConditionFalse, ReasonPortNotActive)
+	osClient.SetPortStatus(port.ID, "ACTIVE")
+	// The pod asks for sg-2 which is not attached to its port.
+	sync(corev1.ConditionFalse, ReasonSecurityGroupsNotAttached)
+
+	pod.Annotations = nil
+	_, err = kubeClient.CoreV1().Pods(pod.Namespace).Update(context.TODO(), pod, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	sync(corev1.ConditionTrue, ReasonNetworkReady)
+
+	kp, err = crdClient.OpenstackV1alpha1().KuryrPorts(kp.Namespace).Get(context.TODO(), kp.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "ACTIVE", kp.Status.Vifs[0].Vif.Status)
+}
@@ -3,6 +3,7 @@ package app
 import (
 	"fmt"
 	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"gopkg.in/yaml.v2"
@@ -12,11 +13,14 @@ import (
 	"projectkuryr/kuryr/pkg/apis"
 	"projectkuryr/kuryr/pkg/openstack/openstackConfig"
 	"projectkuryr/kuryr/pkg/version"
+	"time"
 )
 
 
 const OpenStackResourceUnsetDefaultVal = "UNSET"
 const DefaultIfName = "eth0"
+// 与 k8s 默认的 terminationGracePeriodSeconds 一致
+const DefaultPodDrainTimeout = 30 * time.Second
 
 const (
 	AnnotationCniType = "k8s.v1.cni.cncf.io/networks"
@@ -25,8 +29,17 @@ const (
 	AnnotationPodSg = "podSg"
 	AnnotationPodRouter = "podRouter"
 	AnnotationPodSubnet = "podSubnet"
+	// 可选，双栈 namespace 的 IPv6 subnet
+	AnnotationPodIPv6Subnet = "podIPv6Subnet"
 	AnnotationPodFixedIP = "fixedIP"
 	AnnotationPodIfName = "ifName"
+	// "true" allocates a floating IP from the external network, an IP address
+	// reuses a floating IP pre-allocated by the user.
+	AnnotationPodFloatingIP = "floatingIP"
+	// "direct" asks for a SR-IOV VF, pciSlot is then the PCI address of the VF
+	// on the node of the pod.
+	AnnotationPodVNICType = "vnicType"
+	AnnotationPodPCISlot = "pciSlot"
 
 	AnnotationPodCIDR = "podSubnetCIDR"
 	AnnotationPodNet = "podNet"
@@ -97,6 +110,10 @@ func (o *Options) setDefaults() {
 		o.config.APIPort = apis.KuryrControllerAPIPort
 	}
 
+	if o.config.PodDrainTimeout == 0 {
+		o.config.PodDrainTimeout = DefaultPodDrainTimeout
+	}
+
 	if o.config.Openstack.LinkIface == "" {
 		o.config.Openstack.LinkIface = "eth0"
 	}
@@ -129,9 +146,33 @@ func (o *Options) setDefaults() {
 			o.config.Openstack.PodRouterId = OpenStackResourceUnsetDefaultVal
 		}
 		o.config.Openstack.EnabledDefaultNetworkResources = true
+
+		if o.config.Openstack.PodIPv6SubnetId != "" {
+			ipv6Subnet, err := osClient.GetSubnet(o.config.Openstack.PodIPv6SubnetId)
+			if err == nil {
+				err = validatePodIPv6Subnet(ipv6Subnet, subnet.NetworkID)
+			}
+			if err != nil {
+				klog.Errorf("Invalid IPv6 pod subnet(%s), pods get IPv4 only: %v\n", o.config.Openstack.PodIPv6SubnetId, err)
+				o.config.Openstack.PodIPv6SubnetId = ""
+				return
+			}
+			o.config.Openstack.PodIPv6SubnetCIDR = ipv6Subnet.CIDR
+		}
 	}
 }
 
+// validatePodIPv6Subnet checks that the IPv6 subnet of dual-stack pods is on the pod network.
+func validatePodIPv6Subnet(subnet *subnets.Subnet, podNetID string) error {
+	if subnet.IPVersion != 6 {
+		return fmt.Errorf("subnet %s is not an IPv6 subnet", subnet.ID)
+	}
+	if subnet.NetworkID != podNetID {
+		return fmt.Errorf("subnet %s is not on the pod network %s", subnet.ID, podNetID)
+	}
+	return nil
+}
+
 // complete completes all the required options.
 func (o *Options) complete(args []string) error {
 	if len(o.configFile) > 0 {
@@ -0,0 +1,157 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+
+	kuryrv1alpha1 "projectkuryr/kuryr/pkg/apis/openstack/v1alpha1"
+)
+
+// ConditionNetworkReady is the condition of the readiness gate of the pods whose
+// network is managed by kuryr. A pod which lists it in spec.readinessGates is
+// only Ready, and so only an endpoint of its Services, once its VIFs are ACTIVE
+// and its security groups are attached to them.
+const ConditionNetworkReady corev1.PodConditionType = KURYR_FQDN + "/network-ready"
+
+// The reasons of the ConditionNetworkReady condition.
+const (
+	ReasonNetworkReady              = "NetworkReady"
+	ReasonPortNotActive             = "PortNotActive"
+	ReasonSecurityGroupsNotAttached = "SecurityGroupsNotAttached"
+)
+
+// networkReadyPollInterval is the interval of the checks of the Neutron ports of
+// a pod until its network is ready, nothing notifies kuryr of the port status.
+const networkReadyPollInterval = 5 * time.Second
+
+const neutronPortStatusActive = "ACTIVE"
+
+// hasNetworkReadyGate returns true if the pod has the ConditionNetworkReady
+// readiness gate.
+func hasNetworkReadyGate(pod *corev1.Pod) bool {
+	for _, gate := range pod.Spec.ReadinessGates {
+		if gate.ConditionType == ConditionNetworkReady {
+			return true
+		}
+	}
+	return false
+}
+
+func getPodCondition(pod *corev1.Pod, conditionType corev1.PodConditionType) *corev1.PodCondition {
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type == conditionType {
+			return &pod.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// checkVifReady returns the reason and the message of the ConditionNetworkReady
+// condition for a VIF, the reason is ReasonNetworkReady when it is ready.
+func checkVifReady(vif *kuryrv1alpha1.VIF, expectedSgs []string) (string, string) {
+	if vif.Status != neutronPortStatusActive {
+		return ReasonPortNotActive, fmt.Sprintf("port %s is %s", vif.ID, vif.Status)
+	}
+	for _, sg := range expectedSgs {
+		if !containsString(vif.SecurityGroups, sg) {
+			return ReasonSecurityGroupsNotAttached, fmt.Sprintf("security group %s is not attached to port %s", sg, vif.ID)
+		}
+	}
+	return ReasonNetworkReady, "all the VIFs are ACTIVE with their security groups"
+}
+
+// syncPodNetworkReady refreshes the status and the security groups of the VIFs
+// of the KuryrPort from Neutron and sets the ConditionNetworkReady condition of
+// the pod accordingly. The pod is checked again until its network is ready.
+func (c *NsController) syncPodNetworkReady(pod *corev1.Pod, kp *kuryrv1alpha1.KuryrPort) error {
+	if !hasNetworkReadyGate(pod) {
+		return nil
+	}
+	if cond := getPodCondition(pod, ConditionNetworkReady); cond != nil && cond.Status == corev1.ConditionTrue {
+		return nil
+	}
+
+	kns, err := c.knsLister.KuryrNetworks(pod.Namespace).Get(pod.Namespace)
+	if err != nil {
+		klog.Errorf("get kns(%s/%s) failed %v", pod.Namespace, pod.Namespace, err)
+		return err
+	}
+	expectedSgs := podSecurityGroups(pod.Annotations, kns)
+
+	newKp := kp.DeepCopy()
+	reason, message := ReasonPortNotActive, "the KuryrPort has no VIF"
+	for i := range newKp.Status.Vifs {
+		vif := &newKp.Status.Vifs[i].Vif
+		port, err := c.osClient.GetPort(vif.ID)
+		if err != nil {
+			klog.Errorf("Get port(%s) of pod(%s/%s) Error: %v", vif.ID, pod.Namespace, pod.Name, err)
+			return err
+		}
+		vif.Status = port.Status
+		vif.SecurityGroups = port.SecurityGroups
+		if reason, message = checkVifReady(vif, expectedSgs); reason != ReasonNetworkReady {
+			break
+		}
+	}
+	if !reflect.DeepEqual(kp.Status.Vifs, newKp.Status.Vifs) {
+		if err := c.updateKp(newKp); err != nil {
+			return err
+		}
+	}
+
+	ready := reason == ReasonNetworkReady
+	if err := c.setPodNetworkReady(pod, ready, reason, message); err != nil {
+		return err
+	}
+	if !ready {
+		key, err := cache.MetaNamespaceKeyFunc(pod)
+		if err != nil {
+			return err
+		}
+		klog.V(2).Infof("\tPod(%s/%s) network is not ready: %s", pod.Namespace, pod.Name, message)
+		c.podQueue.AddAfter(key, networkReadyPollInterval)
+		return nil
+	}
+	klog.Infof("\tPod(%s/%s) network is ready", pod.Namespace, pod.Name)
+	return nil
+}
+
+// setPodNetworkReady updates the ConditionNetworkReady condition of the pod if
+// it changed.
+func (c *NsController) setPodNetworkReady(pod *corev1.Pod, ready bool, reason, message string) error {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	if cond := getPodCondition(pod, ConditionNetworkReady); cond != nil &&
+		cond.Status == status && cond.Reason == reason && cond.Message == message {
+		return nil
+	}
+
+	pod = pod.DeepCopy()
+	now := metav1.Now()
+	cond := getPodCondition(pod, ConditionNetworkReady)
+	if cond == nil {
+		pod.Status.Conditions = append(pod.Status.Conditions, corev1.PodCondition{Type: ConditionNetworkReady})
+		cond = &pod.Status.Conditions[len(pod.Status.Conditions)-1]
+	}
+	if cond.Status != status {
+		cond.LastTransitionTime = now
+	}
+	cond.Status = status
+	cond.Reason = reason
+	cond.Message = message
+	cond.LastProbeTime = now
+	_, err := c.kubeclientset.CoreV1().Pods(pod.Namespace).UpdateStatus(context.TODO(), pod, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Errorf("Update condition %s of pod(%s/%s) Error: %v", ConditionNetworkReady, pod.Namespace, pod.Name, err)
+	}
+	return err
+}
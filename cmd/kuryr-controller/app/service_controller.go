@@ -0,0 +1,891 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/loadbalancers"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/pools"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	v1 "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+
+	kuryrv1alpha1 "projectkuryr/kuryr/pkg/apis/openstack/v1alpha1"
+	kuryrclientset "projectkuryr/kuryr/pkg/client/clientset/versioned"
+	kuryrinformers "projectkuryr/kuryr/pkg/client/informers/externalversions/openstack/v1alpha1"
+	kuryrlisters "projectkuryr/kuryr/pkg/client/listers/openstack/v1alpha1"
+	"projectkuryr/kuryr/pkg/openstack/openstackConfig"
+)
+
+const (
+	MessageResourceKlbSynced = "KuryrLoadBalancer synced successfully"
+
+	// lbPollInterval is how long to wait before looking again at a load
+	// balancer whose provisioning status is PENDING_*.
+	lbPollInterval = 5 * time.Second
+)
+
+// SvcController reconciles the Services of type LoadBalancer into Octavia
+// load balancers. The IDs of the Octavia objects and of the floating IP
+// associated with the VIP are kept in a KuryrLoadBalancer named after the
+// Service, so that everything can be released when the Service goes away.
+type SvcController struct {
+	config *ControllerConfig
+	// a standard kubernetes clientset
+	kubeclientset kubernetes.Interface
+	// a clientset for our own API group
+	crdclientset kuryrclientset.Interface
+	osClient     openstackConfig.Interface
+
+	svcLister corelisters.ServiceLister
+	svcSynced cache.InformerSynced
+
+	epLister corelisters.EndpointsLister
+	epSynced cache.InformerSynced
+
+	podLister corelisters.PodLister
+	podSynced cache.InformerSynced
+
+	knsLister kuryrlisters.KuryrNetworkLister
+	knsSynced cache.InformerSynced
+
+	klbLister kuryrlisters.KuryrLoadBalancerLister
+	klbSynced cache.InformerSynced
+
+	svcQueue workqueue.RateLimitingInterface
+
+	// recorder is an event recorder for recording Event resources to the Kubernetes API.
+	recorder record.EventRecorder
+}
+
+func NewSvcController(
+	config *ControllerConfig,
+	kubeClientset kubernetes.Interface,
+	crdClientset kuryrclientset.Interface,
+	osClient openstackConfig.Interface,
+	svcInformer v1.ServiceInformer,
+	epInformer v1.EndpointsInformer,
+	podInformer v1.PodInformer,
+	knsInformer kuryrinformers.KuryrNetworkInformer,
+	klbInformer kuryrinformers.KuryrLoadBalancerInformer,
+	recorder record.EventRecorder) *SvcController {
+
+	c := &SvcController{
+		config:        config,
+		kubeclientset: kubeClientset,
+		crdclientset:  crdClientset,
+		osClient:      osClient,
+		svcLister:     svcInformer.Lister(),
+		svcSynced:     svcInformer.Informer().HasSynced,
+		epLister:      epInformer.Lister(),
+		epSynced:      epInformer.Informer().HasSynced,
+		podLister:     podInformer.Lister(),
+		podSynced:     podInformer.Informer().HasSynced,
+		knsLister:     knsInformer.Lister(),
+		knsSynced:     knsInformer.Informer().HasSynced,
+		klbLister:     klbInformer.Lister(),
+		klbSynced:     klbInformer.Informer().HasSynced,
+		svcQueue:      workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(minRetryDelay, maxRetryDelay), "Service"),
+		recorder:      recorder,
+	}
+
+	klog.Info("Setting up event handlers for service")
+	svcInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			c.enqueue(newObj)
+		},
+		DeleteFunc: c.enqueue,
+	})
+
+	klog.Info("Setting up event handlers for endpoints")
+	epInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if oldObj.(*corev1.Endpoints).ResourceVersion == newObj.(*corev1.Endpoints).ResourceVersion {
+				return
+			}
+			c.enqueue(newObj)
+		},
+	})
+
+	klog.Info("Setting up event handlers for kuryrloadbalancer")
+	klbInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if isFinalize(newObj) {
+				c.enqueue(newObj)
+			}
+		},
+		DeleteFunc: c.enqueue,
+	})
+
+	return c
+}
+
+// enqueue adds the key of a Service, or of the Endpoints or KuryrLoadBalancer
+// named after it.
+func (c *SvcController) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.svcQueue.Add(key)
+}
+
+func (c *SvcController) Run(threadiness int, stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer c.svcQueue.ShutDown()
+
+	klog.Info("Starting Service controller")
+
+	klog.Info("Waiting for informer caches to sync")
+	if ok := cache.WaitForCacheSync(stopCh, c.svcSynced, c.epSynced, c.podSynced, c.knsSynced, c.klbSynced); !ok {
+		klog.Errorf("failed to wait for caches to sync")
+	}
+
+	klog.Info("Starting workers")
+	for i := 0; i < threadiness; i++ {
+		go wait.Until(c.runWorker4Svc, time.Second, stopCh)
+	}
+
+	klog.Info("Started workers")
+	<-stopCh
+	klog.Info("Shutting down workers")
+}
+
+func (c *SvcController) runWorker4Svc() {
+	for c.processNextSvcWorkItem() {
+	}
+}
+
+func (c *SvcController) processNextSvcWorkItem() bool {
+	key, shutdown := c.svcQueue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.svcQueue.Done(key)
+
+	if err := c.syncService(key.(string)); err != nil {
+		// Put the item back on the workqueue to handle any transient errors.
+		c.svcQueue.AddRateLimited(key)
+		klog.Errorf("Failed to sync Service %s: %v", key, err)
+		return true
+	}
+	c.svcQueue.Forget(key)
+	return true
+}
+
+func isLoadBalancerService(svc *corev1.Service) bool {
+	return svc.Spec.Type == corev1.ServiceTypeLoadBalancer
+}
+
+func lbName(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+func (c *SvcController) syncService(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+
+	svc, err := c.svcLister.Services(namespace).Get(name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		svc = nil
+	}
+	klb, err := c.klbLister.KuryrLoadBalancers(namespace).Get(name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		klb = nil
+	}
+
+	if svc == nil || !isLoadBalancerService(svc) || isFinalize(svc) {
+		if err := c.deleteLoadBalancer(klb); err != nil {
+			return err
+		}
+		return c.releaseService(svc)
+	}
+
+	if klb != nil && isFinalize(klb) {
+		// Someone deleted the KuryrLoadBalancer while the Service still needs
+		// a load balancer, release it and build a new one on the next sync.
+		if err := c.deleteLoadBalancer(klb); err != nil {
+			return err
+		}
+		c.svcQueue.AddAfter(key, lbPollInterval)
+		return nil
+	}
+
+	return c.ensureLoadBalancer(key, svc, klb)
+}
+
+// releaseService removes the finalizer and the ingress kuryr put on a Service
+// that no longer needs a load balancer.
+func (c *SvcController) releaseService(svc *corev1.Service) error {
+	if svc == nil {
+		return nil
+	}
+	if !isFinalize(svc) && len(svc.Status.LoadBalancer.Ingress) > 0 && containsString(svc.Finalizers, FinalizerSvc) {
+		svc = svc.DeepCopy()
+		svc.Status.LoadBalancer.Ingress = nil
+		updated, err := c.kubeclientset.CoreV1().Services(svc.Namespace).UpdateStatus(context.TODO(), svc, metav1.UpdateOptions{})
+		if err != nil {
+			return err
+		}
+		svc = updated
+	}
+	if !containsString(svc.Finalizers, FinalizerSvc) {
+		return nil
+	}
+	svc = svc.DeepCopy()
+	svc.Finalizers = removeString(svc.Finalizers, FinalizerSvc)
+	_, err := c.kubeclientset.CoreV1().Services(svc.Namespace).Update(context.TODO(), svc, metav1.UpdateOptions{})
+	return err
+}
+
+// deleteLoadBalancer releases the floating IP and the Octavia load balancer
+// recorded in klb, then deletes klb.
+func (c *SvcController) deleteLoadBalancer(klb *kuryrv1alpha1.KuryrLoadBalancer) error {
+	if klb == nil {
+		return nil
+	}
+	klb = klb.DeepCopy()
+	klog.Infof("\tRelease KuryrLoadBalancer(%s/%s)", klb.Namespace, klb.Name)
+
+	if klb.Status.ServicePubIP != nil {
+		if err := releaseFloatingIP(c.osClient, klb.Status.ServicePubIP); err != nil {
+			return err
+		}
+		klb.Status.ServicePubIP = nil
+		updated, err := c.updateKlb(klb)
+		if err != nil {
+			return err
+		}
+		klb = updated
+	}
+
+	if id := klb.Status.LoadBalancer.ID; id != "" {
+		if err := c.osClient.DeleteLoadBalancer(id, true); err != nil && !isOpenstackNotFound(err) {
+			return err
+		}
+		klog.Infof("\tDelete LoadBalancer(%s).", id)
+	}
+
+	if containsString(klb.Finalizers, FinalizerKuryrLB) {
+		klb.Finalizers = removeString(klb.Finalizers, FinalizerKuryrLB)
+		updated, err := c.updateKlb(klb)
+		if err != nil {
+			return err
+		}
+		klb = updated
+	}
+	if isFinalize(klb) {
+		return nil
+	}
+	err := c.crdclientset.OpenstackV1alpha1().KuryrLoadBalancers(klb.Namespace).Delete(context.TODO(), klb.Name, metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (c *SvcController) updateKlb(klb *kuryrv1alpha1.KuryrLoadBalancer) (*kuryrv1alpha1.KuryrLoadBalancer, error) {
+	return c.crdclientset.OpenstackV1alpha1().KuryrLoadBalancers(klb.Namespace).Update(context.TODO(), klb, metav1.UpdateOptions{})
+}
+
+// serviceSubnet returns the subnet the VIP is allocated from and the project
+// owning the load balancer, the namespace KuryrNetwork wins over the config.
+func (c *SvcController) serviceSubnet(namespace string) (subnetID, projectID string) {
+	subnetID = c.config.Openstack.SvcSubnetId
+	projectID = c.config.Openstack.ProjectId
+	if kns, err := c.knsLister.KuryrNetworks(namespace).Get(namespace); err == nil {
+		if kns.Status.SvcSubnetId != "" {
+			subnetID = kns.Status.SvcSubnetId
+		}
+		if kns.Spec.ProjectId != "" {
+			projectID = kns.Spec.ProjectId
+		}
+	}
+	return subnetID, projectID
+}
+
+// memberSubnet returns the pod subnet of the namespace, members are plugged
+// on it.
+func (c *SvcController) memberSubnet(namespace string) string {
+	if kns, err := c.knsLister.KuryrNetworks(namespace).Get(namespace); err == nil && kns.Status.PodSubnetId != "" {
+		return kns.Status.PodSubnetId
+	}
+	return c.config.Openstack.PodSubnetId
+}
+
+func (c *SvcController) newKuryrLoadBalancerSpec(svc *corev1.Service) (kuryrv1alpha1.KuryrLoadBalancerSpec, error) {
+	subnetID, projectID := c.serviceSubnet(svc.Namespace)
+	if subnetID == "" {
+		return kuryrv1alpha1.KuryrLoadBalancerSpec{}, fmt.Errorf("no service subnet configured for namespace %s", svc.Namespace)
+	}
+	spec := kuryrv1alpha1.KuryrLoadBalancerSpec{
+		Type:           string(svc.Spec.Type),
+		LoadBalancerIP: svc.Spec.LoadBalancerIP,
+		ProjectId:      projectID,
+		SubnetId:       subnetID,
+	}
+	for _, port := range svc.Spec.Ports {
+		if port.Protocol == corev1.ProtocolSCTP {
+			c.recorder.Eventf(svc, corev1.EventTypeWarning, FailedSynced, "Port %d: protocol SCTP is not supported by the load balancer", port.Port)
+			continue
+		}
+		spec.Ports = append(spec.Ports, kuryrv1alpha1.LBPort{
+			Name:     port.Name,
+			Protocol: string(port.Protocol),
+			Port:     port.Port,
+		})
+	}
+	return spec, nil
+}
+
+func (c *SvcController) ensureLoadBalancer(key string, svc *corev1.Service, klb *kuryrv1alpha1.KuryrLoadBalancer) error {
+	spec, err := c.newKuryrLoadBalancerSpec(svc)
+	if err != nil {
+		c.recorder.Event(svc, corev1.EventTypeWarning, FailedSynced, err.Error())
+		return err
+	}
+
+	if !containsString(svc.Finalizers, FinalizerSvc) {
+		svc = svc.DeepCopy()
+		svc.Finalizers = append(svc.Finalizers, FinalizerSvc)
+		if svc, err = c.kubeclientset.CoreV1().Services(svc.Namespace).Update(context.TODO(), svc, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+
+	if klb == nil {
+		klb = &kuryrv1alpha1.KuryrLoadBalancer{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       svc.Name,
+				Namespace:  svc.Namespace,
+				Labels:     svc.Labels,
+				Finalizers: []string{FinalizerKuryrLB},
+			},
+			Spec: spec,
+		}
+		if klb, err = c.crdclientset.OpenstackV1alpha1().KuryrLoadBalancers(svc.Namespace).Create(context.TODO(), klb, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+	} else if !reflect.DeepEqual(klb.Spec, spec) {
+		klb = klb.DeepCopy()
+		klb.Spec = spec
+		if klb, err = c.updateKlb(klb); err != nil {
+			return err
+		}
+	}
+
+	klb = klb.DeepCopy()
+	oldStatus := klb.Status.DeepCopy()
+	done, syncErr := c.syncLoadBalancer(svc, klb)
+	if !reflect.DeepEqual(*oldStatus, klb.Status) {
+		// Save whatever was done even on error, it must be released later.
+		if klb, err = c.updateKlb(klb); err != nil {
+			return err
+		}
+	}
+	if syncErr != nil {
+		return syncErr
+	}
+	if !done || hasDrainingMembers(&klb.Status) {
+		c.svcQueue.AddAfter(key, lbPollInterval)
+	}
+	if !done {
+		return nil
+	}
+
+	if err := c.updateServiceStatus(svc, klb); err != nil {
+		return err
+	}
+	klog.Infof("Successfully synced KuryrLoadBalancer(%s)", key)
+	return nil
+}
+
+// syncLoadBalancer makes at most one change to the Octavia load balancer, as
+// Octavia refuses any change until the previous one is provisioned, and
+// records it in klb.Status. It returns true once nothing is left to do.
+func (c *SvcController) syncLoadBalancer(svc *corev1.Service, klb *kuryrv1alpha1.KuryrLoadBalancer) (bool, error) {
+	status := &klb.Status
+	if status.LoadBalancer.ID == "" {
+		lb, err := c.findOrCreateLoadBalancer(klb)
+		if err != nil {
+			c.recorder.Eventf(svc, corev1.EventTypeWarning, FailedSynced, "Failed to create load balancer: %v", err)
+			return false, err
+		}
+		status.LoadBalancer = kuryrv1alpha1.LBInfo{
+			ID:       lb.ID,
+			Name:     lb.Name,
+			IP:       lb.VipAddress,
+			PortID:   lb.VipPortID,
+			Provider: lb.Provider,
+		}
+		return false, nil
+	}
+
+	lb, err := c.osClient.GetLoadBalancer(status.LoadBalancer.ID)
+	if err != nil {
+		if !isOpenstackNotFound(err) {
+			return false, err
+		}
+		klog.Warningf("LoadBalancer %s of Service %s/%s is gone, recreating it", status.LoadBalancer.ID, svc.Namespace, svc.Name)
+		if err := releaseFloatingIP(c.osClient, status.ServicePubIP); err != nil {
+			return false, err
+		}
+		*status = kuryrv1alpha1.KuryrLoadBalancerStatus{}
+		return false, nil
+	}
+
+	switch lb.ProvisioningStatus {
+	case openstackConfig.ProvisioningStatusActive:
+	case openstackConfig.ProvisioningStatusError:
+		c.recorder.Eventf(svc, corev1.EventTypeWarning, FailedSynced, "Load balancer %s is in ERROR status, recreating it", lb.ID)
+		if err := releaseFloatingIP(c.osClient, status.ServicePubIP); err != nil {
+			return false, err
+		}
+		status.ServicePubIP = nil
+		if err := c.osClient.DeleteLoadBalancer(lb.ID, true); err != nil && !isOpenstackNotFound(err) {
+			return false, err
+		}
+		*status = kuryrv1alpha1.KuryrLoadBalancerStatus{}
+		return false, nil
+	default:
+		klog.Infof("LoadBalancer %s of Service %s/%s is %s, waiting", lb.ID, svc.Namespace, svc.Name, lb.ProvisioningStatus)
+		return false, nil
+	}
+
+	for _, step := range []func(*corev1.Service, *kuryrv1alpha1.KuryrLoadBalancer, *loadbalancers.LoadBalancer) (bool, error){
+		c.syncListeners,
+		c.syncPools,
+		c.syncMembers,
+	} {
+		if changed, err := step(svc, klb, lb); err != nil || changed {
+			return false, err
+		}
+	}
+
+	if err := c.syncServiceFloatingIP(svc, klb, lb); err != nil {
+		c.recorder.Eventf(svc, corev1.EventTypeWarning, FailedSynced, "Failed to associate floating IP with load balancer %s: %v", lb.ID, err)
+		return false, err
+	}
+	return true, nil
+}
+
+// findOrCreateLoadBalancer adopts the load balancer created by a previous sync
+// whose result could not be recorded, or creates a new one.
+func (c *SvcController) findOrCreateLoadBalancer(klb *kuryrv1alpha1.KuryrLoadBalancer) (*loadbalancers.LoadBalancer, error) {
+	name := lbName(klb.Namespace, klb.Name)
+	lbs, err := c.osClient.ListLoadBalancers(loadbalancers.ListOpts{Name: name})
+	if err != nil {
+		return nil, err
+	}
+	for i := range lbs {
+		if lbs[i].ProvisioningStatus == openstackConfig.ProvisioningStatusPendingDelete ||
+			lbs[i].ProvisioningStatus == openstackConfig.ProvisioningStatusError {
+			continue
+		}
+		klog.Infof("Adopt LoadBalancer %s for %s", lbs[i].ID, name)
+		return &lbs[i], nil
+	}
+	lb, err := c.osClient.CreateLoadBalancer(loadbalancers.CreateOpts{
+		Name:        name,
+		Description: "kuryr " + name,
+		VipSubnetID: klb.Spec.SubnetId,
+		ProjectID:   klb.Spec.ProjectId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	klog.Infof("Create LoadBalancer %s(%s) vip %s", name, lb.ID, lb.VipAddress)
+	return lb, nil
+}
+
+func findListener(status *kuryrv1alpha1.KuryrLoadBalancerStatus, protocol string, port int32) *kuryrv1alpha1.LBListener {
+	for i := range status.Listeners {
+		if status.Listeners[i].Protocol == protocol && status.Listeners[i].Port == port {
+			return &status.Listeners[i]
+		}
+	}
+	return nil
+}
+
+func findPool(status *kuryrv1alpha1.KuryrLoadBalancerStatus, listenerID string) *kuryrv1alpha1.LBPool {
+	for i := range status.Pools {
+		if status.Pools[i].ListenerID == listenerID {
+			return &status.Pools[i]
+		}
+	}
+	return nil
+}
+
+func hasPort(ports []kuryrv1alpha1.LBPort, protocol string, port int32) bool {
+	for _, p := range ports {
+		if p.Protocol == protocol && p.Port == port {
+			return true
+		}
+	}
+	return false
+}
+
+// syncListeners adopts the listeners missing from the status, creates one
+// listener per Service port and deletes the stale ones with their pool.
+func (c *SvcController) syncListeners(svc *corev1.Service, klb *kuryrv1alpha1.KuryrLoadBalancer, lb *loadbalancers.LoadBalancer) (bool, error) {
+	status := &klb.Status
+	adopted := false
+	for _, l := range lb.Listeners {
+		known := false
+		for _, sl := range status.Listeners {
+			known = known || sl.ID == l.ID
+		}
+		if known {
+			continue
+		}
+		listener, err := c.osClient.GetListener(l.ID)
+		if err != nil {
+			return false, err
+		}
+		status.Listeners = append(status.Listeners, kuryrv1alpha1.LBListener{
+			ID:       listener.ID,
+			Name:     listener.Name,
+			Protocol: listener.Protocol,
+			Port:     int32(listener.ProtocolPort),
+		})
+		if listener.DefaultPoolID != "" && findPool(status, listener.ID) == nil {
+			status.Pools = append(status.Pools, kuryrv1alpha1.LBPool{ID: listener.DefaultPoolID, ListenerID: listener.ID})
+		}
+		adopted = true
+	}
+	if adopted {
+		return true, nil
+	}
+
+	for _, port := range klb.Spec.Ports {
+		if findListener(status, port.Protocol, port.Port) != nil {
+			continue
+		}
+		name := fmt.Sprintf("%s:%s:%d", lbName(klb.Namespace, klb.Name), port.Protocol, port.Port)
+		listener, err := c.osClient.CreateListener(listeners.CreateOpts{
+			LoadbalancerID: lb.ID,
+			Name:           name,
+			Protocol:       listeners.Protocol(port.Protocol),
+			ProtocolPort:   int(port.Port),
+			ProjectID:      klb.Spec.ProjectId,
+		})
+		if err != nil {
+			return false, err
+		}
+		klog.Infof("Create Listener %s(%s)", name, listener.ID)
+		status.Listeners = append(status.Listeners, kuryrv1alpha1.LBListener{
+			ID:       listener.ID,
+			Name:     name,
+			Protocol: port.Protocol,
+			Port:     port.Port,
+		})
+		return true, nil
+	}
+
+	for i, l := range status.Listeners {
+		if hasPort(klb.Spec.Ports, l.Protocol, l.Port) {
+			continue
+		}
+		// Octavia does not delete the default pool with its listener.
+		if pool := findPool(status, l.ID); pool != nil {
+			if err := c.osClient.DeletePool(pool.ID); err != nil && !isOpenstackNotFound(err) {
+				return false, err
+			}
+			klog.Infof("Delete Pool %s of Listener %s", pool.ID, l.Name)
+			removePool(status, pool.ID)
+			return true, nil
+		}
+		if err := c.osClient.DeleteListener(l.ID); err != nil && !isOpenstackNotFound(err) {
+			return false, err
+		}
+		klog.Infof("Delete Listener %s(%s)", l.Name, l.ID)
+		status.Listeners = append(status.Listeners[:i], status.Listeners[i+1:]...)
+		return true, nil
+	}
+	return false, nil
+}
+
+func removePool(status *kuryrv1alpha1.KuryrLoadBalancerStatus, poolID string) {
+	var poolsLeft []kuryrv1alpha1.LBPool
+	for _, p := range status.Pools {
+		if p.ID != poolID {
+			poolsLeft = append(poolsLeft, p)
+		}
+	}
+	status.Pools = poolsLeft
+	var membersLeft []kuryrv1alpha1.LBMember
+	for _, m := range status.Members {
+		if m.PoolID != poolID {
+			membersLeft = append(membersLeft, m)
+		}
+	}
+	status.Members = membersLeft
+}
+
+// syncPools creates the default pool of every listener.
+func (c *SvcController) syncPools(svc *corev1.Service, klb *kuryrv1alpha1.KuryrLoadBalancer, lb *loadbalancers.LoadBalancer) (bool, error) {
+	status := &klb.Status
+	for _, l := range status.Listeners {
+		if findPool(status, l.ID) != nil {
+			continue
+		}
+		pool, err := c.osClient.CreatePool(pools.CreateOpts{
+			ListenerID: l.ID,
+			Name:       l.Name,
+			Protocol:   pools.Protocol(l.Protocol),
+			LBMethod:   pools.LBMethodRoundRobin,
+			ProjectID:  klb.Spec.ProjectId,
+		})
+		if err != nil {
+			return false, err
+		}
+		klog.Infof("Create Pool %s(%s)", l.Name, pool.ID)
+		status.Pools = append(status.Pools, kuryrv1alpha1.LBPool{ID: pool.ID, ListenerID: l.ID})
+		return true, nil
+	}
+	return false, nil
+}
+
+// desiredMembers returns the members each pool should have according to the
+// Endpoints of the Service.
+func (c *SvcController) desiredMembers(svc *corev1.Service, klb *kuryrv1alpha1.KuryrLoadBalancer) (map[string][]kuryrv1alpha1.LBMember, error) {
+	desired := map[string][]kuryrv1alpha1.LBMember{}
+	ep, err := c.epLister.Endpoints(svc.Namespace).Get(svc.Name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return desired, nil
+		}
+		return nil, err
+	}
+	for _, port := range klb.Spec.Ports {
+		listener := findListener(&klb.Status, port.Protocol, port.Port)
+		if listener == nil {
+			continue
+		}
+		pool := findPool(&klb.Status, listener.ID)
+		if pool == nil {
+			continue
+		}
+		for _, subset := range ep.Subsets {
+			for _, epPort := range subset.Ports {
+				if epPort.Name != port.Name || string(epPort.Protocol) != port.Protocol {
+					continue
+				}
+				for _, addr := range subset.Addresses {
+					member := kuryrv1alpha1.LBMember{PoolID: pool.ID, IP: addr.IP, Port: epPort.Port}
+					if addr.TargetRef != nil && addr.TargetRef.Kind == "Pod" {
+						member.PodName = addr.TargetRef.Name
+					}
+					desired[pool.ID] = append(desired[pool.ID], member)
+				}
+			}
+		}
+	}
+	return desired, nil
+}
+
+// syncMembers reads the members of every pool from Octavia, then adds the
+// missing endpoints and removes the stale members one at a time.
+func (c *SvcController) syncMembers(svc *corev1.Service, klb *kuryrv1alpha1.KuryrLoadBalancer, lb *loadbalancers.LoadBalancer) (bool, error) {
+	status := &klb.Status
+	desired, err := c.desiredMembers(svc, klb)
+	if err != nil {
+		return false, err
+	}
+
+	var current []kuryrv1alpha1.LBMember
+	for _, pool := range status.Pools {
+		members, err := c.osClient.ListMembers(pool.ID)
+		if err != nil {
+			return false, err
+		}
+		for _, m := range members {
+			member := kuryrv1alpha1.LBMember{ID: m.ID, PoolID: pool.ID, IP: m.Address, Port: int32(m.ProtocolPort)}
+			for _, known := range status.Members {
+				if known.ID == m.ID {
+					member.PodName = known.PodName
+					member.DrainingSince = known.DrainingSince
+				}
+			}
+			if m.Weight != 0 {
+				member.DrainingSince = nil
+			} else if member.DrainingSince == nil {
+				now := metav1.Now()
+				member.DrainingSince = &now
+			}
+			current = append(current, member)
+		}
+	}
+	status.Members = current
+
+	for _, pool := range status.Pools {
+		for _, want := range desired[pool.ID] {
+			if member := findMember(status.Members, want); member != nil {
+				if member.DrainingSince == nil {
+					continue
+				}
+				// The endpoint is back, e.g. after a readiness probe flap.
+				weight := 1
+				if _, err := c.osClient.UpdateMember(pool.ID, member.ID, pools.UpdateMemberOpts{Weight: &weight}); err != nil {
+					return false, err
+				}
+				klog.Infof("Restore Member %s:%d(%s) in Pool %s", member.IP, member.Port, member.ID, pool.ID)
+				member.DrainingSince = nil
+				return true, nil
+			}
+			name := want.PodName
+			if name == "" {
+				name = want.IP
+			}
+			member, err := c.osClient.CreateMember(pool.ID, pools.CreateMemberOpts{
+				Name:         name,
+				Address:      want.IP,
+				ProtocolPort: int(want.Port),
+				SubnetID:     c.memberSubnet(svc.Namespace),
+				ProjectID:    klb.Spec.ProjectId,
+			})
+			if err != nil {
+				return false, err
+			}
+			klog.Infof("Create Member %s:%d(%s) in Pool %s", want.IP, want.Port, member.ID, pool.ID)
+			want.ID = member.ID
+			status.Members = append(status.Members, want)
+			return true, nil
+		}
+	}
+
+	for i, m := range status.Members {
+		if findMember(desired[m.PoolID], m) != nil {
+			continue
+		}
+		// Stop sending new connections to the member but let the established
+		// ones finish while the pod is terminating.
+		if m.DrainingSince == nil {
+			weight := 0
+			if _, err := c.osClient.UpdateMember(m.PoolID, m.ID, pools.UpdateMemberOpts{Weight: &weight}); err != nil {
+				return false, err
+			}
+			klog.Infof("Drain Member %s:%d(%s) in Pool %s", m.IP, m.Port, m.ID, m.PoolID)
+			now := metav1.Now()
+			status.Members[i].DrainingSince = &now
+			return true, nil
+		}
+		if !c.memberDrained(svc.Namespace, &m) {
+			continue
+		}
+		if err := c.osClient.DeleteMember(m.PoolID, m.ID); err != nil && !isOpenstackNotFound(err) {
+			return false, err
+		}
+		klog.Infof("Delete Member %s:%d(%s) from Pool %s", m.IP, m.Port, m.ID, m.PoolID)
+		status.Members = append(status.Members[:i], status.Members[i+1:]...)
+		return true, nil
+	}
+	return false, nil
+}
+
+// memberDrained returns true once the pod behind a draining member has exited
+// or the drain timeout has passed.
+func (c *SvcController) memberDrained(namespace string, m *kuryrv1alpha1.LBMember) bool {
+	if time.Since(m.DrainingSince.Time) >= c.config.PodDrainTimeout {
+		return true
+	}
+	if m.PodName == "" {
+		return false
+	}
+	pod, err := c.podLister.Pods(namespace).Get(m.PodName)
+	if err != nil {
+		return errors.IsNotFound(err)
+	}
+	return podContainersExited(pod)
+}
+
+func hasDrainingMembers(status *kuryrv1alpha1.KuryrLoadBalancerStatus) bool {
+	for _, m := range status.Members {
+		if m.DrainingSince != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func findMember(members []kuryrv1alpha1.LBMember, want kuryrv1alpha1.LBMember) *kuryrv1alpha1.LBMember {
+	for i := range members {
+		if members[i].PoolID == want.PoolID && members[i].IP == want.IP && members[i].Port == want.Port {
+			return &members[i]
+		}
+	}
+	return nil
+}
+
+// syncServiceFloatingIP associates spec.loadBalancerIP with the VIP port, or a
+// floating IP allocated from the external network when none is requested.
+func (c *SvcController) syncServiceFloatingIP(svc *corev1.Service, klb *kuryrv1alpha1.KuryrLoadBalancer, lb *loadbalancers.LoadBalancer) error {
+	status := &klb.Status
+	want := klb.Spec.LoadBalancerIP
+	if cur := status.ServicePubIP; cur != nil {
+		if want != "" && cur.AllocMethod == kuryrv1alpha1.FloatingIPAllocMethodUser && cur.Address == want {
+			return nil
+		}
+		if want == "" && cur.AllocMethod == kuryrv1alpha1.FloatingIPAllocMethodPool {
+			return nil
+		}
+		if err := releaseFloatingIP(c.osClient, cur); err != nil {
+			return err
+		}
+		status.ServicePubIP = nil
+	}
+	if want == "" && c.config.Openstack.ExternalNetId == "" {
+		return nil
+	}
+	fip, err := allocateFloatingIP(c.osClient, c.config.Openstack.ExternalNetId, want, lb.VipPortID, klb.Spec.ProjectId,
+		floatingIPDescription("service", klb.Namespace, klb.Name))
+	if err != nil {
+		return err
+	}
+	status.ServicePubIP = fip
+	return nil
+}
+
+// updateServiceStatus publishes the floating IP, or the VIP when there is
+// none, as the Service ingress.
+func (c *SvcController) updateServiceStatus(svc *corev1.Service, klb *kuryrv1alpha1.KuryrLoadBalancer) error {
+	ip := klb.Status.LoadBalancer.IP
+	if klb.Status.ServicePubIP != nil {
+		ip = klb.Status.ServicePubIP.Address
+	}
+	ingress := []corev1.LoadBalancerIngress{{IP: ip}}
+	if reflect.DeepEqual(svc.Status.LoadBalancer.Ingress, ingress) {
+		return nil
+	}
+	svc = svc.DeepCopy()
+	svc.Status.LoadBalancer.Ingress = ingress
+	if _, err := c.kubeclientset.CoreV1().Services(svc.Namespace).UpdateStatus(context.TODO(), svc, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	c.recorder.Event(svc, corev1.EventTypeNormal, SuccessSynced, MessageResourceKlbSynced)
+	return nil
+}
@@ -0,0 +1,359 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/acceptance/openstack/networking/v2/extensions/mtu"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/floatingips"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	crdfake "projectkuryr/kuryr/pkg/client/clientset/versioned/fake"
+	kuryrinformers "projectkuryr/kuryr/pkg/client/informers/externalversions"
+	"projectkuryr/kuryr/pkg/openstack/openstackConfig/fake"
+)
+
+const testSvcKey = "default/web"
+
+type svcControllerTest struct {
+	*SvcController
+	kubeClient *k8sfake.Clientset
+	crdClient  *crdfake.Clientset
+	osClient   *fake.OSClient
+	indexers   map[string]cache.Indexer
+}
+
+func newTestService(loadBalancerIP string) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Type:           corev1.ServiceTypeLoadBalancer,
+			LoadBalancerIP: loadBalancerIP,
+			Ports:          []corev1.ServicePort{{Name: "http", Protocol: corev1.ProtocolTCP, Port: 80}},
+		},
+	}
+}
+
+func newTestEndpoints(ips ...string) *corev1.Endpoints {
+	subset := corev1.EndpointSubset{Ports: []corev1.EndpointPort{{Name: "http", Protocol: corev1.ProtocolTCP, Port: 8080}}}
+	for _, ip := range ips {
+		subset.Addresses = append(subset.Addresses, corev1.EndpointAddress{
+			IP:        ip,
+			TargetRef: &corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "web-" + ip},
+		})
+	}
+	return &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Subsets:    []corev1.EndpointSubset{subset},
+	}
+}
+
+func newSvcControllerTest(externalNet string, objs ...*corev1.Service) *svcControllerTest {
+	osClient := fake.NewOSClient()
+	osClient.PendingPolls = 0
+	for _, n := range []struct{ net, subnet, cidr, gateway string }{
+		{"svc-net", "svc-subnet", "10.0.0.0/24", "10.0.0.1"},
+		{"pod-net", "pod-subnet", "10.1.0.0/24", "10.1.0.1"},
+		{"ext-net", "ext-subnet", "172.24.4.0/24", "172.24.4.1"},
+	} {
+		osClient.AddNetwork(mtu.NetworkMTU{Network: networks.Network{ID: n.net}})
+		osClient.AddSubnet(subnets.Subnet{ID: n.subnet, NetworkID: n.net, CIDR: n.cidr, GatewayIP: n.gateway})
+	}
+
+	kubeClient := k8sfake.NewSimpleClientset()
+	for _, svc := range objs {
+		kubeClient.CoreV1().Services(svc.Namespace).Create(context.TODO(), svc, metav1.CreateOptions{})
+	}
+	crdClient := crdfake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+	crdInformerFactory := kuryrinformers.NewSharedInformerFactory(crdClient, 0)
+	svcInformer := informerFactory.Core().V1().Services()
+	epInformer := informerFactory.Core().V1().Endpoints()
+	podInformer := informerFactory.Core().V1().Pods()
+	knsInformer := crdInformerFactory.Openstack().V1alpha1().KuryrNetworks()
+	klbInformer := crdInformerFactory.Openstack().V1alpha1().KuryrLoadBalancers()
+
+	config := &ControllerConfig{}
+	config.Openstack.SvcSubnetId = "svc-subnet"
+	config.Openstack.PodSubnetId = "pod-subnet"
+	config.Openstack.ProjectId = "project"
+	config.Openstack.ExternalNetId = externalNet
+	config.PodDrainTimeout = time.Minute
+
+	c := NewSvcController(config, kubeClient, crdClient, osClient,
+		svcInformer, epInformer, podInformer, knsInformer, klbInformer, record.NewFakeRecorder(100))
+	return &svcControllerTest{
+		SvcController: c,
+		kubeClient:    kubeClient,
+		crdClient:     crdClient,
+		osClient:      osClient,
+		indexers: map[string]cache.Indexer{
+			"services":           svcInformer.Informer().GetIndexer(),
+			"endpoints":          epInformer.Informer().GetIndexer(),
+			"pods":               podInformer.Informer().GetIndexer(),
+			"kuryrloadbalancers": klbInformer.Informer().GetIndexer(),
+		},
+	}
+}
+
+// refresh copies the objects of the fake clientsets into the listers, as the
+// informers are not started.
+func (c *svcControllerTest) refresh(t *testing.T) {
+	svcs, err := c.kubeClient.CoreV1().Services("").List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	eps, err := c.kubeClient.CoreV1().Endpoints("").List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	pods, err := c.kubeClient.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	klbs, err := c.crdClient.OpenstackV1alpha1().KuryrLoadBalancers("").List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+
+	var objs []interface{}
+	for i := range svcs.Items {
+		objs = append(objs, &svcs.Items[i])
+	}
+	require.NoError(t, c.indexers["services"].Replace(objs, ""))
+	objs = nil
+	for i := range eps.Items {
+		objs = append(objs, &eps.Items[i])
+	}
+	require.NoError(t, c.indexers["endpoints"].Replace(objs, ""))
+	objs = nil
+	for i := range pods.Items {
+		objs = append(objs, &pods.Items[i])
+	}
+	require.NoError(t, c.indexers["pods"].Replace(objs, ""))
+	objs = nil
+	for i := range klbs.Items {
+		objs = append(objs, &klbs.Items[i])
+	}
+	require.NoError(t, c.indexers["kuryrloadbalancers"].Replace(objs, ""))
+}
+
+// converge syncs the Service until it is stable and returns the last error.
+func (c *svcControllerTest) converge(t *testing.T) error {
+	var err error
+	for i := 0; i < 20; i++ {
+		c.refresh(t)
+		err = c.syncService(testSvcKey)
+	}
+	return err
+}
+
+func (c *svcControllerTest) ingress(t *testing.T) []corev1.LoadBalancerIngress {
+	svc, err := c.kubeClient.CoreV1().Services("default").Get(context.TODO(), "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	return svc.Status.LoadBalancer.Ingress
+}
+
+func TestSyncLoadBalancerService(t *testing.T) {
+	tests := []struct {
+		name           string
+		externalNet    string
+		loadBalancerIP string
+		userFIP        string
+		injectErr      bool
+		expectErr      bool
+		expectIngress  string
+		expectMethod   string
+	}{
+		{
+			name:          "vip without external network",
+			expectIngress: "10.0.0.2",
+		},
+		{
+			name:          "floating ip from external network",
+			externalNet:   "ext-net",
+			expectIngress: "172.24.4.2",
+			expectMethod:  "pool",
+		},
+		{
+			name:           "user pre-allocated floating ip",
+			loadBalancerIP: "172.24.4.100",
+			userFIP:        "172.24.4.100",
+			expectIngress:  "172.24.4.100",
+			expectMethod:   "user",
+		},
+		{
+			name:           "unknown loadBalancerIP",
+			loadBalancerIP: "172.24.4.100",
+			expectErr:      true,
+		},
+		{
+			name:          "transient octavia error",
+			externalNet:   "ext-net",
+			injectErr:     true,
+			expectIngress: "172.24.4.2",
+			expectMethod:  "pool",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newSvcControllerTest(tt.externalNet, newTestService(tt.loadBalancerIP))
+			defer c.svcQueue.ShutDown()
+			_, err := c.kubeClient.CoreV1().Endpoints("default").Create(context.TODO(), newTestEndpoints("10.1.0.5", "10.1.0.6"), metav1.CreateOptions{})
+			require.NoError(t, err)
+			if tt.userFIP != "" {
+				_, err := c.osClient.CreateFloatingIP(floatingips.CreateOpts{FloatingNetworkID: "ext-net", FloatingIP: tt.userFIP})
+				require.NoError(t, err)
+			}
+			if tt.injectErr {
+				c.osClient.InjectError(fake.OpCreateListener, errors.New("octavia is down"))
+			}
+
+			err = c.converge(t)
+			if tt.expectErr {
+				assert.Error(t, err)
+				assert.Empty(t, c.ingress(t))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []corev1.LoadBalancerIngress{{IP: tt.expectIngress}}, c.ingress(t))
+
+			klb, err := c.crdClient.OpenstackV1alpha1().KuryrLoadBalancers("default").Get(context.TODO(), "web", metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Len(t, klb.Status.Listeners, 1)
+			assert.Len(t, klb.Status.Pools, 1)
+			assert.Len(t, klb.Status.Members, 2)
+			members, err := c.osClient.ListMembers(klb.Status.Pools[0].ID)
+			require.NoError(t, err)
+			assert.Len(t, members, 2)
+			if tt.expectMethod == "" {
+				assert.Nil(t, klb.Status.ServicePubIP)
+				return
+			}
+			require.NotNil(t, klb.Status.ServicePubIP)
+			assert.Equal(t, tt.expectMethod, klb.Status.ServicePubIP.AllocMethod)
+			assert.Equal(t, klb.Status.LoadBalancer.PortID, klb.Status.ServicePubIP.PortID)
+		})
+	}
+}
+
+func newTestPod(ip string, running bool) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-" + ip, Namespace: "default"},
+		Status: corev1.PodStatus{
+			Phase:             corev1.PodRunning,
+			PodIP:             ip,
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "web"}},
+		},
+	}
+	if running {
+		pod.Status.ContainerStatuses[0].State.Running = &corev1.ContainerStateRunning{}
+	} else {
+		pod.Status.ContainerStatuses[0].State.Terminated = &corev1.ContainerStateTerminated{}
+	}
+	return pod
+}
+
+func TestSyncServiceEndpointsDrain(t *testing.T) {
+	tests := []struct {
+		name         string
+		drainTimeout time.Duration
+		// oldPod is the state of the pod of 10.1.0.5 once it left the endpoints.
+		oldPod        *corev1.Pod
+		expectMembers map[string]int
+	}{
+		{
+			name:          "pod still running",
+			drainTimeout:  time.Minute,
+			oldPod:        newTestPod("10.1.0.5", true),
+			expectMembers: map[string]int{"10.1.0.5": 0, "10.1.0.6": 1, "10.1.0.7": 1},
+		},
+		{
+			name:          "containers exited",
+			drainTimeout:  time.Minute,
+			oldPod:        newTestPod("10.1.0.5", false),
+			expectMembers: map[string]int{"10.1.0.6": 1, "10.1.0.7": 1},
+		},
+		{
+			name:          "pod deleted",
+			drainTimeout:  time.Minute,
+			expectMembers: map[string]int{"10.1.0.6": 1, "10.1.0.7": 1},
+		},
+		{
+			name:          "drain timeout passed",
+			oldPod:        newTestPod("10.1.0.5", true),
+			expectMembers: map[string]int{"10.1.0.6": 1, "10.1.0.7": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newSvcControllerTest("", newTestService(""))
+			defer c.svcQueue.ShutDown()
+			c.config.PodDrainTimeout = tt.drainTimeout
+			_, err := c.kubeClient.CoreV1().Endpoints("default").Create(context.TODO(), newTestEndpoints("10.1.0.5", "10.1.0.6"), metav1.CreateOptions{})
+			require.NoError(t, err)
+			require.NoError(t, c.converge(t))
+
+			if tt.oldPod != nil {
+				_, err = c.kubeClient.CoreV1().Pods("default").Create(context.TODO(), tt.oldPod, metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+			_, err = c.kubeClient.CoreV1().Endpoints("default").Update(context.TODO(), newTestEndpoints("10.1.0.6", "10.1.0.7"), metav1.UpdateOptions{})
+			require.NoError(t, err)
+			require.NoError(t, c.converge(t))
+
+			klb, err := c.crdClient.OpenstackV1alpha1().KuryrLoadBalancers("default").Get(context.TODO(), "web", metav1.GetOptions{})
+			require.NoError(t, err)
+			members, err := c.osClient.ListMembers(klb.Status.Pools[0].ID)
+			require.NoError(t, err)
+			weights := map[string]int{}
+			for _, m := range members {
+				weights[m.Address] = m.Weight
+			}
+			assert.Equal(t, tt.expectMembers, weights)
+			for _, m := range klb.Status.Members {
+				assert.Equal(t, weights[m.IP] == 0, m.DrainingSince != nil, m.IP)
+			}
+		})
+	}
+}
+
+func TestDeleteLoadBalancerService(t *testing.T) {
+	tests := []struct {
+		name           string
+		loadBalancerIP string
+		expectFIPs     int
+	}{
+		{name: "pool floating ip is deleted", expectFIPs: 0},
+		{name: "user floating ip is kept", loadBalancerIP: "172.24.4.100", expectFIPs: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newSvcControllerTest("ext-net", newTestService(tt.loadBalancerIP))
+			defer c.svcQueue.ShutDown()
+			if tt.loadBalancerIP != "" {
+				_, err := c.osClient.CreateFloatingIP(floatingips.CreateOpts{FloatingNetworkID: "ext-net", FloatingIP: tt.loadBalancerIP})
+				require.NoError(t, err)
+			}
+			require.NoError(t, c.converge(t))
+			require.Len(t, c.osClient.LoadBalancerIDs(), 1)
+			require.Len(t, c.osClient.FloatingIPs(), 1)
+
+			require.NoError(t, c.kubeClient.CoreV1().Services("default").Delete(context.TODO(), "web", metav1.DeleteOptions{}))
+			require.NoError(t, c.converge(t))
+
+			assert.Empty(t, c.osClient.LoadBalancerIDs())
+			fips := c.osClient.FloatingIPs()
+			require.Len(t, fips, tt.expectFIPs)
+			for _, fip := range fips {
+				assert.Empty(t, fip.PortID)
+			}
+			klbs, err := c.crdClient.OpenstackV1alpha1().KuryrLoadBalancers("default").List(context.TODO(), metav1.ListOptions{})
+			require.NoError(t, err)
+			assert.Empty(t, klbs.Items)
+		})
+	}
+}
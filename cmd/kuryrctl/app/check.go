@@ -0,0 +1,223 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"projectkuryr/kuryr/pkg/agent/apiserver"
+	"projectkuryr/kuryr/pkg/agent/util"
+	"projectkuryr/kuryr/pkg/apis/openstack/v1alpha1"
+	"projectkuryr/kuryr/pkg/openstack/openstackConfig"
+)
+
+const (
+	// The taps plugged by kuryr-agent have the Interface external-id vm-uuid=kuryr.
+	ovsExternalIDVMUUID  = "vm-uuid"
+	ovsExternalIDIfaceID = "iface-id"
+	kuryrVMUUID          = "kuryr"
+
+	statusOK      = "ok"
+	statusMissing = "missing"
+	statusSkipped = "skipped"
+)
+
+// vifCheck is the state of a VIF of the Node in its KuryrPort, on the OVS bridge and in Neutron.
+type vifCheck struct {
+	Pod       string   `json:"pod,omitempty"`
+	IfName    string   `json:"ifName,omitempty"`
+	PortID    string   `json:"portID"`
+	KuryrPort string   `json:"kuryrPort"`
+	OVS       string   `json:"ovs"`
+	Neutron   string   `json:"neutron"`
+	Problems  []string `json:"problems,omitempty"`
+}
+
+func newCheckCommand(o *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "check",
+		Short: "Compare the KuryrPorts, the OVS ports and the Neutron ports of the Node",
+		Long: "Compare the KuryrPorts, the OVS ports and the Neutron ports of the Node. Neutron is " +
+			"only checked when the OpenStack credentials are set in the OS_* environment variables.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.check()
+		},
+	}
+}
+
+func (o *options) check() error {
+	_, crdClient, err := o.k8sClients()
+	if err != nil {
+		return err
+	}
+	kps, err := crdClient.OpenstackV1alpha1().KuryrPorts(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing KuryrPorts: %v", err)
+	}
+	agentClient, err := o.agentClient()
+	if err != nil {
+		return err
+	}
+	ports, err := agentClient.Ports()
+	if err != nil {
+		return err
+	}
+	podInterfaces, err := agentClient.Pods()
+	if err != nil {
+		return err
+	}
+	osClient, err := newOSClientFromEnv()
+	if err != nil {
+		return err
+	}
+
+	checks := checkVifs(kps.Items, ports, podInterfaces, osClient, o.nodeName)
+	problems := 0
+	headers := []string{"POD", "IFNAME", "PORT", "KURYRPORT", "OVS", "NEUTRON", "PROBLEMS"}
+	var rows [][]string
+	for _, check := range checks {
+		problems += len(check.Problems)
+		pod := check.Pod
+		if pod == "/" {
+			pod = ""
+		}
+		rows = append(rows, []string{pod, check.IfName, check.PortID, check.KuryrPort, check.OVS, check.Neutron, strings.Join(check.Problems, "; ")})
+	}
+	if err := o.print(checks, headers, rows); err != nil {
+		return err
+	}
+	if problems > 0 {
+		return fmt.Errorf("%d problem(s) found on Node %s", problems, o.nodeName)
+	}
+	return nil
+}
+
+// checkVifs compares the VIFs of the KuryrPorts of the Node with the OVS ports and the attachments
+// of kuryr-agent and with the Neutron ports, Neutron is skipped if osClient is nil. The taps of
+// kuryr without KuryrPort are reported as stale.
+func checkVifs(kps []v1alpha1.KuryrPort, ports []apiserver.Port, podInterfaces []apiserver.PodInterface, osClient openstackConfig.Interface, nodeName string) []vifCheck {
+	portsByName := map[string]*apiserver.Port{}
+	for i := range ports {
+		portsByName[ports[i].Name] = &ports[i]
+	}
+	attachedVifs := map[string]bool{}
+	for _, podInterface := range podInterfaces {
+		attachedVifs[podInterface.VifID] = true
+	}
+
+	var checks []vifCheck
+	expectedTaps := map[string]bool{}
+	for _, kp := range kps {
+		if kp.Spec.PodNodeName != nodeName {
+			continue
+		}
+		for _, vif := range kp.Status.Vifs {
+			check := vifCheck{
+				Pod:       kp.Namespace + "/" + kp.Name,
+				IfName:    vif.IfName,
+				PortID:    vif.Vif.ID,
+				KuryrPort: statusOK,
+			}
+			if vif.Vif.VNICType == v1alpha1.VNICTypeDirect {
+				// The VFs are not plugged into the OVS bridge.
+				check.OVS = statusSkipped
+				if !attachedVifs[vif.Vif.ID] {
+					check.Problems = append(check.Problems, "VF not attached by kuryr-agent")
+				}
+			} else {
+				tapName := util.GenerateTapInterfaceName(vif.Vif.ID)
+				expectedTaps[tapName] = true
+				check.OVS = checkOVSPort(portsByName[tapName], &vif.Vif, &check)
+			}
+			check.Neutron = checkNeutronPort(osClient, &vif.Vif, nodeName, &check)
+			checks = append(checks, check)
+		}
+	}
+	// The taps of kuryr without KuryrPort are left by deleted Pods.
+	for _, port := range ports {
+		if port.InterfaceExternalIDs[ovsExternalIDVMUUID] != kuryrVMUUID || expectedTaps[port.Name] {
+			continue
+		}
+		checks = append(checks, vifCheck{
+			Pod:       port.PodNamespace + "/" + port.PodName,
+			PortID:    port.InterfaceExternalIDs[ovsExternalIDIfaceID],
+			KuryrPort: statusMissing,
+			OVS:       port.Name,
+			Neutron:   statusSkipped,
+			Problems:  []string{"stale tap"},
+		})
+	}
+	sort.Slice(checks, func(i, j int) bool {
+		if checks[i].Pod != checks[j].Pod {
+			return checks[i].Pod < checks[j].Pod
+		}
+		return checks[i].IfName < checks[j].IfName
+	})
+	if checks == nil {
+		checks = []vifCheck{}
+	}
+	return checks
+}
+
+func checkOVSPort(port *apiserver.Port, vif *v1alpha1.VIF, check *vifCheck) string {
+	if port == nil {
+		check.Problems = append(check.Problems, "tap not plugged into the OVS bridge")
+		return statusMissing
+	}
+	if port.InterfaceExternalIDs[ovsExternalIDIfaceID] != vif.ID {
+		check.Problems = append(check.Problems, fmt.Sprintf("tap bound to port %s", port.InterfaceExternalIDs[ovsExternalIDIfaceID]))
+	}
+	if port.OFPort <= 0 {
+		check.Problems = append(check.Problems, "tap without ofport")
+	}
+	return port.Name
+}
+
+func checkNeutronPort(osClient openstackConfig.Interface, vif *v1alpha1.VIF, nodeName string, check *vifCheck) string {
+	if osClient == nil {
+		return statusSkipped
+	}
+	port, err := osClient.GetPort(vif.ID)
+	if _, ok := err.(gophercloud.ErrDefault404); ok {
+		check.Problems = append(check.Problems, "Neutron port not found")
+		return statusMissing
+	} else if err != nil {
+		check.Problems = append(check.Problems, fmt.Sprintf("error getting Neutron port: %v", err))
+		return "error"
+	}
+	if port.Status != "ACTIVE" {
+		check.Problems = append(check.Problems, fmt.Sprintf("Neutron port is %s", port.Status))
+	}
+	if port.HostID != nodeName {
+		check.Problems = append(check.Problems, fmt.Sprintf("Neutron port bound to host %q", port.HostID))
+	}
+	if !strings.EqualFold(port.MACAddress, vif.MACAddress) {
+		check.Problems = append(check.Problems, fmt.Sprintf("Neutron port has MAC %s", port.MACAddress))
+	}
+	return port.Status
+}
+
+// newOSClientFromEnv returns the OpenStack client of the credentials of the OS_* environment
+// variables, nil is returned when they are not set.
+func newOSClientFromEnv() (openstackConfig.Interface, error) {
+	if os.Getenv("OS_AUTH_URL") == "" {
+		return nil, nil
+	}
+	authOpts, err := openstack.AuthOptionsFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("error reading the OpenStack credentials: %v", err)
+	}
+	osClient, err := openstackConfig.NewOSClient(&authOpts, os.Getenv("OS_REGION_NAME"))
+	if err != nil {
+		return nil, fmt.Errorf("error creating the OpenStack client: %v", err)
+	}
+	return osClient, nil
+}
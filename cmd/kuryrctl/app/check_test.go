@@ -0,0 +1,133 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/gophercloud/gophercloud/acceptance/openstack/networking/v2/extensions/mtu"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/portsbinding"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"projectkuryr/kuryr/pkg/agent/apiserver"
+	"projectkuryr/kuryr/pkg/agent/util"
+	"projectkuryr/kuryr/pkg/apis/openstack/v1alpha1"
+	"projectkuryr/kuryr/pkg/openstack/openstackConfig/fake"
+)
+
+const testNodeName = "node1"
+
+func newTestKuryrPort(name, nodeName string, vifs ...v1alpha1.VIF) v1alpha1.KuryrPort {
+	kp := v1alpha1.KuryrPort{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns1"},
+		Spec:       v1alpha1.KuryrPortSpec{PodNodeName: nodeName},
+	}
+	for _, vif := range vifs {
+		kp.Status.Vifs = append(kp.Status.Vifs, v1alpha1.KuryrVif{IfName: "eth0", Vif: vif})
+	}
+	return kp
+}
+
+// newTestTap returns the tap of kuryr-agent bound to the Neutron port.
+func newTestTap(vifID string, ofPort int32) apiserver.Port {
+	return apiserver.Port{
+		Name:                 util.GenerateTapInterfaceName(vifID),
+		OFPort:               ofPort,
+		InterfaceExternalIDs: map[string]string{ovsExternalIDVMUUID: kuryrVMUUID, ovsExternalIDIfaceID: vifID},
+	}
+}
+
+func newTestNeutronPort(t *testing.T, osClient *fake.OSClient, hostID, status string) v1alpha1.VIF {
+	port, err := osClient.CreatePort(portsbinding.CreateOptsExt{CreateOptsBuilder: ports.CreateOpts{NetworkID: "pod-net"}, HostID: hostID})
+	require.NoError(t, err)
+	osClient.SetPortStatus(port.ID, status)
+	return v1alpha1.VIF{ID: port.ID, MACAddress: port.MACAddress}
+}
+
+func TestCheckVifs(t *testing.T) {
+	osClient := fake.NewOSClient()
+	osClient.AddNetwork(mtu.NetworkMTU{Network: networks.Network{ID: "pod-net"}})
+	okVif := newTestNeutronPort(t, osClient, testNodeName, "ACTIVE")
+	downVif := newTestNeutronPort(t, osClient, "node2", "DOWN")
+	unpluggedVif := v1alpha1.VIF{ID: "0b7e2f3c-9d4a-4c1e-8f6b-5a2d7e9c1b40"}
+	vfVif := newTestNeutronPort(t, osClient, testNodeName, "ACTIVE")
+	vfVif.VNICType = v1alpha1.VNICTypeDirect
+
+	kps := []v1alpha1.KuryrPort{
+		newTestKuryrPort("p1", testNodeName, okVif),
+		newTestKuryrPort("p2", testNodeName, downVif),
+		newTestKuryrPort("p3", testNodeName, unpluggedVif),
+		newTestKuryrPort("p4", testNodeName, vfVif),
+		// The Pods of the other Nodes are not checked.
+		newTestKuryrPort("p5", "node2", v1alpha1.VIF{ID: "a6c1e0d2-3b4f-4e5a-9c8d-7f1b2e3a4d50"}),
+	}
+	boundToOtherPort := newTestTap(downVif.ID, 0)
+	boundToOtherPort.InterfaceExternalIDs[ovsExternalIDIfaceID] = okVif.ID
+	staleTap := newTestTap("5f0d9c8b-7a6e-4d3c-b2a1-0e9f8d7c6b50", 7)
+	staleTap.PodNamespace, staleTap.PodName = "ns1", "p0"
+	ovsPorts := []apiserver.Port{
+		newTestTap(okVif.ID, 5),
+		boundToOtherPort,
+		staleTap,
+		// Not plugged by kuryr-agent.
+		{Name: "br-int", OFPort: 65534},
+	}
+
+	checks := checkVifs(kps, ovsPorts, nil, osClient, testNodeName)
+	assert.Equal(t, []vifCheck{
+		{
+			Pod:       "ns1/p0",
+			PortID:    staleTap.InterfaceExternalIDs[ovsExternalIDIfaceID],
+			KuryrPort: statusMissing,
+			OVS:       staleTap.Name,
+			Neutron:   statusSkipped,
+			Problems:  []string{"stale tap"},
+		},
+		{Pod: "ns1/p1", IfName: "eth0", PortID: okVif.ID, KuryrPort: statusOK, OVS: util.GenerateTapInterfaceName(okVif.ID), Neutron: "ACTIVE"},
+		{
+			Pod:       "ns1/p2",
+			IfName:    "eth0",
+			PortID:    downVif.ID,
+			KuryrPort: statusOK,
+			OVS:       boundToOtherPort.Name,
+			Neutron:   "DOWN",
+			Problems: []string{
+				"tap bound to port " + okVif.ID,
+				"tap without ofport",
+				"Neutron port is DOWN",
+				`Neutron port bound to host "node2"`,
+			},
+		},
+		{
+			Pod:       "ns1/p3",
+			IfName:    "eth0",
+			PortID:    unpluggedVif.ID,
+			KuryrPort: statusOK,
+			OVS:       statusMissing,
+			Neutron:   statusMissing,
+			Problems:  []string{"tap not plugged into the OVS bridge", "Neutron port not found"},
+		},
+		{
+			Pod:       "ns1/p4",
+			IfName:    "eth0",
+			PortID:    vfVif.ID,
+			KuryrPort: statusOK,
+			OVS:       statusSkipped,
+			Neutron:   "ACTIVE",
+			Problems:  []string{"VF not attached by kuryr-agent"},
+		},
+	}, checks)
+}
+
+func TestCheckVifsWithoutNeutron(t *testing.T) {
+	vif := v1alpha1.VIF{ID: "0b7e2f3c-9d4a-4c1e-8f6b-5a2d7e9c1b40", VNICType: v1alpha1.VNICTypeDirect}
+	podInterfaces := []apiserver.PodInterface{{PodName: "p1", PodNamespace: "ns1", ContainerID: "c1", VifID: vif.ID}}
+
+	checks := checkVifs([]v1alpha1.KuryrPort{newTestKuryrPort("p1", testNodeName, vif)}, nil, podInterfaces, nil, testNodeName)
+	assert.Equal(t, []vifCheck{
+		{Pod: "ns1/p1", IfName: "eth0", PortID: vif.ID, KuryrPort: statusOK, OVS: statusSkipped, Neutron: statusSkipped},
+	}, checks)
+	assert.Equal(t, []vifCheck{}, checkVifs(nil, nil, nil, nil, testNodeName))
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	clientset "k8s.io/client-go/kubernetes"
+	componentbaseconfig "k8s.io/component-base/config"
+
+	"projectkuryr/kuryr/pkg/agent/apiserver"
+	crdclientset "projectkuryr/kuryr/pkg/client/clientset/versioned"
+	"projectkuryr/kuryr/pkg/cni"
+	"projectkuryr/kuryr/pkg/k8s"
+	"projectkuryr/kuryr/pkg/version"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// options are the global options of kuryrctl. kuryrctl runs on a Node, next to kuryr-agent.
+type options struct {
+	kubeconfig   string
+	agentAddress string
+	tokenFile    string
+	cniSocket    string
+	nodeName     string
+	namespace    string
+	output       string
+}
+
+func NewKuryrctlCommand() *cobra.Command {
+	o := &options{}
+	cmd := &cobra.Command{
+		Use:           "kuryrctl",
+		Short:         "kuryrctl helps debugging the networking of the Pods of a Node",
+		Version:       version.GetFullVersionWithRuntimeInfo(),
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if o.output != outputTable && o.output != outputJSON {
+				return fmt.Errorf("unsupported output format %s, use %s or %s", o.output, outputTable, outputJSON)
+			}
+			return nil
+		},
+	}
+	hostname, _ := os.Hostname()
+	if nodeName := os.Getenv("NODE_NAME"); nodeName != "" {
+		hostname = nodeName
+	}
+	flags := cmd.PersistentFlags()
+	flags.StringVar(&o.kubeconfig, "kubeconfig", os.Getenv("KUBECONFIG"), "The kubeconfig file, the in-cluster configuration is used if empty")
+	flags.StringVar(&o.agentAddress, "agent-address", apiserver.DefaultBindAddress, "The address of the kuryr-agent API")
+	flags.StringVar(&o.tokenFile, "token-file", apiserver.DefaultTokenFile, "The token file of the kuryr-agent API")
+	flags.StringVar(&o.cniSocket, "cni-socket", cni.KuryrCNISocketAddr, "The CNI socket of kuryr-agent")
+	flags.StringVar(&o.nodeName, "node", hostname, "The name of the Node")
+	flags.StringVarP(&o.namespace, "namespace", "n", "default", "The namespace of the Pods")
+	flags.StringVarP(&o.output, "output", "o", outputTable, "The output format, table or json")
+
+	cmd.AddCommand(newGetCommand(o))
+	cmd.AddCommand(newTraceCommand(o))
+	cmd.AddCommand(newCheckCommand(o))
+	cmd.AddCommand(newGCCommand(o))
+	return cmd
+}
+
+func (o *options) k8sClients() (clientset.Interface, crdclientset.Interface, error) {
+	k8sClient, _, crdClient, err := k8s.CreateClientsCrd(componentbaseconfig.ClientConnectionConfiguration{Kubeconfig: o.kubeconfig}, "")
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating K8s clients: %v", err)
+	}
+	return k8sClient, crdClient, nil
+}
+
+func (o *options) agentClient() (*apiserver.Client, error) {
+	token, err := apiserver.ReadToken(o.tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the kuryr-agent API token: %v", err)
+	}
+	return apiserver.NewClient(o.agentAddress, token), nil
+}
+
+// podRef returns the namespace and the name of a Pod given as <name> or <namespace>/<name>.
+func (o *options) podRef(pod string) (string, string) {
+	if i := strings.Index(pod, "/"); i >= 0 {
+		return pod[:i], pod[i+1:]
+	}
+	return o.namespace, pod
+}
@@ -0,0 +1,222 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"projectkuryr/kuryr/pkg/agent/apiserver"
+	"projectkuryr/kuryr/pkg/cni"
+)
+
+const (
+	ovsExternalIDContainerID  = "container-id"
+	ovsExternalIDPodName      = "pod-name"
+	ovsExternalIDPodNamespace = "pod-namespace"
+	ovsExternalIDNetwork      = "cni-network"
+)
+
+// container is a container attached by kuryr-agent, it is garbage collected when its Pod is gone.
+type container struct {
+	Pod         string `json:"pod"`
+	ContainerID string `json:"containerID"`
+	IfName      string `json:"ifName,omitempty"`
+	// Network is the CNI network of the container interface, kuryr-agent collects the containers
+	// of one network at a time.
+	Network   string `json:"network,omitempty"`
+	HostIface string `json:"hostIface,omitempty"`
+	VifID     string `json:"vifID,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+func newGCCommand(o *options) *cobra.Command {
+	dryRun := false
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Remove the taps and the state of the containers of the deleted Pods of the Node",
+		Long: "Remove the taps and the state of the containers of the deleted Pods of the Node. The " +
+			"containers are collected by kuryr-agent with the CNI GC command, the containers of the " +
+			"existing Pods are valid.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.gc(dryRun)
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only display the containers which would be collected")
+	return cmd
+}
+
+func (o *options) gc(dryRun bool) error {
+	k8sClient, crdClient, err := o.k8sClients()
+	if err != nil {
+		return err
+	}
+	pods, err := k8sClient.CoreV1().Pods(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{
+		FieldSelector: "spec.nodeName=" + o.nodeName,
+	})
+	if err != nil {
+		return fmt.Errorf("error listing the Pods of Node %s: %v", o.nodeName, err)
+	}
+	kps, err := crdClient.OpenstackV1alpha1().KuryrPorts(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing KuryrPorts: %v", err)
+	}
+	agentClient, err := o.agentClient()
+	if err != nil {
+		return err
+	}
+	podInterfaces, err := agentClient.Pods()
+	if err != nil {
+		return err
+	}
+	ports, err := agentClient.Ports()
+	if err != nil {
+		return err
+	}
+
+	runningPods := map[string]*corev1.Pod{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		runningPods[pod.Namespace+"/"+pod.Name] = pod
+	}
+	podVifs := map[string]map[string]bool{}
+	for _, kp := range kps.Items {
+		vifs := map[string]bool{}
+		for _, vif := range kp.Status.Vifs {
+			vifs[vif.Vif.ID] = true
+		}
+		podVifs[kp.Namespace+"/"+kp.Name] = vifs
+	}
+
+	valid, stale := classifyContainers(listContainers(podInterfaces, ports), runningPods, podVifs)
+	headers := []string{"POD", "CONTAINER", "IFNAME", "NETWORK", "HOST IFACE", "REASON"}
+	var rows [][]string
+	for _, c := range stale {
+		rows = append(rows, []string{c.Pod, shortID(c.ContainerID), c.IfName, c.Network, c.HostIface, c.Reason})
+	}
+	if dryRun || len(stale) == 0 {
+		return o.print(stale, headers, rows)
+	}
+
+	for _, network := range staleNetworks(stale) {
+		if err := o.requestGC(network, valid); err != nil {
+			return err
+		}
+	}
+	return o.print(stale, headers, rows)
+}
+
+// listContainers returns the containers attached by kuryr-agent, from its attachments and from the
+// container-id external-id of its taps.
+func listContainers(podInterfaces []apiserver.PodInterface, ports []apiserver.Port) []container {
+	var containers []container
+	for _, podInterface := range podInterfaces {
+		containers = append(containers, container{
+			Pod:         podInterface.PodNamespace + "/" + podInterface.PodName,
+			ContainerID: podInterface.ContainerID,
+			IfName:      podInterface.IfName,
+			Network:     podInterface.Network,
+			HostIface:   podInterface.HostIface,
+			VifID:       podInterface.VifID,
+		})
+	}
+	for _, port := range ports {
+		if port.InterfaceExternalIDs[ovsExternalIDVMUUID] != kuryrVMUUID {
+			continue
+		}
+		containers = append(containers, container{
+			Pod:         port.ExternalIDs[ovsExternalIDPodNamespace] + "/" + port.ExternalIDs[ovsExternalIDPodName],
+			ContainerID: port.ExternalIDs[ovsExternalIDContainerID],
+			Network:     port.ExternalIDs[ovsExternalIDNetwork],
+			HostIface:   port.Name,
+			VifID:       port.InterfaceExternalIDs[ovsExternalIDIfaceID],
+		})
+	}
+	return containers
+}
+
+// classifyContainers returns the containers whose Pod still runs on the Node with the same VIF, and
+// the other ones, which are stale. A container is valid as soon as one of its interfaces is, all
+// the interfaces of a container are collected together by kuryr-agent.
+func classifyContainers(containers []container, runningPods map[string]*corev1.Pod, podVifs map[string]map[string]bool) ([]container, []container) {
+	validContainers := map[string]bool{}
+	var valid, stale []container
+	for _, c := range containers {
+		if c.ContainerID == "" {
+			// The taps without container are left to the reconciliation of kuryr-agent.
+			continue
+		}
+		if _, ok := runningPods[c.Pod]; !ok {
+			c.Reason = "Pod not found on the Node"
+			stale = append(stale, c)
+			continue
+		}
+		if vifs, ok := podVifs[c.Pod]; ok && c.VifID != "" && !vifs[c.VifID] {
+			c.Reason = "VIF not in the KuryrPort of the Pod"
+			stale = append(stale, c)
+			continue
+		}
+		if !validContainers[c.ContainerID] {
+			validContainers[c.ContainerID] = true
+			valid = append(valid, c)
+		}
+	}
+	// Keep one entry per stale container, which is not valid through another interface.
+	seen := map[string]bool{}
+	var staleContainers []container
+	for _, c := range stale {
+		if validContainers[c.ContainerID] || seen[c.ContainerID] {
+			continue
+		}
+		seen[c.ContainerID] = true
+		staleContainers = append(staleContainers, c)
+	}
+	sort.Slice(staleContainers, func(i, j int) bool { return staleContainers[i].Pod < staleContainers[j].Pod })
+	if staleContainers == nil {
+		staleContainers = []container{}
+	}
+	return valid, staleContainers
+}
+
+// staleNetworks returns the CNI networks of the stale containers. The taps and the attachments saved
+// without network by an older kuryr-agent are in the network with an empty name.
+func staleNetworks(stale []container) []string {
+	networks := sets.NewString()
+	for _, c := range stale {
+		networks.Insert(c.Network)
+	}
+	return networks.List()
+}
+
+// requestGC sends the CNI GC command of the network to kuryr-agent with the valid containers.
+func (o *options) requestGC(network string, valid []container) error {
+	type attachment struct {
+		ContainerID string `json:"containerID"`
+		IfName      string `json:"ifname"`
+	}
+	attachments := []attachment{}
+	for _, c := range valid {
+		attachments = append(attachments, attachment{ContainerID: c.ContainerID, IfName: c.IfName})
+	}
+	netConf, err := json.Marshal(map[string]interface{}{
+		"cniVersion":                "1.1.0",
+		"name":                      network,
+		"type":                      "kuryr-cni",
+		"socket_path":               o.cniSocket,
+		"cni.dev/valid-attachments": attachments,
+	})
+	if err != nil {
+		return err
+	}
+	if err := cni.ActionGC.Request(&skel.CmdArgs{StdinData: netConf}); err != nil {
+		return fmt.Errorf("error sending GC to kuryr-agent: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	"projectkuryr/kuryr/pkg/agent/apiserver"
+)
+
+func newTestGCTap(name, containerID, pod, network, vifID string) apiserver.Port {
+	return apiserver.Port{
+		Name: name,
+		ExternalIDs: map[string]string{
+			ovsExternalIDContainerID:  containerID,
+			ovsExternalIDPodNamespace: "ns1",
+			ovsExternalIDPodName:      pod,
+			ovsExternalIDNetwork:      network,
+		},
+		InterfaceExternalIDs: map[string]string{ovsExternalIDVMUUID: kuryrVMUUID, ovsExternalIDIfaceID: vifID},
+	}
+}
+
+func TestListContainers(t *testing.T) {
+	podInterfaces := []apiserver.PodInterface{
+		{PodName: "p1", PodNamespace: "ns1", ContainerID: "c1", IfName: "eth0", Network: "kuryr", HostIface: "tap1", VifID: "port-1"},
+	}
+	ports := []apiserver.Port{
+		newTestGCTap("tap2", "c2", "p2", "kuryr", "port-2"),
+		// Not plugged by kuryr-agent.
+		{Name: "br-int", ExternalIDs: map[string]string{ovsExternalIDContainerID: "c3"}},
+	}
+
+	assert.Equal(t, []container{
+		{Pod: "ns1/p1", ContainerID: "c1", IfName: "eth0", Network: "kuryr", HostIface: "tap1", VifID: "port-1"},
+		{Pod: "ns1/p2", ContainerID: "c2", Network: "kuryr", HostIface: "tap2", VifID: "port-2"},
+	}, listContainers(podInterfaces, ports))
+}
+
+func TestClassifyContainers(t *testing.T) {
+	runningPods := map[string]*corev1.Pod{"ns1/p1": {}, "ns1/p2": {}, "ns1/p4": {}}
+	podVifs := map[string]map[string]bool{
+		"ns1/p1": {"port-1": true},
+		"ns1/p2": {"port-2-new": true},
+		"ns1/p4": {"port-4": true},
+	}
+	containers := []container{
+		{Pod: "ns1/p1", ContainerID: "c1", IfName: "eth0", Network: "kuryr", VifID: "port-1"},
+		// The tap of the same container is listed from the OVS port too.
+		{Pod: "ns1/p1", ContainerID: "c1", Network: "kuryr", HostIface: "tap1", VifID: "port-1"},
+		// The Pod was recreated with another Neutron port.
+		{Pod: "ns1/p2", ContainerID: "c2", IfName: "eth0", Network: "kuryr", VifID: "port-2"},
+		{Pod: "ns1/p3", ContainerID: "c3", IfName: "net1", Network: "kuryr-sriov", VifID: "port-3"},
+		// The tap plugged again by the reconciliation of kuryr-agent has no container.
+		{Pod: "ns1/p9", HostIface: "tap9", VifID: "port-9"},
+		// The container is valid through its other interface.
+		{Pod: "ns1/p4", ContainerID: "c4", IfName: "eth0", Network: "kuryr", VifID: "port-4"},
+		{Pod: "ns1/p4", ContainerID: "c4", IfName: "net1", Network: "kuryr", VifID: "port-4-old"},
+	}
+
+	valid, stale := classifyContainers(containers, runningPods, podVifs)
+	var validIDs []string
+	for _, c := range valid {
+		validIDs = append(validIDs, c.ContainerID)
+	}
+	assert.Equal(t, []string{"c1", "c4"}, validIDs)
+	assert.Equal(t, []container{
+		{Pod: "ns1/p2", ContainerID: "c2", IfName: "eth0", Network: "kuryr", VifID: "port-2", Reason: "VIF not in the KuryrPort of the Pod"},
+		{Pod: "ns1/p3", ContainerID: "c3", IfName: "net1", Network: "kuryr-sriov", VifID: "port-3", Reason: "Pod not found on the Node"},
+	}, stale)
+	assert.Equal(t, []string{"kuryr", "kuryr-sriov"}, staleNetworks(stale))
+
+	_, stale = classifyContainers(nil, runningPods, podVifs)
+	assert.Equal(t, []container{}, stale)
+}
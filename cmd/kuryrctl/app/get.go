@@ -0,0 +1,126 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"projectkuryr/kuryr/pkg/agent/apiserver"
+	"projectkuryr/kuryr/pkg/apis/openstack/v1alpha1"
+)
+
+// podNetwork is the network of a Pod, its VIFs from its KuryrPort and its interfaces from
+// kuryr-agent when the Pod runs on the Node.
+type podNetwork struct {
+	Namespace  string                   `json:"namespace"`
+	Name       string                   `json:"name"`
+	PodUID     string                   `json:"podUID"`
+	NodeName   string                   `json:"nodeName"`
+	Vifs       []v1alpha1.KuryrVif      `json:"vifs"`
+	Interfaces []apiserver.PodInterface `json:"interfaces,omitempty"`
+}
+
+func newGetCommand(o *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get",
+		Short: "Display the networking resources",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "pod-network <pod>",
+		Short: "Display the VIFs and the interfaces of a Pod, given as <name> or <namespace>/<name>",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.getPodNetwork(args[0])
+		},
+	})
+	return cmd
+}
+
+func (o *options) getPodNetwork(pod string) error {
+	namespace, name := o.podRef(pod)
+	_, crdClient, err := o.k8sClients()
+	if err != nil {
+		return err
+	}
+	// KuryrPorts are named after their Pod.
+	kp, err := crdClient.OpenstackV1alpha1().KuryrPorts(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting the KuryrPort of Pod %s/%s: %v", namespace, name, err)
+	}
+	network := &podNetwork{
+		Namespace: namespace,
+		Name:      name,
+		PodUID:    kp.Spec.PodUid,
+		NodeName:  kp.Spec.PodNodeName,
+		Vifs:      kp.Status.Vifs,
+	}
+	// The interfaces are only known by the kuryr-agent of the Node of the Pod.
+	if kp.Spec.PodNodeName == o.nodeName {
+		agentClient, err := o.agentClient()
+		if err != nil {
+			return err
+		}
+		podInterfaces, err := agentClient.Pods()
+		if err != nil {
+			return err
+		}
+		for _, podInterface := range podInterfaces {
+			if podInterface.PodNamespace == namespace && podInterface.PodName == name {
+				network.Interfaces = append(network.Interfaces, podInterface)
+			}
+		}
+	}
+
+	headers := []string{"IFNAME", "PORT", "VNIC", "STATUS", "MAC", "IPS", "HOST IFACE", "OFPORT", "CONTAINER"}
+	var rows [][]string
+	for _, vif := range network.Vifs {
+		row := []string{vif.IfName, vif.Vif.ID, vif.Vif.VNICType, vif.Vif.Status, vif.Vif.MACAddress, strings.Join(vifIPs(&vif.Vif), ","), "", "", ""}
+		if podInterface := findVifInterface(network.Interfaces, vif.Vif.ID); podInterface != nil {
+			row[6] = podInterface.HostIface
+			if podInterface.PCISlot != "" {
+				row[6] = podInterface.PCISlot
+			}
+			if podInterface.OFPort > 0 {
+				row[7] = strconv.Itoa(int(podInterface.OFPort))
+			}
+			row[8] = shortID(podInterface.ContainerID)
+		}
+		rows = append(rows, row)
+	}
+	return o.print(network, headers, rows)
+}
+
+func vifIPs(vif *v1alpha1.VIF) []string {
+	var ips []string
+	for _, subnet := range vif.Network.Subnets {
+		for _, ip := range subnet.Ips {
+			ips = append(ips, ip.IPAddress)
+		}
+	}
+	return ips
+}
+
+func findVifInterface(podInterfaces []apiserver.PodInterface, vifID string) *apiserver.PodInterface {
+	for i := range podInterfaces {
+		if podInterfaces[i].VifID == vifID {
+			return &podInterfaces[i]
+		}
+	}
+	// The interfaces of the taps plugged before the attachments were saved have no VIF ID.
+	if len(podInterfaces) == 1 && podInterfaces[0].VifID == "" {
+		return &podInterfaces[0]
+	}
+	return nil
+}
+
+// shortID shortens a container ID like the container runtimes do.
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
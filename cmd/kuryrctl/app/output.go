@@ -0,0 +1,37 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// print prints obj as JSON, or else the rows as a table with the headers.
+func (o *options) print(obj interface{}, headers []string, rows [][]string) error {
+	return o.fprint(os.Stdout, obj, headers, rows)
+}
+
+func (o *options) fprint(w io.Writer, obj interface{}, headers []string, rows [][]string) error {
+	if o.output == outputJSON {
+		data, err := json.MarshalIndent(obj, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		for i := range row {
+			if row[i] == "" {
+				row[i] = "-"
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFprint(t *testing.T) {
+	stale := []container{{Pod: "ns1/p1", ContainerID: "c1", Network: "kuryr", Reason: "Pod not found on the Node"}}
+	headers := []string{"POD", "CONTAINER", "IFNAME", "REASON"}
+	for _, tc := range []struct {
+		name           string
+		output         string
+		obj            interface{}
+		expectedOutput string
+	}{
+		{
+			name:   "table",
+			output: outputTable,
+			obj:    stale,
+			expectedOutput: "POD     CONTAINER  IFNAME  REASON\n" +
+				"ns1/p1  c1         -       Pod not found on the Node\n",
+		},
+		{
+			name:   "json",
+			output: outputJSON,
+			obj:    stale,
+			expectedOutput: `[
+  {
+    "pod": "ns1/p1",
+    "containerID": "c1",
+    "network": "kuryr",
+    "reason": "Pod not found on the Node"
+  }
+]
+`,
+		},
+		{
+			name:           "empty json",
+			output:         outputJSON,
+			obj:            []container{},
+			expectedOutput: "[]\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			o := &options{output: tc.output}
+			// The empty cells are replaced in place.
+			rows := [][]string{{"ns1/p1", "c1", "", "Pod not found on the Node"}}
+			var out bytes.Buffer
+			require.NoError(t, o.fprint(&out, tc.obj, headers, rows))
+			assert.Equal(t, tc.expectedOutput, out.String())
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"projectkuryr/kuryr/pkg/agent/apiserver"
+	"projectkuryr/kuryr/pkg/apis/openstack/v1alpha1"
+)
+
+type traceOptions struct {
+	ifName   string
+	dstType  string
+	protocol string
+	dstPort  int
+	flow     string
+	dstMAC   string
+	raw      bool
+}
+
+func newTraceCommand(o *options) *cobra.Command {
+	to := &traceOptions{}
+	cmd := &cobra.Command{
+		Use:   "trace <src-pod> <dst>",
+		Short: "Trace a packet of a Pod of the Node through the OVS bridge",
+		Long: "Trace a packet of a Pod of the Node through the OVS bridge with ofproto/trace, and show " +
+			"the security group flows which accepted or dropped it. The source Pod and a destination " +
+			"Pod or Service are given as <name> or <namespace>/<name>.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.trace(to, args[0], args[1])
+		},
+	}
+	cmd.Flags().StringVar(&to.ifName, "ifname", "", "The interface of the source Pod, its first tap by default")
+	cmd.Flags().StringVar(&to.dstType, "dst-type", "", fmt.Sprintf("The type of the destination, one of %s, an IP or a Pod by default", strings.Join(v1alpha1.SupportedDestinationTypes, ", ")))
+	cmd.Flags().StringVar(&to.protocol, "protocol", "ICMP", "The protocol of the traced packet, TCP, UDP or ICMP")
+	cmd.Flags().IntVar(&to.dstPort, "dst-port", 0, "The destination port of the traced TCP or UDP packet")
+	cmd.Flags().StringVar(&to.flow, "flow", "", "Additional fields of the traced packet, e.g. nw_tos=0")
+	cmd.Flags().StringVar(&to.dstMAC, "dst-mac", "", "The destination MAC, e.g. the MAC of the router for a destination in another subnet")
+	cmd.Flags().BoolVar(&to.raw, "raw", false, "Display the raw output of ofproto/trace")
+	return cmd
+}
+
+func (o *options) trace(to *traceOptions, srcPod, dst string) error {
+	namespace, name := o.podRef(srcPod)
+	q := &apiserver.TraceflowQuery{
+		PodNamespace: namespace,
+		PodName:      name,
+		IfName:       to.ifName,
+		DstMAC:       to.dstMAC,
+		Protocol:     to.protocol,
+		DstPort:      to.dstPort,
+		Flow:         to.flow,
+	}
+	if err := o.resolveDestination(q, to.dstType, dst); err != nil {
+		return err
+	}
+	agentClient, err := o.agentClient()
+	if err != nil {
+		return err
+	}
+	tf, err := agentClient.Traceflow(q)
+	if err != nil {
+		return err
+	}
+	if o.output == outputJSON {
+		return o.print(tf, nil, nil)
+	}
+	if to.raw {
+		fmt.Print(tf.RawOutput)
+		return nil
+	}
+	return printTraceflow(o, tf)
+}
+
+// resolveDestination sets the destination IP of the query, and the destination MAC of a Pod when it
+// isn't set.
+func (o *options) resolveDestination(q *apiserver.TraceflowQuery, dstType, dst string) error {
+	if dstType == "" {
+		dstType = v1alpha1.DstTypePod
+		if net.ParseIP(dst) != nil {
+			dstType = v1alpha1.DstTypeIPv4
+		}
+	}
+	switch dstType {
+	case v1alpha1.DstTypeIPv4:
+		if net.ParseIP(dst) == nil {
+			return fmt.Errorf("invalid destination IP %s", dst)
+		}
+		q.DstIP = dst
+	case v1alpha1.DstTypePod:
+		dstNamespace, dstName := o.podRef(dst)
+		vif, err := o.defaultVif(dstNamespace, dstName)
+		if err != nil {
+			return err
+		}
+		ips := vifIPs(vif)
+		if len(ips) == 0 {
+			return fmt.Errorf("Pod %s/%s has no IP", dstNamespace, dstName)
+		}
+		q.DstIP = ips[0]
+		if q.DstMAC == "" {
+			// The packet is switched to a Pod of the same network, else it goes to the router whose
+			// MAC must be given.
+			q.DstMAC = vif.MACAddress
+		}
+	case v1alpha1.DstTypeService:
+		k8sClient, _, err := o.k8sClients()
+		if err != nil {
+			return err
+		}
+		svcNamespace, svcName := o.podRef(dst)
+		svc, err := k8sClient.CoreV1().Services(svcNamespace).Get(context.TODO(), svcName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("error getting Service %s/%s: %v", svcNamespace, svcName, err)
+		}
+		if net.ParseIP(svc.Spec.ClusterIP) == nil {
+			return fmt.Errorf("Service %s/%s has no cluster IP", svcNamespace, svcName)
+		}
+		// The cluster IP is the VIP of the load balancer, which is reached through the router.
+		q.DstIP = svc.Spec.ClusterIP
+	default:
+		return fmt.Errorf("unsupported destination type %s, must be one of %s", dstType, strings.Join(v1alpha1.SupportedDestinationTypes, ", "))
+	}
+	return nil
+}
+
+func printTraceflow(o *options, tf *apiserver.Traceflow) error {
+	fmt.Printf("Flow: %s\n\n", tf.Flow)
+	headers := []string{"PASS", "BRIDGE", "TABLE", "PRIORITY", "MATCH", "ACTIONS"}
+	var rows [][]string
+	for i, pass := range tf.Passes {
+		for _, stage := range pass.Stages {
+			table, match, priority := strconv.Itoa(stage.Table), stage.Match, strconv.Itoa(stage.Priority)
+			if stage.TableName != "" {
+				table += " (" + stage.TableName + ")"
+			}
+			if stage.NoMatch {
+				match, priority = "no match", ""
+			}
+			rows = append(rows, []string{strconv.Itoa(i), stage.Bridge, table, priority, match, strings.Join(stage.Actions, ",")})
+		}
+	}
+	if err := o.fprint(os.Stdout, nil, headers, rows); err != nil {
+		return err
+	}
+	if len(tf.SecurityGroups) > 0 {
+		fmt.Println()
+		headers = []string{"DIRECTION", "ACTION", "TABLE", "COOKIE", "MATCH"}
+		rows = nil
+		for _, verdict := range tf.SecurityGroups {
+			rows = append(rows, []string{string(verdict.Direction), string(verdict.Action), strconv.Itoa(verdict.Table), verdict.Cookie, verdict.Match})
+		}
+		if err := o.fprint(os.Stdout, nil, headers, rows); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("\nVerdict: %s (datapath actions: %s)\n", tf.Verdict, tf.DatapathActions)
+	return nil
+}
+
+// defaultVif returns the default VIF of the Pod from its KuryrPort.
+func (o *options) defaultVif(namespace, name string) (*v1alpha1.VIF, error) {
+	_, crdClient, err := o.k8sClients()
+	if err != nil {
+		return nil, err
+	}
+	kp, err := crdClient.OpenstackV1alpha1().KuryrPorts(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting the KuryrPort of Pod %s/%s: %v", namespace, name, err)
+	}
+	for i := range kp.Status.Vifs {
+		if kp.Status.Vifs[i].IsDefault {
+			return &kp.Status.Vifs[i].Vif, nil
+		}
+	}
+	if len(kp.Status.Vifs) > 0 {
+		return &kp.Status.Vifs[0].Vif, nil
+	}
+	return nil, fmt.Errorf("Pod %s/%s has no VIF", namespace, name)
+}
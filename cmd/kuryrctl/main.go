@@ -0,0 +1,20 @@
+package main
+
+import (
+	"os"
+
+	"k8s.io/component-base/logs"
+
+	"projectkuryr/kuryr/cmd/kuryrctl/app"
+)
+
+func main() {
+	logs.InitLogs()
+	defer logs.FlushLogs()
+
+	command := app.NewKuryrctlCommand()
+
+	if err := command.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,351 @@
+// Package apiserver serves the node-local HTTP API of kuryr-agent, used to introspect the
+// attachments of the Pods of the Node and to trace their packets. All the requests are authenticated with the bearer token of
+// the token file, which only root can read on the Node.
+package apiserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/containernetworking/cni/pkg/types/current"
+	"k8s.io/klog"
+
+	"projectkuryr/kuryr/pkg/agent/cnistate"
+	"projectkuryr/kuryr/pkg/agent/interfacestore"
+	"projectkuryr/kuryr/pkg/ovs/ovsconfig"
+	"projectkuryr/kuryr/pkg/ovs/ovsctl"
+)
+
+const (
+	// DefaultBindAddress only exposes the API on the Node.
+	DefaultBindAddress = "127.0.0.1:10351"
+	// DefaultTokenFile is the file of the bearer token of the API.
+	DefaultTokenFile = "/var/run/kuryr/agent-api-token"
+)
+
+// Server is the node-local API server of kuryr-agent.
+type Server struct {
+	bindAddress     string
+	token           string
+	ifaceStore      interfacestore.InterfaceStore
+	stateStore      *cnistate.Store
+	ovsBridgeClient ovsconfig.OVSBridgeClient
+	ovsCtlClient    ovsctl.OVSCtlClient
+}
+
+func New(
+	bindAddress, token string,
+	ifaceStore interfacestore.InterfaceStore,
+	stateStore *cnistate.Store,
+	ovsBridgeClient ovsconfig.OVSBridgeClient,
+	ovsCtlClient ovsctl.OVSCtlClient,
+) *Server {
+	return &Server{
+		bindAddress:     bindAddress,
+		token:           token,
+		ifaceStore:      ifaceStore,
+		stateStore:      stateStore,
+		ovsBridgeClient: ovsBridgeClient,
+		ovsCtlClient:    ovsCtlClient,
+	}
+}
+
+// LoadOrCreateToken returns the token of the token file, a random token is written to the file if
+// it doesn't exist yet.
+func LoadOrCreateToken(tokenFile string) (string, error) {
+	token, err := ReadToken(tokenFile)
+	if err == nil {
+		return token, nil
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate the API token: %v", err)
+	}
+	token = hex.EncodeToString(b)
+	if err := os.MkdirAll(filepath.Dir(tokenFile), 0755); err != nil {
+		return "", fmt.Errorf("failed to create the directory of the API token file: %v", err)
+	}
+	if err := ioutil.WriteFile(tokenFile, []byte(token), 0600); err != nil {
+		return "", fmt.Errorf("failed to write the API token file %s: %v", tokenFile, err)
+	}
+	return token, nil
+}
+
+// ReadToken returns the token of the token file.
+func ReadToken(tokenFile string) (string, error) {
+	data, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("API token file %s is empty", tokenFile)
+	}
+	return token, nil
+}
+
+// Handler returns the handler of the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/pods", s.handlePods)
+	mux.HandleFunc("/v1/ports", s.handlePorts)
+	mux.HandleFunc("/v1/ovsflows", s.handleOVSFlows)
+	mux.HandleFunc("/v1/traceflow", s.handleTraceflow)
+	return s.authenticate(mux)
+}
+
+func (s *Server) authenticate(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Run serves the API until stopCh is closed.
+func (s *Server) Run(stopCh <-chan struct{}) {
+	klog.Infof("Starting the agent API server on %s", s.bindAddress)
+	defer klog.Info("Shutting down the agent API server")
+
+	listener, err := net.Listen("tcp", s.bindAddress)
+	if err != nil {
+		klog.Errorf("Failed to bind on %s: %v", s.bindAddress, err)
+		return
+	}
+	server := &http.Server{Handler: s.Handler()}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			klog.Errorf("Failed to serve the agent API: %v", err)
+		}
+	}()
+	<-stopCh
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.Shutdown(ctx)
+}
+
+func writeJSON(w http.ResponseWriter, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(obj); err != nil {
+		klog.Errorf("Failed to encode the API response: %v", err)
+	}
+}
+
+func (s *Server) handlePods(w http.ResponseWriter, r *http.Request) {
+	podInterfaces, err := s.podInterfaces()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, podInterfaces)
+}
+
+// podInterfaces returns the interfaces of the Pods, from the saved attachments and from the
+// interface store for the taps plugged before the attachments were saved.
+func (s *Server) podInterfaces() ([]PodInterface, error) {
+	attachments, err := s.stateStore.List()
+	if err != nil {
+		return nil, err
+	}
+	podInterfaces := []PodInterface{}
+	seenContainers := map[string]bool{}
+	for _, attachment := range attachments {
+		podInterface := PodInterface{
+			PodName:      attachment.PodName,
+			PodNamespace: attachment.PodNamespace,
+			ContainerID:  attachment.ContainerID,
+			IfName:       attachment.IfName,
+			Network:      attachment.Network,
+			Netns:        attachment.Netns,
+			VifID:        attachment.VifID,
+			HostIface:    attachment.HostIface,
+			PCISlot:      attachment.PCISlot,
+		}
+		containerConfig, ok := s.ifaceStore.GetContainerInterface(attachment.ContainerID)
+		if ok && attachment.HostIface != "" && containerConfig.InterfaceName == attachment.HostIface {
+			setInterfaceConfig(&podInterface, containerConfig)
+			seenContainers[attachment.ContainerID] = true
+		} else {
+			setResultConfig(&podInterface, attachment.CNIResult)
+		}
+		podInterfaces = append(podInterfaces, podInterface)
+	}
+	for _, containerConfig := range s.ifaceStore.GetInterfacesByType(interfacestore.ContainerInterface) {
+		if seenContainers[containerConfig.ContainerID] {
+			continue
+		}
+		podInterface := PodInterface{
+			PodName:      containerConfig.PodName,
+			PodNamespace: containerConfig.PodNamespace,
+			ContainerID:  containerConfig.ContainerID,
+			HostIface:    containerConfig.InterfaceName,
+		}
+		setInterfaceConfig(&podInterface, containerConfig)
+		podInterfaces = append(podInterfaces, podInterface)
+	}
+	sort.Slice(podInterfaces, func(i, j int) bool {
+		a, b := podInterfaces[i], podInterfaces[j]
+		if a.PodNamespace != b.PodNamespace {
+			return a.PodNamespace < b.PodNamespace
+		}
+		if a.PodName != b.PodName {
+			return a.PodName < b.PodName
+		}
+		return a.IfName < b.IfName
+	})
+	return podInterfaces, nil
+}
+
+func setInterfaceConfig(podInterface *PodInterface, containerConfig *interfacestore.InterfaceConfig) {
+	if containerConfig.OVSPortConfig != nil {
+		podInterface.PortUUID = containerConfig.PortUUID
+		podInterface.OFPort = containerConfig.OFPort
+	}
+	podInterface.MAC = containerConfig.MAC.String()
+	for _, ip := range containerConfig.IPs {
+		podInterface.IPs = append(podInterface.IPs, ip.String())
+	}
+}
+
+// setResultConfig sets the MAC and the IPs of the container interface from the CNI result.
+func setResultConfig(podInterface *PodInterface, cniResult []byte) {
+	if len(cniResult) == 0 {
+		return
+	}
+	r, err := current.NewResult(cniResult)
+	if err != nil {
+		klog.Warningf("Failed to parse the CNI result of container %s: %v", podInterface.ContainerID, err)
+		return
+	}
+	result := r.(*current.Result)
+	for i, intf := range result.Interfaces {
+		if intf.Name != podInterface.IfName || intf.Sandbox == "" {
+			continue
+		}
+		podInterface.MAC = intf.Mac
+		for _, ipc := range result.IPs {
+			if ipc.Interface != nil && *ipc.Interface == i {
+				podInterface.IPs = append(podInterface.IPs, ipc.Address.IP.String())
+			}
+		}
+	}
+}
+
+func (s *Server) handlePorts(w http.ResponseWriter, r *http.Request) {
+	ovsPorts, err := s.ovsBridgeClient.GetPortList()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ports := make([]Port, 0, len(ovsPorts))
+	for _, ovsPort := range ovsPorts {
+		port := Port{
+			Name:                 ovsPort.Name,
+			UUID:                 ovsPort.UUID,
+			OFPort:               ovsPort.OFPort,
+			IFType:               ovsPort.IFType,
+			ExternalIDs:          ovsPort.ExternalIDs,
+			InterfaceExternalIDs: ovsPort.IFExternalIDs,
+		}
+		if config, ok := s.ifaceStore.GetInterfaceByName(ovsPort.Name); ok && config.Type == interfacestore.ContainerInterface {
+			port.PodName = config.PodName
+			port.PodNamespace = config.PodNamespace
+			port.ContainerID = config.ContainerID
+		}
+		ports = append(ports, port)
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].Name < ports[j].Name })
+	writeJSON(w, ports)
+}
+
+// handleOVSFlows returns the flows of the interfaces of the Pod given as <namespace>/<name> by the
+// pod parameter, the namespace defaults to "default".
+func (s *Server) handleOVSFlows(w http.ResponseWriter, r *http.Request) {
+	pod := r.URL.Query().Get("pod")
+	if pod == "" {
+		http.Error(w, "Missing pod parameter", http.StatusBadRequest)
+		return
+	}
+	namespace, name := "default", pod
+	if i := strings.Index(pod, "/"); i >= 0 {
+		namespace, name = pod[:i], pod[i+1:]
+	}
+	containerConfigs := s.ifaceStore.GetContainerInterfacesByPod(name, namespace)
+	if len(containerConfigs) == 0 {
+		http.Error(w, fmt.Sprintf("Pod %s/%s has no interface on the OVS bridge", namespace, name), http.StatusNotFound)
+		return
+	}
+	flows, err := s.ovsCtlClient.DumpFlows()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	interfaceFlows := []InterfaceFlows{}
+	for _, containerConfig := range containerConfigs {
+		ofPort := int32(0)
+		if containerConfig.OVSPortConfig != nil {
+			ofPort = containerConfig.OFPort
+		}
+		interfaceFlows = append(interfaceFlows, InterfaceFlows{
+			PodName:      name,
+			PodNamespace: namespace,
+			HostIface:    containerConfig.InterfaceName,
+			OFPort:       ofPort,
+			Flows:        matchInterfaceFlows(flows, containerConfig.InterfaceName, ofPort, containerConfig.MAC, containerConfig.IPs),
+		})
+	}
+	writeJSON(w, interfaceFlows)
+}
+
+// matchInterfaceFlows returns the flows matching the port, the MAC or the IPs of an interface, in
+// their match or in their output actions. The port is given by its name in the flows dumped with
+// --names, or by its ofport when OVS doesn't know its name.
+func matchInterfaceFlows(flows []string, portName string, ofPort int32, mac net.HardwareAddr, ips []net.IP) []string {
+	var patterns []string
+	if portName != "" {
+		quoted := fmt.Sprintf(`"?%s"?(,|\s|$)`, regexp.QuoteMeta(portName))
+		patterns = append(patterns, "in_port="+quoted, "output:"+quoted)
+	}
+	if ofPort > 0 {
+		patterns = append(patterns, fmt.Sprintf(`in_port=%d\b`, ofPort), fmt.Sprintf(`output:%d\b`, ofPort))
+	}
+	if len(mac) > 0 {
+		patterns = append(patterns, regexp.QuoteMeta(mac.String()))
+	}
+	for _, ip := range ips {
+		patterns = append(patterns, fmt.Sprintf(`=%s\b`, regexp.QuoteMeta(ip.String())))
+	}
+	matched := []string{}
+	if len(patterns) == 0 {
+		return matched
+	}
+	re := regexp.MustCompile(strings.Join(patterns, "|"))
+	for _, flow := range flows {
+		if re.MatchString(flow) {
+			matched = append(matched, flow)
+		}
+	}
+	return matched
+}
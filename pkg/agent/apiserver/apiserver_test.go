@@ -0,0 +1,159 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"projectkuryr/kuryr/pkg/agent/cnistate"
+	"projectkuryr/kuryr/pkg/agent/interfacestore"
+	"projectkuryr/kuryr/pkg/ovs/ovsconfig"
+	"projectkuryr/kuryr/pkg/ovs/ovsctl"
+)
+
+type fakeOVSBridgeClient struct {
+	ovsconfig.OVSBridgeClient
+	ports []ovsconfig.OVSPortData
+}
+
+func (c *fakeOVSBridgeClient) GetPortList() ([]ovsconfig.OVSPortData, ovsconfig.Error) {
+	return c.ports, nil
+}
+
+type fakeOVSCtlClient struct {
+	ovsctl.OVSCtlClient
+	flows  []string
+	traced *ovsctl.TracingRequest
+}
+
+func (c *fakeOVSCtlClient) DumpFlows(_ ...string) ([]string, error) {
+	return c.flows, nil
+}
+
+func (c *fakeOVSCtlClient) Trace(req *ovsctl.TracingRequest) (string, error) {
+	c.traced = req
+	return "Flow: " + req.Flow + "\n\nbridge(\"br-int\")\n----------------\n 0. in_port=5, priority 100\n    NORMAL\n\nDatapath actions: 7\n", nil
+}
+
+const testToken = "secret"
+
+func newTestServer(t *testing.T) *Server {
+	dir, err := ioutil.TempDir("", "kuryr-agent-api")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	stateStore, err := cnistate.NewStore(dir)
+	require.NoError(t, err)
+	require.NoError(t, stateStore.Save(&cnistate.Attachment{
+		ContainerID: "c1", IfName: "eth0", PodName: "p1", PodNamespace: "ns1", VifID: "port-1", HostIface: "tap1",
+	}))
+	require.NoError(t, stateStore.Save(&cnistate.Attachment{
+		ContainerID: "c2", IfName: "eth0", PodName: "p2", PodNamespace: "ns1", VifID: "port-2", PCISlot: "0000:05:00.1",
+		CNIResult: []byte(`{"cniVersion":"0.4.0","interfaces":[{"name":"eth0","mac":"fa:16:3e:00:00:02","sandbox":"/proc/2/ns/net"}],"ips":[{"version":"4","interface":0,"address":"10.0.0.2/24"}]}`),
+	}))
+
+	ifaceStore := interfacestore.NewInterfaceStore()
+	mac, _ := net.ParseMAC("fa:16:3e:00:00:01")
+	containerConfig := interfacestore.NewContainerInterface("tap1", "c1", "p1", "ns1", mac, []net.IP{net.ParseIP("10.0.0.1")})
+	containerConfig.OVSPortConfig = &interfacestore.OVSPortConfig{PortUUID: "uuid-1", OFPort: 5}
+	ifaceStore.AddInterface(containerConfig)
+
+	ovsBridgeClient := &fakeOVSBridgeClient{ports: []ovsconfig.OVSPortData{
+		{Name: "tap1", UUID: "uuid-1", OFPort: 5},
+		{Name: "br-int", UUID: "uuid-0", OFPort: 65534},
+	}}
+	ovsCtlClient := &fakeOVSCtlClient{flows: []string{
+		"table=0, priority=100,in_port=tap1 actions=resubmit(,10)",
+		"table=0, priority=100,in_port=tap10 actions=resubmit(,10)",
+		"table=60, priority=100,dl_dst=fa:16:3e:00:00:01 actions=output:tap1",
+		"table=60, priority=100,ip,nw_dst=10.0.0.11 actions=output:7",
+	}}
+	return New(DefaultBindAddress, testToken, ifaceStore, stateStore, ovsBridgeClient, ovsCtlClient)
+}
+
+func get(t *testing.T, s *Server, url, token string, obj interface{}) int {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if rec.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), obj))
+	}
+	return rec.Code
+}
+
+func TestAuthentication(t *testing.T) {
+	s := newTestServer(t)
+	var pods []PodInterface
+	assert.Equal(t, http.StatusUnauthorized, get(t, s, "/v1/pods", "", &pods))
+	assert.Equal(t, http.StatusUnauthorized, get(t, s, "/v1/pods", "wrong", &pods))
+	assert.Equal(t, http.StatusOK, get(t, s, "/v1/pods", testToken, &pods))
+}
+
+func TestPods(t *testing.T) {
+	s := newTestServer(t)
+	var pods []PodInterface
+	require.Equal(t, http.StatusOK, get(t, s, "/v1/pods", testToken, &pods))
+	assert.Equal(t, []PodInterface{
+		{
+			PodName: "p1", PodNamespace: "ns1", ContainerID: "c1", IfName: "eth0", VifID: "port-1", HostIface: "tap1",
+			PortUUID: "uuid-1", OFPort: 5, MAC: "fa:16:3e:00:00:01", IPs: []string{"10.0.0.1"},
+		},
+		{
+			PodName: "p2", PodNamespace: "ns1", ContainerID: "c2", IfName: "eth0", VifID: "port-2", PCISlot: "0000:05:00.1",
+			MAC: "fa:16:3e:00:00:02", IPs: []string{"10.0.0.2"},
+		},
+	}, pods)
+}
+
+func TestPorts(t *testing.T) {
+	s := newTestServer(t)
+	var ports []Port
+	require.Equal(t, http.StatusOK, get(t, s, "/v1/ports", testToken, &ports))
+	require.Len(t, ports, 2)
+	assert.Equal(t, Port{Name: "br-int", UUID: "uuid-0", OFPort: 65534}, ports[0])
+	assert.Equal(t, Port{Name: "tap1", UUID: "uuid-1", OFPort: 5, PodName: "p1", PodNamespace: "ns1", ContainerID: "c1"}, ports[1])
+}
+
+func TestOVSFlows(t *testing.T) {
+	s := newTestServer(t)
+	var flows []InterfaceFlows
+	require.Equal(t, http.StatusOK, get(t, s, "/v1/ovsflows?pod=ns1/p1", testToken, &flows))
+	assert.Equal(t, []InterfaceFlows{{
+		PodName:      "p1",
+		PodNamespace: "ns1",
+		HostIface:    "tap1",
+		OFPort:       5,
+		Flows: []string{
+			"table=0, priority=100,in_port=tap1 actions=resubmit(,10)",
+			"table=60, priority=100,dl_dst=fa:16:3e:00:00:01 actions=output:tap1",
+		},
+	}}, flows)
+	assert.Equal(t, http.StatusNotFound, get(t, s, "/v1/ovsflows?pod=ns1/p3", testToken, &flows))
+	assert.Equal(t, http.StatusBadRequest, get(t, s, "/v1/ovsflows", testToken, &flows))
+}
+
+func TestTraceflow(t *testing.T) {
+	s := newTestServer(t)
+	var tf Traceflow
+	require.Equal(t, http.StatusOK, get(t, s, "/v1/traceflow?pod=ns1/p1&dst=10.0.0.2&protocol=TCP&dstPort=80", testToken, &tf))
+	assert.Equal(t, "tap1", tf.InPort)
+	assert.Equal(t, "10.0.0.1", tf.SrcIP)
+	assert.Equal(t, "fa:16:3e:00:00:02", tf.DstMAC)
+	assert.Equal(t, "tcp,tp_dst=80", tf.Flow)
+	require.Len(t, tf.Passes, 1)
+	assert.Equal(t, []string{"NORMAL"}, tf.Passes[0].Stages[0].Actions)
+	assert.Equal(t, "Forwarded", tf.Verdict)
+
+	assert.Equal(t, http.StatusNotFound, get(t, s, "/v1/traceflow?pod=ns1/p2&dst=10.0.0.1", testToken, &tf))
+	assert.Equal(t, http.StatusBadRequest, get(t, s, "/v1/traceflow?pod=ns1/p1&dst=fd00::1", testToken, &tf))
+	assert.Equal(t, http.StatusBadRequest, get(t, s, "/v1/traceflow?pod=ns1/p1", testToken, &tf))
+}
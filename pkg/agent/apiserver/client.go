@@ -0,0 +1,79 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Client is a client of the node-local API of kuryr-agent.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient returns a client of the API served on the address, e.g. DefaultBindAddress.
+func NewClient(address, token string) *Client {
+	return &Client{
+		baseURL:    "http://" + address,
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (c *Client) get(path string, query url.Values, obj interface{}) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query kuryr-agent: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read the response of kuryr-agent: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("kuryr-agent answered %s: %s", resp.Status, body)
+	}
+	return json.Unmarshal(body, obj)
+}
+
+// Pods returns the interfaces of the Pods of the Node.
+func (c *Client) Pods() ([]PodInterface, error) {
+	var podInterfaces []PodInterface
+	err := c.get("/v1/pods", nil, &podInterfaces)
+	return podInterfaces, err
+}
+
+// Ports returns the ports of the OVS bridge.
+func (c *Client) Ports() ([]Port, error) {
+	var ports []Port
+	err := c.get("/v1/ports", nil, &ports)
+	return ports, err
+}
+
+// OVSFlows returns the flows of the interfaces of the Pod.
+func (c *Client) OVSFlows(namespace, name string) ([]InterfaceFlows, error) {
+	var interfaceFlows []InterfaceFlows
+	err := c.get("/v1/ovsflows", url.Values{"pod": []string{namespace + "/" + name}}, &interfaceFlows)
+	return interfaceFlows, err
+}
+
+// Traceflow traces a packet of a Pod of the Node through the OVS bridge.
+func (c *Client) Traceflow(q *TraceflowQuery) (*Traceflow, error) {
+	tf := &Traceflow{}
+	err := c.get("/v1/traceflow", q.values(), tf)
+	return tf, err
+}
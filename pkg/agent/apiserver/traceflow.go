@@ -0,0 +1,168 @@
+package apiserver
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"projectkuryr/kuryr/pkg/agent/traceflow"
+	"projectkuryr/kuryr/pkg/ovs/ovsctl"
+)
+
+func (q *TraceflowQuery) values() url.Values {
+	values := url.Values{}
+	values.Set("pod", q.PodNamespace+"/"+q.PodName)
+	values.Set("dst", q.DstIP)
+	for key, value := range map[string]string{"ifname": q.IfName, "dstMAC": q.DstMAC, "protocol": q.Protocol, "flow": q.Flow} {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	if q.DstPort != 0 {
+		values.Set("dstPort", strconv.Itoa(q.DstPort))
+	}
+	return values
+}
+
+func parseTraceflowQuery(values url.Values) (*TraceflowQuery, error) {
+	q := &TraceflowQuery{
+		PodNamespace: "default",
+		PodName:      values.Get("pod"),
+		IfName:       values.Get("ifname"),
+		DstIP:        values.Get("dst"),
+		DstMAC:       values.Get("dstMAC"),
+		Protocol:     values.Get("protocol"),
+		Flow:         values.Get("flow"),
+	}
+	if q.PodName == "" {
+		return nil, fmt.Errorf("missing pod parameter")
+	}
+	if i := strings.Index(q.PodName, "/"); i >= 0 {
+		q.PodNamespace, q.PodName = q.PodName[:i], q.PodName[i+1:]
+	}
+	if q.DstIP == "" {
+		return nil, fmt.Errorf("missing dst parameter")
+	}
+	if dstPort := values.Get("dstPort"); dstPort != "" {
+		port, err := strconv.Atoi(dstPort)
+		if err != nil || port <= 0 || port > 65535 {
+			return nil, fmt.Errorf("invalid dstPort %s", dstPort)
+		}
+		q.DstPort = port
+	}
+	return q, nil
+}
+
+// handleTraceflow traces a packet from a tap of a Pod of the Node with ofproto/trace.
+func (s *Server) handleTraceflow(w http.ResponseWriter, r *http.Request) {
+	q, err := parseTraceflowQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	podInterfaces, err := s.podInterfaces()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	src := findTapInterface(podInterfaces, q.PodNamespace, q.PodName, q.IfName)
+	if src == nil {
+		http.Error(w, fmt.Sprintf("Pod %s/%s has no tap on the OVS bridge", q.PodNamespace, q.PodName), http.StatusNotFound)
+		return
+	}
+	req, err := newTracingRequest(q, src, podInterfaces)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := traceflow.Trace(s.ovsCtlClient, req)
+	if _, ok := err.(ovsctl.BadRequestError); ok {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf("failed to trace the packet: %v", err), http.StatusInternalServerError)
+		return
+	}
+	tf := &Traceflow{
+		PodName:      q.PodName,
+		PodNamespace: q.PodNamespace,
+		InPort:       req.InPort,
+		SrcIP:        req.SrcIP.String(),
+		DstIP:        req.DstIP.String(),
+		SrcMAC:       req.SrcMAC.String(),
+		Flow:         req.Flow,
+		Result:       result,
+	}
+	if req.DstMAC != nil {
+		tf.DstMAC = req.DstMAC.String()
+	}
+	writeJSON(w, tf)
+}
+
+// newTracingRequest returns the ofproto/trace request of the packet of the query sent by the
+// interface src.
+func newTracingRequest(q *TraceflowQuery, src *PodInterface, podInterfaces []PodInterface) (*ovsctl.TracingRequest, error) {
+	req := &ovsctl.TracingRequest{InPort: src.HostIface}
+	if req.DstIP = net.ParseIP(q.DstIP); req.DstIP == nil {
+		return nil, fmt.Errorf("invalid destination IP %s", q.DstIP)
+	}
+	ipv6 := req.DstIP.To4() == nil
+	for _, ip := range src.IPs {
+		if srcIP := net.ParseIP(ip); srcIP != nil && (srcIP.To4() == nil) == ipv6 {
+			req.SrcIP = srcIP
+			break
+		}
+	}
+	if req.SrcIP == nil {
+		return nil, fmt.Errorf("Pod %s/%s has no IP of the family of %s", q.PodNamespace, q.PodName, req.DstIP)
+	}
+	var err error
+	if req.SrcMAC, err = net.ParseMAC(src.MAC); err != nil {
+		return nil, fmt.Errorf("invalid MAC %q of Pod %s/%s", src.MAC, q.PodNamespace, q.PodName)
+	}
+	if q.DstMAC != "" {
+		if req.DstMAC, err = net.ParseMAC(q.DstMAC); err != nil {
+			return nil, fmt.Errorf("invalid destination MAC %s", q.DstMAC)
+		}
+	} else if dst := findIPInterface(podInterfaces, req.DstIP); dst != nil {
+		// The packet is switched to a Pod of the same network, else it goes to the router whose
+		// MAC must be given.
+		req.DstMAC, _ = net.ParseMAC(dst.MAC)
+	}
+	if req.Flow, err = traceflow.ProtocolFlow(q.Protocol, q.DstPort, ipv6); err != nil {
+		return nil, err
+	}
+	if q.Flow != "" {
+		req.Flow += "," + q.Flow
+	}
+	return req, nil
+}
+
+// findTapInterface returns the interface of the Pod plugged into the OVS bridge, named ifName if not
+// empty.
+func findTapInterface(podInterfaces []PodInterface, namespace, name, ifName string) *PodInterface {
+	for i := range podInterfaces {
+		podInterface := &podInterfaces[i]
+		if podInterface.PodNamespace != namespace || podInterface.PodName != name || podInterface.HostIface == "" {
+			continue
+		}
+		if ifName == "" || podInterface.IfName == ifName {
+			return podInterface
+		}
+	}
+	return nil
+}
+
+func findIPInterface(podInterfaces []PodInterface, ip net.IP) *PodInterface {
+	for i := range podInterfaces {
+		for _, podIP := range podInterfaces[i].IPs {
+			if ip.Equal(net.ParseIP(podIP)) {
+				return &podInterfaces[i]
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package apiserver
+
+import "projectkuryr/kuryr/pkg/agent/traceflow"
+
+// PodInterface is an interface of a Pod of the Node, with its VIF and its OVS port.
+type PodInterface struct {
+	PodName      string `json:"podName"`
+	PodNamespace string `json:"podNamespace"`
+	ContainerID  string `json:"containerID"`
+	IfName       string `json:"ifName,omitempty"`
+	Network      string `json:"network,omitempty"`
+	Netns        string `json:"netns,omitempty"`
+	// VifID is the ID of the Neutron port of the interface.
+	VifID string `json:"vifID,omitempty"`
+	// HostIface is the tap of the interface, it is empty for a VF.
+	HostIface string   `json:"hostIface,omitempty"`
+	PCISlot   string   `json:"pciSlot,omitempty"`
+	PortUUID  string   `json:"portUUID,omitempty"`
+	OFPort    int32    `json:"ofPort,omitempty"`
+	MAC       string   `json:"mac,omitempty"`
+	IPs       []string `json:"ips,omitempty"`
+}
+
+// Port is a port of the OVS bridge, the Pod fields are set for the taps of the Pods.
+type Port struct {
+	Name                 string            `json:"name"`
+	UUID                 string            `json:"uuid"`
+	OFPort               int32             `json:"ofPort"`
+	IFType               string            `json:"ifType,omitempty"`
+	ExternalIDs          map[string]string `json:"externalIDs,omitempty"`
+	InterfaceExternalIDs map[string]string `json:"interfaceExternalIDs,omitempty"`
+	PodName              string            `json:"podName,omitempty"`
+	PodNamespace         string            `json:"podNamespace,omitempty"`
+	ContainerID          string            `json:"containerID,omitempty"`
+}
+
+// InterfaceFlows are the OpenFlow flows matching an interface of a Pod, i.e. its ofport, its MAC or
+// its IPs.
+type InterfaceFlows struct {
+	PodName      string   `json:"podName"`
+	PodNamespace string   `json:"podNamespace"`
+	HostIface    string   `json:"hostIface"`
+	OFPort       int32    `json:"ofPort"`
+	Flows        []string `json:"flows"`
+}
+
+// Traceflow is the trace of a packet of a Pod through the OVS bridge, the fields of the result of
+// ofproto/trace are inlined.
+type Traceflow struct {
+	PodName      string `json:"podName"`
+	PodNamespace string `json:"podNamespace"`
+	InPort       string `json:"inPort"`
+	SrcIP        string `json:"srcIP"`
+	DstIP        string `json:"dstIP"`
+	SrcMAC       string `json:"srcMAC"`
+	DstMAC       string `json:"dstMAC,omitempty"`
+	Flow         string `json:"flow,omitempty"`
+	*traceflow.Result
+}
+
+// TraceflowQuery is a packet of a Pod to trace, the destination is an IP.
+type TraceflowQuery struct {
+	PodNamespace string
+	PodName      string
+	// IfName is the interface of the Pod, its first tap by default.
+	IfName string
+	DstIP  string
+	// DstMAC is the destination MAC, e.g. the MAC of the router for a destination in another
+	// subnet. The MAC of the Pod of the destination IP is used by default if it runs on the Node.
+	DstMAC string
+	// Protocol is one of v1alpha1.SupportedProtocols, ICMP by default.
+	Protocol string
+	DstPort  int
+	// Flow are additional fields of the packet, e.g. nw_tos=0.
+	Flow string
+}
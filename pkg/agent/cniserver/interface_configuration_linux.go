@@ -22,6 +22,7 @@ import (
 	"net"
 	"projectkuryr/kuryr/pkg/agent/util"
 	"projectkuryr/kuryr/pkg/agent/util/arping"
+	"projectkuryr/kuryr/pkg/agent/util/ndp"
 	"projectkuryr/kuryr/pkg/agent/util/ethtool"
 	cnipb "projectkuryr/kuryr/pkg/apis/cni/v1alpha1"
 	"projectkuryr/kuryr/pkg/ovs/ovsconfig"
@@ -42,6 +43,9 @@ const (
 	netDeviceTypeVF   = "vf"
 )
 
+// delLinkByName deletes a link of the host netns, it is replaced by the tests.
+var delLinkByName = ip.DelLinkByName
+
 type ifConfigurator struct {
 	ovsDatapathType             ovsconfig.OVSDatapathType
 	isOvsHardwareOffloadEnabled bool
@@ -82,7 +86,10 @@ func moveIfToNetns(ifname string, netns ns.NetNS) error {
 	return nil
 }
 
-// configureContainerLinkSriov move VF to the container namesapce
+// configureContainerLinkSriov moves the VF to the container netns, renames it to the container
+// interface name and configures its IP addresses and routes. The MAC address and the VLAN of the VF
+// are set on its PF, an empty MAC keeps the VF one and vlan 0 means no VLAN. A VF already moved to
+// the container netns by a previous ADD is released first.
 func (ic *ifConfigurator) configureContainerLinkSriov(
 	podName string,
 	podNamespace string,
@@ -91,8 +98,183 @@ func (ic *ifConfigurator) configureContainerLinkSriov(
 	containerIfaceName string,
 	mtu int,
 	pciAddress string,
+	mac string,
+	vlan int,
 	result *current.Result,
-) error {
+) (err error) {
+	if err := ic.releaseContainerLinkSriov(containerNetNS, containerIfaceName); err != nil {
+		return err
+	}
+	vfName, err := getVFNetDevice(pciAddress)
+	if err != nil {
+		return err
+	}
+	pfLink, vfIndex, err := getVFPF(pciAddress)
+	if err != nil {
+		return err
+	}
+	// The VF is reset if it can't be configured, the rollback DEL doesn't find it when it is not
+	// renamed in the container netns yet.
+	var origMAC net.HardwareAddr
+	for _, vf := range pfLink.Attrs().Vfs {
+		if vf.ID == vfIndex {
+			origMAC = vf.Mac
+		}
+	}
+	defer func() {
+		if err != nil {
+			if resetErr := resetVF(containerNetNS, containerIfaceName, pciAddress, vfName, origMAC); resetErr != nil {
+				klog.Errorf("Failed to reset VF %s after configuration failure: %v", pciAddress, resetErr)
+			}
+		}
+	}()
+	if mac != "" {
+		hardwareAddr, err := net.ParseMAC(mac)
+		if err != nil {
+			return fmt.Errorf("failed to parse MAC %s: %v", mac, err)
+		}
+		if err := netlink.LinkSetVfHardwareAddr(pfLink, vfIndex, hardwareAddr); err != nil {
+			return fmt.Errorf("failed to set MAC %s on VF %s: %v", mac, pciAddress, err)
+		}
+	}
+	if err := netlink.LinkSetVfVlan(pfLink, vfIndex, vlan); err != nil {
+		return fmt.Errorf("failed to set VLAN %d on VF %s: %v", vlan, pciAddress, err)
+	}
+
+	vfLink, err := netlink.LinkByName(vfName)
+	if err != nil {
+		return fmt.Errorf("failed to lookup VF device %s: %v", vfName, err)
+	}
+	if err := netlink.LinkSetDown(vfLink); err != nil {
+		return err
+	}
+	// The alias keeps the PCI address and the host name of the VF, they are needed to release it.
+	if err := netlink.LinkSetAlias(vfLink, vfAlias(pciAddress, vfName)); err != nil {
+		return fmt.Errorf("failed to set alias of VF device %s: %v", vfName, err)
+	}
+	containerNS, err := ns.GetNS(containerNetNS)
+	if err != nil {
+		return err
+	}
+	defer containerNS.Close()
+	klog.V(2).Infof("Moving VF %s (%s) to network namespace of container %s", pciAddress, vfName, containerID)
+	if err := moveIfToNetns(vfName, containerNS); err != nil {
+		return err
+	}
+
+	hostIface := &current.Interface{Name: vfName}
+	containerIface := &current.Interface{Name: containerIfaceName, Sandbox: containerNetNS}
+	result.Interfaces = []*current.Interface{hostIface, containerIface}
+	return containerNS.Do(func(_ ns.NetNS) error {
+		if err := renameLink(vfName, containerIfaceName); err != nil {
+			return fmt.Errorf("failed to rename VF device %s to %s: %v", vfName, containerIfaceName, err)
+		}
+		link, err := netlink.LinkByName(containerIfaceName)
+		if err != nil {
+			return fmt.Errorf("failed to lookup %q: %v", containerIfaceName, err)
+		}
+		if mtu != 0 {
+			if err := netlink.LinkSetMTU(link, mtu); err != nil {
+				return fmt.Errorf("failed to set MTU %d on %s: %v", mtu, containerIfaceName, err)
+			}
+		}
+		containerIface.Mac = link.Attrs().HardwareAddr.String()
+		klog.V(2).Infof("Configuring IP address for container %s", containerID)
+		// result.Interfaces must be set before this.
+		if err := ipam.ConfigureIface(containerIfaceName, result); err != nil {
+			return fmt.Errorf("failed to configure IP address for container %s: %v", containerID, err)
+		}
+		return nil
+	})
+}
+
+// releaseContainerLinkSriov moves the VF back to the host netns with its host name and clears its
+// VLAN. Nothing is done if the container interface is not a VF configured by
+// configureContainerLinkSriov, or if the container netns is gone as the kernel moves the VFs back
+// itself.
+func (ic *ifConfigurator) releaseContainerLinkSriov(containerNetNS, containerIfaceName string) error {
+	if containerNetNS == "" || ns.IsNSorErr(containerNetNS) != nil {
+		return nil
+	}
+	var pciAddress, vfName string
+	if err := ns.WithNetNSPath(containerNetNS, func(hostNS ns.NetNS) error {
+		link, err := netlink.LinkByName(containerIfaceName)
+		if err != nil {
+			if _, ok := err.(netlink.LinkNotFoundError); ok {
+				return nil
+			}
+			return fmt.Errorf("failed to lookup %q: %v", containerIfaceName, err)
+		}
+		var isVF bool
+		if pciAddress, vfName, isVF = parseVFAlias(link.Attrs().Alias); !isVF {
+			return nil
+		}
+		klog.V(2).Infof("Moving VF %s (%s) back to the host network namespace", pciAddress, vfName)
+		if err := netlink.LinkSetDown(link); err != nil {
+			return err
+		}
+		if err := netlink.LinkSetName(link, vfName); err != nil {
+			return fmt.Errorf("failed to rename %s to %s: %v", containerIfaceName, vfName, err)
+		}
+		if err := netlink.LinkSetNsFd(link, int(hostNS.Fd())); err != nil {
+			return fmt.Errorf("failed to move VF device %s to the host netns: %v", vfName, err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if pciAddress == "" {
+		return nil
+	}
+	return clearVF(pciAddress, vfName, nil)
+}
+
+// resetVF moves the VF back to the host netns with its host name if configureContainerLinkSriov
+// already moved it to the container netns, then clears it with clearVF.
+func resetVF(containerNetNS, containerIfaceName, pciAddress, vfName string, mac net.HardwareAddr) error {
+	if containerNetNS != "" && ns.IsNSorErr(containerNetNS) == nil {
+		if err := ns.WithNetNSPath(containerNetNS, func(hostNS ns.NetNS) error {
+			// The VF is not renamed yet if the rename failed.
+			for _, name := range []string{containerIfaceName, vfName} {
+				link, err := netlink.LinkByName(name)
+				if err != nil || link.Attrs().Alias != vfAlias(pciAddress, vfName) {
+					continue
+				}
+				netlink.LinkSetDown(link)
+				if err := netlink.LinkSetName(link, vfName); err != nil {
+					return fmt.Errorf("failed to rename %s to %s: %v", name, vfName, err)
+				}
+				if err := netlink.LinkSetNsFd(link, int(hostNS.Fd())); err != nil {
+					return fmt.Errorf("failed to move VF device %s to the host netns: %v", vfName, err)
+				}
+				return nil
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return clearVF(pciAddress, vfName, mac)
+}
+
+// clearVF clears the alias of the VF in the host netns and its VLAN, and restores its MAC address
+// on its PF when mac is not nil.
+func clearVF(pciAddress, vfName string, mac net.HardwareAddr) error {
+	if link, err := netlink.LinkByName(vfName); err == nil {
+		netlink.LinkSetAlias(link, "")
+	}
+	pfLink, vfIndex, err := getVFPF(pciAddress)
+	if err != nil {
+		return err
+	}
+	if err := netlink.LinkSetVfVlan(pfLink, vfIndex, 0); err != nil {
+		return fmt.Errorf("failed to clear VLAN of VF %s: %v", pciAddress, err)
+	}
+	if mac != nil {
+		if err := netlink.LinkSetVfHardwareAddr(pfLink, vfIndex, mac); err != nil {
+			return fmt.Errorf("failed to restore MAC %s on VF %s: %v", mac, pciAddress, err)
+		}
+	}
 	return nil
 }
 
@@ -193,10 +375,11 @@ func (ic *ifConfigurator) configureContainerLinkVeth(
 	return nil
 }
 
-// advertiseContainerAddr sends 3 GARP packets in another goroutine with 50ms interval, if the
-// container interface is assigned an IPv4 address. It's because Openflow entries are installed
-// asynchronously, and the gratuitous ARP could be sent out after the Openflow entries are
-// installed. Using another goroutine to ensure the processing of CNI ADD request is not blocked.
+// advertiseContainerAddr sends 3 GARP packets for the IPv4 addresses and 3 unsolicited neighbor
+// advertisements for the IPv6 addresses of the container interface in another goroutine with 50ms
+// interval. It's because Openflow entries are installed asynchronously, and the advertisements
+// could be sent out after the Openflow entries are installed. Using another goroutine to ensure
+// the processing of CNI ADD request is not blocked.
 func (ic *ifConfigurator) advertiseContainerAddr(containerNetNS string, containerIfaceName string, result *current.Result) error {
 	if err := ns.IsNSorErr(containerNetNS); err != nil {
 		return fmt.Errorf("%s is not a valid network namespace: %v", containerNetNS, err)
@@ -212,24 +395,39 @@ func (ic *ifConfigurator) advertiseContainerAddr(containerNetNS string, containe
 			klog.Errorf("Failed to find container interface %s in ns %s: %v", containerIfaceName, containerNetNS, err)
 			return nil
 		}
-		var targetIP net.IP
+		var ipv4s, ipv6s []net.IP
 		for _, ipc := range result.IPs {
-			if ipc.Version == "4" {
-				targetIP = ipc.Address.IP
+			if ipc.Interface != nil && *ipc.Interface >= len(result.Interfaces) {
+				continue
+			}
+			if ipc.Interface != nil && result.Interfaces[*ipc.Interface].Name != containerIfaceName {
+				continue
+			}
+			if ipc.Address.IP.To4() != nil {
+				ipv4s = append(ipv4s, ipc.Address.IP)
+			} else {
+				ipv6s = append(ipv6s, ipc.Address.IP)
 			}
 		}
-		if targetIP == nil {
-			klog.V(2).Infof("No IPv4 address found for container interface %s in ns %s, skip sending Gratuitous ARP", containerIfaceName, containerNetNS)
+		if len(ipv4s) == 0 && len(ipv6s) == 0 {
+			klog.V(2).Infof("No IP address found for container interface %s in ns %s, skip sending Gratuitous ARP", containerIfaceName, containerNetNS)
 			return nil
 		}
 		ticker := time.NewTicker(50 * time.Millisecond)
 		defer ticker.Stop()
 		count := 0
 		for {
-			// Send gratuitous ARP to network in case of stale mappings for this IP address
-			// (e.g. if a previous - deleted - Pod was using the same IP).
-			if err := arping.GratuitousARPOverIface(targetIP, iface); err != nil {
-				klog.Warningf("Failed to send gratuitous ARP #%d: %v", count, err)
+			// Send gratuitous ARP and unsolicited NA to network in case of stale mappings for
+			// these IP addresses (e.g. if a previous - deleted - Pod was using the same IP).
+			for _, ip := range ipv4s {
+				if err := arping.GratuitousARPOverIface(ip, iface); err != nil {
+					klog.Warningf("Failed to send gratuitous ARP #%d for %s: %v", count, ip, err)
+				}
+			}
+			for _, ip := range ipv6s {
+				if err := ndp.NeighborAdvertisement(ip, iface); err != nil {
+					klog.Warningf("Failed to send neighbor advertisement #%d for %s: %v", count, ip, err)
+				}
 			}
 			count++
 			if count == 3 {
@@ -260,7 +458,7 @@ func (ic *ifConfigurator) configureContainerLink(
 		}
 		klog.V(2).Infof("Moving SR-IOV %s device to network namespace of container %s", sriovVFDeviceID, containerID)
 		// Move SR-IOV VF to network namespace
-		return ic.configureContainerLinkSriov(podName, podNamespace, containerID, containerNetNS, containerIfaceName, mtu, sriovVFDeviceID, result)
+		return ic.configureContainerLinkSriov(podName, podNamespace, containerID, containerNetNS, containerIfaceName, mtu, sriovVFDeviceID, "", 0, result)
 	} else {
 		klog.V(2).Infof("Create veth pair for container %s", containerID)
 		// Create veth pair and link up
@@ -271,7 +469,7 @@ func (ic *ifConfigurator) configureContainerLink(
 func (ic *ifConfigurator) removeContainerLink(containerID, hostInterfaceName string) error {
 	klog.V(2).Infof("Deleting veth devices for container %s", containerID)
 	// Don't return an error if the device is already removed as CniDel can be called multiple times.
-	if err := ip.DelLinkByName(hostInterfaceName); err != nil {
+	if err := delLinkByName(hostInterfaceName); err != nil {
 		if err != ip.ErrLinkNotFound {
 			return fmt.Errorf("failed to delete veth devices for container %s: %v", containerID, err)
 		}
@@ -294,6 +492,7 @@ func (ic *ifConfigurator) checkContainerInterface(
 	containerIface *current.Interface,
 	containerIPs []*current.IPConfig,
 	containerRoutes []*cnitypes.Route,
+	mtu int,
 	sriovVFDeviceID string) (interface{}, error) {
 	var networkInterface interface{}
 	// Check netns configuration
@@ -316,6 +515,17 @@ func (ic *ifConfigurator) checkContainerInterface(
 		if errlink != nil {
 			return errlink
 		}
+		// Check container MTU config, 0 means the MTU is unknown.
+		if mtu != 0 {
+			link, err := netlink.LinkByName(containerIface.Name)
+			if err != nil {
+				return fmt.Errorf("failed to find link for interface %s", containerIface.Name)
+			}
+			if link.Attrs().MTU != mtu {
+				return fmt.Errorf("interface %s MTU %d doesn't match expected MTU: %d",
+					containerIface.Name, link.Attrs().MTU, mtu)
+			}
+		}
 		// Check container IP config
 		if err := ip.ValidateExpectedInterfaceIPs(containerIface.Name, containerIPs); err != nil {
 			return err
@@ -338,6 +548,13 @@ func validateContainerVFInterface(intf *current.Interface, sriovVFDeviceID strin
 	if err != nil {
 		return nil, err
 	}
+	if pciAddress, _, isVF := parseVFAlias(link.Attrs().Alias); !isVF || pciAddress != sriovVFDeviceID {
+		return nil, fmt.Errorf("interface %s is not the VF %s", intf.Name, sriovVFDeviceID)
+	}
+	if intf.Mac != link.Attrs().HardwareAddr.String() {
+		return nil, fmt.Errorf("interface %s MAC %s doesn't match container MAC: %s",
+			intf.Name, intf.Mac, link.Attrs().HardwareAddr.String())
+	}
 
 	return link, nil
 }
@@ -461,6 +678,7 @@ func validateInterface(intf *current.Interface, inNetns bool, ifType string) (ne
 		if !isVeth(link) {
 			return nil, fmt.Errorf("interface %s is not of type veth", intf.Name)
 		}
+		return link, nil
 	} else if ifType == netDeviceTypeVF {
 
 		return link, nil
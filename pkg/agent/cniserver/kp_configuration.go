@@ -1,21 +1,70 @@
 package cniserver
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/containernetworking/cni/pkg/types/current"
+	corev1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog"
 	"net"
-	"os/exec"
+	"projectkuryr/kuryr/pkg/agent/cnistate"
+	"projectkuryr/kuryr/pkg/agent/interfacestore"
+	"projectkuryr/kuryr/pkg/agent/openflow"
+	"projectkuryr/kuryr/pkg/agent/util"
+	"projectkuryr/kuryr/pkg/apis/openstack/v1alpha1"
+	"projectkuryr/kuryr/pkg/k8s"
 	"projectkuryr/kuryr/pkg/ovs/ovsconfig"
 )
 
+const (
+	// ovsExternalIDIfaceID is the external-id of the tap Interface holding
+	// the ID of the Neutron port bound to it.
+	ovsExternalIDIfaceID = "iface-id"
+	// ovsExternalIDIfaceStatus is the external-id of the tap Interface
+	// telling the Neutron OVS agent whether the port is in use.
+	ovsExternalIDIfaceStatus = "iface-status"
+	// ovsExternalIDVMUUID is the external-id of the tap Interface set to
+	// kuryrVMUUID for all the taps plugged by kuryr.
+	ovsExternalIDVMUUID = "vm-uuid"
+	kuryrVMUUID         = "kuryr"
+	// ovsExternalIDCNIResult is the external-id of the tap Port holding the
+	// result of CmdAdd, returned again when the ADD is repeated.
+	ovsExternalIDCNIResult = "cni-result"
+	// ovsExternalIDNetwork is the external-id of the tap Port holding the
+	// name of the CNI network of the container interface.
+	ovsExternalIDNetwork = "cni-network"
+)
+
+// checkCachedInterfaces checks the interfaces of a cached CNI result in the host and the
+// container netns, it is replaced by the tests.
+var checkCachedInterfaces = (*kpConfigurator).checkInterfaces
+
+// configureTapLinkVeth creates the veth pair of a tap in the container netns, it is replaced by
+// the tests.
+var configureTapLinkVeth = (*ifConfigurator).configureContainerTapLinkVeth
+
+// interfaceByName looks up a link of the host netns, it is replaced by the tests.
+var interfaceByName = net.InterfaceByName
+
 type kpConfigurator struct {
 	ovsBridgeClient ovsconfig.OVSBridgeClient
+	ifaceStore      interfacestore.InterfaceStore
 	gatewayMAC      net.HardwareAddr
 	ifConfigurator  *ifConfigurator
+	// stateStore persists the attachments, they survive the restarts of the agent unlike ifaceStore.
+	stateStore      *cnistate.Store
+	// ofClient sends the packets of the taps to the Service pipeline of kuryrProxy, it is nil
+	// when kuryrProxy is disabled.
+	ofClient        openflow.Client
 }
 
 func newKpConfigurator(
 	ovsBridgeClient ovsconfig.OVSBridgeClient,
+	ofClient openflow.Client,
+	ifaceStore interfacestore.InterfaceStore,
+	stateStore *cnistate.Store,
 	ovsDatapathType ovsconfig.OVSDatapathType,
 	isOvsHardwareOffloadEnabled bool,
 ) (*kpConfigurator, error) {
@@ -25,24 +74,78 @@ func newKpConfigurator(
 	}
 	return &kpConfigurator{
 		ovsBridgeClient: ovsBridgeClient,
+		ifaceStore:      ifaceStore,
 		ifConfigurator:  ifConfigurator,
+		stateStore:      stateStore,
+		ofClient:        ofClient,
 	}, nil
 }
 
-func createOvsVifCmd(bridge, dev, ifaceId, mac, instanceId string) []string{
-	// ovs-vsctl -- --if-exists del-port $tapname -- add-port br-int $tapname -- set Interface $tapname external-ids:iface-id=$neutron_port external-ids:iface-status=active external-ids:attached-mac=$port_mac external-ids:vm-uuid=kuryr
-	cmd := []string{"--", "--if-exists", "del-port", dev, "--",
-		"add-port", bridge, dev,
-		"--", "set", "Interface", dev,
-		"external-ids:iface-id=" + ifaceId,
-		"external-ids:iface-status=active",
-		"external-ids:attached-mac=" + mac,
-		"external-ids:vm-uuid=" + instanceId}
-	return cmd
+// installPodServiceFlows sends the IP packets of the tap to the Service pipeline of kuryrProxy,
+// nothing is done when kuryrProxy is disabled.
+func (kc *kpConfigurator) installPodServiceFlows(tapName string, ips []net.IP, ofPort int32) error {
+	if kc.ofClient == nil {
+		return nil
+	}
+	if err := kc.ofClient.InstallPodServiceFlows(tapName, ips, uint32(ofPort)); err != nil {
+		return fmt.Errorf("failed to install Service flows of tap %s: %v", tapName, err)
+	}
+	return nil
+}
+
+// uninstallPodServiceFlows removes the flows installed by installPodServiceFlows.
+func (kc *kpConfigurator) uninstallPodServiceFlows(tapName string) error {
+	if kc.ofClient == nil {
+		return nil
+	}
+	if err := kc.ofClient.UninstallPodServiceFlows(tapName); err != nil {
+		return fmt.Errorf("failed to uninstall Service flows of tap %s: %v", tapName, err)
+	}
+	return nil
+}
+
+// getPortOwner returns the container owning the OVS port, i.e. the value of
+// its container-id external-id. "" is returned if the port does not exist or
+// has no owner.
+func (kc *kpConfigurator) getPortOwner(portName string) (string, error) {
+	ports, err := kc.ovsBridgeClient.GetPortList()
+	if err != nil {
+		return "", fmt.Errorf("failed to list OVS ports: %v", err)
+	}
+	for _, port := range ports {
+		if port.Name == portName {
+			return port.ExternalIDs[ovsExternalIDContainerID], nil
+		}
+	}
+	return "", nil
+}
+
+// findOVSPort returns the OVS port of the container, looked up by the
+// container-id external-id or else by the port name. A port owned by another
+// container is never returned. nil is returned if the port is not found.
+func (kc *kpConfigurator) findOVSPort(containerID, portName string) (*ovsconfig.OVSPortData, error) {
+	ports, err := kc.ovsBridgeClient.GetPortList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list OVS ports: %v", err)
+	}
+	for i := range ports {
+		port := &ports[i]
+		owner := port.ExternalIDs[ovsExternalIDContainerID]
+		if owner != "" && owner == containerID {
+			return port, nil
+		}
+		if portName != "" && port.Name == portName && owner == "" {
+			return port, nil
+		}
+	}
+	return nil, nil
 }
 
 func (kc *kpConfigurator) configureTap(
+	podName string,
+	podNamespace string,
 	portId string,
+	network string,
 	containerID string,
 	hostIfaceName string,
 	containerNetNS string,
@@ -53,7 +156,7 @@ func (kc *kpConfigurator) configureTap(
 	createOVSPort bool,
 	containerAccess *containerAccessArbitrator,
 ) error {
-	err := kc.ifConfigurator.configureContainerTapLinkVeth(containerID, hostIfaceName, containerNetNS, containerIFDev, mtu, mac, result)
+	err := configureTapLinkVeth(kc.ifConfigurator, containerID, hostIfaceName, containerNetNS, containerIFDev, mtu, mac, result)
 	if err != nil {
 		klog.Errorf("configureContainerLink Error: %s\n", err)
 		return err
@@ -63,32 +166,149 @@ func (kc *kpConfigurator) configureTap(
 		return nil
 	}
 
-	hostIface := result.Interfaces[0]
-	//containerIface := result.Interfaces[1]
-
 	// Delete veth pair if any failure occurs in later manipulation.
 	success := false
 	defer func() {
 		if !success {
-			_ = kc.ifConfigurator.removeContainerLink(containerID, hostIface.Name)
+			_ = kc.ifConfigurator.removeContainerLink(containerID, hostIfaceName)
 		}
 	}()
 
-	cmdArgs := createOvsVifCmd("br-int", hostIfaceName, portId, mac, "kuryr")
-	cmd := exec.Command("ovs-vsctl", cmdArgs...)
-	err = cmd.Run()
+	containerConfig := buildContainerConfig(hostIfaceName, containerID, podName, podNamespace, result.Interfaces[1], result.IPs)
+	cniResult, err := json.Marshal(result)
 	if err != nil {
-		klog.Error(err)
-	}else{
-		success = true
+		return fmt.Errorf("failed to serialize CNI result of container %s: %v", containerID, err)
 	}
+	portUUID, err := kc.plugTap(containerConfig, portId, network, string(cniResult))
+	if err != nil {
+		return fmt.Errorf("failed to add OVS port for container %s: %v", containerID, err)
+	}
+	// GetOFPort will wait for up to 5 seconds for OVSDB to report the OFPort number.
+	ofPort, err := kc.ovsBridgeClient.GetOFPort(hostIfaceName)
+	if err != nil {
+		_ = kc.ovsBridgeClient.DeletePort(portUUID)
+		return fmt.Errorf("failed to get of_port of OVS port %s: %v", hostIfaceName, err)
+	}
+	if err := kc.installPodServiceFlows(hostIfaceName, containerConfig.IPs, ofPort); err != nil {
+		_ = kc.ovsBridgeClient.DeletePort(portUUID)
+		return err
+	}
+	containerConfig.OVSPortConfig = &interfacestore.OVSPortConfig{PortUUID: portUUID, OFPort: ofPort}
+	kc.ifaceStore.AddInterface(containerConfig)
+	success = true
+	klog.Infof("Plugged tap %s of container %s into OVS bridge %s", hostIfaceName, containerID, kc.ovsBridgeClient.GetBridgeName())
+	// Announce the addresses of the Pod so that the neighbors don't keep using the MAC of a
+	// previous Pod which had the same IPs.
+	if err := kc.ifConfigurator.advertiseContainerAddr(containerNetNS, containerIFDev, result); err != nil {
+		klog.Errorf("Failed to advertise the addresses of container %s: %v", containerID, err)
+	}
+	return nil
+}
 
-	defer func() {
-		if !success {
-			klog.Error("rollback ..........")
+// configureVF passes the SR-IOV VF of a direct Neutron port to the container. The port is bound by
+// the Neutron SR-IOV agent, nothing is plugged into the OVS bridge.
+func (kc *kpConfigurator) configureVF(
+	podName string,
+	podNamespace string,
+	containerID string,
+	containerNetNS string,
+	containerIFDev string,
+	vif *v1alpha1.VIF,
+	result *current.Result,
+) error {
+	if vif.PCISlot == "" {
+		return fmt.Errorf("no PCI slot for direct port %s", vif.ID)
+	}
+	if err := kc.ifConfigurator.configureContainerLinkSriov(podName, podNamespace, containerID, containerNetNS, containerIFDev,
+		vif.Network.MTU, vif.PCISlot, vif.MACAddress, vif.Vlan, result); err != nil {
+		return err
+	}
+	klog.Infof("Moved VF %s of port %s to container %s", vif.PCISlot, vif.ID, containerID)
+	if err := kc.ifConfigurator.advertiseContainerAddr(containerNetNS, containerIFDev, result); err != nil {
+		klog.Errorf("Failed to advertise the addresses of container %s: %v", containerID, err)
+	}
+	return nil
+}
+
+// plugTap attaches the tap of the container to the OVS bridge and returns the
+// port UUID. The Port external-ids identify the container and its CNI network,
+// and cache the CNI result, if not empty. The Interface ones are used by the
+// Neutron OVS agent to bind the Neutron port.
+func (kc *kpConfigurator) plugTap(containerConfig *interfacestore.InterfaceConfig, portID, network, cniResult string) (string, error) {
+	tapName := containerConfig.InterfaceName
+	// Replace the port left by a previous attempt, like ovs-vsctl --if-exists del-port.
+	if err := kc.deletePortByName(tapName); err != nil {
+		return "", err
+	}
+	ifExternalIDs := map[string]interface{}{
+		ovsExternalIDIfaceID:     portID,
+		ovsExternalIDIfaceStatus: "active",
+		ovsExternalIDMAC:         containerConfig.MAC.String(),
+		ovsExternalIDVMUUID:      kuryrVMUUID,
+	}
+	externalIDs := BuildOVSPortExternalIDs(containerConfig)
+	if network != "" {
+		externalIDs[ovsExternalIDNetwork] = network
+	}
+	if cniResult != "" {
+		externalIDs[ovsExternalIDCNIResult] = cniResult
+	}
+	portUUID, err := kc.ovsBridgeClient.CreatePortExt(tapName, tapName, externalIDs, ifExternalIDs)
+	if err != nil {
+		return "", err
+	}
+	return portUUID, nil
+}
+
+// getCachedResult returns the result of the previous ADD of the container for
+// the VIF, if the tap and the container interface still match it. nil is
+// returned if the interface must be configured.
+func (kc *kpConfigurator) getCachedResult(containerID, containerNetNS, containerIFDev string, vif *v1alpha1.KuryrVif) *current.Result {
+	port, err := kc.findOVSPort(containerID, "")
+	if err != nil {
+		klog.Errorf("Failed to look up the OVS port of container %s: %v", containerID, err)
+		return nil
+	}
+	if port == nil || port.Name != util.GenerateTapInterfaceName(vif.Vif.ID) || port.ExternalIDs[ovsExternalIDCNIResult] == "" {
+		return nil
+	}
+	cachedResult, err := current.NewResult([]byte(port.ExternalIDs[ovsExternalIDCNIResult]))
+	if err != nil {
+		klog.Errorf("Failed to parse the cached CNI result of container %s: %v", containerID, err)
+		return nil
+	}
+	result := cachedResult.(*current.Result)
+	var containerIface *current.Interface
+	for _, intf := range result.Interfaces {
+		if intf.Name == containerIFDev && intf.Sandbox == containerNetNS {
+			containerIface = intf
 		}
-	}()
+	}
+	if containerIface == nil {
+		return nil
+	}
+	if err := checkCachedInterfaces(kc, containerID, containerNetNS, vif.Vif.ID, vif.Vif.Network.MTU, "", vifContainerConfig(containerID, vif), containerIface, result); err != nil {
+		klog.Infof("Interfaces of container %s don't match the cached CNI result, configuring them again: %v", containerID, err)
+		return nil
+	}
+	return result
+}
 
+// deletePortByName deletes the OVS port, nothing is done if it does not exist.
+func (kc *kpConfigurator) deletePortByName(portName string) error {
+	ports, err := kc.ovsBridgeClient.GetPortList()
+	if err != nil {
+		return fmt.Errorf("failed to list OVS ports: %v", err)
+	}
+	for _, port := range ports {
+		if port.Name != portName {
+			continue
+		}
+		klog.V(2).Infof("Deleting existing OVS port %s", portName)
+		if err := kc.ovsBridgeClient.DeletePort(port.UUID); err != nil {
+			return fmt.Errorf("failed to delete OVS port %s: %v", portName, err)
+		}
+	}
 	return nil
 }
 
@@ -120,3 +340,384 @@ func (kc *kpConfigurator) configureInterfaces(
 
 	return nil
 }
+
+// removeInterfaces unplugs the tap of the container from the OVS bridge,
+// deletes the veth pair and forgets the container. hostIfaceName is the tap
+// name of the attachment or of the KuryrPort, it is empty when both are gone.
+// The tap is left alone when its OVS port is owned by another container, i.e.
+// it was plugged again for a new container of the Pod. Nothing is done for the
+// resources already removed, so CmdDel can be called multiple times.
+func (kc *kpConfigurator) removeInterfaces(containerID, hostIfaceName string) error {
+	containerConfig, found := kc.ifaceStore.GetContainerInterface(containerID)
+	var portUUID string
+	if found {
+		hostIfaceName = containerConfig.InterfaceName
+		if containerConfig.OVSPortConfig != nil {
+			portUUID = containerConfig.PortUUID
+		}
+	}
+	if portUUID == "" {
+		port, err := kc.findOVSPort(containerID, hostIfaceName)
+		if err != nil {
+			return err
+		}
+		if port != nil {
+			portUUID = port.UUID
+			hostIfaceName = port.Name
+		} else if hostIfaceName != "" {
+			owner, err := kc.getPortOwner(hostIfaceName)
+			if err != nil {
+				return err
+			}
+			if owner != "" {
+				klog.Infof("Tap %s is plugged for container %s, not removing it for container %s", hostIfaceName, owner, containerID)
+				hostIfaceName = ""
+			}
+		}
+	}
+
+	if portUUID != "" {
+		klog.V(2).Infof("Deleting OVS port %s for container %s", portUUID, containerID)
+		if err := kc.ovsBridgeClient.DeletePort(portUUID); err != nil {
+			return fmt.Errorf("failed to delete OVS port for container %s: %v", containerID, err)
+		}
+	} else {
+		klog.V(2).Infof("Did not find the OVS port for container %s", containerID)
+	}
+	if hostIfaceName != "" {
+		if err := kc.uninstallPodServiceFlows(hostIfaceName); err != nil {
+			return err
+		}
+		if err := kc.ifConfigurator.removeContainerLink(containerID, hostIfaceName); err != nil {
+			return err
+		}
+	}
+	if found {
+		kc.ifaceStore.DeleteInterface(containerConfig)
+	}
+	klog.Infof("Removed interfaces for container %s", containerID)
+	return nil
+}
+
+// checkInterfaces verifies that the container interface of prevResult is
+// configured in the netns and that its tap is plugged into the OVS bridge with
+// the Neutron port, or that it is the VF with pciSlot for a direct port.
+// portID, mtu, pciSlot and expectedConfig come from the attachment or the
+// KuryrPort, they are not checked when both are gone.
+func (kc *kpConfigurator) checkInterfaces(
+	containerID string,
+	containerNetNS string,
+	portID string,
+	mtu int,
+	pciSlot string,
+	expectedConfig *interfacestore.InterfaceConfig,
+	containerIface *current.Interface,
+	prevResult *current.Result,
+) error {
+	link, err := kc.ifConfigurator.checkContainerInterface(containerNetNS, containerID, containerIface,
+		prevResult.IPs, prevResult.Routes, mtu, pciSlot)
+	if err != nil {
+		return err
+	}
+	if pciSlot != "" {
+		return nil
+	}
+	containerVeth, ok := link.(*vethPair)
+	if !ok {
+		return fmt.Errorf("interface %s of container %s is not a veth", containerIface.Name, containerID)
+	}
+	hostVeth, err := kc.ifConfigurator.validateContainerPeerInterface(prevResult.Interfaces, containerVeth)
+	if err != nil {
+		return err
+	}
+
+	port, err := kc.findOVSPort(containerID, hostVeth.name)
+	if err != nil {
+		return err
+	}
+	if port == nil || port.Name != hostVeth.name {
+		return fmt.Errorf("tap %s of container %s is not attached to the OVS bridge", hostVeth.name, containerID)
+	}
+	externalIDs, err := kc.ovsBridgeClient.GetInterfaceExternalIDs(hostVeth.name)
+	if err != nil {
+		return fmt.Errorf("failed to get external IDs of OVS interface %s: %v", hostVeth.name, err)
+	}
+	ifaceID := externalIDs[ovsExternalIDIfaceID]
+	if ifaceID == "" || (portID != "" && ifaceID != portID) {
+		return fmt.Errorf("OVS interface %s iface-id %q doesn't match Neutron port %s", hostVeth.name, ifaceID, portID)
+	}
+	return kc.validateContainerConfig(containerID, containerIface.Mac, prevResult.IPs, expectedConfig)
+}
+
+// validateContainerConfig compares the MAC and IPs of prevResult with the ones
+// recorded by CmdAdd. The interface store is not persisted, the configuration
+// of a container added before the agent restarted is expectedConfig instead,
+// nothing is compared when it is nil too.
+func (kc *kpConfigurator) validateContainerConfig(containerID, containerMAC string, ips []*current.IPConfig, expectedConfig *interfacestore.InterfaceConfig) error {
+	containerConfig, found := kc.ifaceStore.GetContainerInterface(containerID)
+	if !found {
+		if expectedConfig == nil {
+			klog.V(2).Infof("Container %s not found in the interface store", containerID)
+			return nil
+		}
+		containerConfig = expectedConfig
+	}
+	if containerConfig.MAC.String() != containerMAC {
+		return fmt.Errorf("interface MAC %s doesn't match container MAC: %s", containerConfig.MAC.String(), containerMAC)
+	}
+	expectedIPs := sets.NewString()
+	for _, ip := range containerConfig.IPs {
+		expectedIPs.Insert(ip.String())
+	}
+	resultIPs := sets.NewString()
+	for _, ipc := range ips {
+		resultIPs.Insert(ipc.Address.IP.String())
+	}
+	if !expectedIPs.Equal(resultIPs) {
+		return fmt.Errorf("interface IPs %v don't match container IPs: %v", expectedIPs.List(), resultIPs.List())
+	}
+	return nil
+}
+
+// vifContainerConfig returns the configuration of the container interface of
+// the VIF.
+func vifContainerConfig(containerID string, vif *v1alpha1.KuryrVif) *interfacestore.InterfaceConfig {
+	mac, _ := net.ParseMAC(vif.Vif.MACAddress)
+	return interfacestore.NewContainerInterface(util.GenerateTapInterfaceName(vif.Vif.ID), containerID, "", "", mac, vifIPs(vif))
+}
+
+// attachmentContainerConfig returns the configuration of the container
+// interface from the CNI result saved with the attachment, nil is returned if
+// it cannot be parsed.
+func attachmentContainerConfig(attachment *cnistate.Attachment) *interfacestore.InterfaceConfig {
+	if len(attachment.CNIResult) == 0 {
+		return nil
+	}
+	r, err := current.NewResult(attachment.CNIResult)
+	if err != nil {
+		klog.Errorf("Failed to parse the CNI result of the attachment of container %s: %v", attachment.ContainerID, err)
+		return nil
+	}
+	result := r.(*current.Result)
+	for _, intf := range result.Interfaces {
+		if intf.Name == attachment.IfName && intf.Sandbox != "" {
+			return buildContainerConfig(attachment.HostIface, attachment.ContainerID, attachment.PodName, attachment.PodNamespace, intf, result.IPs)
+		}
+	}
+	return nil
+}
+
+// vifIPs returns the fixed IPs of the Neutron port of the VIF.
+func vifIPs(vif *v1alpha1.KuryrVif) []net.IP {
+	var ips []net.IP
+	for _, subnet := range vif.Vif.Network.Subnets {
+		for _, ip := range subnet.Ips {
+			ips = append(ips, net.ParseIP(ip.IPAddress))
+		}
+	}
+	return ips
+}
+
+// reconcile is called when the agent starts, before the CNI requests are
+// served. It removes the taps of the Pods deleted while the agent was down,
+// plugs again the taps of the running Pods missing from the OVS bridge and
+// rebuilds the interface store from the OVS ports. The KuryrPorts are matched
+// to the running Pods by UID. The attachments saved by CmdAdd give back their
+// container to the taps plugged again.
+func (kc *kpConfigurator) reconcile(pods []corev1.Pod, kps []*v1alpha1.KuryrPort) error {
+	runningPods := sets.NewString()
+	for i := range pods {
+		pod := &pods[i]
+		// Skip Pods for which we are not in charge of the networking.
+		if pod.Spec.HostNetwork || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		runningPods.Insert(string(pod.UID))
+	}
+	type podVif struct {
+		kp  *v1alpha1.KuryrPort
+		vif *v1alpha1.KuryrVif
+	}
+	// desiredTaps are the taps of the running Pods, by name.
+	desiredTaps := map[string]podVif{}
+	for _, kp := range kps {
+		if !runningPods.Has(kp.Spec.PodUid) {
+			continue
+		}
+		for i := range kp.Status.Vifs {
+			vif := &kp.Status.Vifs[i]
+			// The VFs of the direct ports are not plugged into the OVS bridge.
+			if vif.Vif.VNICType == v1alpha1.VNICTypeDirect {
+				continue
+			}
+			desiredTaps[util.GenerateTapInterfaceName(vif.Vif.ID)] = podVif{kp: kp, vif: vif}
+		}
+	}
+
+	attachments, err := kc.stateStore.List()
+	if err != nil {
+		klog.Warningf("Failed to list the saved attachments: %v", err)
+	}
+	// tapAttachments are the saved attachments, by tap name.
+	tapAttachments := map[string]*cnistate.Attachment{}
+	for _, attachment := range attachments {
+		if attachment.HostIface != "" {
+			tapAttachments[attachment.HostIface] = attachment
+		}
+	}
+
+	ports, err := kc.ovsBridgeClient.GetPortList()
+	if err != nil {
+		return fmt.Errorf("failed to list OVS ports: %v", err)
+	}
+	pluggedTaps := sets.NewString()
+	for i := range ports {
+		port := &ports[i]
+		if port.IFExternalIDs[ovsExternalIDVMUUID] != kuryrVMUUID {
+			continue
+		}
+		containerID := port.ExternalIDs[ovsExternalIDContainerID]
+		desired, ok := desiredTaps[port.Name]
+		if !ok {
+			klog.Infof("Removing stale tap %s of container %s", port.Name, containerID)
+			if err := kc.removeInterfaces(containerID, port.Name); err != nil {
+				klog.Errorf("Failed to remove stale tap %s: %v", port.Name, err)
+			} else if attachment, ok := tapAttachments[port.Name]; ok {
+				kc.deleteAttachment(attachment)
+			}
+			continue
+		}
+		pluggedTaps.Insert(port.Name)
+		if err := kc.installPodServiceFlows(port.Name, vifIPs(desired.vif), port.OFPort); err != nil {
+			klog.Errorf("Failed to reconcile tap %s: %v", port.Name, err)
+		}
+		// The store is keyed by container ID, the taps plugged again by a
+		// previous reconciliation may have none and are looked up by name.
+		if attachment, ok := tapAttachments[port.Name]; ok && containerID == "" && attachment.VifID == desired.vif.Vif.ID {
+			containerID = attachment.ContainerID
+		}
+		if containerID == "" {
+			continue
+		}
+		mac, _ := net.ParseMAC(desired.vif.Vif.MACAddress)
+		containerConfig := interfacestore.NewContainerInterface(port.Name, containerID, desired.kp.Name, desired.kp.Namespace, mac, vifIPs(desired.vif))
+		containerConfig.OVSPortConfig = &interfacestore.OVSPortConfig{PortUUID: port.UUID, OFPort: port.OFPort}
+		kc.ifaceStore.AddInterface(containerConfig)
+	}
+
+	for tapName, desired := range desiredTaps {
+		if pluggedTaps.Has(tapName) {
+			continue
+		}
+		namespacedName := k8s.NamespacedName(desired.kp.Namespace, desired.kp.Name)
+		if _, err := interfaceByName(tapName); err != nil {
+			// Without the container netns there is nothing we can do, the
+			// Pod must be recreated to get its network back.
+			klog.Warningf("Tap %s of Pod %s not found", tapName, namespacedName)
+			continue
+		}
+		klog.Infof("Plugging tap %s of Pod %s into the OVS bridge", tapName, namespacedName)
+		containerID, network, cniResult := "", "", ""
+		if attachment, ok := tapAttachments[tapName]; ok && attachment.VifID == desired.vif.Vif.ID {
+			containerID, network, cniResult = attachment.ContainerID, attachment.Network, string(attachment.CNIResult)
+		}
+		mac, _ := net.ParseMAC(desired.vif.Vif.MACAddress)
+		containerConfig := interfacestore.NewContainerInterface(tapName, containerID, desired.kp.Name, desired.kp.Namespace, mac, vifIPs(desired.vif))
+		portUUID, err := kc.plugTap(containerConfig, desired.vif.Vif.ID, network, cniResult)
+		if err != nil {
+			klog.Errorf("Failed to plug tap %s of Pod %s: %v", tapName, namespacedName, err)
+			continue
+		}
+		if kc.ofClient != nil {
+			if ofPort, err := kc.ovsBridgeClient.GetOFPort(tapName); err != nil {
+				klog.Errorf("Failed to get of_port of OVS port %s: %v", tapName, err)
+			} else if err := kc.installPodServiceFlows(tapName, containerConfig.IPs, ofPort); err != nil {
+				klog.Errorf("Failed to reconcile tap %s: %v", tapName, err)
+			}
+		}
+		if containerID != "" {
+			containerConfig.OVSPortConfig = &interfacestore.OVSPortConfig{PortUUID: portUUID}
+			kc.ifaceStore.AddInterface(containerConfig)
+		}
+	}
+	return nil
+}
+
+// gc removes the taps of the containers of the network which are not in
+// validContainerIDs, along with their cached CNI result, their interface store
+// entry and their saved attachments of the network. The taps and attachments
+// of the other networks are left alone, as the runtime only lists the valid
+// attachments of the network it collects. The taps plugged again by reconcile
+// without an attachment have no container ID and no network, they are left to
+// the next reconciliation as their owner is unknown. The containers are locked
+// one at a time so that gc doesn't race with their ADD or DEL.
+func (kc *kpConfigurator) gc(network string, validContainerIDs sets.String, containerAccess *containerAccessArbitrator) error {
+	ports, err := kc.ovsBridgeClient.GetPortList()
+	if err != nil {
+		return fmt.Errorf("failed to list OVS ports: %v", err)
+	}
+	staleContainers := map[string]string{}
+	for i := range ports {
+		port := &ports[i]
+		if port.IFExternalIDs[ovsExternalIDVMUUID] != kuryrVMUUID || port.ExternalIDs[ovsExternalIDNetwork] != network {
+			continue
+		}
+		containerID := port.ExternalIDs[ovsExternalIDContainerID]
+		if containerID == "" || validContainerIDs.Has(containerID) {
+			continue
+		}
+		staleContainers[containerID] = port.Name
+	}
+	attachments, listErr := kc.stateStore.List()
+	if listErr != nil {
+		return listErr
+	}
+	for _, attachment := range attachments {
+		if attachment.Network != network || validContainerIDs.Has(attachment.ContainerID) {
+			continue
+		}
+		if _, ok := staleContainers[attachment.ContainerID]; !ok {
+			staleContainers[attachment.ContainerID] = attachment.HostIface
+		}
+	}
+
+	var errs []error
+	for containerID, hostIfaceName := range staleContainers {
+		klog.Infof("Garbage collecting tap %s of container %s in network %s", hostIfaceName, containerID, network)
+		containerAccess.lockContainer(containerID)
+		err := kc.removeInterfaces(containerID, hostIfaceName)
+		if err == nil {
+			err = kc.deleteContainerAttachments(containerID, network)
+		}
+		containerAccess.unlockContainer(containerID)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// deleteAttachment forgets the attachment, an error is only logged as the
+// attachment is deleted again by the next GC.
+func (kc *kpConfigurator) deleteAttachment(attachment *cnistate.Attachment) {
+	if err := kc.stateStore.Delete(attachment.ContainerID, attachment.IfName); err != nil {
+		klog.Errorf("Failed to delete the attachment of container %s: %v", attachment.ContainerID, err)
+	}
+}
+
+// deleteContainerAttachments forgets the attachments of the container in the network.
+func (kc *kpConfigurator) deleteContainerAttachments(containerID, network string) error {
+	attachments, err := kc.stateStore.GetByContainer(containerID)
+	if err != nil {
+		return err
+	}
+	for _, attachment := range attachments {
+		if attachment.Network != network {
+			continue
+		}
+		if err := kc.stateStore.Delete(containerID, attachment.IfName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package cniserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/klog"
+
+	cnipb "projectkuryr/kuryr/pkg/apis/cni/v1alpha1"
+	"projectkuryr/kuryr/pkg/cni"
+	"projectkuryr/kuryr/pkg/utils/tracing"
+)
+
+type requestIDKey struct{}
+
+// requestIDInterceptor adds the ID of the request sent by kuryr-cni to the context of the request,
+// an ID is generated for the kuryr-cni which don't send one.
+func requestIDInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	requestID := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(cni.RequestIDHeader); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if requestID == "" {
+		requestID = strings.Replace(string(uuid.NewUUID()), "-", "", -1)[:16]
+	}
+	return handler(context.WithValue(ctx, requestIDKey{}, requestID), req)
+}
+
+// chainUnaryInterceptors returns an interceptor calling the interceptors in order, grpc only
+// supports chaining from v1.28.
+func chainUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		chained := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], chained
+			chained = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, next)
+			}
+		}
+		return chained(ctx, req)
+	}
+}
+
+// requestLogger logs the messages of a CNI request followed by the key=value fields which identify
+// it, klog v1 has no structured logging.
+type requestLogger struct {
+	command string
+	fields  []string
+	// podUID is the trace ID of the spans of the request.
+	podUID string
+}
+
+func newRequestLogger(ctx context.Context, command string, cniConfig *CNIConfig) *requestLogger {
+	l := &requestLogger{command: command}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	l.with("command", command).with("request", requestID)
+	if cniConfig.K8S_POD_NAME != "" {
+		l.with("pod", fmt.Sprintf("%s/%s", cniConfig.K8S_POD_NAMESPACE, cniConfig.K8S_POD_NAME))
+	}
+	if cniConfig.ContainerId != "" {
+		l.with("container", cniConfig.ContainerId).with("ifname", cniConfig.Ifname)
+	}
+	l.podUID = string(cniConfig.K8S_POD_UID)
+	return l
+}
+
+// with adds a field to the messages, e.g. the VIF of the request once it is known.
+func (l *requestLogger) with(key string, value interface{}) *requestLogger {
+	if value != "" {
+		l.fields = append(l.fields, fmt.Sprintf("%s=%v", key, value))
+	}
+	return l
+}
+
+func (l *requestLogger) message(format string, args []interface{}) string {
+	return fmt.Sprintf(format, args...) + " " + strings.Join(l.fields, " ")
+}
+
+func (l *requestLogger) infof(level klog.Level, format string, args ...interface{}) {
+	if klog.V(level) {
+		klog.InfoDepth(1, l.message(format, args))
+	}
+}
+
+func (l *requestLogger) warningf(format string, args ...interface{}) {
+	klog.WarningDepth(1, l.message(format, args))
+}
+
+func (l *requestLogger) errorf(format string, args ...interface{}) {
+	klog.ErrorDepth(1, l.message(format, args))
+}
+
+// start starts the span of the request, the returned function logs the outcome of the request with
+// its duration and ends the span.
+func (l *requestLogger) start(ctx context.Context) (context.Context, func(response *cnipb.CniCmdResponse)) {
+	startTime := time.Now()
+	ctx, span := tracing.Start(ctx, l.podUID, "cni."+strings.ToLower(l.command))
+	for _, field := range l.fields {
+		kv := strings.SplitN(field, "=", 2)
+		span.SetAttribute(kv[0], kv[1])
+	}
+	return ctx, func(response *cnipb.CniCmdResponse) {
+		duration := time.Since(startTime)
+		if err := responseError(response); err != nil {
+			l.with("duration", duration).errorf("CNI %s failed: %v", l.command, err)
+			span.End(err)
+			return
+		}
+		l.with("duration", duration).infof(0, "CNI %s succeeded", l.command)
+		span.End(nil)
+	}
+}
+
+// step starts a step of the request, the returned function logs the duration of the step and ends
+// its span.
+func (l *requestLogger) step(ctx context.Context, name string) func(err error) {
+	startTime := time.Now()
+	_, span := tracing.Start(ctx, l.podUID, name)
+	return func(err error) {
+		duration := time.Since(startTime)
+		if err != nil {
+			l.infof(2, "Step failed step=%s duration=%v error=%q", name, duration, err.Error())
+		} else {
+			l.infof(2, "Step finished step=%s duration=%v", name, duration)
+		}
+		span.End(err)
+	}
+}
+
+// responseError returns the error of a CNI response, nil is returned for a successful response.
+func responseError(response *cnipb.CniCmdResponse) error {
+	if response == nil || response.Error == nil {
+		return nil
+	}
+	return fmt.Errorf("%s: %s", response.Error.Code, response.Error.Message)
+}
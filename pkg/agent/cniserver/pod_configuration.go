@@ -30,6 +30,7 @@ type k8sArgs struct {
 	K8S_POD_NAME               cnitypes.UnmarshallableString
 	K8S_POD_NAMESPACE          cnitypes.UnmarshallableString
 	K8S_POD_INFRA_CONTAINER_ID cnitypes.UnmarshallableString
+	K8S_POD_UID                cnitypes.UnmarshallableString
 }
 
 const (
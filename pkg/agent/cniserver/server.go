@@ -10,10 +10,18 @@ import (
 	"github.com/containernetworking/cni/pkg/version"
 	"github.com/containernetworking/plugins/pkg/ip"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog"
 	"net"
 	"projectkuryr/kuryr/pkg/agent/cniserver/ipam"
+	"projectkuryr/kuryr/pkg/agent/cnistate"
 	"projectkuryr/kuryr/pkg/agent/config"
 	"projectkuryr/kuryr/pkg/agent/interfacestore"
 	"projectkuryr/kuryr/pkg/agent/openflow"
@@ -26,11 +34,14 @@ import (
 	crdclientset "projectkuryr/kuryr/pkg/client/clientset/versioned"
 	kuryrinformers "projectkuryr/kuryr/pkg/client/informers/externalversions/openstack/v1alpha1"
 	kuryrlisters "projectkuryr/kuryr/pkg/client/listers/openstack/v1alpha1"
+	"sort"
 	"strings"
 	"sync"
 
 	"projectkuryr/kuryr/pkg/cni"
+	"projectkuryr/kuryr/pkg/k8s"
 	"projectkuryr/kuryr/pkg/ovs/ovsconfig"
+	"projectkuryr/kuryr/pkg/utils/tracing"
 	"time"
 )
 
@@ -78,6 +89,56 @@ func (arbitrator *containerAccessArbitrator) unlockContainer(containerKey string
 	arbitrator.cond.Broadcast()
 }
 
+// kpNotifier wakes up the CNI requests waiting for the KuryrPort of their Pod when it is created or
+// updated.
+type kpNotifier struct {
+	mutex   sync.Mutex
+	waiters map[string]map[chan struct{}]bool
+}
+
+func newKpNotifier() *kpNotifier {
+	return &kpNotifier{waiters: make(map[string]map[chan struct{}]bool)}
+}
+
+// subscribe returns a channel receiving the updates of the KuryrPort. Every call to subscribe must
+// be followed by a call to unsubscribe with the returned channel.
+func (n *kpNotifier) subscribe(key string) chan struct{} {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	ch := make(chan struct{}, 1)
+	if n.waiters[key] == nil {
+		n.waiters[key] = make(map[chan struct{}]bool)
+	}
+	n.waiters[key][ch] = true
+	return ch
+}
+
+func (n *kpNotifier) unsubscribe(key string, ch chan struct{}) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	delete(n.waiters[key], ch)
+	if len(n.waiters[key]) == 0 {
+		delete(n.waiters, key)
+	}
+}
+
+func (n *kpNotifier) notify(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for ch := range n.waiters[key] {
+		// The channel is buffered, a pending notification is enough for the waiter to get the
+		// latest KuryrPort from the lister.
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
 type CNIServer struct {
 	cniSocket            string
 	supportedCNIVersions map[string]bool
@@ -85,6 +146,7 @@ type CNIServer struct {
 	crdClient 			crdclientset.Interface
 	kpInformer 			kuryrinformers.KuryrPortInformer
 	kpLister 			kuryrlisters.KuryrPortLister
+	kpNotifier           *kpNotifier
 	nodeConfig           *config.NodeConfig
 	hostProcPathPrefix   string
 	kubeClient           clientset.Interface
@@ -92,6 +154,9 @@ type CNIServer struct {
 	podConfigurator      *podConfigurator
 	kpConfigurator      *kpConfigurator
 	isChaining           bool
+	// bootID changes every time the agent starts, kuryr-cni caches the capabilities of the server
+	// per boot ID.
+	bootID               string
 	routeClient          route.Interface
 	//networkReadyCh notifies that the network is ready so new Pods can be created. Therefore, CmdAdd waits for it.
 	networkReadyCh <-chan struct{}
@@ -121,6 +186,15 @@ type NetworkConfig struct {
 
 	RawPrevResult map[string]interface{} `json:"prevResult,omitempty"`
 	PrevResult    cnitypes.Result        `json:"-"`
+
+	// The attachments which must not be garbage collected, passed by the runtime to GC.
+	ValidAttachments []Attachment `json:"cni.dev/valid-attachments,omitempty"`
+}
+
+// Attachment is a container interface attached to the network by the plugin.
+type Attachment struct {
+	ContainerID string `json:"containerID"`
+	IfName      string `json:"ifname"`
 }
 
 type CNIConfig struct {
@@ -187,12 +261,70 @@ func (s *CNIServer) checkRequestMessage(request *cnipb.CniCmdRequest) (*CNIConfi
 	return cniConfig, nil
 }
 
+func (s *CNIServer) unsupportedFieldResponse(key string, value interface{}) *cnipb.CniCmdResponse {
+	cniErrorCode := cnipb.ErrorCode_UNSUPPORTED_FIELD
+	cniErrorMsg := fmt.Sprintf("Network configuration does not support key %s and value %v", key, value)
+	return s.generateCNIErrorResponse(cniErrorCode, cniErrorMsg)
+}
+
+func (s *CNIServer) checkInterfaceFailureResponse(err error) *cnipb.CniCmdResponse {
+	cniErrorCode := cnipb.ErrorCode_CHECK_INTERFACE_FAILURE
+	cniErrorMsg := err.Error()
+	return s.generateCNIErrorResponse(cniErrorCode, cniErrorMsg)
+}
+
+func parsePrevResult(conf *NetworkConfig) error {
+	if conf.RawPrevResult == nil {
+		return nil
+	}
+
+	resultBytes, err := json.Marshal(conf.RawPrevResult)
+	if err != nil {
+		return fmt.Errorf("could not serialize prevResult: %v", err)
+	}
+	conf.RawPrevResult = nil
+	conf.PrevResult, err = version.NewResult(conf.CNIVersion, resultBytes)
+	if err != nil {
+		return fmt.Errorf("could not parse prevResult: %v", err)
+	}
+	return nil
+}
+
+func (s *CNIServer) parsePrevResultFromRequest(networkConfig *NetworkConfig) (*current.Result, *cnipb.CniCmdResponse) {
+	if networkConfig.PrevResult == nil && networkConfig.RawPrevResult == nil {
+		klog.Errorf("Previous network configuration not specified")
+		return nil, s.unsupportedFieldResponse("prevResult", "")
+	}
+
+	if err := parsePrevResult(networkConfig); err != nil {
+		klog.Errorf("Failed to parse previous network configuration")
+		return nil, s.decodingFailureResponse("prevResult")
+	}
+	// Convert whatever the result was into the current Result type (for the current CNI version)
+	prevResult, err := current.NewResultFromResult(networkConfig.PrevResult)
+	if err != nil {
+		klog.Errorf("Failed to construct prevResult using previous network configuration")
+		return nil, s.unsupportedFieldResponse("prevResult", networkConfig.PrevResult)
+	}
+	prevResult.CNIVersion = networkConfig.CNIVersion
+	return prevResult, nil
+}
+
 func (s *CNIServer) tryAgainLaterResponse() *cnipb.CniCmdResponse {
 	cniErrorCode := cnipb.ErrorCode_TRY_AGAIN_LATER
 	cniErrorMsg := "Server is busy, please retry later"
 	return s.generateCNIErrorResponse(cniErrorCode, cniErrorMsg)
 }
 
+// podUIDMismatchResponse is returned when the KuryrPort of the Pod belongs to another instance of the
+// Pod with the same name. It is not retried by kuryr-cni, the runtime retries the sandbox once the
+// KuryrPort of the Pod is created.
+func (s *CNIServer) podUIDMismatchResponse(kpKey, kpPodUID, podUID string) *cnipb.CniCmdResponse {
+	cniErrorCode := cnipb.ErrorCode_UNKNOWN_CONTAINER
+	cniErrorMsg := fmt.Sprintf("KuryrPort %s belongs to Pod UID %s, not to Pod UID %s", kpKey, kpPodUID, podUID)
+	return s.generateCNIErrorResponse(cniErrorCode, cniErrorMsg)
+}
+
 // updateResultIfaceConfig processes the result from the IPAM plugin and does the following:
 //   * updates the IP configuration for each assigned IP address: this includes computing the
 //     gateway (if missing) based on the subnet and setting the interface pointer to the container
@@ -235,83 +367,149 @@ func updateResultIfaceConfig(result *current.Result, defaultIPv4Gateway net.IP,
 	}
 }
 
+// updateResultIfaceConfigFromVif adds the IPs of the VIF to the result with the gateway, host routes
+// and DNS nameservers of their subnet. The default route of the default VIF goes through the subnet
+// gateway, unless a host route of the subnet is already a default route.
 func updateResultIfaceConfigFromVif(result *current.Result, vif *v1alpha1.KuryrVif) error {
-	defaultV4RouteDst := "0.0.0.0/0"
-	defaultV6RouteDst := "::/0"
-
 	for _, subnet := range vif.Vif.Network.Subnets {
-		ips := subnet.Ips
-		for idx, ip := range ips {
-			gw := net.ParseIP(subnet.Gateway)
-			_, ipNet, err := net.ParseCIDR(subnet.Cidr)
-			if err != nil {
-				klog.Infof("ParseCIDR(%s) Error: %s", subnet.Cidr, err)
-				return err
-			}
-			cniRoute := &cnitypes.Route{
-				Dst: *ipNet,
-				GW:  gw,
-			}
-			klog.Infof("cniRoute>: %v, idx: %d\n", cniRoute, idx)
-			//ipRoutes = append(ipRoutes, cniRoute)
-
-			if vif.IsDefault {
-				var ipNetDft *net.IPNet
-				if subnet.IPVersion == 4 {
-					_, ipNetDft, err = net.ParseCIDR(defaultV4RouteDst)
-					if err != nil {
-						klog.Infof("ParseCIDR(%s) Error: %s", defaultV4RouteDst, err)
-						return err
-					}
-				}else{
-					_, ipNetDft, err = net.ParseCIDR(defaultV6RouteDst)
-					if err != nil {
-						klog.Infof("ParseCIDR(%s) Error: %s", defaultV6RouteDst, err)
-						return err
-					}
-				}
+		_, ipNet, err := net.ParseCIDR(subnet.Cidr)
+		if err != nil {
+			return fmt.Errorf("invalid CIDR %s of VIF %s: %v", subnet.Cidr, vif.Vif.ID, err)
+		}
+		// The IP version is missing from the KuryrPorts created by older controllers.
+		ipVersion, defaultRouteDst := "4", "0.0.0.0/0"
+		if subnet.IPVersion == 6 || (subnet.IPVersion == 0 && ipNet.IP.To4() == nil) {
+			ipVersion, defaultRouteDst = "6", "::/0"
+		}
+		gw := net.ParseIP(subnet.Gateway)
 
-				defaultRoute := &cnitypes.Route{
-					Dst: *ipNetDft,
-					GW:  gw,
-				}
-				result.Routes = append(result.Routes, defaultRoute)
+		for _, ip := range subnet.Ips {
+			address := net.ParseIP(ip.IPAddress)
+			if address == nil {
+				return fmt.Errorf("invalid IP %s of VIF %s", ip.IPAddress, vif.Vif.ID)
 			}
-			index := 1
-			ipConfig := current.IPConfig{
-				Version: fmt.Sprintf("%d", subnet.IPVersion),
-				Interface: &index, //
-				Address: net.IPNet{
-					IP: net.ParseIP(ip.IPAddress),
-					Mask: ipNet.Mask,
-				},
-				//Gateway: net.ParseIP(subnet.Gateway),
+			result.IPs = append(result.IPs, &current.IPConfig{
+				Version:   ipVersion,
+				Interface: current.Int(1),
+				Address:   net.IPNet{IP: address, Mask: ipNet.Mask},
+				Gateway:   gw,
+			})
+		}
+
+		for _, route := range subnet.Routes {
+			_, dst, err := net.ParseCIDR(route.Cidr)
+			if err != nil {
+				return fmt.Errorf("invalid host route %s of VIF %s: %v", route.Cidr, vif.Vif.ID, err)
 			}
-			result.IPs = append(result.IPs, &ipConfig)
+			result.Routes = append(result.Routes, &cnitypes.Route{Dst: *dst, GW: net.ParseIP(route.Gateway)})
+		}
+		if vif.IsDefault && gw != nil && !hasRoute(result, defaultRouteDst) {
+			_, dst, _ := net.ParseCIDR(defaultRouteDst)
+			result.Routes = append(result.Routes, &cnitypes.Route{Dst: *dst, GW: gw})
 		}
+
+		result.DNS.Nameservers = appendNameservers(result.DNS.Nameservers, subnet.DNS...)
 	}
 
 	return nil
 }
 
+// appendNameservers appends the DNS nameservers which are not already in the list.
+func appendNameservers(nameservers []string, newNameservers ...string) []string {
+	for _, nameserver := range newNameservers {
+		found := false
+		for _, existing := range nameservers {
+			if existing == nameserver {
+				found = true
+				break
+			}
+		}
+		if !found {
+			nameservers = append(nameservers, nameserver)
+		}
+	}
+	return nameservers
+}
+
+// removeChainedRoutes removes from the result the routes to the destinations already routed by the
+// previous plugins in the chain, e.g. the default route of the Pod is owned by the primary plugin.
+func removeChainedRoutes(result, prevResult *current.Result) {
+	var routes []*cnitypes.Route
+	for _, route := range result.Routes {
+		if !hasRoute(prevResult, route.Dst.String()) {
+			routes = append(routes, route)
+		}
+	}
+	result.Routes = routes
+}
+
+// mergeChainedResult returns the result of the previous plugins in the chain with the interfaces,
+// IPs, routes and DNS nameservers configured by kuryr appended to it.
+func mergeChainedResult(prevResult, result *current.Result) *current.Result {
+	merged := *prevResult
+	offset := len(prevResult.Interfaces)
+	merged.Interfaces = append(append([]*current.Interface{}, prevResult.Interfaces...), result.Interfaces...)
+	merged.IPs = append([]*current.IPConfig{}, prevResult.IPs...)
+	for _, ipc := range result.IPs {
+		ipConfig := *ipc
+		if ipc.Interface != nil {
+			ipConfig.Interface = current.Int(*ipc.Interface + offset)
+		}
+		merged.IPs = append(merged.IPs, &ipConfig)
+	}
+	merged.Routes = append(append([]*cnitypes.Route{}, prevResult.Routes...), result.Routes...)
+	merged.DNS.Nameservers = appendNameservers(append([]string{}, prevResult.DNS.Nameservers...), result.DNS.Nameservers...)
+	return &merged
+}
+
+// containerIfaceResult returns the part of the result of the chain configured for the container
+// interface, the IPs of the interfaces of the other plugins are left out.
+func containerIfaceResult(prevResult *current.Result, containerIface *current.Interface) *current.Result {
+	result := *prevResult
+	result.IPs = nil
+	for _, ipc := range prevResult.IPs {
+		if ipc.Interface != nil && *ipc.Interface < len(prevResult.Interfaces) && prevResult.Interfaces[*ipc.Interface] == containerIface {
+			result.IPs = append(result.IPs, ipc)
+		}
+	}
+	return &result
+}
+
+func hasRoute(result *current.Result, dst string) bool {
+	for _, route := range result.Routes {
+		if route.Dst.String() == dst {
+			return true
+		}
+	}
+	return false
+}
+
 // reconcile performs startup reconciliation for the CNI server. The CNI server is in charge of
-// installing Pod flows, so as part of this reconciliation process we retrieve the Pod list from the
-// K8s apiserver and replay the necessary flows.
+// plugging the Pod taps into the OVS bridge, so as part of this reconciliation process we retrieve
+// the Pods and KuryrPorts of the Node and sync the taps with them.
 func (s *CNIServer) reconcile() error {
 	klog.Infof("Reconciliation for CNI server")
 	// For performance reasons, use ResourceVersion="0" in the ListOptions to ensure the request is served from
 	// the watch cache in kube-apiserver.
 
-	return nil
-	//pods, err := s.kubeClient.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
-	//	FieldSelector:   "spec.nodeName=" + s.nodeConfig.Name,
-	//	ResourceVersion: "0",
-	//})
-	//if err != nil {
-	//	return fmt.Errorf("failed to list Pods running on Node %s: %v", s.nodeConfig.Name, err)
-	//}
-	//
-	//return s.podConfigurator.reconcile(pods.Items, s.containerAccess)
+	pods, err := s.kubeClient.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
+		FieldSelector:   "spec.nodeName=" + s.nodeConfig.Name,
+		ResourceVersion: "0",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list Pods running on Node %s: %v", s.nodeConfig.Name, err)
+	}
+	kps, err := s.kpLister.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("failed to list KuryrPorts: %v", err)
+	}
+	var nodeKps []*v1alpha1.KuryrPort
+	for _, kp := range kps {
+		if kp.Spec.PodNodeName == s.nodeConfig.Name {
+			nodeKps = append(nodeKps, kp)
+		}
+	}
+	return s.kpConfigurator.reconcile(pods.Items, nodeKps)
 }
 
 func (s *CNIServer) configInterfaceFailureResponse(err error) *cnipb.CniCmdResponse {
@@ -320,14 +518,38 @@ func (s *CNIServer) configInterfaceFailureResponse(err error) *cnipb.CniCmdRespo
 	return s.generateCNIErrorResponse(cniErrorCode, cniErrorMsg)
 }
 
-func (s *CNIServer) CmdAdd(ctx context.Context, request *cnipb.CniCmdRequest) (*cnipb.CniCmdResponse, error) {
-	klog.Infof("Received CmdAdd request %v", request)
+func (s *CNIServer) CmdAdd(ctx context.Context, request *cnipb.CniCmdRequest) (resp *cnipb.CniCmdResponse, _ error) {
 	cniConfig, response := s.checkRequestMessage(request)
 	if response != nil {
 		return response, nil
 	}
+	log := newRequestLogger(ctx, "ADD", cniConfig)
+	log.infof(2, "Received CmdAdd request")
+	log.infof(4, "CmdAdd network configuration: %s", cniConfig.NetworkConfiguration)
 
-	klog.Infof("\ncniConfig>: %+v\n\n", cniConfig)
+	// The spans of the Pod are linked by its UID.
+	podUID, err := s.getPodUID(ctx, cniConfig)
+	if err != nil {
+		log.errorf("Failed to get the UID of the Pod: %v", err)
+		return s.tryAgainLaterResponse(), nil
+	}
+	log.podUID = podUID
+	ctx, end := log.start(ctx)
+	defer func() { end(resp) }()
+
+	// When chained after another plugin, kuryr only adds the VIF named by CNI_IFNAME to the
+	// result of the previous plugins.
+	var prevResult *current.Result
+	if s.isChaining && cniConfig.RawPrevResult != nil {
+		prevResult, response = s.parsePrevResultFromRequest(cniConfig.NetworkConfig)
+		if response != nil {
+			return response, nil
+		}
+		if intf := parseContainerIfaceFromResults(cniConfig.CniCmdArgs, prevResult); intf != nil && intf.Sandbox != "" {
+			log.errorf("Interface is already configured by a previous plugin")
+			return s.unsupportedFieldResponse("prevResult", cniConfig.Ifname), nil
+		}
+	}
 
 	netNS := s.hostNetNsPath(cniConfig.Netns)
 	isInfraContainer := isInfraContainer(netNS)
@@ -337,40 +559,97 @@ func (s *CNIServer) CmdAdd(ctx context.Context, request *cnipb.CniCmdRequest) (*
 	//podNamespace := string(cniConfig.K8S_POD_NAMESPACE)
 
 	success := false
+	// The rollback is only needed once the KuryrPort of the Pod is found, nothing is configured
+	// before.
+	kpFound := false
 	defer func() {
 		// Rollback to delete configurations once ADD is failure.
-		if !success {
+		if !success && kpFound {
 			if isInfraContainer {
-				klog.Warningf("CmdAdd for container %v failed, and try to rollback", cniConfig.ContainerId)
+				log.warningf("CmdAdd failed, rolling back")
 				if _, err := s.CmdDel(ctx, request); err != nil {
-					klog.Warningf("Failed to rollback after CNI add failure: %v", err)
+					log.warningf("Failed to rollback after CNI add failure: %v", err)
 				}
-			} else {
-				klog.Warningf("CmdAdd for container %v failed", cniConfig.ContainerId)
 			}
 		}
 	}()
 
 	infraContainer := cniConfig.getInfraContainer()
-	//s.containerAccess.lockContainer(infraContainer)
-	//defer s.containerAccess.unlockContainer(infraContainer)
-
-	klog.Infof("CmdAdd:> infraContainer: %s\n", infraContainer[:12])
+	s.containerAccess.lockContainer(infraContainer)
+	defer s.containerAccess.unlockContainer(infraContainer)
 
-	kp, err := s.kpInformer.Lister().KuryrPorts(string(cniConfig.K8S_POD_NAMESPACE)).Get(string(cniConfig.K8S_POD_NAME))
-	if err != nil {
-		klog.Errorf("Get KuryrPort(%s/%s) Error: %s", string(cniConfig.K8S_POD_NAMESPACE), string(cniConfig.K8S_POD_NAME), err)
+	endStep := log.step(ctx, "waitForKuryrPort")
+	kp, response := s.waitForKuryrPort(ctx, cniConfig, podUID)
+	endStep(responseError(response))
+	if response != nil {
+		return response, nil
 	}
+	kpFound = true
 
 	result := &current.Result{CNIVersion: cniVersion}
+	var attachment *cnistate.Attachment
 //  多张网卡的处理逻辑不清晰
 	for _, vif := range kp.Status.Vifs {
 		if vif.IfName != cniConfig.Ifname { // 一次只处理一张网卡，网卡上可以有多个ip
 			continue
 		}
-		err = updateResultIfaceConfigFromVif(result, &vif)
+		log.with("vif", vif.Vif.ID)
+		// A repeated ADD, e.g. retried by kubelet, returns the result of the previous one as long as
+		// the interfaces still match it.
+		isDirect := vif.Vif.VNICType == v1alpha1.VNICTypeDirect
+		if isInfraContainer && !isDirect {
+			if cachedResult := s.kpConfigurator.getCachedResult(cniConfig.ContainerId, netNS, cniConfig.Ifname, &vif); cachedResult != nil {
+				log.infof(2, "CmdAdd already done, returning the cached result")
+				// The attachment is saved again in case the previous ADD was served by an
+				// agent without the state store.
+				if err := s.storeAttachment(newAttachment(cniConfig, netNS, &vif), cachedResult); err != nil {
+					log.errorf("Failed to save the attachment: %v", err)
+					return s.configInterfaceFailureResponse(err), nil
+				}
+				cachedResult.CNIVersion = cniVersion
+				if prevResult != nil {
+					cachedResult = mergeChainedResult(prevResult, cachedResult)
+				}
+				var resultBytes bytes.Buffer
+				_ = cachedResult.PrintTo(&resultBytes)
+				success = true
+				return &cnipb.CniCmdResponse{CniResult: resultBytes.Bytes()}, nil
+			}
+		}
+		err := updateResultIfaceConfigFromVif(result, &vif)
 		if err != nil {
-			klog.Errorf("Invoke updateResultIfaceConfigFromVif(%s/%s) Error: %s", string(cniConfig.K8S_POD_NAMESPACE), string(cniConfig.K8S_POD_NAME), err)
+			log.errorf("Failed to build the result of the VIF: %v", err)
+			return s.configInterfaceFailureResponse(err), nil
+		}
+		if prevResult != nil {
+			removeChainedRoutes(result, prevResult)
+		}
+		attachment = newAttachment(cniConfig, netNS, &vif)
+
+		if isDirect {
+			// The VF assigned to the Pod by the SR-IOV device plugin must be the one of the port,
+			// or the Pod would take the VF of another Pod.
+			if cniConfig.DeviceID != "" && cniConfig.DeviceID != vif.Vif.PCISlot {
+				err := fmt.Errorf("VF %s of port %s is not the VF %s assigned to the Pod", vif.Vif.PCISlot, vif.Vif.ID, cniConfig.DeviceID)
+				log.errorf("Failed to configure the VF: %v", err)
+				return s.configInterfaceFailureResponse(err), nil
+			}
+			endStep := log.step(ctx, "configureVF")
+			err := s.kpConfigurator.configureVF(
+				string(cniConfig.K8S_POD_NAME),
+				string(cniConfig.K8S_POD_NAMESPACE),
+				cniConfig.ContainerId,
+				netNS,
+				cniConfig.Ifname,
+				&vif.Vif,
+				result,
+			)
+			endStep(err)
+			if err != nil {
+				log.errorf("Failed to configure the VF: %v", err)
+				return s.configInterfaceFailureResponse(err), nil
+			}
+			continue
 		}
 
 		hostIfaceName := util.GenerateTapInterfaceName(vif.Vif.ID)
@@ -378,10 +657,12 @@ func (s *CNIServer) CmdAdd(ctx context.Context, request *cnipb.CniCmdRequest) (*
 		containerIface := &current.Interface{Name: cniConfig.Ifname, Sandbox: netNS, Mac: vif.Vif.MACAddress}
 		result.Interfaces = []*current.Interface{hostIface, containerIface}
 
-		klog.Infof("resultInner>: %+v\n\n", result)
-
-		if err = s.kpConfigurator.configureTap(
+		endStep := log.step(ctx, "configureTap")
+		err = s.kpConfigurator.configureTap(
+			string(cniConfig.K8S_POD_NAME),
+			string(cniConfig.K8S_POD_NAMESPACE),
 			vif.Vif.ID,
+			cniConfig.Name,
 			cniConfig.ContainerId,
 			hostIfaceName,
 			netNS,
@@ -391,56 +672,366 @@ func (s *CNIServer) CmdAdd(ctx context.Context, request *cnipb.CniCmdRequest) (*
 			result,
 			isInfraContainer,
 			s.containerAccess,
-		); err != nil {
-			klog.Errorf("Failed to configure interfaces for container %s: %v", cniConfig.ContainerId, err)
+		)
+		endStep(err)
+		if err != nil {
+			log.errorf("Failed to configure the interfaces: %v", err)
 			return s.configInterfaceFailureResponse(err), nil
 		}
 	}
 	//updateResultIfaceConfig(result, s.nodeConfig.GatewayConfig.IPv4, s.nodeConfig.GatewayConfig.IPv6)
 	//updateResultDNSConfig(result, cniConfig)
+	// The attachment is saved before the result is returned, so that DEL finds it even if the
+	// KuryrPort is deleted first.
+	if attachment != nil {
+		endStep := log.step(ctx, "storeAttachment")
+		err := s.storeAttachment(attachment, result)
+		endStep(err)
+		if err != nil {
+			log.errorf("Failed to save the attachment: %v", err)
+			return s.configInterfaceFailureResponse(err), nil
+		}
+	}
+	if prevResult != nil {
+		result = mergeChainedResult(prevResult, result)
+	}
 
 	var resultBytes bytes.Buffer
 	_ = result.PrintTo(&resultBytes)
-	klog.Infof("CmdAdd for container %v succeeded", cniConfig.ContainerId)
 	// mark success as true to avoid rollback
 	success = true
 	return &cnipb.CniCmdResponse{CniResult: resultBytes.Bytes()}, nil
 }
 
-func (s *CNIServer) CmdDel(_ context.Context, request *cnipb.CniCmdRequest) (
-	*cnipb.CniCmdResponse, error) {
-	klog.Infof("Received CmdDel request %v", request)
-
+func (s *CNIServer) CmdDel(ctx context.Context, request *cnipb.CniCmdRequest) (
+	resp *cnipb.CniCmdResponse, _ error) {
 	cniConfig, response := s.checkRequestMessage(request)
 	if response != nil {
 		return response, nil
 	}
+	log := newRequestLogger(ctx, "DEL", cniConfig)
+	log.infof(2, "Received CmdDel request")
+	// The UID of the Pod is empty when the runtime doesn't pass it and the Pod is gone.
+	podUID, _ := s.getPodUID(ctx, cniConfig)
+	log.podUID = podUID
+	ctx, end := log.start(ctx)
+	defer func() { end(resp) }()
 
 	infraContainer := cniConfig.getInfraContainer()
 	s.containerAccess.lockContainer(infraContainer)
 	defer s.containerAccess.unlockContainer(infraContainer)
 
-	klog.Infof("CmdDel for container %v succeeded", cniConfig.ContainerId)
+	// Only the tap or the VF of kuryr is removed, so the interfaces of the other plugins of a chain
+	// are left to them.
+	// The VF of a direct port is recognized in the container netns, even when the KuryrPort is gone.
+	endStep := log.step(ctx, "releaseVF")
+	err := s.kpConfigurator.ifConfigurator.releaseContainerLinkSriov(s.hostNetNsPath(cniConfig.Netns), cniConfig.Ifname)
+	endStep(err)
+	if err != nil {
+		log.errorf("Failed to release the VF: %v", err)
+		return s.configInterfaceFailureResponse(err), nil
+	}
+	// The tap is taken from the attachment of the container. The KuryrPort is only used when no
+	// attachment was saved, and only if it belongs to the Pod of the request: a DEL of an old
+	// container of a recreated Pod must not find the tap of the new one.
+	hostIfaceName := ""
+	if attachment := s.getAttachment(cniConfig); attachment != nil {
+		hostIfaceName = attachment.HostIface
+		log.with("vif", attachment.VifID)
+	} else if vif := s.getKuryrVif(cniConfig, podUID); vif != nil {
+		hostIfaceName = util.GenerateTapInterfaceName(vif.Vif.ID)
+		log.with("vif", vif.Vif.ID)
+	}
+	endStep = log.step(ctx, "removeInterfaces")
+	err = s.kpConfigurator.removeInterfaces(cniConfig.ContainerId, hostIfaceName)
+	endStep(err)
+	if err != nil {
+		log.errorf("Failed to remove the interfaces: %v", err)
+		return s.configInterfaceFailureResponse(err), nil
+	}
+	endStep = log.step(ctx, "deleteAttachment")
+	err = s.kpConfigurator.stateStore.Delete(cniConfig.ContainerId, cniConfig.Ifname)
+	endStep(err)
+	if err != nil {
+		log.errorf("Failed to delete the attachment: %v", err)
+		return s.configInterfaceFailureResponse(err), nil
+	}
 	return &cnipb.CniCmdResponse{CniResult: []byte("")}, nil
 }
 
-func (s *CNIServer) CmdCheck(_ context.Context, request *cnipb.CniCmdRequest) (
-	*cnipb.CniCmdResponse, error) {
-	klog.Infof("Received CmdCheck request %v", request)
+// getPodUID returns the UID of the Pod, from the CNI args when the runtime passes it or else from
+// the K8s apiserver.
+func (s *CNIServer) getPodUID(ctx context.Context, cniConfig *CNIConfig) (string, error) {
+	if podUID := string(cniConfig.K8S_POD_UID); podUID != "" {
+		return podUID, nil
+	}
+	pod, err := s.kubeClient.CoreV1().Pods(string(cniConfig.K8S_POD_NAMESPACE)).Get(ctx, string(cniConfig.K8S_POD_NAME), metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get Pod %s/%s: %v", string(cniConfig.K8S_POD_NAMESPACE), string(cniConfig.K8S_POD_NAME), err)
+	}
+	return string(pod.UID), nil
+}
+
+// waitForKuryrPort waits for the KuryrPort of the Pod to be created with the VIF of the requested
+// interface. tryAgainLaterResponse is returned if the KuryrPort is not ready after
+// networkReadyTimeout. The KuryrPort of another Pod with the same name, i.e. the previous instance
+// of a recreated Pod, is rejected at once with podUIDMismatchResponse.
+func (s *CNIServer) waitForKuryrPort(ctx context.Context, cniConfig *CNIConfig, podUID string) (*v1alpha1.KuryrPort, *cnipb.CniCmdResponse) {
+	podNamespace, podName := string(cniConfig.K8S_POD_NAMESPACE), string(cniConfig.K8S_POD_NAME)
+	key := k8s.NamespacedName(podNamespace, podName)
+	ch := s.kpNotifier.subscribe(key)
+	defer s.kpNotifier.unsubscribe(key, ch)
+	timer := time.NewTimer(networkReadyTimeout)
+	defer timer.Stop()
+	for {
+		kp, err := s.kpLister.KuryrPorts(podNamespace).Get(podName)
+		if err == nil {
+			if kp.Spec.PodUid != podUID {
+				klog.Warningf("Rejecting KuryrPort %s of Pod UID %s for Pod UID %s", key, kp.Spec.PodUid, podUID)
+				return nil, s.podUIDMismatchResponse(key, kp.Spec.PodUid, podUID)
+			}
+			for _, vif := range kp.Status.Vifs {
+				if vif.IfName == cniConfig.Ifname {
+					return kp, nil
+				}
+			}
+			klog.V(2).Infof("KuryrPort %s has no VIF for interface %s yet", key, cniConfig.Ifname)
+		} else if !errors.IsNotFound(err) {
+			klog.Errorf("Get KuryrPort(%s) Error: %s", key, err)
+		}
+
+		select {
+		case <-ch:
+		case <-timer.C:
+			klog.Errorf("KuryrPort %s of Pod UID %s not ready after %v", key, podUID, networkReadyTimeout)
+			return nil, s.tryAgainLaterResponse()
+		case <-ctx.Done():
+			klog.Errorf("Stopped waiting for KuryrPort %s: %v", key, ctx.Err())
+			return nil, s.tryAgainLaterResponse()
+		}
+	}
+}
+
+// getKuryrVif returns the VIF of the requested interface from the KuryrPort of
+// the Pod, or nil if the KuryrPort is gone or belongs to another Pod than the
+// one of podUID.
+func (s *CNIServer) getKuryrVif(cniConfig *CNIConfig, podUID string) *v1alpha1.KuryrVif {
+	kp, err := s.kpLister.KuryrPorts(string(cniConfig.K8S_POD_NAMESPACE)).Get(string(cniConfig.K8S_POD_NAME))
+	if err != nil {
+		klog.V(2).Infof("Get KuryrPort(%s/%s) Error: %s", string(cniConfig.K8S_POD_NAMESPACE), string(cniConfig.K8S_POD_NAME), err)
+		return nil
+	}
+	if podUID == "" || kp.Spec.PodUid != podUID {
+		klog.V(2).Infof("Ignoring KuryrPort %s/%s of Pod UID %s for Pod UID %q", kp.Namespace, kp.Name, kp.Spec.PodUid, podUID)
+		return nil
+	}
+	for i := range kp.Status.Vifs {
+		if kp.Status.Vifs[i].IfName == cniConfig.Ifname {
+			return &kp.Status.Vifs[i]
+		}
+	}
+	return nil
+}
+
+// newAttachment returns the attachment of the VIF to the container interface, its CNI result is set
+// once the interface is configured.
+func newAttachment(cniConfig *CNIConfig, netNS string, vif *v1alpha1.KuryrVif) *cnistate.Attachment {
+	attachment := &cnistate.Attachment{
+		ContainerID:  cniConfig.ContainerId,
+		Network:      cniConfig.Name,
+		Netns:        netNS,
+		IfName:       cniConfig.Ifname,
+		PodName:      string(cniConfig.K8S_POD_NAME),
+		PodNamespace: string(cniConfig.K8S_POD_NAMESPACE),
+		VifID:        vif.Vif.ID,
+		MTU:          vif.Vif.Network.MTU,
+	}
+	if vif.Vif.VNICType == v1alpha1.VNICTypeDirect {
+		attachment.PCISlot = vif.Vif.PCISlot
+	} else {
+		attachment.HostIface = util.GenerateTapInterfaceName(vif.Vif.ID)
+	}
+	return attachment
+}
 
+// storeAttachment saves the attachment with the CNI result of kuryr, i.e. without the interfaces of
+// the other plugins of a chain.
+func (s *CNIServer) storeAttachment(attachment *cnistate.Attachment, result *current.Result) error {
+	var resultBytes bytes.Buffer
+	if err := result.PrintTo(&resultBytes); err != nil {
+		return err
+	}
+	attachment.CNIResult = resultBytes.Bytes()
+	return s.kpConfigurator.stateStore.Save(attachment)
+}
+
+// getAttachment returns the saved attachment of the container interface, nil is returned if it is
+// not found.
+func (s *CNIServer) getAttachment(cniConfig *CNIConfig) *cnistate.Attachment {
+	attachment, err := s.kpConfigurator.stateStore.Get(cniConfig.ContainerId, cniConfig.Ifname)
+	if err != nil {
+		klog.Errorf("Failed to get the attachment of container %s: %v", cniConfig.ContainerId, err)
+		return nil
+	}
+	return attachment
+}
+
+func (s *CNIServer) CmdCheck(ctx context.Context, request *cnipb.CniCmdRequest) (
+	resp *cnipb.CniCmdResponse, _ error) {
 	cniConfig, response := s.checkRequestMessage(request)
 	if response != nil {
 		return response, nil
 	}
+	log := newRequestLogger(ctx, "CHECK", cniConfig)
+	log.infof(2, "Received CmdCheck request")
+	podUID, _ := s.getPodUID(ctx, cniConfig)
+	log.podUID = podUID
+	_, end := log.start(ctx)
+	defer func() { end(resp) }()
 
 	infraContainer := cniConfig.getInfraContainer()
 	s.containerAccess.lockContainer(infraContainer)
 	defer s.containerAccess.unlockContainer(infraContainer)
 
-	klog.Infof("CmdCheck for container %v succeeded", cniConfig.ContainerId)
+	if valid, _ := version.GreaterThanOrEqualTo(cniConfig.CNIVersion, "0.4.0"); valid {
+		prevResult, response := s.parsePrevResultFromRequest(cniConfig.NetworkConfig)
+		if response != nil {
+			return response, nil
+		}
+		containerIface := parseContainerIfaceFromResults(cniConfig.CniCmdArgs, prevResult)
+		if containerIface == nil {
+			log.errorf("Failed to find the interface in prevResult")
+			return s.unsupportedFieldResponse("prevResult", cniConfig.Ifname), nil
+		}
+		// The prevResult of a chain also holds the interfaces of the other plugins, only the one of
+		// kuryr is checked.
+		if s.isChaining {
+			prevResult = containerIfaceResult(prevResult, containerIface)
+		}
+		// The interface is checked against the attachment of the container, or else against the
+		// VIF of the KuryrPort of the Pod.
+		portID, mtu, pciSlot := "", 0, ""
+		var expectedConfig *interfacestore.InterfaceConfig
+		if attachment := s.getAttachment(cniConfig); attachment != nil {
+			portID, mtu, pciSlot = attachment.VifID, attachment.MTU, attachment.PCISlot
+			expectedConfig = attachmentContainerConfig(attachment)
+		} else if vif := s.getKuryrVif(cniConfig, podUID); vif != nil {
+			portID, mtu = vif.Vif.ID, vif.Vif.Network.MTU
+			if vif.Vif.VNICType == v1alpha1.VNICTypeDirect {
+				pciSlot = vif.Vif.PCISlot
+			}
+			expectedConfig = vifContainerConfig(cniConfig.ContainerId, vif)
+		}
+		if err := s.kpConfigurator.checkInterfaces(
+			cniConfig.ContainerId,
+			s.hostNetNsPath(cniConfig.Netns),
+			portID,
+			mtu,
+			pciSlot,
+			expectedConfig,
+			containerIface,
+			prevResult,
+		); err != nil {
+			log.errorf("Failed to check the interfaces: %v", err)
+			return s.checkInterfaceFailureResponse(err), nil
+		}
+	}
+	return &cnipb.CniCmdResponse{CniResult: []byte("")}, nil
+}
+
+// CmdStatus tells the runtime whether the server is ready to serve ADD requests. The server only
+// listens once the taps are reconciled, the OVS bridge must also be reachable.
+// The cniVersion of the request is not validated, STATUS doesn't return a result.
+func (s *CNIServer) CmdStatus(ctx context.Context, request *cnipb.CniCmdRequest) (
+	*cnipb.CniCmdResponse, error) {
+	cniConfig, err := s.loadNetworkConfig(request)
+	if err != nil {
+		klog.Errorf("Failed to parse network configuration: %v", err)
+		return s.decodingFailureResponse("network config"), nil
+	}
+	// STATUS is sent periodically by the runtime, it is only logged at a high verbosity.
+	log := newRequestLogger(ctx, "STATUS", cniConfig)
+	log.infof(4, "Received CmdStatus request")
+	if s.networkReadyCh != nil {
+		select {
+		case <-s.networkReadyCh:
+		default:
+			return s.generateCNIErrorResponse(cnipb.ErrorCode_PLUGIN_NOT_AVAILABLE, "The network of the Node is not ready"), nil
+		}
+	}
+	if _, err := s.kpConfigurator.ovsBridgeClient.GetPortList(); err != nil {
+		log.errorf("Failed to reach the OVS bridge: %v", err)
+		return s.generateCNIErrorResponse(
+			cnipb.ErrorCode_PLUGIN_NOT_AVAILABLE_LIMITED_CONNECTIVITY,
+			fmt.Sprintf("Failed to reach the OVS bridge: %v", err),
+		), nil
+	}
+	return &cnipb.CniCmdResponse{CniResult: []byte("")}, nil
+}
+
+// CmdGC removes the taps and the cached state of the containers of the network which are not in the
+// valid attachments of the request, the runtime only lists the attachments of the network of the
+// request. Like CmdStatus, the cniVersion of the request is not validated.
+func (s *CNIServer) CmdGC(ctx context.Context, request *cnipb.CniCmdRequest) (
+	resp *cnipb.CniCmdResponse, _ error) {
+	cniConfig, err := s.loadNetworkConfig(request)
+	if err != nil {
+		klog.Errorf("Failed to parse network configuration: %v", err)
+		return s.decodingFailureResponse("network config"), nil
+	}
+	log := newRequestLogger(ctx, "GC", cniConfig).with("validAttachments", len(cniConfig.ValidAttachments))
+	log.infof(2, "Received CmdGC request")
+	_, end := log.start(ctx)
+	defer func() { end(resp) }()
+	// Without the valid attachments all the taps would be removed.
+	if cniConfig.ValidAttachments == nil {
+		return s.generateCNIErrorResponse(
+			cnipb.ErrorCode_INVALID_NETWORK_CONFIG,
+			"Network configuration misses cni.dev/valid-attachments",
+		), nil
+	}
+	validContainerIDs := sets.NewString()
+	for _, attachment := range cniConfig.ValidAttachments {
+		validContainerIDs.Insert(attachment.ContainerID)
+	}
+	if err := s.kpConfigurator.gc(cniConfig.Name, validContainerIDs, s.containerAccess); err != nil {
+		log.errorf("Failed to garbage collect interfaces: %v", err)
+		return s.configInterfaceFailureResponse(err), nil
+	}
 	return &cnipb.CniCmdResponse{CniResult: []byte("")}, nil
 }
 
+// GetCapabilities reports the version of the CNI service, the CNI spec versions and the optional
+// features supported by the server, along with its boot ID.
+func (s *CNIServer) GetCapabilities(_ context.Context, _ *cnipb.CniCapabilitiesRequest) (
+	*cnipb.CniCapabilitiesResponse, error) {
+	cniVersions := make([]string, 0, len(s.supportedCNIVersions))
+	for cniVersion := range s.supportedCNIVersions {
+		cniVersions = append(cniVersions, cniVersion)
+	}
+	sort.Strings(cniVersions)
+	features := []string{cni.FeatureCheck, cni.FeatureStatus, cni.FeatureGC}
+	if s.isChaining {
+		features = append(features, cni.FeatureChaining)
+	}
+	return &cnipb.CniCapabilitiesResponse{
+		ApiVersion:           s.serverVersion,
+		SupportedCniVersions: cniVersions,
+		Features:             features,
+		BootId:               s.bootID,
+	}, nil
+}
+
+// bootIDInterceptor sends the boot ID of the server with all the responses, so that kuryr-cni
+// knows when the capabilities it cached are outdated.
+func (s *CNIServer) bootIDInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	if err := grpc.SetHeader(ctx, metadata.Pairs(cni.BootIDHeader, s.bootID)); err != nil {
+		klog.Warningf("Failed to set the boot ID header: %v", err)
+	}
+	return handler(ctx, req)
+}
+
 func buildVersionSet() map[string]bool {
 	versionSet := make(map[string]bool)
 	for _, ver := range version.All.SupportedVersions() {
@@ -463,23 +1054,36 @@ func init() {
 
 func New(
 	cniSocket, hostProcPathPrefix string,
+	kubeClient clientset.Interface,
 	crdClient crdclientset.Interface,
 	kpInformer kuryrinformers.KuryrPortInformer,
+	isChaining bool,
 	networkReadyCh <-chan struct{},
 	routeClient route.Interface,
 	nodeConfig *config.NodeConfig,
 ) *CNIServer {
+	notifier := newKpNotifier()
+	kpInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: notifier.notify,
+		UpdateFunc: func(_, newObj interface{}) {
+			notifier.notify(newObj)
+		},
+	})
 	return &CNIServer{
 		cniSocket:            cniSocket,
 		supportedCNIVersions: supportedCNIVersionSet,
 		serverVersion:        cni.KuryrCNIVersion,
 
+		kubeClient:           kubeClient,
 		crdClient: 			  crdClient,
 		kpLister: 				kpInformer.Lister(),
 		kpInformer: kpInformer,
+		kpNotifier:           notifier,
 		hostProcPathPrefix: hostProcPathPrefix,
 
 		containerAccess:      newContainerAccessArbitrator(),
+		isChaining:           isChaining,
+		bootID:               string(uuid.NewUUID()),
 		routeClient:          routeClient,
 		nodeConfig:           nodeConfig,
 		networkReadyCh:       networkReadyCh,
@@ -508,11 +1112,20 @@ func (s *CNIServer) Run(stopCh <-chan struct{}) {
 	klog.Info("Starting CNI server")
 	defer klog.Info("Shutting down CNI server")
 
+	// The taps are reconciled before serving the CNI requests, reconcile relies on the KuryrPorts
+	// cache.
+	if !cache.WaitForCacheSync(stopCh, s.kpInformer.Informer().HasSynced) {
+		return
+	}
+	if err := s.reconcile(); err != nil {
+		klog.Errorf("Error during CNI server reconciliation: %v", err)
+	}
+
 	listener, err := util.ListenLocalSocket(s.cniSocket)
 	if err != nil {
 		klog.Fatalf("Failed to bind on %s: %v", s.cniSocket, err)
 	}
-	rpcServer := grpc.NewServer()
+	rpcServer := grpc.NewServer(grpc.UnaryInterceptor(chainUnaryInterceptors(requestIDInterceptor, tracing.UnaryServerInterceptor, s.bootIDInterceptor)))
 
 	cnipb.RegisterCniServer(rpcServer, s)
 	klog.Info("CNI server is listening ...")
@@ -524,12 +1137,20 @@ func (s *CNIServer) Run(stopCh <-chan struct{}) {
 	<-stopCh
 }
 
+// InitializeCniServer creates the configurator of the taps. ofClient is nil when kuryrProxy is
+// disabled.
 func (s *CNIServer) InitializeCniServer(
 	ovsBridgeClient ovsconfig.OVSBridgeClient,
+	ofClient openflow.Client,
+	ifaceStore interfacestore.InterfaceStore,
+	stateStore *cnistate.Store,
 ) error {
 	var err error
 	s.kpConfigurator, err = newKpConfigurator(
 		ovsBridgeClient,
+		ofClient,
+		ifaceStore,
+		stateStore,
 		ovsBridgeClient.GetOVSDatapathType(),
 		ovsBridgeClient.IsHardwareOffloadEnabled(),
 	)
@@ -0,0 +1,614 @@
+package cniserver
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containernetworking/cni/pkg/types/current"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+
+	"projectkuryr/kuryr/pkg/agent/cnistate"
+	"projectkuryr/kuryr/pkg/agent/interfacestore"
+	"projectkuryr/kuryr/pkg/agent/util"
+	cnipb "projectkuryr/kuryr/pkg/apis/cni/v1alpha1"
+	"projectkuryr/kuryr/pkg/apis/openstack/v1alpha1"
+	kuryrlisters "projectkuryr/kuryr/pkg/client/listers/openstack/v1alpha1"
+	"projectkuryr/kuryr/pkg/ovs/ovsconfig"
+)
+
+const (
+	testNetworkConfig = `{"cniVersion":"0.4.0","name":"kuryr","type":"kuryr-cni"}`
+	// The Neutron ports of the previous and of the recreated instance of the Pod.
+	oldVifID = "9c2d7e11-52a8-4f6e-b3a0-2e7d4c9f8b10"
+	newVifID = "4a3fa0d4-1f0e-4b4c-9d55-8f0c6f1b6a21"
+)
+
+// fakeOVSBridgeClient holds the OVS ports of the bridge, the other methods of
+// ovsconfig.OVSBridgeClient are not used by the tests.
+type fakeOVSBridgeClient struct {
+	ovsconfig.OVSBridgeClient
+	ports []ovsconfig.OVSPortData
+	// portListErr is returned by GetPortList when the bridge is not reachable.
+	portListErr ovsconfig.Error
+	// createPortErr and ofPortErr are returned by CreatePortExt and GetOFPort.
+	createPortErr ovsconfig.Error
+	ofPortErr     ovsconfig.Error
+	// createdPorts and deletedPorts record the names of the ports created and deleted.
+	createdPorts []string
+	deletedPorts []string
+}
+
+func (c *fakeOVSBridgeClient) GetPortList() ([]ovsconfig.OVSPortData, ovsconfig.Error) {
+	if c.portListErr != nil {
+		return nil, c.portListErr
+	}
+	// A copy like the OVSDB client, as the ports are deleted while the callers iterate them.
+	return append([]ovsconfig.OVSPortData{}, c.ports...), nil
+}
+
+func (c *fakeOVSBridgeClient) CreatePortExt(name, ifDev string, externalIDs, ifExternalIDs map[string]interface{}) (string, ovsconfig.Error) {
+	if c.createPortErr != nil {
+		return "", c.createPortErr
+	}
+	c.createdPorts = append(c.createdPorts, name)
+	port := ovsconfig.OVSPortData{UUID: name + "-uuid", Name: name, ExternalIDs: map[string]string{}}
+	for k, v := range externalIDs {
+		port.ExternalIDs[k] = v.(string)
+	}
+	c.ports = append(c.ports, port)
+	return port.UUID, nil
+}
+
+func (c *fakeOVSBridgeClient) GetOFPort(ifName string) (int32, ovsconfig.Error) {
+	if c.ofPortErr != nil {
+		return 0, c.ofPortErr
+	}
+	return 1, nil
+}
+
+func (c *fakeOVSBridgeClient) DeletePort(portUUID string) ovsconfig.Error {
+	for i, port := range c.ports {
+		if port.UUID == portUUID {
+			c.deletedPorts = append(c.deletedPorts, port.Name)
+			c.ports = append(c.ports[:i], c.ports[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (c *fakeOVSBridgeClient) portNames() []string {
+	var names []string
+	for _, port := range c.ports {
+		names = append(names, port.Name)
+	}
+	return names
+}
+
+func newTapPort(name, containerID string) ovsconfig.OVSPortData {
+	port := ovsconfig.OVSPortData{UUID: name + "-uuid", Name: name, ExternalIDs: map[string]string{}}
+	if containerID != "" {
+		port.ExternalIDs[ovsExternalIDContainerID] = containerID
+	}
+	return port
+}
+
+// newKuryrTapPort returns a tap plugged by kuryr for the container in the CNI network.
+func newKuryrTapPort(name, containerID, network string) ovsconfig.OVSPortData {
+	port := newTapPort(name, containerID)
+	port.IFExternalIDs = map[string]string{ovsExternalIDVMUUID: kuryrVMUUID}
+	if network != "" {
+		port.ExternalIDs[ovsExternalIDNetwork] = network
+	}
+	return port
+}
+
+var defaultDelLinkByName = delLinkByName
+
+// fakeLinks replaces delLinkByName to record the deleted links.
+func fakeLinks(t *testing.T) *[]string {
+	var deleted []string
+	delLinkByName = func(name string) error {
+		deleted = append(deleted, name)
+		return nil
+	}
+	t.Cleanup(func() {
+		delLinkByName = defaultDelLinkByName
+	})
+	return &deleted
+}
+
+func newTestCNIServer(t *testing.T, bridgeClient *fakeOVSBridgeClient, kps ...*v1alpha1.KuryrPort) *CNIServer {
+	dir, err := ioutil.TempDir("", "kuryr-cniserver")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	stateStore, err := cnistate.NewStore(filepath.Join(dir, "cni"))
+	require.NoError(t, err)
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, kp := range kps {
+		require.NoError(t, indexer.Add(kp))
+	}
+	return &CNIServer{
+		supportedCNIVersions: supportedCNIVersionSet,
+		kpLister:             kuryrlisters.NewKuryrPortLister(indexer),
+		kpNotifier:           newKpNotifier(),
+		containerAccess:      newContainerAccessArbitrator(),
+		kpConfigurator: &kpConfigurator{
+			ovsBridgeClient: bridgeClient,
+			ifaceStore:      interfacestore.NewInterfaceStore(),
+			ifConfigurator:  &ifConfigurator{},
+			stateStore:      stateStore,
+		},
+	}
+}
+
+func newTestKuryrPort(podUID, vifID string) *v1alpha1.KuryrPort {
+	return &v1alpha1.KuryrPort{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default"},
+		Spec:       v1alpha1.KuryrPortSpec{PodUid: podUID},
+		Status: v1alpha1.KuryrPortStatus{
+			Vifs: []v1alpha1.KuryrVif{{IfName: "eth0", IsDefault: true, Vif: v1alpha1.VIF{ID: vifID}}},
+		},
+	}
+}
+
+func newTestRequest(containerID, podUID string) *cnipb.CniCmdRequest {
+	return &cnipb.CniCmdRequest{
+		CniArgs: &cnipb.CniCmdArgs{
+			ContainerId:          containerID,
+			Ifname:               "eth0",
+			Args:                 "K8S_POD_NAMESPACE=default;K8S_POD_NAME=pod1;K8S_POD_UID=" + podUID,
+			NetworkConfiguration: []byte(testNetworkConfig),
+		},
+	}
+}
+
+func TestCmdAddRejectsKuryrPortOfAnotherPod(t *testing.T) {
+	deleted := fakeLinks(t)
+	tap := util.GenerateTapInterfaceName(oldVifID)
+	bridgeClient := &fakeOVSBridgeClient{ports: []ovsconfig.OVSPortData{newTapPort(tap, "c-old")}}
+	// The KuryrPort of the previous instance of the Pod is not deleted yet.
+	s := newTestCNIServer(t, bridgeClient, newTestKuryrPort("uid-old", oldVifID))
+
+	resp, err := s.CmdAdd(context.Background(), newTestRequest("c-new", "uid-new"))
+	require.NoError(t, err)
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, cnipb.ErrorCode_UNKNOWN_CONTAINER, resp.Error.Code)
+	// No rollback DEL is done.
+	assert.Equal(t, []string{tap}, bridgeClient.portNames())
+	assert.Empty(t, *deleted)
+}
+
+func TestCmdAddWaitsForKuryrPort(t *testing.T) {
+	s := newTestCNIServer(t, &fakeOVSBridgeClient{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	resp, err := s.CmdAdd(ctx, newTestRequest("c1", "uid-1"))
+	require.NoError(t, err)
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, cnipb.ErrorCode_TRY_AGAIN_LATER, resp.Error.Code)
+}
+
+func TestCmdAddRejectsVFNotAssignedToPod(t *testing.T) {
+	kp := newTestKuryrPort("uid-1", newVifID)
+	kp.Status.Vifs[0].Vif.VNICType = v1alpha1.VNICTypeDirect
+	kp.Status.Vifs[0].Vif.PCISlot = "0000:03:10.2"
+	s := newTestCNIServer(t, &fakeOVSBridgeClient{}, kp)
+	request := newTestRequest("c1", "uid-1")
+	// The device plugin assigned another VF to the Pod.
+	request.CniArgs.NetworkConfiguration = []byte(`{"cniVersion":"0.4.0","name":"kuryr","type":"kuryr-cni","deviceID":"0000:03:10.3"}`)
+
+	resp, err := s.CmdAdd(context.Background(), request)
+	require.NoError(t, err)
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, cnipb.ErrorCode_CONFIG_INTERFACE_FAILURE, resp.Error.Code)
+	assert.Contains(t, resp.Error.Message, "0000:03:10.3")
+}
+
+func TestCmdAddReturnsCachedResult(t *testing.T) {
+	deleted := fakeLinks(t)
+	defaultCheckCachedInterfaces := checkCachedInterfaces
+	t.Cleanup(func() { checkCachedInterfaces = defaultCheckCachedInterfaces })
+	// The interfaces of the container still match the cached result.
+	checkCachedInterfaces = func(*kpConfigurator, string, string, string, int, string, *interfacestore.InterfaceConfig, *current.Interface, *current.Result) error {
+		return nil
+	}
+	netNS := "/var/run/netns/c1"
+	tap := util.GenerateTapInterfaceName(newVifID)
+	cachedResult := `{"cniVersion":"0.4.0",` +
+		`"interfaces":[{"name":"` + tap + `"},{"name":"eth0","mac":"fa:16:3e:00:00:01","sandbox":"` + netNS + `"}],` +
+		`"ips":[{"version":"4","interface":1,"address":"10.0.0.5/24","gateway":"10.0.0.1"}]}`
+	port := newKuryrTapPort(tap, "c1", "kuryr")
+	port.ExternalIDs[ovsExternalIDCNIResult] = cachedResult
+	bridgeClient := &fakeOVSBridgeClient{ports: []ovsconfig.OVSPortData{port}}
+	s := newTestCNIServer(t, bridgeClient, newTestKuryrPort("uid-1", newVifID))
+	request := newTestRequest("c1", "uid-1")
+	request.CniArgs.Netns = netNS
+
+	resp, err := s.CmdAdd(context.Background(), request)
+	require.NoError(t, err)
+	require.Nil(t, resp.Error)
+	result, err := current.NewResult(resp.CniResult)
+	require.NoError(t, err)
+	ips := result.(*current.Result).IPs
+	require.Len(t, ips, 1)
+	assert.Equal(t, "10.0.0.5/24", ips[0].Address.String())
+	// The tap is neither plugged again nor deleted.
+	assert.Empty(t, bridgeClient.createdPorts)
+	assert.Empty(t, bridgeClient.deletedPorts)
+	assert.Empty(t, *deleted)
+	attachment, err := s.kpConfigurator.stateStore.Get("c1", "eth0")
+	require.NoError(t, err)
+	require.NotNil(t, attachment)
+	assert.Equal(t, "kuryr", attachment.Network)
+}
+
+func TestCmdDelIgnoresKuryrPortOfRecreatedPod(t *testing.T) {
+	deleted := fakeLinks(t)
+	tap := util.GenerateTapInterfaceName(newVifID)
+	bridgeClient := &fakeOVSBridgeClient{ports: []ovsconfig.OVSPortData{newTapPort(tap, "c-new")}}
+	s := newTestCNIServer(t, bridgeClient, newTestKuryrPort("uid-new", newVifID))
+
+	// A DEL retried by kubelet for the container of the previous Pod.
+	resp, err := s.CmdDel(context.Background(), newTestRequest("c-old", "uid-old"))
+	require.NoError(t, err)
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, []string{tap}, bridgeClient.portNames())
+	assert.Empty(t, *deleted)
+}
+
+func TestCmdDelUsesAttachment(t *testing.T) {
+	deleted := fakeLinks(t)
+	oldTap := util.GenerateTapInterfaceName(oldVifID)
+	newTap := util.GenerateTapInterfaceName(newVifID)
+	bridgeClient := &fakeOVSBridgeClient{ports: []ovsconfig.OVSPortData{newTapPort(oldTap, ""), newTapPort(newTap, "c-new")}}
+	// The KuryrPort already names the VIF of the new Pod.
+	s := newTestCNIServer(t, bridgeClient, newTestKuryrPort("uid-new", newVifID))
+	attachment := &cnistate.Attachment{ContainerID: "c-old", IfName: "eth0", VifID: oldVifID, HostIface: oldTap}
+	require.NoError(t, s.kpConfigurator.stateStore.Save(attachment))
+
+	resp, err := s.CmdDel(context.Background(), newTestRequest("c-old", "uid-old"))
+	require.NoError(t, err)
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, []string{newTap}, bridgeClient.portNames())
+	assert.Equal(t, []string{oldTap}, *deleted)
+	attachment, err = s.kpConfigurator.stateStore.Get("c-old", "eth0")
+	require.NoError(t, err)
+	assert.Nil(t, attachment)
+}
+
+func TestCmdDelUsesKuryrPortOfPod(t *testing.T) {
+	deleted := fakeLinks(t)
+	tap := util.GenerateTapInterfaceName(newVifID)
+	// The tap of a failed ADD is not owned yet, it is only known from the KuryrPort.
+	bridgeClient := &fakeOVSBridgeClient{ports: []ovsconfig.OVSPortData{newTapPort(tap, "")}}
+	s := newTestCNIServer(t, bridgeClient, newTestKuryrPort("uid-new", newVifID))
+
+	resp, err := s.CmdDel(context.Background(), newTestRequest("c-new", "uid-new"))
+	require.NoError(t, err)
+	assert.Nil(t, resp.Error)
+	assert.Empty(t, bridgeClient.portNames())
+	assert.Equal(t, []string{tap}, *deleted)
+}
+
+func TestRemoveInterfaces(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		ports         []ovsconfig.OVSPortData
+		expectedPorts []string
+		expectedLinks []string
+	}{
+		{
+			name:          "tap owned by the container",
+			ports:         []ovsconfig.OVSPortData{newTapPort("tap1", "c1")},
+			expectedLinks: []string{"tap1"},
+		},
+		{
+			name:          "tap without owner",
+			ports:         []ovsconfig.OVSPortData{newTapPort("tap1", "")},
+			expectedLinks: []string{"tap1"},
+		},
+		{
+			name:          "tap not plugged",
+			expectedLinks: []string{"tap1"},
+		},
+		{
+			name:          "tap owned by another container",
+			ports:         []ovsconfig.OVSPortData{newTapPort("tap1", "c2")},
+			expectedPorts: []string{"tap1"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			deleted := fakeLinks(t)
+			bridgeClient := &fakeOVSBridgeClient{ports: tc.ports}
+			s := newTestCNIServer(t, bridgeClient)
+
+			require.NoError(t, s.kpConfigurator.removeInterfaces("c1", "tap1"))
+			assert.Equal(t, tc.expectedPorts, bridgeClient.portNames())
+			assert.Equal(t, tc.expectedLinks, *deleted)
+		})
+	}
+}
+
+func TestGC(t *testing.T) {
+	deleted := fakeLinks(t)
+	bridgeClient := &fakeOVSBridgeClient{ports: []ovsconfig.OVSPortData{
+		newKuryrTapPort("tap-valid", "c-valid", "kuryr"),
+		newKuryrTapPort("tap-stale", "c-stale", "kuryr"),
+		// Collected by the GC of the other network.
+		newKuryrTapPort("tap-other", "c-other", "kuryr-sriov"),
+		// Plugged again by reconcile, its owner is unknown.
+		newKuryrTapPort("tap-reconciled", "", ""),
+		// Not plugged by kuryr.
+		newTapPort("tap-foreign", "c-foreign"),
+	}}
+	s := newTestCNIServer(t, bridgeClient)
+	for _, attachment := range []*cnistate.Attachment{
+		{ContainerID: "c-valid", Network: "kuryr", IfName: "eth0", HostIface: "tap-valid"},
+		{ContainerID: "c-stale", Network: "kuryr", IfName: "eth0", HostIface: "tap-stale"},
+		{ContainerID: "c-other", Network: "kuryr-sriov", IfName: "net1", HostIface: "tap-other"},
+		// The tap of the container is already gone.
+		{ContainerID: "c-unplugged", Network: "kuryr", IfName: "eth0", HostIface: "tap-unplugged"},
+	} {
+		require.NoError(t, s.kpConfigurator.stateStore.Save(attachment))
+	}
+
+	require.NoError(t, s.kpConfigurator.gc("kuryr", sets.NewString("c-valid"), s.containerAccess))
+	assert.ElementsMatch(t, []string{"tap-valid", "tap-other", "tap-reconciled", "tap-foreign"}, bridgeClient.portNames())
+	assert.ElementsMatch(t, []string{"tap-stale", "tap-unplugged"}, *deleted)
+	attachments, err := s.kpConfigurator.stateStore.List()
+	require.NoError(t, err)
+	var containerIDs []string
+	for _, attachment := range attachments {
+		containerIDs = append(containerIDs, attachment.ContainerID)
+	}
+	assert.ElementsMatch(t, []string{"c-valid", "c-other"}, containerIDs)
+}
+
+func TestConfigureTapRollback(t *testing.T) {
+	defaultConfigureTapLinkVeth := configureTapLinkVeth
+	t.Cleanup(func() { configureTapLinkVeth = defaultConfigureTapLinkVeth })
+	configureTapLinkVeth = func(*ifConfigurator, string, string, string, string, int, string, *current.Result) error {
+		return nil
+	}
+	for _, tc := range []struct {
+		name         string
+		bridgeClient *fakeOVSBridgeClient
+	}{
+		{
+			name:         "port not created",
+			bridgeClient: &fakeOVSBridgeClient{createPortErr: ovsconfig.NewTransactionError(assert.AnError, false)},
+		},
+		{
+			name:         "of_port not allocated",
+			bridgeClient: &fakeOVSBridgeClient{ofPortErr: ovsconfig.NewTransactionError(assert.AnError, false)},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			deleted := fakeLinks(t)
+			s := newTestCNIServer(t, tc.bridgeClient)
+			tap := util.GenerateTapInterfaceName(newVifID)
+			result := &current.Result{
+				Interfaces: []*current.Interface{
+					{Name: tap, Mac: "fa:16:3e:00:00:02"},
+					{Name: "eth0", Mac: "fa:16:3e:00:00:01", Sandbox: "/var/run/netns/c1"},
+				},
+				IPs: []*current.IPConfig{{Version: "4", Address: parseCIDR("10.0.0.5/24"), Interface: current.Int(1)}},
+			}
+
+			err := s.kpConfigurator.configureTap("pod1", "default", newVifID, "kuryr", "c1", tap,
+				"/var/run/netns/c1", "eth0", 1450, "fa:16:3e:00:00:01", result, true, s.containerAccess)
+			require.Error(t, err)
+			// Neither the port nor the veth pair is left behind.
+			assert.Empty(t, tc.bridgeClient.portNames())
+			assert.Equal(t, []string{tap}, *deleted)
+			_, found := s.kpConfigurator.ifaceStore.GetContainerInterface("c1")
+			assert.False(t, found)
+		})
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	deleted := fakeLinks(t)
+	defaultInterfaceByName := interfaceByName
+	t.Cleanup(func() { interfaceByName = defaultInterfaceByName })
+	const unpluggedVifID = "d8e0b5a2-7c3f-4e1b-a6d9-0f2c8b4e7a13"
+	liveTap := util.GenerateTapInterfaceName(newVifID)
+	unpluggedTap := util.GenerateTapInterfaceName(unpluggedVifID)
+	staleTap := util.GenerateTapInterfaceName(oldVifID)
+	// Only the tap which is not plugged is looked up in the host netns.
+	interfaceByName = func(name string) (*net.Interface, error) {
+		if name == unpluggedTap {
+			return &net.Interface{Name: name}, nil
+		}
+		return nil, assert.AnError
+	}
+	bridgeClient := &fakeOVSBridgeClient{ports: []ovsconfig.OVSPortData{
+		newKuryrTapPort(liveTap, "c-live", "kuryr"),
+		// The Pod of the tap is gone.
+		newKuryrTapPort(staleTap, "c-stale", "kuryr"),
+		// Not plugged by kuryr.
+		newTapPort("tap-foreign", "c-foreign"),
+	}}
+	liveKp := newTestKuryrPort("uid-live", newVifID)
+	unpluggedKp := newTestKuryrPort("uid-unplugged", unpluggedVifID)
+	unpluggedKp.Name = "pod2"
+	s := newTestCNIServer(t, bridgeClient)
+	for _, attachment := range []*cnistate.Attachment{
+		{ContainerID: "c-stale", Network: "kuryr", IfName: "eth0", VifID: oldVifID, HostIface: staleTap},
+		{ContainerID: "c-unplugged", Network: "kuryr", IfName: "eth0", VifID: unpluggedVifID, HostIface: unpluggedTap},
+	} {
+		require.NoError(t, s.kpConfigurator.stateStore.Save(attachment))
+	}
+	pods := []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{UID: "uid-live"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+		{ObjectMeta: metav1.ObjectMeta{UID: "uid-unplugged"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+	}
+
+	require.NoError(t, s.kpConfigurator.reconcile(pods, []*v1alpha1.KuryrPort{liveKp, unpluggedKp}))
+	assert.Equal(t, []string{staleTap}, bridgeClient.deletedPorts)
+	assert.Equal(t, []string{staleTap}, *deleted)
+	assert.Equal(t, []string{unpluggedTap}, bridgeClient.createdPorts)
+	assert.ElementsMatch(t, []string{liveTap, "tap-foreign", unpluggedTap}, bridgeClient.portNames())
+	// The tap plugged again keeps the network of its attachment.
+	assert.Equal(t, "kuryr", bridgeClient.ports[len(bridgeClient.ports)-1].ExternalIDs[ovsExternalIDNetwork])
+	for _, containerID := range []string{"c-live", "c-unplugged"} {
+		_, found := s.kpConfigurator.ifaceStore.GetContainerInterface(containerID)
+		assert.True(t, found, "container %s not in the interface store", containerID)
+	}
+	attachment, err := s.kpConfigurator.stateStore.Get("c-stale", "eth0")
+	require.NoError(t, err)
+	assert.Nil(t, attachment)
+}
+
+func TestCmdStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		networkReady  bool
+		portListErr   ovsconfig.Error
+		expectErr     bool
+		expectedError cnipb.ErrorCode
+	}{
+		{name: "ready", networkReady: true},
+		{name: "network not ready", expectErr: true, expectedError: cnipb.ErrorCode_PLUGIN_NOT_AVAILABLE},
+		{
+			name:          "OVS bridge not reachable",
+			networkReady:  true,
+			portListErr:   ovsconfig.NewTransactionError(assert.AnError, true),
+			expectErr:     true,
+			expectedError: cnipb.ErrorCode_PLUGIN_NOT_AVAILABLE_LIMITED_CONNECTIVITY,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestCNIServer(t, &fakeOVSBridgeClient{portListErr: tc.portListErr})
+			networkReadyCh := make(chan struct{})
+			if tc.networkReady {
+				close(networkReadyCh)
+			}
+			s.networkReadyCh = networkReadyCh
+
+			resp, err := s.CmdStatus(context.Background(), newTestRequest("", ""))
+			require.NoError(t, err)
+			if tc.expectErr {
+				require.NotNil(t, resp.Error)
+				assert.Equal(t, tc.expectedError, resp.Error.Code)
+			} else {
+				assert.Nil(t, resp.Error)
+			}
+		})
+	}
+}
+
+func TestCmdGC(t *testing.T) {
+	deleted := fakeLinks(t)
+	bridgeClient := &fakeOVSBridgeClient{ports: []ovsconfig.OVSPortData{
+		newKuryrTapPort("tap1", "c1", "kuryr"),
+		newKuryrTapPort("tap2", "c2", "kuryr"),
+	}}
+	s := newTestCNIServer(t, bridgeClient)
+
+	// Without the valid attachments, nothing is collected.
+	resp, err := s.CmdGC(context.Background(), newTestRequest("", ""))
+	require.NoError(t, err)
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, cnipb.ErrorCode_INVALID_NETWORK_CONFIG, resp.Error.Code)
+	assert.Equal(t, []string{"tap1", "tap2"}, bridgeClient.portNames())
+
+	request := newTestRequest("", "")
+	request.CniArgs.NetworkConfiguration = []byte(`{"cniVersion":"1.1.0","name":"kuryr","type":"kuryr-cni",` +
+		`"cni.dev/valid-attachments":[{"containerID":"c1","ifname":"eth0"}]}`)
+	resp, err = s.CmdGC(context.Background(), request)
+	require.NoError(t, err)
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, []string{"tap1"}, bridgeClient.portNames())
+	assert.Equal(t, []string{"tap2"}, *deleted)
+}
+
+func TestValidateContainerConfig(t *testing.T) {
+	mac := "fa:16:3e:11:22:33"
+	attachment := &cnistate.Attachment{
+		ContainerID: "c1",
+		IfName:      "eth0",
+		VifID:       newVifID,
+		HostIface:   util.GenerateTapInterfaceName(newVifID),
+		CNIResult: []byte(`{"cniVersion":"0.4.0",` +
+			`"interfaces":[{"name":"` + util.GenerateTapInterfaceName(newVifID) + `","mac":"` + mac + `"},{"name":"eth0","mac":"` + mac + `","sandbox":"/proc/42/ns/net"}],` +
+			`"ips":[{"version":"4","interface":1,"address":"10.0.0.5/24"}]}`),
+	}
+	vif := newTestKuryrPort("uid-new", newVifID).Status.Vifs[0]
+	vif.Vif.MACAddress = mac
+	vif.Vif.Network.Subnets = []v1alpha1.Subnet{{Ips: []v1alpha1.IP{{IPAddress: "10.0.0.6"}}}}
+	prevResultIPs := func(cidr string) []*current.IPConfig {
+		ip, ipNet, _ := net.ParseCIDR(cidr)
+		ipNet.IP = ip
+		return []*current.IPConfig{{Version: "4", Address: *ipNet}}
+	}
+
+	for _, tc := range []struct {
+		name           string
+		expectedConfig *interfacestore.InterfaceConfig
+		mac            string
+		ips            []*current.IPConfig
+		expectedErr    bool
+	}{
+		{
+			name:           "matching attachment",
+			expectedConfig: attachmentContainerConfig(attachment),
+			mac:            mac,
+			ips:            prevResultIPs("10.0.0.5/24"),
+		},
+		{
+			name:           "IPs not matching the attachment",
+			expectedConfig: attachmentContainerConfig(attachment),
+			mac:            mac,
+			ips:            prevResultIPs("10.0.0.6/24"),
+			expectedErr:    true,
+		},
+		{
+			name:           "MAC not matching the attachment",
+			expectedConfig: attachmentContainerConfig(attachment),
+			mac:            "fa:16:3e:44:55:66",
+			ips:            prevResultIPs("10.0.0.5/24"),
+			expectedErr:    true,
+		},
+		{
+			name:           "matching VIF",
+			expectedConfig: vifContainerConfig("c1", &vif),
+			mac:            mac,
+			ips:            prevResultIPs("10.0.0.6/24"),
+		},
+		{
+			name:           "IPs not matching the VIF",
+			expectedConfig: vifContainerConfig("c1", &vif),
+			mac:            mac,
+			ips:            prevResultIPs("10.0.0.5/24"),
+			expectedErr:    true,
+		},
+		{
+			name: "no configuration known",
+			mac:  mac,
+			ips:  prevResultIPs("10.0.0.5/24"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestCNIServer(t, &fakeOVSBridgeClient{})
+			err := s.kpConfigurator.validateContainerConfig("c1", tc.mac, tc.ips, tc.expectedConfig)
+			if tc.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
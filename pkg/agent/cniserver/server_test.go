@@ -0,0 +1,159 @@
+package cniserver
+
+import (
+	"net"
+	"testing"
+
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/types/current"
+	"github.com/stretchr/testify/assert"
+)
+
+func parseCIDR(cidr string) net.IPNet {
+	ip, ipNet, _ := net.ParseCIDR(cidr)
+	ipNet.IP = ip
+	return *ipNet
+}
+
+func newRoutes(dsts ...string) []*cnitypes.Route {
+	var routes []*cnitypes.Route
+	for _, dst := range dsts {
+		routes = append(routes, &cnitypes.Route{Dst: parseCIDR(dst)})
+	}
+	return routes
+}
+
+func routeDsts(routes []*cnitypes.Route) []string {
+	var dsts []string
+	for _, route := range routes {
+		dsts = append(dsts, route.Dst.String())
+	}
+	return dsts
+}
+
+func newIPConfig(address string, intf *int) *current.IPConfig {
+	return &current.IPConfig{Version: "4", Address: parseCIDR(address), Interface: intf}
+}
+
+func TestRemoveChainedRoutes(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		routes         []*cnitypes.Route
+		prevRoutes     []*cnitypes.Route
+		expectedRoutes []string
+	}{
+		{
+			name:           "no previous routes",
+			routes:         newRoutes("0.0.0.0/0", "10.10.0.0/16"),
+			expectedRoutes: []string{"0.0.0.0/0", "10.10.0.0/16"},
+		},
+		{
+			name:           "default route of the previous plugin",
+			routes:         newRoutes("0.0.0.0/0", "10.10.0.0/16"),
+			prevRoutes:     newRoutes("0.0.0.0/0"),
+			expectedRoutes: []string{"10.10.0.0/16"},
+		},
+		{
+			name:       "all routes already routed",
+			routes:     newRoutes("0.0.0.0/0", "10.10.0.0/16"),
+			prevRoutes: newRoutes("10.10.0.0/16", "0.0.0.0/0"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result := &current.Result{Routes: tc.routes}
+			removeChainedRoutes(result, &current.Result{Routes: tc.prevRoutes})
+			assert.Equal(t, tc.expectedRoutes, routeDsts(result.Routes))
+		})
+	}
+}
+
+func TestMergeChainedResult(t *testing.T) {
+	prevResult := func() *current.Result {
+		return &current.Result{
+			CNIVersion: "0.4.0",
+			Interfaces: []*current.Interface{{Name: "eth0", Sandbox: "/var/run/netns/c1"}},
+			IPs:        []*current.IPConfig{newIPConfig("192.168.1.5/24", current.Int(0))},
+			Routes:     newRoutes("0.0.0.0/0"),
+			DNS:        cnitypes.DNS{Nameservers: []string{"10.96.0.10"}},
+		}
+	}
+	for _, tc := range []struct {
+		name                string
+		result              *current.Result
+		expectedIPs         []string
+		expectedInterfaces  []*int
+		expectedRoutes      []string
+		expectedNameservers []string
+	}{
+		{
+			name: "interface index of the IPs shifted",
+			result: &current.Result{
+				Interfaces: []*current.Interface{{Name: "tap1"}, {Name: "net1", Sandbox: "/var/run/netns/c1"}},
+				IPs:        []*current.IPConfig{newIPConfig("10.10.0.5/16", current.Int(1))},
+				Routes:     newRoutes("10.10.0.0/16"),
+			},
+			expectedIPs:         []string{"192.168.1.5/24", "10.10.0.5/16"},
+			expectedInterfaces:  []*int{current.Int(0), current.Int(2)},
+			expectedRoutes:      []string{"0.0.0.0/0", "10.10.0.0/16"},
+			expectedNameservers: []string{"10.96.0.10"},
+		},
+		{
+			name: "IP without interface",
+			result: &current.Result{
+				Interfaces: []*current.Interface{{Name: "tap1"}, {Name: "net1", Sandbox: "/var/run/netns/c1"}},
+				IPs:        []*current.IPConfig{newIPConfig("10.10.0.5/16", nil)},
+			},
+			expectedIPs:         []string{"192.168.1.5/24", "10.10.0.5/16"},
+			expectedInterfaces:  []*int{current.Int(0), nil},
+			expectedRoutes:      []string{"0.0.0.0/0"},
+			expectedNameservers: []string{"10.96.0.10"},
+		},
+		{
+			name: "DNS nameservers merged",
+			result: &current.Result{
+				Interfaces: []*current.Interface{{Name: "tap1"}, {Name: "net1", Sandbox: "/var/run/netns/c1"}},
+				DNS:        cnitypes.DNS{Nameservers: []string{"10.96.0.10", "8.8.8.8"}},
+			},
+			expectedIPs:         []string{"192.168.1.5/24"},
+			expectedInterfaces:  []*int{current.Int(0)},
+			expectedRoutes:      []string{"0.0.0.0/0"},
+			expectedNameservers: []string{"10.96.0.10", "8.8.8.8"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			prev := prevResult()
+			merged := mergeChainedResult(prev, tc.result)
+
+			assert.Equal(t, "0.4.0", merged.CNIVersion)
+			assert.Len(t, merged.Interfaces, len(prev.Interfaces)+len(tc.result.Interfaces))
+			var ips []string
+			var interfaces []*int
+			for _, ipc := range merged.IPs {
+				ips = append(ips, ipc.Address.String())
+				interfaces = append(interfaces, ipc.Interface)
+			}
+			assert.Equal(t, tc.expectedIPs, ips)
+			assert.Equal(t, tc.expectedInterfaces, interfaces)
+			assert.Equal(t, tc.expectedRoutes, routeDsts(merged.Routes))
+			assert.Equal(t, tc.expectedNameservers, merged.DNS.Nameservers)
+			// The result of the previous plugins is left unchanged.
+			assert.Equal(t, prevResult(), prev)
+		})
+	}
+}
+
+// TestChainedResultDefaultRoute checks that the default route of the previous plugin is kept once
+// when kuryr also has one.
+func TestChainedResultDefaultRoute(t *testing.T) {
+	prevResult := &current.Result{
+		Interfaces: []*current.Interface{{Name: "eth0", Sandbox: "/var/run/netns/c1"}},
+		Routes:     newRoutes("0.0.0.0/0"),
+	}
+	result := &current.Result{
+		Interfaces: []*current.Interface{{Name: "tap1"}, {Name: "net1", Sandbox: "/var/run/netns/c1"}},
+		Routes:     newRoutes("0.0.0.0/0", "10.10.0.0/16"),
+	}
+	removeChainedRoutes(result, prevResult)
+	merged := mergeChainedResult(prevResult, result)
+	assert.Equal(t, []string{"0.0.0.0/0", "10.10.0.0/16"}, routeDsts(merged.Routes))
+}
@@ -0,0 +1,94 @@
+// +build linux
+
+package cniserver
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+)
+
+const (
+	sysBusPCIDevices = "/sys/bus/pci/devices"
+	// vfAliasPrefix starts the alias of the VFs moved to a container netns, the alias is
+	// "kuryr-vf/<PCI address>/<host name of the VF>".
+	vfAliasPrefix = "kuryr-vf/"
+)
+
+func vfAlias(pciAddress, vfName string) string {
+	return vfAliasPrefix + pciAddress + "/" + vfName
+}
+
+// parseVFAlias returns the PCI address and the host name of the VF with the alias, false is
+// returned if the alias was not set by vfAlias.
+func parseVFAlias(alias string) (string, string, bool) {
+	if !strings.HasPrefix(alias, vfAliasPrefix) {
+		return "", "", false
+	}
+	parts := strings.Split(strings.TrimPrefix(alias, vfAliasPrefix), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// getPCINetDevice returns the name of the network device of the PCI device in the host netns.
+func getPCINetDevice(pciAddress string) (string, error) {
+	files, err := ioutil.ReadDir(filepath.Join(sysBusPCIDevices, pciAddress, "net"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("no network device found for PCI device %s in the host netns", pciAddress)
+		}
+		return "", fmt.Errorf("failed to read network devices of PCI device %s: %v", pciAddress, err)
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no network device found for PCI device %s in the host netns", pciAddress)
+	}
+	return files[0].Name(), nil
+}
+
+// getVFNetDevice returns the name of the network device of the VF in the host netns.
+func getVFNetDevice(pciAddress string) (string, error) {
+	if _, err := os.Stat(filepath.Join(sysBusPCIDevices, pciAddress, "physfn")); err != nil {
+		return "", fmt.Errorf("PCI device %s is not a SR-IOV VF: %v", pciAddress, err)
+	}
+	return getPCINetDevice(pciAddress)
+}
+
+// getVFPF returns the link of the PF of the VF and the index of the VF on the PF.
+func getVFPF(pciAddress string) (netlink.Link, int, error) {
+	pfPath, err := filepath.EvalSymlinks(filepath.Join(sysBusPCIDevices, pciAddress, "physfn"))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find the PF of VF %s: %v", pciAddress, err)
+	}
+	pfAddress := filepath.Base(pfPath)
+	pfName, err := getPCINetDevice(pfAddress)
+	if err != nil {
+		return nil, 0, err
+	}
+	pfLink, err := netlink.LinkByName(pfName)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to lookup PF device %s: %v", pfName, err)
+	}
+	virtfns, err := filepath.Glob(filepath.Join(sysBusPCIDevices, pfAddress, "virtfn*"))
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, virtfn := range virtfns {
+		vfPath, err := filepath.EvalSymlinks(virtfn)
+		if err != nil || filepath.Base(vfPath) != pciAddress {
+			continue
+		}
+		vfIndex, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(virtfn), "virtfn"))
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to parse the index of VF %s: %v", pciAddress, err)
+		}
+		return pfLink, vfIndex, nil
+	}
+	return nil, 0, fmt.Errorf("VF %s not found on PF %s", pciAddress, pfName)
+}
@@ -0,0 +1,171 @@
+// Package cnistate persists the attachments made by the CNI server, so that the agent still knows
+// what it plugged after a restart, and DEL and CHECK work once the KuryrPort is gone.
+package cnistate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"k8s.io/klog"
+)
+
+// DefaultDir is the directory of the store on the Node.
+const DefaultDir = "/var/lib/kuryr/cni"
+
+const fileSuffix = ".json"
+
+// Attachment is an interface of a container attached to the network by the CNI server.
+type Attachment struct {
+	ContainerID  string `json:"containerID"`
+	Netns        string `json:"netns"`
+	IfName       string `json:"ifName"`
+	PodName      string `json:"podName"`
+	PodNamespace string `json:"podNamespace"`
+	// Network is the name of the CNI network of the attachment, the GC of a network only removes
+	// its attachments.
+	Network string `json:"network,omitempty"`
+	// VifID is the ID of the Neutron port of the interface.
+	VifID string `json:"vifID"`
+	MTU   int    `json:"mtu,omitempty"`
+	// HostIface is the tap of the interface, it is empty for a VF.
+	HostIface string `json:"hostIface,omitempty"`
+	// PCISlot is the PCI address of the VF of a direct port.
+	PCISlot   string          `json:"pciSlot,omitempty"`
+	CNIResult json.RawMessage `json:"cniResult,omitempty"`
+}
+
+// Store keeps one JSON file per attachment in a directory. A file is replaced atomically, so that a
+// crash of the agent leaves either the previous or the new attachment.
+type Store struct {
+	dir   string
+	mutex sync.Mutex
+}
+
+// NewStore returns the store of the directory, which is created if needed.
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create the CNI state directory %s: %v", dir, err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+func (s *Store) path(containerID, ifName string) string {
+	return filepath.Join(s.dir, containerID+"_"+ifName+fileSuffix)
+}
+
+// Save records the attachment, replacing the previous one of the same container interface.
+func (s *Store) Save(attachment *Attachment) error {
+	data, err := json.Marshal(attachment)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tmpFile, err := ioutil.TempFile(s.dir, ".tmp-")
+	if err != nil {
+		return fmt.Errorf("failed to create the state of container %s: %v", attachment.ContainerID, err)
+	}
+	defer os.Remove(tmpFile.Name())
+	_, err = tmpFile.Write(data)
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), s.path(attachment.ContainerID, attachment.IfName))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save the state of container %s: %v", attachment.ContainerID, err)
+	}
+	return s.syncDir()
+}
+
+// syncDir makes the renames of the directory durable.
+func (s *Store) syncDir() error {
+	dir, err := os.Open(s.dir)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// Get returns the attachment of the container interface, nil is returned if it is not found.
+func (s *Store) Get(containerID, ifName string) (*Attachment, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.read(s.path(containerID, ifName))
+}
+
+func (s *Store) read(path string) (*Attachment, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	attachment := &Attachment{}
+	if err := json.Unmarshal(data, attachment); err != nil {
+		return nil, fmt.Errorf("failed to parse the state file %s: %v", path, err)
+	}
+	return attachment, nil
+}
+
+// Delete forgets the attachment of the container interface, nothing is done if it is not found.
+func (s *Store) Delete(containerID, ifName string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := os.Remove(s.path(containerID, ifName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete the state of container %s: %v", containerID, err)
+	}
+	return nil
+}
+
+// List returns all the attachments. The files which cannot be parsed are skipped, they are
+// replaced by the next Save of their container interface.
+func (s *Store) List() ([]*Attachment, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the CNI state directory %s: %v", s.dir, err)
+	}
+	var attachments []*Attachment
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") || !strings.HasSuffix(file.Name(), fileSuffix) {
+			continue
+		}
+		attachment, err := s.read(filepath.Join(s.dir, file.Name()))
+		if err != nil {
+			klog.Warningf("Skipping CNI state file %s: %v", file.Name(), err)
+			continue
+		}
+		if attachment != nil {
+			attachments = append(attachments, attachment)
+		}
+	}
+	return attachments, nil
+}
+
+// GetByContainer returns the attachments of the container.
+func (s *Store) GetByContainer(containerID string) ([]*Attachment, error) {
+	attachments, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	var containerAttachments []*Attachment
+	for _, attachment := range attachments {
+		if attachment.ContainerID == containerID {
+			containerAttachments = append(containerAttachments, attachment)
+		}
+	}
+	return containerAttachments, nil
+}
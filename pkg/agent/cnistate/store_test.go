@@ -0,0 +1,61 @@
+package cnistate
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuryr-cni-state")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store, err := NewStore(filepath.Join(dir, "cni"))
+	require.NoError(t, err)
+
+	attachment, err := store.Get("c1", "eth0")
+	require.NoError(t, err)
+	assert.Nil(t, attachment)
+
+	eth0 := &Attachment{
+		ContainerID: "c1",
+		Netns:       "/proc/42/ns/net",
+		IfName:      "eth0",
+		VifID:       "port-1",
+		MTU:         1450,
+		HostIface:   "tapport-1",
+		CNIResult:   json.RawMessage(`{"cniVersion":"0.4.0"}`),
+	}
+	eth1 := &Attachment{ContainerID: "c1", IfName: "eth1", VifID: "port-2", PCISlot: "0000:05:00.1"}
+	other := &Attachment{ContainerID: "c2", IfName: "eth0", VifID: "port-3"}
+	for _, a := range []*Attachment{eth0, eth1, other} {
+		require.NoError(t, store.Save(a))
+	}
+
+	attachment, err = store.Get("c1", "eth0")
+	require.NoError(t, err)
+	assert.Equal(t, eth0, attachment)
+
+	// A file which cannot be parsed is skipped.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "cni", "c3_eth0.json"), []byte("{"), 0600))
+	attachments, err := store.List()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*Attachment{eth0, eth1, other}, attachments)
+
+	attachments, err = store.GetByContainer("c1")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*Attachment{eth0, eth1}, attachments)
+
+	eth0.MTU = 1500
+	require.NoError(t, store.Save(eth0))
+	require.NoError(t, store.Delete("c1", "eth1"))
+	require.NoError(t, store.Delete("c1", "eth1"))
+	attachments, err = store.GetByContainer("c1")
+	require.NoError(t, err)
+	assert.Equal(t, []*Attachment{eth0}, attachments)
+}
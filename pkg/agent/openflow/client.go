@@ -43,6 +43,22 @@ type Client interface {
 	// installed.
 	Initialize(roundInfo types.RoundInfo, config *config.NodeConfig, encapMode config.TrafficEncapModeType) (<-chan struct{}, error)
 
+	// InitializeServicePipeline sets up only the Service pipeline of kuryrProxy on an OVS bridge
+	// managed by the Neutron OVS agent, it is called instead of Initialize. The Service tables are
+	// out of the range used by Neutron and no table-miss flow is installed in the tables of
+	// Neutron, the IP packets of the Pods enter the Service pipeline from the flows installed by
+	// InstallPodServiceFlows and go back to the classifier table once load-balanced. Only the
+	// flows of the current round number with the KuryrService cookie category are deleted before
+	// the new ones are installed. The returned channel is used like the one of Initialize.
+	InitializeServicePipeline(roundInfo types.RoundInfo, config *config.NodeConfig) (<-chan struct{}, error)
+
+	// InstallPodServiceFlows installs the flows of the classifier table sending the IP packets
+	// from the tap of a Pod, and to the IPs of the Pod, to the Service pipeline of kuryrProxy.
+	InstallPodServiceFlows(interfaceName string, podInterfaceIPs []net.IP, ofPort uint32) error
+
+	// UninstallPodServiceFlows removes the flows installed by InstallPodServiceFlows.
+	UninstallPodServiceFlows(interfaceName string) error
+
 	// InstallGatewayFlows sets up flows related to an OVS gateway port, the gateway must exist.
 	InstallGatewayFlows() error
 
@@ -90,7 +106,7 @@ type Client interface {
 
 	// InstallServiceGroup installs a group for Service LB. Each endpoint
 	// is a bucket of the group. For now, each bucket has the same weight.
-	//InstallServiceGroup(groupID binding.GroupIDType, withSessionAffinity bool, endpoints []proxy.Endpoint) error
+	InstallServiceGroup(groupID binding.GroupIDType, withSessionAffinity bool, endpoints []proxy.Endpoint) error
 	// UninstallServiceGroup removes the group and its buckets that are
 	// installed by InstallServiceGroup.
 	UninstallServiceGroup(groupID binding.GroupIDType) error
@@ -98,10 +114,10 @@ type Client interface {
 	// InstallEndpointFlows installs flows for accessing Endpoints.
 	// If an Endpoint is on the current Node, then flows for hairpin and endpoint
 	// L2 forwarding should also be installed.
-	//InstallEndpointFlows(protocol binding.Protocol, endpoints []proxy.Endpoint) error
+	InstallEndpointFlows(protocol binding.Protocol, endpoints []proxy.Endpoint) error
 	// UninstallEndpointFlows removes flows of the Endpoint installed by
 	// InstallEndpointFlows.
-	//UninstallEndpointFlows(protocol binding.Protocol, endpoint proxy.Endpoint) error
+	UninstallEndpointFlows(protocol binding.Protocol, endpoint proxy.Endpoint) error
 
 	// InstallServiceFlows installs flows for accessing Service with clusterIP.
 	// It installs the flow that uses the group/bucket to do service LB. If the
@@ -207,7 +223,7 @@ type Client interface {
 
 	// GetServiceFlowKeys returns the keys (match strings) of the cached
 	// flows for a Service (port) and its endpoints.
-	//GetServiceFlowKeys(svcIP net.IP, svcPort uint16, protocol binding.Protocol, endpoints []proxy.Endpoint) []string
+	GetServiceFlowKeys(svcIP net.IP, svcPort uint16, protocol binding.Protocol, endpoints []proxy.Endpoint) []string
 
 	// GetNetworkPolicyFlowKeys returns the keys (match strings) of the cached
 	// flows for a NetworkPolicy. Flows are grouped by policy rules, and duplicated
@@ -487,7 +503,8 @@ func (c *client) InstallEndpointFlows(protocol binding.Protocol, endpoints []pro
 		portVal := portToUint16(endpointPort)
 		cacheKey := generateEndpointFlowCacheKey(endpoint.IP(), endpointPort, protocol)
 		flows = append(flows, c.endpointDNATFlow(endpointIP, portVal, protocol))
-		if endpoint.GetIsLocal() {
+		// The hairpin connections of kuryrProxy are routed back by the Neutron router.
+		if endpoint.GetIsLocal() && !c.serviceOnly {
 			flows = append(flows, c.hairpinSNATFlow(endpointIP))
 		}
 		if err := c.addFlows(c.serviceFlowCache, cacheKey, flows); err != nil {
@@ -732,8 +749,11 @@ func (c *client) ReplayFlows() {
 	c.replayMutex.Lock()
 	defer c.replayMutex.Unlock()
 
-	if err := c.initialize(); err != nil {
-		klog.Errorf("Error during flow replay: %v", err)
+	// The flows of the Service pipeline of kuryrProxy are replayed with the fixed flows below.
+	if !c.serviceOnly {
+		if err := c.initialize(); err != nil {
+			klog.Errorf("Error during flow replay: %v", err)
+		}
 	}
 
 	addFixedFlows := func(flows []binding.Flow) {
@@ -784,6 +804,10 @@ func (c *client) ReplayFlows() {
 
 func (c *client) deleteFlowsByRoundNum(roundNum uint64) error {
 	cookieID, cookieMask := cookie.CookieMaskForRound(roundNum)
+	if c.serviceOnly {
+		// The other flows of the bridge are installed by the Neutron OVS agent.
+		cookieID, cookieMask = cookie.CookieMaskForRoundAndCategory(roundNum, cookie.KuryrService)
+	}
 	return c.bridge.DeleteFlowsByCookie(cookieID, cookieMask)
 }
 
@@ -37,6 +37,9 @@ const (
 	Service
 	Policy
 	SNAT
+	// KuryrService is the category of the flows installed by kuryrProxy on the
+	// bridge managed by the Neutron OVS agent.
+	KuryrService
 )
 
 func (c Category) String() string {
@@ -55,6 +58,8 @@ func (c Category) String() string {
 		return "Policy"
 	case SNAT:
 		return "SNAT"
+	case KuryrService:
+		return "KuryrService"
 	default:
 		return "Invalid"
 	}
@@ -81,6 +86,13 @@ func CookieMaskForRound(round uint64) (uint64, uint64) {
 	return round << (64 - BitwidthRound), RoundMask
 }
 
+// CookieMaskForRoundAndCategory returns a cookie and mask value that can be
+// used to select the flows of the provided category belonging to the provided
+// round.
+func CookieMaskForRoundAndCategory(round uint64, cat Category) (uint64, uint64) {
+	return newID(round, cat, 0).Raw(), RoundMask | CategoryMask
+}
+
 // Raw returns the unit64 type value of the ID.
 func (i ID) Raw() uint64 {
 	return uint64(i)
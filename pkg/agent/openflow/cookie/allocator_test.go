@@ -74,3 +74,19 @@ func TestConcurrentAllocate(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestCookieMaskForRoundAndCategory(t *testing.T) {
+	a := NewAllocator(3)
+	cookieID, cookieMask := CookieMaskForRoundAndCategory(3, KuryrService)
+	for _, tc := range []struct {
+		id      ID
+		matched bool
+	}{
+		{a.Request(KuryrService), true},
+		{a.RequestWithObjectID(KuryrService, 42), true},
+		{a.Request(Service), false},
+		{NewAllocator(4).Request(KuryrService), false},
+	} {
+		assert.Equal(t, tc.matched, tc.id.Raw()&cookieMask == cookieID, tc.id.String())
+	}
+}
@@ -0,0 +1,165 @@
+package openflow
+
+import (
+	"fmt"
+	"net"
+
+	"projectkuryr/kuryr/pkg/agent/config"
+	"projectkuryr/kuryr/pkg/agent/openflow/cookie"
+	"projectkuryr/kuryr/pkg/agent/types"
+	binding "projectkuryr/kuryr/pkg/ovs/openflow"
+)
+
+// The Service pipeline of kuryrProxy shares the OVS bridge with the Neutron OVS agent, which owns
+// the classifier table and the tables below kuryrServiceConntrackTable. The IP packets of the
+// Pods are sent to the Service pipeline by the flows of the classifier table matching their tap
+// and their IPs, they are load-balanced there and resubmitted to the classifier table with the
+// kuryrServiceMark, where they are processed by the Neutron pipeline.
+
+// generateServicePipeline replaces the pipeline of the client with the Service pipeline of
+// kuryrProxy. The Service tables are keyed by the ones of the Antrea pipeline, so that the
+// flows and the groups of the Services are generated by the same functions.
+func (c *client) generateServicePipeline() {
+	bridge := c.bridge
+	c.pipeline = map[binding.TableIDType]binding.Table{
+		ClassifierTable:         bridge.CreateTable(ClassifierTable, kuryrServiceConntrackTable, binding.TableMissActionNone),
+		conntrackTable:          bridge.CreateTable(kuryrServiceConntrackTable, kuryrServiceConntrackStateTable, binding.TableMissActionNone),
+		conntrackStateTable:     bridge.CreateTable(kuryrServiceConntrackStateTable, kuryrServiceReturnTable, binding.TableMissActionNone),
+		sessionAffinityTable:    bridge.CreateTable(kuryrSessionAffinityTable, binding.LastTableID, binding.TableMissActionNone),
+		serviceLBTable:          bridge.CreateTable(kuryrServiceLBTable, kuryrEndpointDNATTable, binding.TableMissActionNext),
+		endpointDNATTable:       bridge.CreateTable(kuryrEndpointDNATTable, kuryrServiceReturnTable, binding.TableMissActionNext),
+		kuryrServiceReturnTable: bridge.CreateTable(kuryrServiceReturnTable, binding.LastTableID, binding.TableMissActionNone),
+	}
+}
+
+// servicePipelineFlows generates the fixed flows of the Service pipeline of kuryrProxy:
+//  1. Send the packets to ct_zone(0xfff0) with NAT, which translates the packets of the
+//     connections already load-balanced, in both directions.
+//  2. Let the packets of the connections already load-balanced go back to the classifier table.
+//  3. Let the other packets go to the sessionAffinityTable first and then the serviceLBTable
+//     for the Endpoint selection. The packets to no Service go back to the classifier table
+//     from the table-miss flows.
+func (c *client) servicePipelineFlows() []binding.Flow {
+	connectionTrackTable := c.pipeline[conntrackTable]
+	connectionTrackStateTable := c.pipeline[conntrackStateTable]
+	lbTable := c.pipeline[serviceLBTable]
+	epDNATTable := c.pipeline[endpointDNATTable]
+	returnTable := c.pipeline[kuryrServiceReturnTable]
+	flows := []binding.Flow{
+		connectionTrackStateTable.BuildFlow(priorityMiss).
+			Action().ResubmitToTable(c.pipeline[sessionAffinityTable].GetID()).
+			Action().ResubmitToTable(lbTable.GetID()).
+			Cookie(c.cookieAllocator.Request(c.serviceCategory).Raw()).
+			Done(),
+		c.serviceNeedLBFlow(),
+		lbTable.BuildFlow(priorityMiss).
+			Action().GotoTable(lbTable.GetNext()).
+			Cookie(c.cookieAllocator.Request(c.serviceCategory).Raw()).
+			Done(),
+		c.sessionAffinityReselectFlow(),
+		epDNATTable.BuildFlow(priorityMiss).
+			Action().GotoTable(epDNATTable.GetNext()).
+			Cookie(c.cookieAllocator.Request(c.serviceCategory).Raw()).
+			Done(),
+		returnTable.BuildFlow(priorityMiss).
+			Action().ResubmitToTable(ClassifierTable).
+			Cookie(c.cookieAllocator.Request(c.serviceCategory).Raw()).
+			Done(),
+	}
+	for _, proto := range c.ipProtocols {
+		ctZone := CtZone
+		if proto == binding.ProtocolIPv6 {
+			ctZone = CtZoneV6
+		}
+		flows = append(flows,
+			connectionTrackTable.BuildFlow(priorityNormal).MatchProtocol(proto).
+				Action().CT(false, connectionTrackTable.GetNext(), ctZone).NAT().CTDone().
+				Cookie(c.cookieAllocator.Request(c.serviceCategory).Raw()).
+				Done(),
+			connectionTrackStateTable.BuildFlow(priorityNormal).MatchProtocol(proto).
+				MatchCTMark(ServiceCTMark, nil).
+				MatchCTStateNew(false).MatchCTStateTrk(true).
+				Action().GotoTable(connectionTrackStateTable.GetNext()).
+				Cookie(c.cookieAllocator.Request(c.serviceCategory).Raw()).
+				Done(),
+		)
+	}
+	return flows
+}
+
+// podServiceClassifierFlows generates the flows sending the IP packets from the tap of a Pod,
+// and to the IPs of the Pod for the replies of the Endpoints, to the Service pipeline of
+// kuryrProxy. The packets coming back from the Service pipeline have the kuryrServiceMark and
+// skip these flows.
+func (c *client) podServiceClassifierFlows(podInterfaceIPs []net.IP, ofPort uint32) []binding.Flow {
+	classifierTable := c.pipeline[ClassifierTable]
+	var flows []binding.Flow
+	for _, proto := range c.ipProtocols {
+		flows = append(flows, classifierTable.BuildFlow(priorityHigh).MatchProtocol(proto).
+			MatchInPort(ofPort).
+			MatchRegRange(int(marksReg), 0, kuryrServiceMarkRange).
+			Action().LoadRegRange(int(marksReg), kuryrServiceMark, kuryrServiceMarkRange).
+			Action().GotoTable(classifierTable.GetNext()).
+			Cookie(c.cookieAllocator.Request(c.serviceCategory).Raw()).
+			Done())
+	}
+	for _, ip := range podInterfaceIPs {
+		flows = append(flows, classifierTable.BuildFlow(priorityHigh).MatchProtocol(getIPProtocol(ip)).
+			MatchDstIP(ip).
+			MatchRegRange(int(marksReg), 0, kuryrServiceMarkRange).
+			Action().LoadRegRange(int(marksReg), kuryrServiceMark, kuryrServiceMarkRange).
+			Action().GotoTable(classifierTable.GetNext()).
+			Cookie(c.cookieAllocator.Request(c.serviceCategory).Raw()).
+			Done())
+	}
+	return flows
+}
+
+func (c *client) InitializeServicePipeline(roundInfo types.RoundInfo, nodeConfig *config.NodeConfig) (<-chan struct{}, error) {
+	c.nodeConfig = nodeConfig
+	c.serviceOnly = true
+	c.serviceCategory = cookie.KuryrService
+	if c.IsIPv4Enabled() {
+		c.ipProtocols = append(c.ipProtocols, binding.ProtocolIP)
+	}
+	if c.IsIPv6Enabled() {
+		c.ipProtocols = append(c.ipProtocols, binding.ProtocolIPv6)
+	}
+	// The tables must be created before the bridge is connected.
+	c.generateServicePipeline()
+
+	connCh := make(chan struct{})
+	if err := c.bridge.Connect(maxRetryForOFSwitch, connCh); err != nil {
+		return nil, err
+	}
+	// Ignore first notification, it is not a "reconnection".
+	<-connCh
+
+	c.roundInfo = roundInfo
+	c.cookieAllocator = cookie.NewAllocator(roundInfo.RoundNum)
+	if err := c.deleteFlowsByRoundNum(roundInfo.RoundNum); err != nil {
+		return nil, fmt.Errorf("error when deleting existing flows for current round number: %v", err)
+	}
+	flows := c.servicePipelineFlows()
+	if err := c.ofEntryOperations.AddAll(flows); err != nil {
+		return nil, fmt.Errorf("failed to install Service pipeline flows: %v", err)
+	}
+	c.defaultServiceFlows = flows
+	return connCh, nil
+}
+
+func (c *client) InstallPodServiceFlows(interfaceName string, podInterfaceIPs []net.IP, ofPort uint32) error {
+	c.replayMutex.RLock()
+	defer c.replayMutex.RUnlock()
+	// The tap may have been plugged again with another ofPort.
+	if err := c.deleteFlows(c.podFlowCache, interfaceName); err != nil {
+		return err
+	}
+	return c.addFlows(c.podFlowCache, interfaceName, c.podServiceClassifierFlows(podInterfaceIPs, ofPort))
+}
+
+func (c *client) UninstallPodServiceFlows(interfaceName string) error {
+	c.replayMutex.RLock()
+	defer c.replayMutex.RUnlock()
+	return c.deleteFlows(c.podFlowCache, interfaceName)
+}
@@ -0,0 +1,120 @@
+package openflow
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"projectkuryr/kuryr/pkg/agent/config"
+	"projectkuryr/kuryr/pkg/agent/openflow/cookie"
+	binding "projectkuryr/kuryr/pkg/ovs/openflow"
+	"projectkuryr/kuryr/pkg/ovs/ovsconfig"
+	k8sproxy "projectkuryr/kuryr/pkg/proxy"
+)
+
+// fakeOFEntryOperations records the flows instead of sending them to OVS.
+type fakeOFEntryOperations struct {
+	OFEntryOperations
+	flows map[string]binding.Flow
+}
+
+func (f *fakeOFEntryOperations) AddAll(flows []binding.Flow) error {
+	for _, flow := range flows {
+		f.flows[flow.MatchString()] = flow
+	}
+	return nil
+}
+
+func (f *fakeOFEntryOperations) DeleteAll(flows []binding.Flow) error {
+	for _, flow := range flows {
+		delete(f.flows, flow.MatchString())
+	}
+	return nil
+}
+
+// newTestServiceClient returns a client with the Service pipeline of kuryrProxy, as set up by
+// InitializeServicePipeline without the connection to the bridge.
+func newTestServiceClient(nodeConfig *config.NodeConfig) (*client, *fakeOFEntryOperations) {
+	c := NewClient("br-int", "", ovsconfig.OVSDatapathSystem, false, false, false).(*client)
+	ofEntryOperations := &fakeOFEntryOperations{flows: map[string]binding.Flow{}}
+	c.ofEntryOperations = ofEntryOperations
+	c.nodeConfig = nodeConfig
+	c.serviceOnly = true
+	c.serviceCategory = cookie.KuryrService
+	if c.IsIPv4Enabled() {
+		c.ipProtocols = append(c.ipProtocols, binding.ProtocolIP)
+	}
+	if c.IsIPv6Enabled() {
+		c.ipProtocols = append(c.ipProtocols, binding.ProtocolIPv6)
+	}
+	c.generateServicePipeline()
+	c.cookieAllocator = cookie.NewAllocator(1)
+	return c, ofEntryOperations
+}
+
+func flowTable(t *testing.T, flow binding.Flow) string {
+	match := flow.MatchString()
+	require.True(t, strings.HasPrefix(match, "table="), match)
+	return strings.SplitN(strings.TrimPrefix(match, "table="), ",", 2)[0]
+}
+
+func TestServicePipelineFlows(t *testing.T) {
+	_, podCIDR, _ := net.ParseCIDR("10.0.0.0/16")
+	_, podCIDRv6, _ := net.ParseCIDR("fd00::/64")
+	c, _ := newTestServiceClient(&config.NodeConfig{PodIPv4CIDR: podCIDR, PodIPv6CIDR: podCIDRv6})
+
+	flows := c.servicePipelineFlows()
+	// The table-miss and reselect flows, then the conntrack and bypass flows per IP family.
+	assert.Len(t, flows, 6+2*2)
+	for _, flow := range flows {
+		// No flow is installed in the tables of the Neutron pipeline.
+		assert.Contains(t, []string{"200", "201", "202", "203", "204", "205"}, flowTable(t, flow), flow.MatchString())
+		assert.False(t, flow.IsDropFlow(), flow.MatchString())
+	}
+}
+
+func TestPodServiceFlows(t *testing.T) {
+	_, podCIDR, _ := net.ParseCIDR("10.0.0.0/16")
+	c, ofEntryOperations := newTestServiceClient(&config.NodeConfig{PodIPv4CIDR: podCIDR})
+	podIP := net.ParseIP("10.0.0.5")
+
+	require.NoError(t, c.InstallPodServiceFlows("tap1", []net.IP{podIP}, 3))
+	var matches []string
+	for match, flow := range ofEntryOperations.flows {
+		assert.Equal(t, "0", flowTable(t, flow))
+		assert.Equal(t, priorityHigh, flow.FlowPriority())
+		assert.Contains(t, match, "reg0[17..17]=0x0")
+		matches = append(matches, match)
+	}
+	assert.ElementsMatch(t, []string{
+		"table=0,ip,in_port=3,reg0[17..17]=0x0",
+		"table=0,ip,nw_dst=10.0.0.5,reg0[17..17]=0x0",
+	}, matches)
+
+	// The flows of the tap plugged again replace the previous ones.
+	require.NoError(t, c.InstallPodServiceFlows("tap1", []net.IP{podIP}, 4))
+	assert.Contains(t, ofEntryOperations.flows, "table=0,ip,in_port=4,reg0[17..17]=0x0")
+	assert.NotContains(t, ofEntryOperations.flows, "table=0,ip,in_port=3,reg0[17..17]=0x0")
+
+	require.NoError(t, c.UninstallPodServiceFlows("tap1"))
+	assert.Empty(t, ofEntryOperations.flows)
+}
+
+func TestEndpointFlowsInServicePipeline(t *testing.T) {
+	_, podCIDR, _ := net.ParseCIDR("10.0.0.0/16")
+	c, ofEntryOperations := newTestServiceClient(&config.NodeConfig{PodIPv4CIDR: podCIDR})
+	svcIP := net.ParseIP("10.96.0.10")
+	endpoint := k8sproxy.NewBaseEndpointInfo("10.0.0.5", 8080, true, nil)
+
+	require.NoError(t, c.InstallEndpointFlows(binding.ProtocolTCP, []k8sproxy.Endpoint{endpoint}))
+	// The DNAT flow of the local Endpoint, without hairpin flow.
+	require.Len(t, ofEntryOperations.flows, 1)
+	for _, flow := range ofEntryOperations.flows {
+		assert.Equal(t, "204", flowTable(t, flow))
+	}
+	assert.Len(t, c.GetServiceFlowKeys(svcIP, 80, binding.ProtocolTCP, []k8sproxy.Endpoint{endpoint}), 1)
+	assert.Equal(t, "203", flowTable(t, c.serviceLearnFlow(1, svcIP, 80, binding.ProtocolTCP, 300)))
+}
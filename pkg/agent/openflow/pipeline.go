@@ -68,6 +68,15 @@ const (
 	hairpinSNATTable             binding.TableIDType = 106
 	L2ForwardingOutTable         binding.TableIDType = 110
 
+	// Tables of the Service pipeline installed by kuryrProxy on the bridge
+	// managed by the Neutron OVS agent, out of the range used by Neutron.
+	kuryrServiceConntrackTable      binding.TableIDType = 200
+	kuryrServiceConntrackStateTable binding.TableIDType = 201
+	kuryrSessionAffinityTable       binding.TableIDType = 202
+	kuryrServiceLBTable             binding.TableIDType = 203
+	kuryrEndpointDNATTable          binding.TableIDType = 204
+	kuryrServiceReturnTable         binding.TableIDType = 205
+
 	// Flow priority level
 	priorityHigh            = uint16(210)
 	priorityNormal          = uint16(200)
@@ -130,6 +139,12 @@ var (
 		{conntrackCommitTable, "ConntrackCommit"},
 		{hairpinSNATTable, "HairpinSNATTable"},
 		{L2ForwardingOutTable, "Output"},
+		{kuryrServiceConntrackTable, "KuryrServiceConntrackZone"},
+		{kuryrServiceConntrackStateTable, "KuryrServiceConntrackState"},
+		{kuryrSessionAffinityTable, "KuryrSessionAffinity"},
+		{kuryrServiceLBTable, "KuryrServiceLB"},
+		{kuryrEndpointDNATTable, "KuryrEndpointDNAT"},
+		{kuryrServiceReturnTable, "KuryrServiceReturn"},
 	}
 )
 
@@ -232,6 +247,9 @@ const (
 	macRewriteMark = 0b1
 	// cnpDenyMark indicates the packet is denied(Drop/Reject).
 	cnpDenyMark = 0b1
+	// kuryrServiceMark indicates the packet went through the Service
+	// pipeline of kuryrProxy and is back to the Neutron pipeline.
+	kuryrServiceMark = 0b1
 
 	// gatewayCTMark is used to to mark connections initiated through the host gateway interface
 	// (i.e. for which the first packet of the connection was received through the gateway).
@@ -274,6 +292,10 @@ var (
 	// number of an interface.
 	// is found or not. Its value is 0x1 if yes.
 	ofPortMarkRange = binding.Range{16, 16}
+	// kuryrServiceMarkRange takes the 17th bit of register marksReg to indicate
+	// if the packet went through the Service pipeline of kuryrProxy. Its value
+	// is 0x1 if yes.
+	kuryrServiceMarkRange = binding.Range{17, 17}
 	// ofPortRegRange takes a 32-bit range of register PortCacheReg to cache the ofPort
 	// number of the interface.
 	ofPortRegRange = binding.Range{0, 31}
@@ -389,6 +411,11 @@ type client struct {
 	ipProtocols []binding.Protocol
 	// ovsctlClient is the interface for executing OVS "ovs-ofctl" and "ovs-appctl" commands.
 	ovsctlClient ovsctl.OVSCtlClient
+	// serviceOnly is set when only the Service pipeline of kuryrProxy is
+	// installed, see InitializeServicePipeline.
+	serviceOnly bool
+	// serviceCategory is the cookie category of the Service flows.
+	serviceCategory cookie.Category
 }
 
 func (c *client) GetTunnelVirtualMAC() net.HardwareAddr {
@@ -1202,8 +1229,8 @@ func (c *client) sessionAffinityReselectFlow() binding.Flow {
 	return c.pipeline[endpointDNATTable].BuildFlow(priorityLow).
 		MatchRegRange(int(serviceLearnReg), marksRegServiceSelected, serviceLearnRegRange).
 		Action().LoadRegRange(int(serviceLearnReg), marksRegServiceNeedLB, serviceLearnRegRange).
-		Action().ResubmitToTable(serviceLBTable).
-		Cookie(c.cookieAllocator.Request(cookie.Service).Raw()).
+		Action().ResubmitToTable(c.pipeline[serviceLBTable].GetID()).
+		Cookie(c.cookieAllocator.Request(c.serviceCategory).Raw()).
 		Done()
 }
 
@@ -1249,7 +1276,7 @@ func (c *client) serviceCIDRDNATFlows(serviceCIDRs []*net.IPNet) []binding.Flow
 // serviceNeedLBFlow generates flows to mark packets as LB needed.
 func (c *client) serviceNeedLBFlow() binding.Flow {
 	return c.pipeline[sessionAffinityTable].BuildFlow(priorityMiss).
-		Cookie(c.cookieAllocator.Request(cookie.Service).Raw()).
+		Cookie(c.cookieAllocator.Request(c.serviceCategory).Raw()).
 		Action().LoadRegRange(int(serviceLearnReg), marksRegServiceNeedLB, serviceLearnRegRange).
 		Done()
 }
@@ -1743,7 +1770,7 @@ func (c *client) loadBalancerServiceFromOutsideFlow(svcIP net.IP, svcPort uint16
 // sessionAffinityTable according to the Endpoint selection decision.
 func (c *client) serviceLearnFlow(groupID binding.GroupIDType, svcIP net.IP, svcPort uint16, protocol binding.Protocol, affinityTimeout uint16) binding.Flow {
 	// Using unique cookie ID here to avoid learned flow cascade deletion.
-	cookieID := c.cookieAllocator.RequestWithObjectID(cookie.Service, uint32(groupID)).Raw()
+	cookieID := c.cookieAllocator.RequestWithObjectID(c.serviceCategory, uint32(groupID)).Raw()
 	learnFlowBuilder := c.pipeline[serviceLBTable].BuildFlow(priorityLow).
 		MatchRegRange(int(serviceLearnReg), marksRegServiceNeedLearn, serviceLearnRegRange).
 		MatchDstIP(svcIP).
@@ -1755,7 +1782,7 @@ func (c *client) serviceLearnFlow(groupID binding.GroupIDType, svcIP net.IP, svc
 	// desired behavior based on the K8s spec. Note that existing connections will keep going to
 	// the same endpoint because of connection tracking; and that is also the desired behavior.
 	learnFlowBuilderLearnAction := learnFlowBuilder.
-		Action().Learn(sessionAffinityTable, priorityNormal, 0, affinityTimeout, cookieID).
+		Action().Learn(c.pipeline[sessionAffinityTable].GetID(), priorityNormal, 0, affinityTimeout, cookieID).
 		DeleteLearned()
 	ipProtocol := binding.ProtocolIP
 	switch protocol {
@@ -1785,7 +1812,7 @@ func (c *client) serviceLearnFlow(groupID binding.GroupIDType, svcIP net.IP, svc
 			LoadReg(int(marksReg), macRewriteMark, macRewriteMarkRange).
 			Done().
 			Action().LoadRegRange(int(serviceLearnReg), marksRegServiceSelected, serviceLearnRegRange).
-			Action().GotoTable(endpointDNATTable).
+			Action().GotoTable(c.pipeline[endpointDNATTable].GetID()).
 			Done()
 	} else if ipProtocol == binding.ProtocolIPv6 {
 		return learnFlowBuilderLearnAction.
@@ -1797,7 +1824,7 @@ func (c *client) serviceLearnFlow(groupID binding.GroupIDType, svcIP net.IP, svc
 			LoadReg(int(marksReg), macRewriteMark, macRewriteMarkRange).
 			Done().
 			Action().LoadRegRange(int(serviceLearnReg), marksRegServiceSelected, serviceLearnRegRange).
-			Action().GotoTable(endpointDNATTable).
+			Action().GotoTable(c.pipeline[endpointDNATTable].GetID()).
 			Done()
 	}
 	return nil
@@ -1812,7 +1839,7 @@ func (c *client) serviceLBFlow(groupID binding.GroupIDType, svcIP net.IP, svcPor
 		MatchDstIP(svcIP).
 		MatchRegRange(int(serviceLearnReg), marksRegServiceNeedLB, serviceLearnRegRange).
 		Action().Group(groupID).
-		Cookie(c.cookieAllocator.Request(cookie.Service).Raw()).
+		Cookie(c.cookieAllocator.Request(c.serviceCategory).Raw()).
 		Done()
 }
 
@@ -1825,7 +1852,7 @@ func (c *client) endpointDNATFlow(endpointIP net.IP, endpointPort uint16, protoc
 	table := c.pipeline[endpointDNATTable]
 
 	flowBuilder := table.BuildFlow(priorityNormal).
-		Cookie(c.cookieAllocator.Request(cookie.Service).Raw()).
+		Cookie(c.cookieAllocator.Request(c.serviceCategory).Raw()).
 		MatchRegRange(int(endpointPortReg), unionVal, binding.Range{0, 18}).
 		MatchProtocol(protocol)
 	ctZone := CtZone
@@ -1878,10 +1905,10 @@ func (c *client) serviceEndpointGroup(groupID binding.GroupIDType, withSessionAf
 	var resubmitTableID binding.TableIDType
 	var lbResultMark uint32
 	if withSessionAffinity {
-		resubmitTableID = serviceLBTable
+		resubmitTableID = c.pipeline[serviceLBTable].GetID()
 		lbResultMark = marksRegServiceNeedLearn
 	} else {
-		resubmitTableID = endpointDNATTable
+		resubmitTableID = c.pipeline[endpointDNATTable].GetID()
 		lbResultMark = marksRegServiceSelected
 	}
 
@@ -2012,6 +2039,7 @@ func NewClient(bridgeName, mgmtAddr string, ovsDatapathType ovsconfig.OVSDatapat
 		enableProxy:              enableProxy,
 		enableAntreaPolicy:       enableKuryrPolicy,
 		enableEgress:             enableEgress,
+		serviceCategory:          cookie.Service,
 		nodeFlowCache:            newFlowCategoryCache(),
 		podFlowCache:             newFlowCategoryCache(),
 		serviceFlowCache:         newFlowCategoryCache(),
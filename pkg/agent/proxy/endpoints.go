@@ -0,0 +1,217 @@
+package proxy
+
+import (
+	"reflect"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
+	apimachinerytypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+
+	k8sproxy "projectkuryr/kuryr/pkg/proxy"
+)
+
+// endpointsChange describes an Endpoints change, previous is the state from
+// before all of them, current is the state after applying all of them.
+type endpointsChange struct {
+	previous EndpointsMap
+	current  EndpointsMap
+}
+
+// endpointsChangesTracker tracks Endpoints changes. The Endpoints of a Service
+// come either from its Endpoints object or from its EndpointSlices, the
+// proxier only registers one of the two sources.
+type endpointsChangesTracker struct {
+	// hostname is used to tell whether an Endpoint is on the current Node.
+	hostname string
+	isIPv6   bool
+
+	sync.RWMutex
+	// changes contains the Endpoints changes since the last Update.
+	changes map[apimachinerytypes.NamespacedName]*endpointsChange
+	// slices stores the EndpointSlices of each Service keyed by slice name.
+	slices map[apimachinerytypes.NamespacedName]map[string]*discovery.EndpointSlice
+	// initialized tells whether the Endpoints have been synced.
+	initialized bool
+}
+
+func newEndpointsChangesTracker(hostname string, isIPv6 bool) *endpointsChangesTracker {
+	return &endpointsChangesTracker{
+		hostname: hostname,
+		isIPv6:   isIPv6,
+		changes:  map[apimachinerytypes.NamespacedName]*endpointsChange{},
+		slices:   map[apimachinerytypes.NamespacedName]map[string]*discovery.EndpointSlice{},
+	}
+}
+
+// OnEndpointUpdate records the change of an Endpoints object, pass nil as
+// previous for an add and nil as current for a delete. It returns true if
+// there are changes to sync.
+func (t *endpointsChangesTracker) OnEndpointUpdate(previous, current *corev1.Endpoints) bool {
+	endpoints := current
+	if endpoints == nil {
+		endpoints = previous
+	}
+	// previous == nil && current == nil is unexpected, we should return false directly.
+	if endpoints == nil {
+		return false
+	}
+	namespacedName := apimachinerytypes.NamespacedName{Namespace: endpoints.Namespace, Name: endpoints.Name}
+
+	t.Lock()
+	defer t.Unlock()
+	t.record(namespacedName, t.endpointsToEndpointsMap(previous), t.endpointsToEndpointsMap(current))
+	return len(t.changes) > 0
+}
+
+// OnEndpointSliceUpdate records the change of an EndpointSlice, the Endpoints
+// of its Service are recomputed from all the slices of the Service.
+func (t *endpointsChangesTracker) OnEndpointSliceUpdate(endpointSlice *discovery.EndpointSlice, removeSlice bool) bool {
+	serviceName, ok := endpointSlice.Labels[discovery.LabelServiceName]
+	if !ok || serviceName == "" {
+		klog.Warningf("EndpointSlice %s/%s has no %s label", endpointSlice.Namespace, endpointSlice.Name, discovery.LabelServiceName)
+		return false
+	}
+	namespacedName := apimachinerytypes.NamespacedName{Namespace: endpointSlice.Namespace, Name: serviceName}
+
+	t.Lock()
+	defer t.Unlock()
+	previous := t.slicesToEndpointsMap(namespacedName)
+	if removeSlice {
+		delete(t.slices[namespacedName], endpointSlice.Name)
+		if len(t.slices[namespacedName]) == 0 {
+			delete(t.slices, namespacedName)
+		}
+	} else {
+		if t.slices[namespacedName] == nil {
+			t.slices[namespacedName] = map[string]*discovery.EndpointSlice{}
+		}
+		t.slices[namespacedName][endpointSlice.Name] = endpointSlice
+	}
+	t.record(namespacedName, previous, t.slicesToEndpointsMap(namespacedName))
+	return len(t.changes) > 0
+}
+
+// record must be called with the lock held.
+func (t *endpointsChangesTracker) record(namespacedName apimachinerytypes.NamespacedName, previous, current EndpointsMap) {
+	change, exists := t.changes[namespacedName]
+	if !exists {
+		change = &endpointsChange{previous: previous}
+		t.changes[namespacedName] = change
+	}
+	change.current = current
+	// if change.previous equal to change.current, it means no change
+	if reflect.DeepEqual(change.previous, change.current) {
+		delete(t.changes, namespacedName)
+	}
+}
+
+func (t *endpointsChangesTracker) OnEndpointsSynced() {
+	t.Lock()
+	defer t.Unlock()
+	t.initialized = true
+}
+
+func (t *endpointsChangesTracker) Synced() bool {
+	t.RLock()
+	defer t.RUnlock()
+	return t.initialized
+}
+
+// Update applies the pending changes to em.
+func (t *endpointsChangesTracker) Update(em EndpointsMap) {
+	t.Lock()
+	defer t.Unlock()
+	for _, change := range t.changes {
+		for spn := range change.previous {
+			delete(em, spn)
+		}
+		for spn, endpoints := range change.current {
+			em[spn] = endpoints
+		}
+	}
+	t.changes = map[apimachinerytypes.NamespacedName]*endpointsChange{}
+}
+
+// endpointsToEndpointsMap translates an Endpoints object to an EndpointsMap.
+// The not ready addresses are ignored.
+func (t *endpointsChangesTracker) endpointsToEndpointsMap(endpoints *corev1.Endpoints) EndpointsMap {
+	if endpoints == nil {
+		return nil
+	}
+	endpointsMap := make(EndpointsMap)
+	for i := range endpoints.Subsets {
+		ss := &endpoints.Subsets[i]
+		for i := range ss.Ports {
+			port := &ss.Ports[i]
+			if port.Port == 0 {
+				klog.Warningf("Ignoring invalid endpoint port %s", port.Name)
+				continue
+			}
+			svcPortName := k8sproxy.ServicePortName{
+				NamespacedName: apimachinerytypes.NamespacedName{Namespace: endpoints.Namespace, Name: endpoints.Name},
+				Port:           port.Name,
+				Protocol:       port.Protocol,
+			}
+			for i := range ss.Addresses {
+				addr := &ss.Addresses[i]
+				if addr.IP == "" {
+					klog.Warningf("Ignoring invalid endpoint port %s with empty host", port.Name)
+					continue
+				}
+				isLocal := addr.NodeName != nil && *addr.NodeName == t.hostname
+				t.addEndpoint(endpointsMap, svcPortName, k8sproxy.NewBaseEndpointInfo(addr.IP, int(port.Port), isLocal, nil))
+			}
+		}
+	}
+	return endpointsMap
+}
+
+// slicesToEndpointsMap merges the EndpointSlices of a Service into an
+// EndpointsMap. It must be called with the lock held.
+func (t *endpointsChangesTracker) slicesToEndpointsMap(namespacedName apimachinerytypes.NamespacedName) EndpointsMap {
+	slices, ok := t.slices[namespacedName]
+	if !ok {
+		return nil
+	}
+	addressType := discovery.AddressTypeIPv4
+	if t.isIPv6 {
+		addressType = discovery.AddressTypeIPv6
+	}
+	endpointsMap := make(EndpointsMap)
+	for _, slice := range slices {
+		if slice.AddressType != addressType {
+			continue
+		}
+		for _, port := range slice.Ports {
+			if port.Port == nil || *port.Port == 0 {
+				continue
+			}
+			svcPortName := k8sproxy.ServicePortName{NamespacedName: namespacedName}
+			if port.Name != nil {
+				svcPortName.Port = *port.Name
+			}
+			if port.Protocol != nil {
+				svcPortName.Protocol = *port.Protocol
+			}
+			for _, endpoint := range slice.Endpoints {
+				if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+					continue
+				}
+				isLocal := endpoint.Topology[corev1.LabelHostname] == t.hostname
+				for _, ip := range endpoint.Addresses {
+					t.addEndpoint(endpointsMap, svcPortName, k8sproxy.NewBaseEndpointInfo(ip, int(*port.Port), isLocal, endpoint.Topology))
+				}
+			}
+		}
+	}
+	return endpointsMap
+}
+
+func (t *endpointsChangesTracker) addEndpoint(endpointsMap EndpointsMap, svcPortName k8sproxy.ServicePortName, endpoint *k8sproxy.BaseEndpointInfo) {
+	if endpointsMap[svcPortName] == nil {
+		endpointsMap[svcPortName] = map[string]k8sproxy.Endpoint{}
+	}
+	endpointsMap[svcPortName][endpoint.String()] = endpoint
+}
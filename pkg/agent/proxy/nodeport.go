@@ -0,0 +1,203 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base32"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+
+	k8sproxy "projectkuryr/kuryr/pkg/proxy"
+)
+
+const (
+	natTable         = "nat"
+	preRoutingChain  = "PREROUTING"
+	outputChain      = "OUTPUT"
+	postRoutingChain = "POSTROUTING"
+
+	kuryrNodePortsChain     = "KURYR-NODEPORTS"
+	kuryrPostRoutingChain   = "KURYR-NP-POSTROUTING"
+	kuryrMarkMasqChain      = "KURYR-NP-MARK-MASQ"
+	kuryrServiceChainPrefix = "KURYR-NP-SVC-"
+	kuryrLocalChainPrefix   = "KURYR-NP-XLB-"
+
+	// masqueradeMark marks the NodePort traffic to SNAT with the Node IP. It
+	// must not collide with the marks of kube-proxy (0x4000 and 0x8000).
+	masqueradeMark = "0x10000/0x10000"
+)
+
+// IPTablesClient is the subset of the iptables client used to program the
+// NodePort Services.
+type IPTablesClient interface {
+	EnsureChain(table string, chain string) error
+	EnsureRule(table string, chain string, ruleSpec []string) error
+	Restore(data []byte, flush bool, useIPv6 bool) error
+}
+
+// nodePortRules DNATs the traffic received on the NodePort of the Services to
+// their Endpoints with iptables. The traffic is SNAT'd with the Node IP unless
+// the Service has externalTrafficPolicy Local, in which case only the local
+// Endpoints are used and the source IP of the client is preserved.
+type nodePortRules struct {
+	ipt         IPTablesClient
+	isIPv6      bool
+	initialized bool
+	// serviceChains are the per Service chains written by the last restore,
+	// the chains no longer used are deleted by the next one.
+	serviceChains sets.String
+	lastData      []byte
+}
+
+func newNodePortRules(ipt IPTablesClient, isIPv6 bool) *nodePortRules {
+	return &nodePortRules{ipt: ipt, isIPv6: isIPv6, serviceChains: sets.NewString()}
+}
+
+// initialize links the kuryr chains to the built-in ones. iptables-restore
+// cannot be used for these rules as the built-in chains have rules not
+// managed by kuryr.
+func (r *nodePortRules) initialize() error {
+	jumpRules := []struct{ srcChain, dstChain, comment string }{
+		{preRoutingChain, kuryrNodePortsChain, "kuryr: jump to kuryr NodePort rules"},
+		{outputChain, kuryrNodePortsChain, "kuryr: jump to kuryr NodePort rules"},
+		{postRoutingChain, kuryrPostRoutingChain, "kuryr: jump to kuryr NodePort postrouting rules"},
+	}
+	for _, rule := range jumpRules {
+		if err := r.ipt.EnsureChain(natTable, rule.dstChain); err != nil {
+			return err
+		}
+		ruleSpec := []string{"-j", rule.dstChain, "-m", "comment", "--comment", rule.comment}
+		if rule.dstChain == kuryrNodePortsChain {
+			ruleSpec = append([]string{"-m", "addrtype", "--dst-type", "LOCAL"}, ruleSpec...)
+		}
+		if err := r.ipt.EnsureRule(natTable, rule.srcChain, ruleSpec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sync writes the NodePort rules of the Services, it does nothing if they did
+// not change since the last call.
+func (r *nodePortRules) sync(serviceMap k8sproxy.ServiceMap, endpointsMap EndpointsMap) error {
+	if !r.initialized {
+		if err := r.initialize(); err != nil {
+			return fmt.Errorf("error initializing NodePort iptables chains: %v", err)
+		}
+		r.initialized = true
+	}
+	data, serviceChains := r.iptablesData(serviceMap, endpointsMap)
+	if bytes.Equal(data, r.lastData) {
+		return nil
+	}
+	// Setting --noflush to keep the previous contents (i.e. non kuryr managed chains) of the table.
+	if err := r.ipt.Restore(data, false, r.isIPv6); err != nil {
+		return err
+	}
+	r.serviceChains = serviceChains
+	r.lastData = data
+	return nil
+}
+
+// serviceChainSuffix returns a hash of the Service port, so that the chain
+// names stay within the 28 characters allowed by iptables.
+func serviceChainSuffix(svcPortName k8sproxy.ServicePortName) string {
+	hash := sha256.Sum256([]byte(svcPortName.String() + string(svcPortName.Protocol)))
+	return base32.StdEncoding.EncodeToString(hash[:])[:12]
+}
+
+func writeLine(buf *bytes.Buffer, words ...string) {
+	buf.WriteString(strings.Join(words, " ") + "\n")
+}
+
+// writeDNATRules spreads the traffic of chain evenly among the endpoints.
+func writeDNATRules(buf *bytes.Buffer, chain, protocol string, endpoints []string) {
+	for i, endpoint := range endpoints {
+		args := []string{"-A", chain, "-p", protocol}
+		if remaining := len(endpoints) - i; remaining > 1 {
+			args = append(args, "-m", "statistic", "--mode", "random", "--probability", fmt.Sprintf("%0.10f", 1.0/float64(remaining)))
+		}
+		writeLine(buf, append(args, "-j", "DNAT", "--to-destination", endpoint)...)
+	}
+}
+
+func (r *nodePortRules) iptablesData(serviceMap k8sproxy.ServiceMap, endpointsMap EndpointsMap) ([]byte, sets.String) {
+	var svcPortNames []k8sproxy.ServicePortName
+	for svcPortName, svcPort := range serviceMap {
+		if svcPort.NodePort() != 0 {
+			svcPortNames = append(svcPortNames, svcPortName)
+		}
+	}
+	sort.Slice(svcPortNames, func(i, j int) bool {
+		return svcPortNames[i].String() < svcPortNames[j].String()
+	})
+
+	serviceChains := sets.NewString()
+	chains := new(bytes.Buffer)
+	rules := new(bytes.Buffer)
+	writeLine(chains, "*nat")
+	writeLine(chains, fmt.Sprintf(":%s - [0:0]", kuryrNodePortsChain))
+	writeLine(chains, fmt.Sprintf(":%s - [0:0]", kuryrPostRoutingChain))
+	writeLine(chains, fmt.Sprintf(":%s - [0:0]", kuryrMarkMasqChain))
+	writeLine(rules, "-A", kuryrMarkMasqChain, "-j", "MARK", "--or-mark", masqueradeMark)
+	writeLine(rules, "-A", kuryrPostRoutingChain, "-m", "mark", "--mark", masqueradeMark,
+		"-m", "comment", "--comment", `"kuryr: masquerade NodePort traffic"`, "-j", "MASQUERADE")
+
+	for _, svcPortName := range svcPortNames {
+		svcPort := serviceMap[svcPortName]
+		protocol := strings.ToLower(string(svcPort.Protocol()))
+		comment := fmt.Sprintf(`"%s"`, svcPortName.String())
+		var allEndpoints, localEndpoints []string
+		for _, endpoint := range endpointsMap[svcPortName] {
+			allEndpoints = append(allEndpoints, endpoint.String())
+			if endpoint.GetIsLocal() {
+				localEndpoints = append(localEndpoints, endpoint.String())
+			}
+		}
+		sort.Strings(allEndpoints)
+		sort.Strings(localEndpoints)
+
+		suffix := serviceChainSuffix(svcPortName)
+		svcChain := kuryrServiceChainPrefix + suffix
+		serviceChains.Insert(svcChain)
+		writeLine(chains, fmt.Sprintf(":%s - [0:0]", svcChain))
+		writeDNATRules(rules, svcChain, protocol, allEndpoints)
+
+		match := []string{"-A", kuryrNodePortsChain, "-m", "comment", "--comment", comment,
+			"-p", protocol, "-m", protocol, "--dport", fmt.Sprint(svcPort.NodePort())}
+		if !svcPort.OnlyNodeLocalEndpoints() {
+			writeLine(rules, append(match, "-j", kuryrMarkMasqChain)...)
+			writeLine(rules, append(match, "-j", svcChain)...)
+			continue
+		}
+
+		localChain := kuryrLocalChainPrefix + suffix
+		serviceChains.Insert(localChain)
+		writeLine(chains, fmt.Sprintf(":%s - [0:0]", localChain))
+		writeLine(rules, append(match, "-j", localChain)...)
+		// The traffic from the Node itself may use any Endpoint, it is SNAT'd
+		// so that the replies come back through the Node.
+		writeLine(rules, "-A", localChain, "-m", "addrtype", "--src-type", "LOCAL", "-j", kuryrMarkMasqChain)
+		writeLine(rules, "-A", localChain, "-m", "addrtype", "--src-type", "LOCAL", "-j", svcChain)
+		// Without local Endpoints the external traffic is not DNAT'd and gets
+		// rejected by the Node, the health check reports the Node unavailable.
+		writeDNATRules(rules, localChain, protocol, localEndpoints)
+	}
+
+	// The chains of the removed Services are flushed by their declaration and
+	// then deleted.
+	staleChains := r.serviceChains.Difference(serviceChains).List()
+	for _, chain := range staleChains {
+		writeLine(chains, fmt.Sprintf(":%s - [0:0]", chain))
+		writeLine(rules, "-X", chain)
+	}
+	if len(staleChains) > 0 {
+		klog.V(2).Infof("Deleting stale NodePort chains %v", staleChains)
+	}
+	writeLine(rules, "COMMIT")
+	return append(chains.Bytes(), rules.Bytes()...), serviceChains
+}
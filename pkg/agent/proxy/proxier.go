@@ -1,239 +1,444 @@
 package proxy
 
-
-/*
-// ProxyServer represents all the parameters required to start the Kubernetes proxy server. All
-// fields are required.
-type ProxyServer struct {
-	Client                 clientset.Interface
-	EventClient            v1core.EventsGetter
-	MetricsBindAddress     string
-	BindAddressHardFail    bool
-	HealthzServer          healthcheck.ProxierHealthUpdater // interface 指针
-
-	Recorder record.EventRecorder
-	NodeRef  *corev1.ObjectReference
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+
+	"projectkuryr/kuryr/pkg/agent/openflow"
+	"projectkuryr/kuryr/pkg/healthcheck"
+	binding "projectkuryr/kuryr/pkg/ovs/openflow"
+	k8sproxy "projectkuryr/kuryr/pkg/proxy"
+	"projectkuryr/kuryr/pkg/proxy/config"
+)
+
+const (
+	resyncPeriod = time.Minute
+	// syncPeriod is how often the OVS flows are fully re-checked even without
+	// Service or Endpoints change.
+	syncPeriod = 30 * time.Second
+	// minSyncInterval batches the changes received in a burst into one sync.
+	minSyncInterval = time.Second
+)
+
+// proxier programs OVS groups and flows for the ClusterIP of the Services, so
+// that Services work on clusters without Octavia. The NodePort of the Services
+// are DNAT'd with iptables on the Node.
+type proxier struct {
+	once                sync.Once
+	endpointsConfig     *config.EndpointsConfig
+	endpointSliceConfig *config.EndpointSliceConfig
+	serviceConfig       *config.ServiceConfig
+	// endpointsChanges and serviceChanges contains all changes to endpoints and
+	// services that happened since last syncProxyRules call. For a single object,
+	// changes are accumulated. Once both endpointsChanges and serviceChanges
+	// have been synced, syncProxyRules will start syncing rules to OVS.
+	endpointsChanges   *endpointsChangesTracker
+	serviceChanges     *k8sproxy.ServiceChangeTracker
+	serviceInitialized bool
+	// serviceMap stores services we expect to be installed.
+	serviceMap k8sproxy.ServiceMap
+	// serviceInstalledMap stores services we actually installed.
+	serviceInstalledMap k8sproxy.ServiceMap
+	// endpointsMap stores endpoints we expect to be installed.
+	endpointsMap EndpointsMap
+	// endpointsInstalledMap stores endpoints we actually installed.
+	endpointsInstalledMap EndpointsMap
+	// endpointReferences counts the Service ports using the flows of an
+	// Endpoint, as they are shared by the Services selecting the same Pod port.
+	endpointReferences map[string]int
+	groupCounter       GroupCounter
+	// serviceEndpointsMapsMutex protects serviceMap, endpointsMap,
+	// serviceInstalledMap, endpointsInstalledMap and endpointReferences.
+	serviceEndpointsMapsMutex sync.Mutex
+	// initializedMutex protects serviceInitialized.
+	initializedMutex sync.RWMutex
+
+	// nodePortRules programs the NodePort of the Services, it is nil when
+	// NodePort is not handled by the proxier.
+	nodePortRules *nodePortRules
+	// serviceHealthServer serves the health check node port of the Services
+	// with externalTrafficPolicy Local, it may be nil.
+	serviceHealthServer healthcheck.ServiceHealthServer
+
+	syncCh   chan struct{}
+	stopChan <-chan struct{}
+	ofClient openflow.Client
+	isIPv6   bool
 }
 
-func newProxyServer(config *AgentConfig, cleanupAndExit bool, master string) (*ProxyServer, error) {
-	// Create K8s Clientset, CRD Clientset and SharedInformerFactory for the given config.
-	//k8sClient, _, err := k8s.CreateClients(o.config.ClientConnection, o.config.KubeAPIServerOverride)
-	k8sClient, eventClient, err := k8s.CreateClients(config.ClientConnection, master)
-	if err != nil {
-		klog.Errorln("Error: CreateClients ", err)
-		return nil, err
+var _ k8sproxy.Provider = &proxier{}
+var _ config.EndpointSliceHandler = &proxier{}
+
+// NewProxier creates the OVS proxier. The Endpoints of the Services are read
+// from the EndpointSlices when enableEndpointSlice is true, from the Endpoints
+// otherwise. The NodePort of the Services are programmed with ipt unless it is
+// nil, serviceHealthServer may be nil as well.
+func NewProxier(
+	hostname string,
+	informerFactory informers.SharedInformerFactory,
+	ofClient openflow.Client,
+	recorder record.EventRecorder,
+	isIPv6 bool,
+	enableEndpointSlice bool,
+	ipt IPTablesClient,
+	serviceHealthServer healthcheck.ServiceHealthServer) *proxier {
+	ipFamily := corev1.IPv4Protocol
+	if isIPv6 {
+		ipFamily = corev1.IPv6Protocol
 	}
-	//informerFactory := informers.NewSharedInformerFactory(k8sClient, informerDefaultResync)
-
-	hostname, err := node.GetHostname("")
-	if err != nil {
-		fmt.Println("Error: GetHostname ", err)
-		return nil, err
+	p := &proxier{
+		serviceConfig:         config.NewServiceConfig(informerFactory.Core().V1().Services(), resyncPeriod),
+		endpointsChanges:      newEndpointsChangesTracker(hostname, isIPv6),
+		serviceChanges:        k8sproxy.NewServiceChangeTracker(NewServiceInfo, ipFamily, recorder),
+		serviceMap:            k8sproxy.ServiceMap{},
+		serviceInstalledMap:   k8sproxy.ServiceMap{},
+		endpointsMap:          EndpointsMap{},
+		endpointsInstalledMap: EndpointsMap{},
+		endpointReferences:    map[string]int{},
+		groupCounter:          NewGroupCounter(),
+		serviceHealthServer:   serviceHealthServer,
+		syncCh:                make(chan struct{}, 1),
+		ofClient:              ofClient,
+		isIPv6:                isIPv6,
 	}
-	// Creates a new event broadcaster.
-	eventBroadcaster := record.NewBroadcaster()
-	// NewRecorder 返回一个EventRecorder，该EventRecorder可用于向该EventBroadcaster发送事件，事件源设置为给定的事件源。
-	recorder := eventBroadcaster.NewRecorder(Scheme, corev1.EventSource{Component: "kuryr-agent", Host: hostname})
-
-	nodeRef := &corev1.ObjectReference{
-		Kind:      "Node",
-		Name:      hostname,
-		UID:       types.UID(hostname),
-		Namespace: "",
+	if ipt != nil {
+		p.nodePortRules = newNodePortRules(ipt, isIPv6)
 	}
+	p.serviceConfig.RegisterEventHandler(p)
+	if enableEndpointSlice {
+		p.endpointSliceConfig = config.NewEndpointSliceConfig(informerFactory.Discovery().V1beta1().EndpointSlices(), resyncPeriod)
+		p.endpointSliceConfig.RegisterEventHandler(p)
+	} else {
+		p.endpointsConfig = config.NewEndpointsConfig(informerFactory.Core().V1().Endpoints(), resyncPeriod)
+		p.endpointsConfig.RegisterEventHandler(p)
+	}
+	return p
+}
 
-	klog.Infof("newProxyServer: nodeRef: %+v\n", *nodeRef)
-
-	// 实例化一个 proxierHealthServer
-	var healthzServer healthcheck.ProxierHealthUpdater // 声明 healthzServer 为 ProxierHealthUpdater interface 指针。
-	// 多路复用
-	healthzServer = healthcheck.NewProxierHealthServer(config.HealthzBindAddress, 2*time.Second, recorder, nodeRef)
-
-	serviceChange := &ServiceChangeTracker{
-		items:						make(map[types.NamespacedName]*serviceChange),
-		ipFamily:					corev1.IPv4Protocol,
-		recorder:					recorder,
-		processServiceMapChange: 	nil,
+func endpointFlowKey(protocol binding.Protocol, endpoint k8sproxy.Endpoint) string {
+	return fmt.Sprintf("%s/%s", endpoint.String(), protocol)
+}
 
+// uninstallEndpoints releases the Endpoints of svcPortName that are not in
+// keep, the flows of an Endpoint are removed with its last reference.
+func (p *proxier) uninstallEndpoints(svcPortName k8sproxy.ServicePortName, protocol binding.Protocol, keep map[string]k8sproxy.Endpoint) bool {
+	for key, endpoint := range p.endpointsInstalledMap[svcPortName] {
+		if _, ok := keep[key]; ok {
+			continue
+		}
+		flowKey := endpointFlowKey(protocol, endpoint)
+		if p.endpointReferences[flowKey] <= 1 {
+			if err := p.ofClient.UninstallEndpointFlows(protocol, endpoint); err != nil {
+				klog.Errorf("Failed to remove flows of Endpoint %s of Service %s: %v", endpoint, svcPortName, err)
+				return false
+			}
+			delete(p.endpointReferences, flowKey)
+		} else {
+			p.endpointReferences[flowKey]--
+		}
+		delete(p.endpointsInstalledMap[svcPortName], key)
+		klog.V(2).Infof("Endpoint %s of Service %s removed", endpoint, svcPortName)
 	}
-	var proxier Provider
-	proxier = &Proxier{
-		serviceChanges: serviceChange,
+	if len(p.endpointsInstalledMap[svcPortName]) == 0 {
+		delete(p.endpointsInstalledMap, svcPortName)
 	}
+	return true
+}
 
-	return &ProxyServer{
-		Client:                 k8sClient,
-		EventClient:			eventClient,
-		HealthzServer:          healthzServer,
-		BindAddressHardFail: 	false,
-		MetricsBindAddress:     config.MetricsBindAddress,
-		Recorder:               recorder,
-		NodeRef:                nodeRef,
-		Proxier: 				proxier,
-	}, nil
+func (p *proxier) removeStaleServices() {
+	for svcPortName, svcPort := range p.serviceInstalledMap {
+		if _, ok := p.serviceMap[svcPortName]; ok {
+			continue
+		}
+		svcInfo := svcPort.(*ServiceInfo)
+		if err := p.ofClient.UninstallServiceFlows(svcInfo.ClusterIP(), uint16(svcInfo.Port()), svcInfo.OFProtocol); err != nil {
+			klog.Errorf("Failed to remove flows of Service %s: %v", svcPortName, err)
+			continue
+		}
+		if !p.uninstallEndpoints(svcPortName, svcInfo.OFProtocol, nil) {
+			continue
+		}
+		groupID, _ := p.groupCounter.Get(svcPortName)
+		if err := p.ofClient.UninstallServiceGroup(groupID); err != nil {
+			klog.Errorf("Failed to remove group of Service %s: %v", svcPortName, err)
+			continue
+		}
+		delete(p.serviceInstalledMap, svcPortName)
+		p.groupCounter.Recycle(svcPortName)
+		klog.V(2).Infof("Service %s removed", svcPortName)
+	}
 }
 
-func (s *ProxyServer) birthCry() {
-	fmt.Println("#### ProxyServer.birthCry")
-	s.Recorder.Eventf(s.NodeRef, EventTypeNormal, "Starting", "Starting kuryr-agent.")
+// serviceChanged tells whether the flows of an installed Service port must be
+// replaced.
+func serviceChanged(installed, svcInfo *ServiceInfo) bool {
+	return !installed.ClusterIP().Equal(svcInfo.ClusterIP()) ||
+		installed.Port() != svcInfo.Port() ||
+		installed.OFProtocol != svcInfo.OFProtocol ||
+		installed.SessionAffinityType() != svcInfo.SessionAffinityType() ||
+		installed.StickyMaxAgeSeconds() != svcInfo.StickyMaxAgeSeconds()
 }
 
-func (s *ProxyServer) CleanupAndExit() error {
-	fmt.Println("\there is Options.CleanupAndExit")
-	return nil
+func (p *proxier) installServices() {
+	for svcPortName, svcPort := range p.serviceMap {
+		svcInfo := svcPort.(*ServiceInfo)
+		groupID, _ := p.groupCounter.Get(svcPortName)
+		endpoints := p.endpointsMap[svcPortName]
+		installedEndpoints := p.endpointsInstalledMap[svcPortName]
+
+		installedSvcPort, installed := p.serviceInstalledMap[svcPortName]
+		needRemoval := installed && serviceChanged(installedSvcPort.(*ServiceInfo), svcInfo)
+		needUpdate := !installed || needRemoval || len(endpoints) != len(installedEndpoints)
+		var newEndpoints []k8sproxy.Endpoint
+		for key, endpoint := range endpoints {
+			if _, ok := installedEndpoints[key]; !ok {
+				newEndpoints = append(newEndpoints, endpoint)
+				needUpdate = true
+			}
+		}
+		if !needUpdate {
+			continue
+		}
+
+		if needRemoval {
+			pSvcInfo := installedSvcPort.(*ServiceInfo)
+			if err := p.ofClient.UninstallServiceFlows(pSvcInfo.ClusterIP(), uint16(pSvcInfo.Port()), pSvcInfo.OFProtocol); err != nil {
+				klog.Errorf("Failed to remove flows of Service %s: %v", svcPortName, err)
+				continue
+			}
+			delete(p.serviceInstalledMap, svcPortName)
+			// The protocol of the installed Endpoints may change as well.
+			if !p.uninstallEndpoints(svcPortName, pSvcInfo.OFProtocol, nil) {
+				continue
+			}
+			installedEndpoints = nil
+			newEndpoints = newEndpoints[:0]
+			for _, endpoint := range endpoints {
+				newEndpoints = append(newEndpoints, endpoint)
+			}
+		}
+
+		if err := p.ofClient.InstallEndpointFlows(svcInfo.OFProtocol, newEndpoints); err != nil {
+			klog.Errorf("Failed to install flows of Endpoints of Service %s: %v", svcPortName, err)
+			continue
+		}
+		if p.endpointsInstalledMap[svcPortName] == nil {
+			p.endpointsInstalledMap[svcPortName] = map[string]k8sproxy.Endpoint{}
+		}
+		for _, endpoint := range newEndpoints {
+			p.endpointsInstalledMap[svcPortName][endpoint.String()] = endpoint
+			p.endpointReferences[endpointFlowKey(svcInfo.OFProtocol, endpoint)]++
+		}
+
+		allEndpoints := make([]k8sproxy.Endpoint, 0, len(endpoints))
+		for _, endpoint := range endpoints {
+			allEndpoints = append(allEndpoints, endpoint)
+		}
+		withSessionAffinity := svcInfo.SessionAffinityType() == corev1.ServiceAffinityClientIP
+		if err := p.ofClient.InstallServiceGroup(groupID, withSessionAffinity, allEndpoints); err != nil {
+			klog.Errorf("Failed to install group of Service %s: %v", svcPortName, err)
+			continue
+		}
+		// The group no longer points to the removed Endpoints.
+		if !p.uninstallEndpoints(svcPortName, svcInfo.OFProtocol, endpoints) {
+			continue
+		}
+
+		if _, ok := p.serviceInstalledMap[svcPortName]; !ok {
+			var affinityTimeout uint16
+			if withSessionAffinity {
+				affinityTimeout = uint16(svcInfo.StickyMaxAgeSeconds())
+			}
+			if err := p.ofClient.InstallServiceFlows(groupID, svcInfo.ClusterIP(), uint16(svcInfo.Port()), svcInfo.OFProtocol, affinityTimeout); err != nil {
+				klog.Errorf("Failed to install flows of Service %s: %v", svcPortName, err)
+				continue
+			}
+		}
+		p.serviceInstalledMap[svcPortName] = svcPort
+		klog.V(2).Infof("Service %s installed with %d Endpoints", svcPortName, len(allEndpoints))
+	}
 }
 
-func (s *ProxyServer) Run() error {
-	// 线程1： 轮询监视 apiserver
-	// 线程2:  监听端口 响应 kubelet 的 addNetwork 和 delNetwork
-	// 线程3： 健康检查
+func (p *proxier) isInitialized() bool {
+	p.initializedMutex.RLock()
+	defer p.initializedMutex.RUnlock()
+	return p.serviceInitialized && p.endpointsChanges.Synced()
+}
 
-	var errCh chan error
-	if s.BindAddressHardFail {
-		errCh = make(chan error)
+// syncProxyRules applies the pending Service and Endpoints changes to OVS.
+// The failed operations are retried on the next sync, as the installed maps
+// only record what has been programmed.
+func (p *proxier) syncProxyRules() {
+	start := time.Now()
+	defer func() {
+		klog.V(4).Infof("syncProxyRules took %v", time.Since(start))
+	}()
+	if !p.isInitialized() {
+		klog.V(4).Info("Not syncing rules until both Services and Endpoints have been synced")
+		return
 	}
 
-	fmt.Println("\tProxyServer.Run(): Start up a healthz server ")
-	// Start up a healthz server if requested
-	serveHealthz(s.HealthzServer, errCh)
+	p.serviceEndpointsMapsMutex.Lock()
+	defer p.serviceEndpointsMapsMutex.Unlock()
+	p.endpointsChanges.Update(p.endpointsMap)
+	p.serviceMap.Update(p.serviceChanges)
 
-	fmt.Println("\tProxyServer.Run(): Start up a Metrics server on ", s.MetricsBindAddress)
-	// Start up a metrics server if requested
-	serveMetrics(s.MetricsBindAddress, "/metrics", true, errCh)
+	p.removeStaleServices()
+	p.installServices()
 
-	kuryrPortRequirement, err := labels.NewRequirement("KuryrPort", selection.Exists, []string{})
-	if err != nil{
-		fmt.Println("Error!!! kuryrPortRequirement", err)
+	if p.nodePortRules != nil {
+		if err := p.nodePortRules.sync(p.serviceMap, p.endpointsMap); err != nil {
+			klog.Errorf("Failed to sync NodePort rules: %v", err)
+		}
 	}
-
-	podRequirement, err := labels.NewRequirement("Pod", selection.Exists, []string{})
-	if err != nil{
-		fmt.Println("Error!!! kuryrPortRequirement", err)
+	if p.serviceHealthServer != nil {
+		if err := p.serviceHealthServer.SyncServices(p.serviceMap.HealthCheckNodePorts()); err != nil {
+			klog.Errorf("Error syncing healthcheck Services: %v", err)
+		}
+		if err := p.serviceHealthServer.SyncEndpoints(p.endpointsMap.LocalReadyEndpoints()); err != nil {
+			klog.Errorf("Error syncing healthcheck Endpoints: %v", err)
+		}
 	}
+}
 
-	labelSelector := labels.NewSelector()
-	labelSelector = labelSelector.Add(*podRequirement, *kuryrPortRequirement)
-
-	// Make informers that filter out objects that want a non-default service proxy.
-	//informerFactory := informers.NewSharedInformerFactoryWithOptions(s.Client, time.Second,
-	//	informers.WithTweakListOptions(func(options *metav1.ListOptions) {
-	//		options.LabelSelector = labelSelector.String()
-	//	}))
-	informerFactory := informers.NewSharedInformerFactoryWithOptions(s.Client, time.Second)
-
-	serviceConfig := NewServiceConfig(informerFactory.Core().V1().Services(), time.Second)
-	serviceConfig.RegisterEventHandler(s.Proxier)
-	go serviceConfig.Run(wait.NeverStop)
-
-	// Birth Cry after the birth is successful
-	s.birthCry()
-	return nil
+// Sync requests a sync of the flows, requests received while one is pending
+// are merged.
+func (p *proxier) Sync() {
+	select {
+	case p.syncCh <- struct{}{}:
+	default:
+	}
 }
 
-type Provider interface {
-	ServiceHandler
+// SyncLoop runs until stopChan is closed, syncing on request and every
+// syncPeriod.
+func (p *proxier) SyncLoop() {
+	ticker := time.NewTicker(syncPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stopChan:
+			return
+		case <-p.syncCh:
+		case <-ticker.C:
+		}
+		p.syncProxyRules()
+		// Let the changes received in a burst accumulate.
+		select {
+		case <-p.stopChan:
+			return
+		case <-time.After(minSyncInterval):
+		}
+	}
+}
 
-	// Sync immediately synchronizes the Provider's current state to proxy rules.
-	Sync()
-	// SyncLoop runs periodic work.
-	// This is expected to run as a goroutine or as the main loop of the app.
-	// It does not return.
-	SyncLoop()
+func (p *proxier) OnEndpointsAdd(endpoints *corev1.Endpoints) {
+	p.OnEndpointsUpdate(nil, endpoints)
 }
 
-type ServiceHandler interface {
-	// OnServiceAdd is called whenever creation of new service object
-	// is observed.
-	OnServiceAdd(service *corev1.Service)
-	// OnServiceUpdate is called whenever modification of an existing
-	// service object is observed.
-	OnServiceUpdate(oldService, service *corev1.Service)
-	// OnServiceDelete is called whenever deletion of an existing service
-	// object is observed.
-	OnServiceDelete(service *corev1.Service)
-	// OnServiceSynced is called once all the initial event handlers were
-	// called and the state is fully propagated to local cache.
-	OnServiceSynced()
+func (p *proxier) OnEndpointsUpdate(oldEndpoints, endpoints *corev1.Endpoints) {
+	if p.endpointsChanges.OnEndpointUpdate(oldEndpoints, endpoints) && p.isInitialized() {
+		p.Sync()
+	}
 }
 
-// proxyRun defines the interface to run a specified ProxyServer
-type proxyRun interface {
-	Run() error
-	CleanupAndExit() error
+func (p *proxier) OnEndpointsDelete(endpoints *corev1.Endpoints) {
+	p.OnEndpointsUpdate(endpoints, nil)
 }
 
-// NewServiceConfig creates a new ServiceConfig.
-func NewServiceConfig(serviceInformer coreinformers.ServiceInformer, resyncPeriod time.Duration) *ServiceConfig {
-	result := &ServiceConfig{
-		listerSynced: serviceInformer.Informer().HasSynced,
+func (p *proxier) OnEndpointsSynced() {
+	p.endpointsChanges.OnEndpointsSynced()
+	if p.isInitialized() {
+		p.Sync()
 	}
+}
 
-	serviceInformer.Informer().AddEventHandlerWithResyncPeriod(
-		cache.ResourceEventHandlerFuncs{
-			AddFunc:    result.handleAddService,
-			UpdateFunc: result.handleUpdateService,
-			DeleteFunc: result.handleDeleteService,
-		},
-		resyncPeriod,
-	)
+func (p *proxier) OnEndpointSliceAdd(endpointSlice *discovery.EndpointSlice) {
+	if p.endpointsChanges.OnEndpointSliceUpdate(endpointSlice, false) && p.isInitialized() {
+		p.Sync()
+	}
+}
 
-	return result
+func (p *proxier) OnEndpointSliceUpdate(oldEndpointSlice, newEndpointSlice *discovery.EndpointSlice) {
+	p.OnEndpointSliceAdd(newEndpointSlice)
 }
 
-// RegisterEventHandler registers a handler which is called on every service change.
-func (c *ServiceConfig) RegisterEventHandler(handler ServiceHandler) {
-	c.eventHandlers = append(c.eventHandlers, handler)
+func (p *proxier) OnEndpointSliceDelete(endpointSlice *discovery.EndpointSlice) {
+	if p.endpointsChanges.OnEndpointSliceUpdate(endpointSlice, true) && p.isInitialized() {
+		p.Sync()
+	}
 }
 
-// Run waits for cache synced and invokes handlers after syncing.
-func (c *ServiceConfig) Run(stopCh <-chan struct{}) {
-	klog.Info("Starting service config controller")
+func (p *proxier) OnEndpointSlicesSynced() {
+	p.OnEndpointsSynced()
+}
 
-	if !cache.WaitForNamedCacheSync("service config", stopCh, c.listerSynced) {
-		return
-	}
+func (p *proxier) OnServiceAdd(service *corev1.Service) {
+	p.OnServiceUpdate(nil, service)
+}
 
-	for i := range c.eventHandlers {
-		klog.V(3).Info("Calling handler.OnServiceSynced()")
-		c.eventHandlers[i].OnServiceSynced()
+func (p *proxier) OnServiceUpdate(oldService, service *corev1.Service) {
+	if p.serviceChanges.Update(oldService, service) && p.isInitialized() {
+		p.Sync()
 	}
 }
 
-func (c *ServiceConfig) handleAddService(obj interface{}) {
-	service, ok := obj.(*corev1.Service)
-	if !ok {
-		utilruntime.HandleError(fmt.Errorf("unexpected object type: %v", obj))
-		return
-	}
-	//for i := range c.eventHandlers {
-	//	klog.V(4).Info("Calling handler.OnServiceAdd")
-	//	c.eventHandlers[i].OnServiceAdd(service)
-	//}
-	fmt.Println("############ handleAddService> service:", service)
+func (p *proxier) OnServiceDelete(service *corev1.Service) {
+	p.OnServiceUpdate(service, nil)
 }
 
-func (c *ServiceConfig) handleUpdateService(oldObj, newObj interface{}) {
-	oldService, ok := oldObj.(*corev1.Service)
-	if !ok {
-		utilruntime.HandleError(fmt.Errorf("unexpected object type: %v", oldObj))
-		return
-	}
-	service, ok := newObj.(*corev1.Service)
-	if !ok {
-		utilruntime.HandleError(fmt.Errorf("unexpected object type: %v", newObj))
-		return
+func (p *proxier) OnServiceSynced() {
+	p.initializedMutex.Lock()
+	p.serviceInitialized = true
+	p.initializedMutex.Unlock()
+	if p.isInitialized() {
+		p.Sync()
 	}
+}
 
-	fmt.Println("############ handleUpdateService> oldService:", oldService, "\nservice: ", service)
+// Run starts the Service and Endpoints configs and runs the sync loop until
+// stopCh is closed. The informer factory must be started by the caller.
+func (p *proxier) Run(stopCh <-chan struct{}) {
+	p.once.Do(func() {
+		p.stopChan = stopCh
+		go p.serviceConfig.Run(stopCh)
+		if p.endpointSliceConfig != nil {
+			go p.endpointSliceConfig.Run(stopCh)
+		} else {
+			go p.endpointsConfig.Run(stopCh)
+		}
+		p.SyncLoop()
+	})
 }
 
-func (c *ServiceConfig) handleDeleteService(obj interface{}) {
-	service, ok := obj.(*corev1.Service)
-	if !ok {
-		utilruntime.HandleError(fmt.Errorf("unexpected object type: %v", obj))
-		return
+// GetServiceFlowKeys returns the keys of the flows installed for the Service
+// port, they can be used to look the flows up in OVS.
+func (p *proxier) GetServiceFlowKeys(serviceName, namespace string) ([]string, error) {
+	p.serviceEndpointsMapsMutex.Lock()
+	defer p.serviceEndpointsMapsMutex.Unlock()
+	var flows []string
+	found := false
+	for svcPortName, svcPort := range p.serviceInstalledMap {
+		if svcPortName.Name != serviceName || svcPortName.Namespace != namespace {
+			continue
+		}
+		found = true
+		svcInfo := svcPort.(*ServiceInfo)
+		var endpoints []k8sproxy.Endpoint
+		for _, endpoint := range p.endpointsInstalledMap[svcPortName] {
+			endpoints = append(endpoints, endpoint)
+		}
+		flows = append(flows, p.ofClient.GetServiceFlowKeys(svcInfo.ClusterIP(), uint16(svcInfo.Port()), svcInfo.OFProtocol, endpoints)...)
 	}
-	fmt.Println("############ handleDeleteService> service:", service)
+	if !found {
+		return nil, fmt.Errorf("Service %s/%s is not installed", namespace, serviceName)
+	}
+	return flows, nil
 }
-
-*/
@@ -0,0 +1,268 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apimachinerytypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	"projectkuryr/kuryr/pkg/agent/openflow"
+	binding "projectkuryr/kuryr/pkg/ovs/openflow"
+	k8sproxy "projectkuryr/kuryr/pkg/proxy"
+)
+
+const testHostname = "node-1"
+
+type fakeServiceFlows struct {
+	groupID         binding.GroupIDType
+	affinityTimeout uint16
+}
+
+// fakeOFClient records the Service groups and flows, the other methods of
+// openflow.Client are not used by the proxier.
+type fakeOFClient struct {
+	openflow.Client
+	groups        map[binding.GroupIDType][]string
+	affinity      map[binding.GroupIDType]bool
+	serviceFlows  map[string]fakeServiceFlows
+	endpointFlows map[string]bool
+}
+
+func newFakeOFClient() *fakeOFClient {
+	return &fakeOFClient{
+		groups:        map[binding.GroupIDType][]string{},
+		affinity:      map[binding.GroupIDType]bool{},
+		serviceFlows:  map[string]fakeServiceFlows{},
+		endpointFlows: map[string]bool{},
+	}
+}
+
+func (c *fakeOFClient) InstallServiceGroup(groupID binding.GroupIDType, withSessionAffinity bool, endpoints []k8sproxy.Endpoint) error {
+	var members []string
+	for _, endpoint := range endpoints {
+		members = append(members, endpoint.String())
+	}
+	sort.Strings(members)
+	c.groups[groupID] = members
+	c.affinity[groupID] = withSessionAffinity
+	return nil
+}
+
+func (c *fakeOFClient) UninstallServiceGroup(groupID binding.GroupIDType) error {
+	delete(c.groups, groupID)
+	delete(c.affinity, groupID)
+	return nil
+}
+
+func (c *fakeOFClient) InstallEndpointFlows(protocol binding.Protocol, endpoints []k8sproxy.Endpoint) error {
+	for _, endpoint := range endpoints {
+		c.endpointFlows[endpointFlowKey(protocol, endpoint)] = true
+	}
+	return nil
+}
+
+func (c *fakeOFClient) UninstallEndpointFlows(protocol binding.Protocol, endpoint k8sproxy.Endpoint) error {
+	delete(c.endpointFlows, endpointFlowKey(protocol, endpoint))
+	return nil
+}
+
+func serviceFlowKey(svcIP net.IP, svcPort uint16, protocol binding.Protocol) string {
+	return fmt.Sprintf("%s:%d/%s", svcIP, svcPort, protocol)
+}
+
+func (c *fakeOFClient) InstallServiceFlows(groupID binding.GroupIDType, svcIP net.IP, svcPort uint16, protocol binding.Protocol, affinityTimeout uint16) error {
+	c.serviceFlows[serviceFlowKey(svcIP, svcPort, protocol)] = fakeServiceFlows{groupID: groupID, affinityTimeout: affinityTimeout}
+	return nil
+}
+
+func (c *fakeOFClient) UninstallServiceFlows(svcIP net.IP, svcPort uint16, protocol binding.Protocol) error {
+	delete(c.serviceFlows, serviceFlowKey(svcIP, svcPort, protocol))
+	return nil
+}
+
+func newTestProxier(ofClient openflow.Client) *proxier {
+	informerFactory := informers.NewSharedInformerFactory(k8sfake.NewSimpleClientset(), 0)
+	p := NewProxier(testHostname, informerFactory, ofClient, record.NewFakeRecorder(10), false, false, nil, nil)
+	p.OnServiceSynced()
+	p.OnEndpointsSynced()
+	return p
+}
+
+func newTestService(name, clusterIP string, affinity corev1.ServiceAffinity) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Type:            corev1.ServiceTypeClusterIP,
+			ClusterIP:       clusterIP,
+			SessionAffinity: affinity,
+			Ports:           []corev1.ServicePort{{Name: "http", Protocol: corev1.ProtocolTCP, Port: 80}},
+		},
+	}
+}
+
+func newTestEndpoints(name string, ips ...string) *corev1.Endpoints {
+	subset := corev1.EndpointSubset{Ports: []corev1.EndpointPort{{Name: "http", Protocol: corev1.ProtocolTCP, Port: 8080}}}
+	nodeName := testHostname
+	for _, ip := range ips {
+		subset.Addresses = append(subset.Addresses, corev1.EndpointAddress{IP: ip, NodeName: &nodeName})
+	}
+	return &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Subsets:    []corev1.EndpointSubset{subset},
+	}
+}
+
+func TestProxierClusterIP(t *testing.T) {
+	ofClient := newFakeOFClient()
+	p := newTestProxier(ofClient)
+
+	svc := newTestService("web", "10.96.0.10", corev1.ServiceAffinityNone)
+	p.OnServiceAdd(svc)
+	p.OnEndpointsAdd(newTestEndpoints("web", "10.1.0.5", "10.1.0.6"))
+	p.syncProxyRules()
+
+	flows, ok := ofClient.serviceFlows["10.96.0.10:80/tcp"]
+	if assert.True(t, ok, "Service flows not installed") {
+		assert.Equal(t, []string{"10.1.0.5:8080", "10.1.0.6:8080"}, ofClient.groups[flows.groupID])
+		assert.False(t, ofClient.affinity[flows.groupID])
+		assert.Zero(t, flows.affinityTimeout)
+	}
+	assert.Len(t, ofClient.endpointFlows, 2)
+
+	// An Endpoint removed from the Service loses its flows.
+	p.OnEndpointsUpdate(newTestEndpoints("web", "10.1.0.5", "10.1.0.6"), newTestEndpoints("web", "10.1.0.6"))
+	p.syncProxyRules()
+	assert.Equal(t, []string{"10.1.0.6:8080"}, ofClient.groups[flows.groupID])
+	assert.Equal(t, map[string]bool{"10.1.0.6:8080/tcp": true}, ofClient.endpointFlows)
+
+	// Enabling session affinity replaces the Service flows.
+	affinitySvc := newTestService("web", "10.96.0.10", corev1.ServiceAffinityClientIP)
+	p.OnServiceUpdate(svc, affinitySvc)
+	p.syncProxyRules()
+	flows = ofClient.serviceFlows["10.96.0.10:80/tcp"]
+	assert.True(t, ofClient.affinity[flows.groupID])
+	assert.Equal(t, uint16(corev1.DefaultClientIPServiceAffinitySeconds), flows.affinityTimeout)
+
+	p.OnServiceDelete(affinitySvc)
+	p.OnEndpointsDelete(newTestEndpoints("web", "10.1.0.6"))
+	p.syncProxyRules()
+	assert.Empty(t, ofClient.serviceFlows)
+	assert.Empty(t, ofClient.groups)
+	assert.Empty(t, ofClient.endpointFlows)
+}
+
+func TestProxierSharedEndpoint(t *testing.T) {
+	ofClient := newFakeOFClient()
+	p := newTestProxier(ofClient)
+
+	for name, ip := range map[string]string{"web": "10.96.0.10", "web-alias": "10.96.0.11"} {
+		p.OnServiceAdd(newTestService(name, ip, corev1.ServiceAffinityNone))
+		p.OnEndpointsAdd(newTestEndpoints(name, "10.1.0.5"))
+	}
+	p.syncProxyRules()
+	assert.Len(t, ofClient.serviceFlows, 2)
+	assert.Len(t, ofClient.groups, 2)
+
+	// The Endpoint flows are kept as long as a Service uses them.
+	p.OnServiceDelete(newTestService("web", "10.96.0.10", corev1.ServiceAffinityNone))
+	p.syncProxyRules()
+	assert.Len(t, ofClient.serviceFlows, 1)
+	assert.Equal(t, map[string]bool{"10.1.0.5:8080/tcp": true}, ofClient.endpointFlows)
+
+	p.OnServiceDelete(newTestService("web-alias", "10.96.0.11", corev1.ServiceAffinityNone))
+	p.syncProxyRules()
+	assert.Empty(t, ofClient.endpointFlows)
+	assert.Empty(t, ofClient.groups)
+}
+
+type fakeIPTables struct {
+	jumps    []string
+	restored string
+}
+
+func (c *fakeIPTables) EnsureChain(table string, chain string) error {
+	return nil
+}
+
+func (c *fakeIPTables) EnsureRule(table string, chain string, ruleSpec []string) error {
+	c.jumps = append(c.jumps, chain+" "+strings.Join(ruleSpec, " "))
+	return nil
+}
+
+func (c *fakeIPTables) Restore(data []byte, flush bool, useIPv6 bool) error {
+	c.restored = string(data)
+	return nil
+}
+
+type fakeServiceHealthServer struct {
+	services  map[apimachinerytypes.NamespacedName]uint16
+	endpoints map[apimachinerytypes.NamespacedName]int
+}
+
+func (s *fakeServiceHealthServer) SyncServices(newServices map[apimachinerytypes.NamespacedName]uint16) error {
+	s.services = newServices
+	return nil
+}
+
+func (s *fakeServiceHealthServer) SyncEndpoints(newEndpoints map[apimachinerytypes.NamespacedName]int) error {
+	s.endpoints = newEndpoints
+	return nil
+}
+
+func TestProxierNodePortLocal(t *testing.T) {
+	ofClient := newFakeOFClient()
+	ipt := &fakeIPTables{}
+	hcServer := &fakeServiceHealthServer{}
+	informerFactory := informers.NewSharedInformerFactory(k8sfake.NewSimpleClientset(), 0)
+	p := NewProxier(testHostname, informerFactory, ofClient, record.NewFakeRecorder(10), false, false, ipt, hcServer)
+	p.OnServiceSynced()
+	p.OnEndpointsSynced()
+
+	svc := newTestService("web", "10.96.0.10", corev1.ServiceAffinityNone)
+	svc.Spec.Type = corev1.ServiceTypeLoadBalancer
+	svc.Spec.Ports[0].NodePort = 30080
+	svc.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyTypeLocal
+	svc.Spec.HealthCheckNodePort = 30081
+	endpoints := newTestEndpoints("web", "10.1.0.5", "10.1.0.6")
+	otherNode := "node-2"
+	endpoints.Subsets[0].Addresses[1].NodeName = &otherNode
+	p.OnServiceAdd(svc)
+	p.OnEndpointsAdd(endpoints)
+	p.syncProxyRules()
+
+	assert.Len(t, ipt.jumps, 3)
+	suffix := serviceChainSuffix(k8sproxy.ServicePortName{
+		NamespacedName: apimachinerytypes.NamespacedName{Namespace: "default", Name: "web"},
+		Port:           "http",
+		Protocol:       corev1.ProtocolTCP,
+	})
+	svcChain, localChain := kuryrServiceChainPrefix+suffix, kuryrLocalChainPrefix+suffix
+	// The external traffic only goes to the local Endpoint and is not SNAT'd.
+	assert.Contains(t, ipt.restored, `-A KURYR-NODEPORTS -m comment --comment "default/web:http" -p tcp -m tcp --dport 30080 -j `+localChain+"\n")
+	assert.NotContains(t, ipt.restored, "--dport 30080 -j "+kuryrMarkMasqChain)
+	assert.Contains(t, ipt.restored, "-A "+localChain+" -p tcp -j DNAT --to-destination 10.1.0.5:8080\n")
+	assert.NotContains(t, ipt.restored, "-A "+localChain+" -p tcp -j DNAT --to-destination 10.1.0.6:8080")
+	assert.Contains(t, ipt.restored, "-A "+svcChain+" -p tcp -m statistic --mode random --probability 0.5000000000 -j DNAT --to-destination 10.1.0.5:8080\n")
+	assert.Contains(t, ipt.restored, "-A "+svcChain+" -p tcp -j DNAT --to-destination 10.1.0.6:8080\n")
+
+	nsn := apimachinerytypes.NamespacedName{Namespace: "default", Name: "web"}
+	assert.Equal(t, map[apimachinerytypes.NamespacedName]uint16{nsn: 30081}, hcServer.services)
+	assert.Equal(t, map[apimachinerytypes.NamespacedName]int{nsn: 1}, hcServer.endpoints)
+
+	// The chains of a deleted Service are removed.
+	p.OnServiceDelete(svc)
+	p.OnEndpointsDelete(endpoints)
+	p.syncProxyRules()
+	assert.Contains(t, ipt.restored, "-X "+svcChain+"\n")
+	assert.Contains(t, ipt.restored, "-X "+localChain+"\n")
+	assert.Empty(t, hcServer.services)
+}
@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apimachinerytypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	binding "projectkuryr/kuryr/pkg/ovs/openflow"
+	k8sproxy "projectkuryr/kuryr/pkg/proxy"
+)
+
+// ServiceInfo is the proxy.ServicePort of the OVS proxier, it caches the
+// OpenFlow protocol of the port.
+type ServiceInfo struct {
+	*k8sproxy.BaseServiceInfo
+	OFProtocol binding.Protocol
+}
+
+// NewServiceInfo returns a new k8sproxy.ServicePort which abstracts a serviceInfo.
+func NewServiceInfo(port *corev1.ServicePort, service *corev1.Service, baseInfo *k8sproxy.BaseServiceInfo) k8sproxy.ServicePort {
+	info := &ServiceInfo{BaseServiceInfo: baseInfo}
+	isIPv6 := baseInfo.ClusterIP().To4() == nil
+	switch port.Protocol {
+	case corev1.ProtocolTCP:
+		info.OFProtocol = binding.ProtocolTCP
+		if isIPv6 {
+			info.OFProtocol = binding.ProtocolTCPv6
+		}
+	case corev1.ProtocolUDP:
+		info.OFProtocol = binding.ProtocolUDP
+		if isIPv6 {
+			info.OFProtocol = binding.ProtocolUDPv6
+		}
+	case corev1.ProtocolSCTP:
+		info.OFProtocol = binding.ProtocolSCTP
+		if isIPv6 {
+			info.OFProtocol = binding.ProtocolSCTPv6
+		}
+	}
+	return info
+}
+
+// EndpointsMap maps a ServicePortName to its Endpoints keyed by their String().
+type EndpointsMap map[k8sproxy.ServicePortName]map[string]k8sproxy.Endpoint
+
+// LocalReadyEndpoints returns the number of local Endpoint IPs of each Service,
+// the Endpoints only contain the ready addresses.
+func (em EndpointsMap) LocalReadyEndpoints() map[apimachinerytypes.NamespacedName]int {
+	ips := make(map[apimachinerytypes.NamespacedName]sets.String)
+	for svcPortName, endpoints := range em {
+		for _, endpoint := range endpoints {
+			if !endpoint.GetIsLocal() {
+				continue
+			}
+			if ips[svcPortName.NamespacedName] == nil {
+				ips[svcPortName.NamespacedName] = sets.NewString()
+			}
+			ips[svcPortName.NamespacedName].Insert(endpoint.IP())
+		}
+	}
+	counts := make(map[apimachinerytypes.NamespacedName]int)
+	for nsn, set := range ips {
+		counts[nsn] = set.Len()
+	}
+	return counts
+}
+
+// GroupCounter allocates the OpenFlow group of every Service port.
+type GroupCounter interface {
+	// Get returns the group ID of svcPortName, allocating it if needed. The
+	// second value is false when the group ID was just allocated.
+	Get(svcPortName k8sproxy.ServicePortName) (binding.GroupIDType, bool)
+	// Recycle releases the group ID of svcPortName so that it can be reused.
+	Recycle(svcPortName k8sproxy.ServicePortName) bool
+}
+
+type groupCounter struct {
+	mu             sync.Mutex
+	groupIDCounter binding.GroupIDType
+	recycled       []binding.GroupIDType
+	groupMap       map[k8sproxy.ServicePortName]binding.GroupIDType
+}
+
+func NewGroupCounter() GroupCounter {
+	return &groupCounter{groupMap: map[k8sproxy.ServicePortName]binding.GroupIDType{}}
+}
+
+func (c *groupCounter) Get(svcPortName k8sproxy.ServicePortName) (binding.GroupIDType, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if id, ok := c.groupMap[svcPortName]; ok {
+		return id, true
+	}
+	var id binding.GroupIDType
+	if len(c.recycled) != 0 {
+		id = c.recycled[len(c.recycled)-1]
+		c.recycled = c.recycled[:len(c.recycled)-1]
+	} else {
+		c.groupIDCounter++
+		id = c.groupIDCounter
+	}
+	c.groupMap[svcPortName] = id
+	return id, false
+}
+
+func (c *groupCounter) Recycle(svcPortName k8sproxy.ServicePortName) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if id, ok := c.groupMap[svcPortName]; ok {
+		delete(c.groupMap, svcPortName)
+		c.recycled = append(c.recycled, id)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package traceflow
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Pass is a pass of the packet through the OpenFlow pipeline, the packet is recirculated in a new
+// pass after the conntrack actions.
+type Pass struct {
+	// Header describes the recirculation which resumed the pipeline, it is empty for the first pass.
+	Header          string  `json:"header,omitempty"`
+	Flow            string  `json:"flow"`
+	Stages          []Stage `json:"stages"`
+	FinalFlow       string  `json:"finalFlow,omitempty"`
+	Megaflow        string  `json:"megaflow,omitempty"`
+	DatapathActions string  `json:"datapathActions,omitempty"`
+}
+
+// Stage is a flow of an OpenFlow table matched by the packet, with the actions it applied.
+type Stage struct {
+	Bridge    string `json:"bridge"`
+	Table     int    `json:"table"`
+	TableName string `json:"tableName,omitempty"`
+	// NoMatch is set when the packet matched no flow of the table.
+	NoMatch  bool     `json:"noMatch,omitempty"`
+	Match    string   `json:"match,omitempty"`
+	Priority int      `json:"priority"`
+	Cookie   string   `json:"cookie,omitempty"`
+	Actions  []string `json:"actions,omitempty"`
+	// Notes are the explanations of the actions given by ofproto/trace.
+	Notes []string `json:"notes,omitempty"`
+}
+
+var (
+	bridgeLine = regexp.MustCompile(`^bridge\("(.*)"\)$`)
+	// A stage starts with the table, e.g. "71. ip,reg5=0x5, priority 65, cookie 0x1".
+	stageLine = regexp.MustCompile(`^\s*(\d+)\. (.*)$`)
+)
+
+// parseTrace parses the output of ofproto/trace in its passes.
+func parseTrace(output string) []Pass {
+	var passes []Pass
+	var pass *Pass
+	var stage *Stage
+	bridge, header := "", ""
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "---") || strings.HasPrefix(trimmed, "==="):
+		case strings.HasPrefix(line, "Flow: "):
+			passes = append(passes, Pass{Header: header, Flow: strings.TrimPrefix(line, "Flow: ")})
+			pass, stage, header = &passes[len(passes)-1], nil, ""
+		case strings.HasPrefix(line, "Final flow: ") && pass != nil:
+			pass.FinalFlow = strings.TrimPrefix(line, "Final flow: ")
+			stage = nil
+		case strings.HasPrefix(line, "Megaflow: ") && pass != nil:
+			pass.Megaflow = strings.TrimPrefix(line, "Megaflow: ")
+		case strings.HasPrefix(line, "Datapath actions: ") && pass != nil:
+			pass.DatapathActions = strings.TrimPrefix(line, "Datapath actions: ")
+		case strings.HasPrefix(line, "recirc("):
+			header = line
+		case bridgeLine.MatchString(trimmed):
+			bridge = bridgeLine.FindStringSubmatch(trimmed)[1]
+		case stageLine.MatchString(line) && pass != nil:
+			m := stageLine.FindStringSubmatch(line)
+			table, _ := strconv.Atoi(m[1])
+			pass.Stages = append(pass.Stages, parseStage(bridge, table, m[2]))
+			stage = &pass.Stages[len(pass.Stages)-1]
+		case stage != nil && strings.HasPrefix(trimmed, "->"):
+			stage.Notes = append(stage.Notes, strings.TrimSpace(strings.TrimPrefix(trimmed, "->")))
+		case stage != nil:
+			stage.Actions = append(stage.Actions, trimmed)
+		}
+	}
+	return passes
+}
+
+// parseStage parses the flow of a stage, e.g. "ip,in_port=5, priority 65, cookie 0x1".
+func parseStage(bridge string, table int, flow string) Stage {
+	stage := Stage{Bridge: bridge, Table: table, TableName: tableNames[table]}
+	if strings.HasPrefix(flow, "No match") {
+		stage.NoMatch = true
+		return stage
+	}
+	fields := strings.Split(flow, ", ")
+	for _, field := range fields {
+		switch {
+		case strings.HasPrefix(field, "priority "):
+			stage.Priority, _ = strconv.Atoi(strings.TrimPrefix(field, "priority "))
+		case strings.HasPrefix(field, "cookie "):
+			stage.Cookie = strings.TrimPrefix(field, "cookie ")
+		default:
+			if stage.Match != "" {
+				stage.Match += ", "
+			}
+			stage.Match += field
+		}
+	}
+	return stage
+}
@@ -0,0 +1,204 @@
+// Package traceflow traces a packet of a Pod through the OVS bridge with ofproto/trace, and tells
+// which flows of the OVS firewall driver of Neutron accepted or dropped it.
+package traceflow
+
+import (
+	"fmt"
+	"strings"
+
+	"projectkuryr/kuryr/pkg/apis/openstack/v1alpha1"
+	"projectkuryr/kuryr/pkg/ovs/ovsctl"
+)
+
+// The tables of the integration bridge used by neutron-openvswitch-agent and its OVS firewall
+// driver.
+const (
+	tableLocalSwitching       = 0
+	tableTransient            = 60
+	tableTransientEgress      = 61
+	tableBaseEgress           = 71
+	tableRulesEgress          = 72
+	tableAcceptOrIngress      = 73
+	tableBaseIngress          = 81
+	tableRulesIngress         = 82
+	tableAcceptedEgress       = 91
+	tableAcceptedIngress      = 92
+	tableDropped              = 93
+	tableAcceptedEgressNormal = 94
+)
+
+var tableNames = map[int]string{
+	tableLocalSwitching:       "LOCAL_SWITCHING",
+	tableTransient:            "TRANSIENT",
+	tableTransientEgress:      "TRANSIENT_EGRESS",
+	tableBaseEgress:           "BASE_EGRESS",
+	tableRulesEgress:          "RULES_EGRESS",
+	tableAcceptOrIngress:      "ACCEPT_OR_INGRESS",
+	tableBaseIngress:          "BASE_INGRESS",
+	tableRulesIngress:         "RULES_INGRESS",
+	tableAcceptedEgress:       "ACCEPTED_EGRESS_TRAFFIC",
+	tableAcceptedIngress:      "ACCEPTED_INGRESS_TRAFFIC",
+	tableDropped:              "DROPPED_TRAFFIC",
+	tableAcceptedEgressNormal: "ACCEPTED_EGRESS_TRAFFIC_NORMAL",
+}
+
+// Direction is the direction of the traffic filtered by security groups, relative to the port.
+type Direction string
+
+const (
+	DirectionEgress  Direction = "Egress"
+	DirectionIngress Direction = "Ingress"
+)
+
+// Verdicts of the packet at the end of the pipeline.
+const (
+	VerdictForwarded = "Forwarded"
+	VerdictDropped   = "Dropped"
+)
+
+// SecurityGroupVerdict is the decision of the security groups of a port on the packet.
+type SecurityGroupVerdict struct {
+	Direction Direction           `json:"direction"`
+	Action    v1alpha1.RuleAction `json:"action"`
+	Table     int                 `json:"table"`
+	// Match is the match of the flow of the rules which decided, it is empty when no rule matched.
+	Match  string `json:"match,omitempty"`
+	Cookie string `json:"cookie,omitempty"`
+}
+
+// Result is the parsed result of ofproto/trace.
+type Result struct {
+	Passes         []Pass                 `json:"passes"`
+	SecurityGroups []SecurityGroupVerdict `json:"securityGroups"`
+	// Verdict is Dropped when the last pass drops the packet.
+	Verdict         string `json:"verdict"`
+	DatapathActions string `json:"datapathActions"`
+	RawOutput       string `json:"rawOutput"`
+}
+
+// ProtocolFlow returns the fields of the traced packet for a protocol of
+// v1alpha1.SupportedProtocols and an optional destination port.
+func ProtocolFlow(protocol string, dstPort int, ipv6 bool) (string, error) {
+	if protocol == "" {
+		protocol = "ICMP"
+	}
+	proto, ok := v1alpha1.SupportedProtocols[strings.ToUpper(protocol)]
+	if !ok {
+		return "", fmt.Errorf("unsupported protocol %s", protocol)
+	}
+	flow := strings.ToLower(v1alpha1.ProtocolsToString[proto])
+	if ipv6 {
+		flow += "6"
+	}
+	if dstPort != 0 {
+		if proto == v1alpha1.ICMPProtocol {
+			return "", fmt.Errorf("destination port is not supported for ICMP")
+		}
+		flow += fmt.Sprintf(",tp_dst=%d", dstPort)
+	}
+	return flow, nil
+}
+
+// Trace traces the packet of the request and parses the output of ofproto/trace.
+func Trace(client ovsctl.OVSCtlClient, req *ovsctl.TracingRequest) (*Result, error) {
+	output, err := client.Trace(req)
+	if err != nil {
+		return nil, err
+	}
+	return newResult(output), nil
+}
+
+func newResult(output string) *Result {
+	result := &Result{
+		Passes:         parseTrace(output),
+		SecurityGroups: []SecurityGroupVerdict{},
+		RawOutput:      output,
+	}
+	if len(result.Passes) > 0 {
+		result.DatapathActions = result.Passes[len(result.Passes)-1].DatapathActions
+	}
+	result.Verdict = VerdictForwarded
+	if result.DatapathActions == "" || result.DatapathActions == "drop" {
+		result.Verdict = VerdictDropped
+	}
+
+	seen := map[Direction]bool{}
+	addVerdict := func(verdict SecurityGroupVerdict) {
+		if !seen[verdict.Direction] {
+			seen[verdict.Direction] = true
+			result.SecurityGroups = append(result.SecurityGroups, verdict)
+		}
+	}
+	// The rules which matched the packet in the last rules table of each direction, the accept and
+	// drop tables don't tell which rule sent the packet there.
+	var lastRule *Stage
+	for i := range result.Passes {
+		for j := range result.Passes[i].Stages {
+			stage := &result.Passes[i].Stages[j]
+			direction, isRules := rulesTableDirection(stage.Table)
+			switch {
+			case isRules && (stage.NoMatch || dropsPacket(stage)):
+				addVerdict(newVerdict(direction, v1alpha1.RuleActionDrop, stage))
+				lastRule = nil
+			case isRules:
+				lastRule = stage
+			case stage.Table == tableAcceptOrIngress || stage.Table == tableAcceptedEgress || stage.Table == tableAcceptedEgressNormal:
+				addVerdict(newVerdict(DirectionEgress, v1alpha1.RuleActionAllow, lastRuleOf(lastRule, DirectionEgress, stage)))
+			case stage.Table == tableAcceptedIngress:
+				addVerdict(newVerdict(DirectionIngress, v1alpha1.RuleActionAllow, lastRuleOf(lastRule, DirectionIngress, stage)))
+			case stage.Table == tableDropped:
+				direction := DirectionEgress
+				if lastRule != nil {
+					direction, _ = rulesTableDirection(lastRule.Table)
+				}
+				addVerdict(newVerdict(direction, v1alpha1.RuleActionDrop, lastRuleOf(lastRule, direction, stage)))
+			}
+		}
+	}
+	return result
+}
+
+// rulesTableDirection returns the direction of a table of the firewall which matches the rules.
+func rulesTableDirection(table int) (Direction, bool) {
+	switch table {
+	case tableBaseEgress, tableRulesEgress:
+		return DirectionEgress, true
+	case tableBaseIngress, tableRulesIngress:
+		return DirectionIngress, true
+	}
+	return "", false
+}
+
+// lastRuleOf returns the last stage of the rules of the direction, or else the stage.
+func lastRuleOf(lastRule *Stage, direction Direction, stage *Stage) *Stage {
+	if lastRule != nil {
+		if d, _ := rulesTableDirection(lastRule.Table); d == direction {
+			return lastRule
+		}
+	}
+	return stage
+}
+
+// dropsPacket returns whether the only action of the stage is drop. The conntrack stages also drop
+// the packet once it is recirculated.
+func dropsPacket(stage *Stage) bool {
+	if len(stage.Actions) == 0 {
+		return true
+	}
+	for _, action := range stage.Actions {
+		if action != "drop" {
+			return false
+		}
+	}
+	return true
+}
+
+func newVerdict(direction Direction, action v1alpha1.RuleAction, stage *Stage) SecurityGroupVerdict {
+	return SecurityGroupVerdict{
+		Direction: direction,
+		Action:    action,
+		Table:     stage.Table,
+		Match:     stage.Match,
+		Cookie:    stage.Cookie,
+	}
+}
@@ -0,0 +1,93 @@
+package traceflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"projectkuryr/kuryr/pkg/apis/openstack/v1alpha1"
+)
+
+const testTrace = `Flow: icmp,in_port=5,vlan_tci=0x0000,dl_src=fa:16:3e:00:00:01,dl_dst=fa:16:3e:00:00:02,nw_src=10.0.0.1,nw_dst=10.0.0.2,nw_tos=0,nw_ecn=0,nw_ttl=64,icmp_type=0,icmp_code=0
+
+bridge("br-int")
+----------------
+ 0. in_port=5, priority 100, cookie 0x1
+    set_field:0x5->reg5
+    set_field:0x1->reg6
+    resubmit(,71)
+71. ip,reg5=0x5,in_port=5,dl_src=fa:16:3e:00:00:01,nw_src=10.0.0.1, priority 65, cookie 0x2
+    ct(table=72,zone=NXM_NX_REG6[0..15])
+    drop
+     -> A clone of the packet is forked to recirculate. The forked pipeline will be resumed at table 72.
+
+Final flow: unchanged
+Megaflow: recirc_id=0,eth,ip,in_port=5,nw_frag=no
+Datapath actions: ct(zone=1),recirc(0x1)
+
+===============================================================================
+recirc(0x1) - resume conntrack with default ct_state=trk|new (use --ct-next to customize)
+===============================================================================
+
+Flow: recirc_id=0x1,ct_state=new|trk,ct_zone=1,eth,icmp,reg5=0x5,reg6=0x1,in_port=5,nw_src=10.0.0.1,nw_dst=10.0.0.2
+
+bridge("br-int")
+----------------
+    thaw
+        Resuming from table 72
+72. ct_state=+new-est,icmp,reg5=0x5, priority 77, cookie 0x3
+    resubmit(,73)
+73. reg6=0x1,dl_dst=fa:16:3e:00:00:02, priority 100, cookie 0x4
+    set_field:0x6->reg7
+    resubmit(,81)
+81. ct_state=-trk,ip,reg5=0x6, priority 90, cookie 0x5
+    ct(table=82,zone=NXM_NX_REG6[0..15])
+    drop
+82. No match.
+    drop
+
+Final flow: unchanged
+Megaflow: recirc_id=0x1,ct_state=+new-est+trk,eth,icmp,in_port=5
+Datapath actions: drop
+`
+
+func TestParseTrace(t *testing.T) {
+	result := newResult(testTrace)
+	require.Len(t, result.Passes, 2)
+
+	first := result.Passes[0]
+	assert.Empty(t, first.Header)
+	require.Len(t, first.Stages, 2)
+	assert.Equal(t, Stage{
+		Bridge: "br-int", Table: 71, TableName: "BASE_EGRESS", Match: "ip,reg5=0x5,in_port=5,dl_src=fa:16:3e:00:00:01,nw_src=10.0.0.1",
+		Priority: 65, Cookie: "0x2", Actions: []string{"ct(table=72,zone=NXM_NX_REG6[0..15])", "drop"},
+		Notes: []string{"A clone of the packet is forked to recirculate. The forked pipeline will be resumed at table 72."},
+	}, first.Stages[1])
+	assert.Equal(t, "ct(zone=1),recirc(0x1)", first.DatapathActions)
+
+	second := result.Passes[1]
+	assert.Contains(t, second.Header, "recirc(0x1)")
+	require.Len(t, second.Stages, 4)
+	assert.Equal(t, 72, second.Stages[0].Table)
+	assert.True(t, second.Stages[3].NoMatch)
+
+	assert.Equal(t, VerdictDropped, result.Verdict)
+	assert.Equal(t, []SecurityGroupVerdict{
+		{Direction: DirectionEgress, Action: v1alpha1.RuleActionAllow, Table: 72, Match: "ct_state=+new-est,icmp,reg5=0x5", Cookie: "0x3"},
+		{Direction: DirectionIngress, Action: v1alpha1.RuleActionDrop, Table: 82},
+	}, result.SecurityGroups)
+}
+
+func TestProtocolFlow(t *testing.T) {
+	flow, err := ProtocolFlow("", 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, "icmp", flow)
+	flow, err = ProtocolFlow("tcp", 80, true)
+	require.NoError(t, err)
+	assert.Equal(t, "tcp6,tp_dst=80", flow)
+	_, err = ProtocolFlow("ICMP", 80, false)
+	assert.Error(t, err)
+	_, err = ProtocolFlow("GRE", 0, false)
+	assert.Error(t, err)
+}
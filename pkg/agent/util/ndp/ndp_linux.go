@@ -0,0 +1,67 @@
+package ndp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	// naFlagOverride asks the receivers to replace the cached link-layer address.
+	naFlagOverride = 0x20000000
+	// optTargetLinkLayerAddress is the NDP option carrying the MAC address of the target.
+	optTargetLinkLayerAddress = 2
+	// ndpHopLimit must be used by all NDP messages, receivers drop the others.
+	ndpHopLimit = 255
+)
+
+// NeighborAdvertisement sends an unsolicited neighbor advertisement for srcIP to all the nodes of
+// the link of 'iface', so that the neighbors update the MAC address they cache for srcIP.
+func NeighborAdvertisement(srcIP net.IP, iface *net.Interface) error {
+	if srcIP.To4() != nil || srcIP.To16() == nil {
+		return fmt.Errorf("%s is not an IPv6 address", srcIP)
+	}
+	msg, err := newNeighborAdvertisement(srcIP, iface.HardwareAddr)
+	if err != nil {
+		return err
+	}
+
+	srcAddr := srcIP.String()
+	if srcIP.IsLinkLocalUnicast() {
+		srcAddr += "%" + iface.Name
+	}
+	conn, err := icmp.ListenPacket("ip6:ipv6-icmp", srcAddr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	pc := conn.IPv6PacketConn()
+	if err := pc.SetMulticastInterface(iface); err != nil {
+		return err
+	}
+	if err := pc.SetMulticastHopLimit(ndpHopLimit); err != nil {
+		return err
+	}
+	// The checksum is computed by the kernel for ICMPv6 raw sockets.
+	_, err = conn.WriteTo(msg, &net.IPAddr{IP: net.IPv6linklocalallnodes, Zone: iface.Name})
+	return err
+}
+
+func newNeighborAdvertisement(target net.IP, mac net.HardwareAddr) ([]byte, error) {
+	body := bytes.NewBuffer(nil)
+	binary.Write(body, binary.BigEndian, uint32(naFlagOverride)) // Flags and reserved.
+	body.Write(target.To16())                                    // Target address.
+	// Target link-layer address option, its length is in units of 8 bytes.
+	body.Write([]byte{optTargetLinkLayerAddress, uint8((2 + len(mac)) / 8)})
+	body.Write(mac)
+	msg := icmp.Message{
+		Type: ipv6.ICMPTypeNeighborAdvertisement,
+		Code: 0,
+		Body: &icmp.RawBody{Data: body.Bytes()},
+	}
+	return msg.Marshal(nil)
+}
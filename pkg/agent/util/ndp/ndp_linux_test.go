@@ -0,0 +1,24 @@
+package ndp
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestNewNeighborAdvertisement(t *testing.T) {
+	mac, _ := net.ParseMAC("42:af:b8:14:cb:4e")
+	got, err := newNeighborAdvertisement(net.ParseIP("fd00:10::5"), mac)
+	if err != nil {
+		t.Fatalf("newNeighborAdvertisement() error = %v", err)
+	}
+	want := []byte{
+		0x88, 0x00, 0x00, 0x00, 0x20, 0x00, 0x00, 0x00,
+		0xfd, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05,
+		0x02, 0x01, 0x42, 0xaf, 0xb8, 0x14, 0xcb, 0x4e,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newNeighborAdvertisement() = %v, want %v", got, want)
+	}
+}
@@ -39,9 +39,12 @@ const (
 	ErrorCode_DECODING_FAILURE              ErrorCode = 6
 	ErrorCode_INVALID_NETWORK_CONFIG        ErrorCode = 7
 	ErrorCode_TRY_AGAIN_LATER               ErrorCode = 11
-	ErrorCode_IPAM_FAILURE                  ErrorCode = 101
-	ErrorCode_CONFIG_INTERFACE_FAILURE      ErrorCode = 102
-	ErrorCode_CHECK_INTERFACE_FAILURE       ErrorCode = 103
+	// returned by STATUS when the plugin cannot serve ADD requests.
+	ErrorCode_PLUGIN_NOT_AVAILABLE                      ErrorCode = 50
+	ErrorCode_PLUGIN_NOT_AVAILABLE_LIMITED_CONNECTIVITY ErrorCode = 51
+	ErrorCode_IPAM_FAILURE                              ErrorCode = 101
+	ErrorCode_CONFIG_INTERFACE_FAILURE                  ErrorCode = 102
+	ErrorCode_CHECK_INTERFACE_FAILURE                   ErrorCode = 103
 	// these errors are not used by the servers, but we declare them here to
 	// make sure they are reserved.
 	ErrorCode_UNKNOWN_RPC_ERROR        ErrorCode = 201
@@ -58,6 +61,8 @@ var ErrorCode_name = map[int32]string{
 	6:   "DECODING_FAILURE",
 	7:   "INVALID_NETWORK_CONFIG",
 	11:  "TRY_AGAIN_LATER",
+	50:  "PLUGIN_NOT_AVAILABLE",
+	51:  "PLUGIN_NOT_AVAILABLE_LIMITED_CONNECTIVITY",
 	101: "IPAM_FAILURE",
 	102: "CONFIG_INTERFACE_FAILURE",
 	103: "CHECK_INTERFACE_FAILURE",
@@ -66,20 +71,22 @@ var ErrorCode_name = map[int32]string{
 }
 
 var ErrorCode_value = map[string]int32{
-	"UNKNOWN":                       0,
-	"INCOMPATIBLE_CNI_VERSION":      1,
-	"UNSUPPORTED_FIELD":             2,
-	"UNKNOWN_CONTAINER":             3,
-	"INVALID_ENVIRONMENT_VARIABLES": 4,
-	"IO_FAILURE":                    5,
-	"DECODING_FAILURE":              6,
-	"INVALID_NETWORK_CONFIG":        7,
-	"TRY_AGAIN_LATER":               11,
-	"IPAM_FAILURE":                  101,
-	"CONFIG_INTERFACE_FAILURE":      102,
-	"CHECK_INTERFACE_FAILURE":       103,
-	"UNKNOWN_RPC_ERROR":             201,
-	"INCOMPATIBLE_API_VERSION":      202,
+	"UNKNOWN":                                   0,
+	"INCOMPATIBLE_CNI_VERSION":                  1,
+	"UNSUPPORTED_FIELD":                         2,
+	"UNKNOWN_CONTAINER":                         3,
+	"INVALID_ENVIRONMENT_VARIABLES":             4,
+	"IO_FAILURE":                                5,
+	"DECODING_FAILURE":                          6,
+	"INVALID_NETWORK_CONFIG":                    7,
+	"TRY_AGAIN_LATER":                           11,
+	"PLUGIN_NOT_AVAILABLE":                      50,
+	"PLUGIN_NOT_AVAILABLE_LIMITED_CONNECTIVITY": 51,
+	"IPAM_FAILURE":                              101,
+	"CONFIG_INTERFACE_FAILURE":                  102,
+	"CHECK_INTERFACE_FAILURE":                   103,
+	"UNKNOWN_RPC_ERROR":                         201,
+	"INCOMPATIBLE_API_VERSION":                  202,
 }
 
 func (x ErrorCode) String() string {
@@ -310,60 +317,173 @@ func (m *CniCmdResponse) GetError() *Error {
 	return nil
 }
 
+type CniCapabilitiesRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CniCapabilitiesRequest) Reset()         { *m = CniCapabilitiesRequest{} }
+func (m *CniCapabilitiesRequest) String() string { return proto.CompactTextString(m) }
+func (*CniCapabilitiesRequest) ProtoMessage()    {}
+func (*CniCapabilitiesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e9dc6d0da63f0ae4, []int{4}
+}
+
+func (m *CniCapabilitiesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CniCapabilitiesRequest.Unmarshal(m, b)
+}
+func (m *CniCapabilitiesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CniCapabilitiesRequest.Marshal(b, m, deterministic)
+}
+func (m *CniCapabilitiesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CniCapabilitiesRequest.Merge(m, src)
+}
+func (m *CniCapabilitiesRequest) XXX_Size() int {
+	return xxx_messageInfo_CniCapabilitiesRequest.Size(m)
+}
+func (m *CniCapabilitiesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CniCapabilitiesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CniCapabilitiesRequest proto.InternalMessageInfo
+
+type CniCapabilitiesResponse struct {
+	// Semantic version of the CNI gRPC service implemented by the server.
+	ApiVersion string `protobuf:"bytes,1,opt,name=api_version,json=apiVersion,proto3" json:"api_version,omitempty"`
+	// Versions of the CNI spec supported by the server.
+	SupportedCniVersions []string `protobuf:"bytes,2,rep,name=supported_cni_versions,json=supportedCniVersions,proto3" json:"supported_cni_versions,omitempty"`
+	// Optional features supported by the server.
+	Features []string `protobuf:"bytes,3,rep,name=features,proto3" json:"features,omitempty"`
+	// Changes every time the server is started, so that clients can cache
+	// the capabilities until the server is restarted.
+	BootId               string   `protobuf:"bytes,4,opt,name=boot_id,json=bootId,proto3" json:"boot_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CniCapabilitiesResponse) Reset()         { *m = CniCapabilitiesResponse{} }
+func (m *CniCapabilitiesResponse) String() string { return proto.CompactTextString(m) }
+func (*CniCapabilitiesResponse) ProtoMessage()    {}
+func (*CniCapabilitiesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e9dc6d0da63f0ae4, []int{5}
+}
+
+func (m *CniCapabilitiesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CniCapabilitiesResponse.Unmarshal(m, b)
+}
+func (m *CniCapabilitiesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CniCapabilitiesResponse.Marshal(b, m, deterministic)
+}
+func (m *CniCapabilitiesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CniCapabilitiesResponse.Merge(m, src)
+}
+func (m *CniCapabilitiesResponse) XXX_Size() int {
+	return xxx_messageInfo_CniCapabilitiesResponse.Size(m)
+}
+func (m *CniCapabilitiesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CniCapabilitiesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CniCapabilitiesResponse proto.InternalMessageInfo
+
+func (m *CniCapabilitiesResponse) GetApiVersion() string {
+	if m != nil {
+		return m.ApiVersion
+	}
+	return ""
+}
+
+func (m *CniCapabilitiesResponse) GetSupportedCniVersions() []string {
+	if m != nil {
+		return m.SupportedCniVersions
+	}
+	return nil
+}
+
+func (m *CniCapabilitiesResponse) GetFeatures() []string {
+	if m != nil {
+		return m.Features
+	}
+	return nil
+}
+
+func (m *CniCapabilitiesResponse) GetBootId() string {
+	if m != nil {
+		return m.BootId
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterEnum("kuryr.pkg.apis.cni.v1alpha1.ErrorCode", ErrorCode_name, ErrorCode_value)
 	proto.RegisterType((*CniCmdArgs)(nil), "kuryr.pkg.apis.cni.v1alpha1.CniCmdArgs")
 	proto.RegisterType((*CniCmdRequest)(nil), "kuryr.pkg.apis.cni.v1alpha1.CniCmdRequest")
 	proto.RegisterType((*Error)(nil), "kuryr.pkg.apis.cni.v1alpha1.Error")
 	proto.RegisterType((*CniCmdResponse)(nil), "kuryr.pkg.apis.cni.v1alpha1.CniCmdResponse")
+	proto.RegisterType((*CniCapabilitiesRequest)(nil), "kuryr.pkg.apis.cni.v1alpha1.CniCapabilitiesRequest")
+	proto.RegisterType((*CniCapabilitiesResponse)(nil), "kuryr.pkg.apis.cni.v1alpha1.CniCapabilitiesResponse")
 }
 
 func init() { proto.RegisterFile("pkg/apis/cni/v1alpha1/cni.proto", fileDescriptor_e9dc6d0da63f0ae4) }
 
 var fileDescriptor_e9dc6d0da63f0ae4 = []byte{
-	// 671 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x53, 0xdd, 0x4e, 0xdb, 0x48,
-	0x14, 0xc6, 0xf9, 0x85, 0x93, 0x2c, 0xeb, 0x9d, 0x0d, 0xac, 0x17, 0x16, 0x2d, 0xe4, 0x62, 0x17,
-	0xb1, 0x92, 0x23, 0xc2, 0xcd, 0xaa, 0x77, 0xce, 0x64, 0x42, 0x47, 0x84, 0x71, 0x34, 0x71, 0x82,
-	0xda, 0x1b, 0xcb, 0xd8, 0x13, 0x63, 0x25, 0x19, 0xa7, 0xb6, 0xd3, 0x8a, 0x87, 0xe8, 0xeb, 0xf4,
-	0xa2, 0x6f, 0xd0, 0x3e, 0x46, 0x9f, 0xa4, 0xb2, 0x9d, 0x84, 0xaa, 0xaa, 0x28, 0x37, 0xdc, 0x9d,
-	0xf3, 0x7d, 0xe7, 0xef, 0x3b, 0x73, 0x06, 0xfe, 0x5e, 0x4c, 0xfd, 0x96, 0xb3, 0x08, 0xe2, 0x96,
-	0x2b, 0x83, 0xd6, 0xdb, 0x73, 0x67, 0xb6, 0xb8, 0x73, 0xce, 0x53, 0x47, 0x5f, 0x44, 0x61, 0x12,
-	0xa2, 0xc3, 0xe9, 0x32, 0xba, 0x8f, 0xf4, 0xc5, 0xd4, 0xd7, 0xd3, 0x30, 0x3d, 0x65, 0xd6, 0x61,
-	0x07, 0x7f, 0xfa, 0x61, 0xe8, 0xcf, 0x44, 0x2b, 0x0b, 0xbd, 0x5d, 0x4e, 0x5a, 0x8e, 0xbc, 0xcf,
-	0xf3, 0x9a, 0x1f, 0x15, 0x00, 0x2c, 0x03, 0x3c, 0xf7, 0x8c, 0xc8, 0x8f, 0xd1, 0x09, 0xd4, 0xdd,
-	0x50, 0x26, 0x4e, 0x20, 0x45, 0x64, 0x07, 0x9e, 0xa6, 0x1c, 0x2b, 0xa7, 0x3b, 0xbc, 0xb6, 0xc1,
-	0xa8, 0x87, 0x1a, 0x50, 0x96, 0x22, 0x91, 0xb1, 0x56, 0xc8, 0xb8, 0xdc, 0x41, 0xfb, 0x50, 0x09,
-	0x26, 0xd2, 0x99, 0x0b, 0xad, 0x98, 0xc1, 0x2b, 0x0f, 0x21, 0x28, 0x39, 0x91, 0x1f, 0x6b, 0xa5,
-	0x0c, 0xcd, 0xec, 0x14, 0x5b, 0x38, 0xc9, 0x9d, 0x56, 0xce, 0xb1, 0xd4, 0x46, 0x17, 0xb0, 0x27,
-	0x45, 0xf2, 0x2e, 0x8c, 0xa6, 0xb6, 0x1b, 0xca, 0x49, 0xe0, 0x2f, 0x23, 0x27, 0x09, 0x42, 0xa9,
-	0x55, 0x8e, 0x95, 0xd3, 0x3a, 0x6f, 0xac, 0x48, 0xfc, 0x2d, 0xd7, 0x1c, 0xc2, 0x2f, 0xf9, 0xec,
-	0x5c, 0xbc, 0x59, 0x8a, 0x38, 0x41, 0x1d, 0xd8, 0x76, 0x65, 0x60, 0x67, 0x1d, 0xd3, 0xd1, 0x6b,
-	0xed, 0x7f, 0xf5, 0x47, 0x16, 0xa3, 0x3f, 0x28, 0xe7, 0x55, 0x57, 0x06, 0xa9, 0xd1, 0x7c, 0xaf,
-	0x40, 0x99, 0x44, 0x51, 0x18, 0xa1, 0x17, 0x50, 0x72, 0x43, 0x4f, 0x64, 0x95, 0x76, 0xdb, 0xff,
-	0x3c, 0x5a, 0x29, 0xcb, 0xc0, 0xa1, 0x27, 0x78, 0x96, 0x83, 0x34, 0xa8, 0xce, 0x45, 0x1c, 0x3b,
-	0xbe, 0x58, 0xed, 0x69, 0xed, 0x22, 0x1d, 0xaa, 0x9e, 0x48, 0x9c, 0x60, 0x16, 0x6b, 0xc5, 0xe3,
-	0xe2, 0x69, 0xad, 0xdd, 0xd0, 0xf3, 0xe7, 0xd1, 0xd7, 0xcf, 0xa3, 0x1b, 0xf2, 0x9e, 0xaf, 0x83,
-	0x9a, 0x01, 0xec, 0xae, 0x45, 0xc6, 0x8b, 0x50, 0xc6, 0x02, 0x1d, 0x01, 0xa4, 0x2a, 0x23, 0x11,
-	0x2f, 0x67, 0x49, 0x36, 0x5d, 0x9d, 0xef, 0xb8, 0x32, 0xe0, 0x19, 0x80, 0xfe, 0x87, 0xb2, 0x48,
-	0xa7, 0xc9, 0x1a, 0xd7, 0xda, 0xcd, 0x9f, 0xcf, 0xcd, 0xf3, 0x84, 0xb3, 0x2f, 0x05, 0xd8, 0xd9,
-	0x08, 0x41, 0x35, 0xa8, 0x8e, 0xd8, 0x15, 0x33, 0x6f, 0x98, 0xba, 0x85, 0xfe, 0x02, 0x8d, 0x32,
-	0x6c, 0x5e, 0x0f, 0x0c, 0x8b, 0x76, 0xfa, 0xc4, 0xc6, 0x8c, 0xda, 0x63, 0xc2, 0x87, 0xd4, 0x64,
-	0xaa, 0x82, 0xf6, 0xe0, 0xb7, 0x11, 0x1b, 0x8e, 0x06, 0x03, 0x93, 0x5b, 0xa4, 0x6b, 0xf7, 0x28,
-	0xe9, 0x77, 0xd5, 0x42, 0x0e, 0x67, 0x15, 0x6c, 0x6c, 0x32, 0xcb, 0xa0, 0x8c, 0x70, 0xb5, 0x88,
-	0x4e, 0xe0, 0x88, 0xb2, 0xb1, 0xd1, 0xa7, 0x5d, 0x9b, 0xb0, 0x31, 0xe5, 0x26, 0xbb, 0x26, 0xcc,
-	0xb2, 0xc7, 0x06, 0xa7, 0x46, 0xa7, 0x4f, 0x86, 0x6a, 0x09, 0xed, 0x02, 0x50, 0xd3, 0xee, 0x19,
-	0xb4, 0x3f, 0xe2, 0x44, 0x2d, 0xa3, 0x06, 0xa8, 0x5d, 0x82, 0xcd, 0x2e, 0x65, 0x97, 0x1b, 0xb4,
-	0x82, 0x0e, 0x60, 0x7f, 0x5d, 0x88, 0x11, 0xeb, 0xc6, 0xe4, 0x57, 0x69, 0x9f, 0x1e, 0xbd, 0x54,
-	0xab, 0xe8, 0x77, 0xf8, 0xd5, 0xe2, 0xaf, 0x6c, 0xe3, 0xd2, 0xa0, 0xcc, 0xee, 0x1b, 0x16, 0xe1,
-	0x6a, 0x0d, 0xa9, 0x50, 0xa7, 0x03, 0xe3, 0x7a, 0x53, 0x42, 0xa4, 0xba, 0xf2, 0x14, 0x9b, 0x32,
-	0x8b, 0xf0, 0x9e, 0x81, 0xc9, 0x86, 0x9d, 0xa0, 0x43, 0xf8, 0x03, 0xbf, 0x24, 0xf8, 0xea, 0x07,
-	0xa4, 0x8f, 0xf6, 0x1f, 0xd4, 0xf1, 0x01, 0xb6, 0x09, 0xe7, 0x26, 0x57, 0x3f, 0x29, 0xe8, 0xe8,
-	0xbb, 0x55, 0x19, 0x83, 0x87, 0x55, 0x7d, 0x56, 0xda, 0x1f, 0x0a, 0x50, 0xc4, 0x32, 0x40, 0x2e,
-	0x54, 0xd2, 0xdb, 0xf3, 0x3c, 0x74, 0xf6, 0x84, 0x1b, 0x5d, 0x5d, 0xf8, 0xc1, 0x7f, 0x4f, 0x8a,
-	0xcd, 0x0f, 0xa5, 0xb9, 0x85, 0x04, 0x6c, 0xe3, 0xb9, 0x87, 0xef, 0x84, 0x3b, 0x7d, 0xce, 0x36,
-	0xb9, 0x96, 0xae, 0x98, 0x3d, 0x63, 0x93, 0x0e, 0xbc, 0xde, 0x5e, 0x93, 0xb7, 0x95, 0xec, 0xaf,
-	0x5c, 0x7c, 0x0d, 0x00, 0x00, 0xff, 0xff, 0xfd, 0x11, 0xeb, 0x67, 0x18, 0x05, 0x00, 0x00,
+	// 856 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x54, 0xc1, 0x72, 0xdb, 0x36,
+	0x13, 0x8e, 0x22, 0x4b, 0xb2, 0x56, 0xfe, 0x1d, 0xfe, 0xa8, 0x62, 0xb3, 0x4a, 0x3d, 0x71, 0x74,
+	0x68, 0xdd, 0x74, 0x4a, 0x4d, 0xe4, 0x1c, 0x3a, 0xbd, 0xd1, 0x10, 0xad, 0x62, 0x2c, 0x83, 0x1a,
+	0x88, 0x52, 0x26, 0xbd, 0x60, 0x28, 0x12, 0x92, 0x31, 0x92, 0x40, 0x96, 0xa4, 0xd2, 0xf1, 0xa5,
+	0x87, 0xde, 0xfb, 0x18, 0x3d, 0xf5, 0x0d, 0xfa, 0x06, 0xed, 0x53, 0x75, 0x40, 0x4a, 0x72, 0xa6,
+	0xc9, 0x24, 0xe9, 0xc1, 0x37, 0xec, 0xf7, 0xed, 0xee, 0xb7, 0xbb, 0x58, 0x00, 0x9e, 0xc6, 0x8b,
+	0x79, 0xc7, 0x8f, 0x65, 0xda, 0x09, 0x94, 0xec, 0xbc, 0x79, 0xe1, 0x2f, 0xe3, 0x1b, 0xff, 0x85,
+	0x36, 0xac, 0x38, 0x89, 0xb2, 0x08, 0x3d, 0x59, 0xac, 0x93, 0xdb, 0xc4, 0x8a, 0x17, 0x73, 0x4b,
+	0xbb, 0x59, 0x9a, 0xd9, 0xba, 0xb5, 0x3e, 0x9f, 0x47, 0xd1, 0x7c, 0x29, 0x3a, 0xb9, 0xeb, 0x74,
+	0x3d, 0xeb, 0xf8, 0xea, 0xb6, 0x88, 0x6b, 0xff, 0x59, 0x02, 0xc0, 0x4a, 0xe2, 0x55, 0x68, 0x27,
+	0xf3, 0x14, 0x3d, 0x83, 0x83, 0x20, 0x52, 0x99, 0x2f, 0x95, 0x48, 0xb8, 0x0c, 0xcd, 0xd2, 0x69,
+	0xe9, 0xac, 0xce, 0x1a, 0x3b, 0x8c, 0x84, 0xa8, 0x09, 0x15, 0x25, 0x32, 0x95, 0x9a, 0x0f, 0x73,
+	0xae, 0x30, 0xd0, 0x11, 0x54, 0xe5, 0x4c, 0xf9, 0x2b, 0x61, 0x96, 0x73, 0x78, 0x63, 0x21, 0x04,
+	0x7b, 0x7e, 0x32, 0x4f, 0xcd, 0xbd, 0x1c, 0xcd, 0xcf, 0x1a, 0x8b, 0xfd, 0xec, 0xc6, 0xac, 0x14,
+	0x98, 0x3e, 0xa3, 0x73, 0x78, 0xac, 0x44, 0xf6, 0x73, 0x94, 0x2c, 0x78, 0x10, 0xa9, 0x99, 0x9c,
+	0xaf, 0x13, 0x3f, 0x93, 0x91, 0x32, 0xab, 0xa7, 0xa5, 0xb3, 0x03, 0xd6, 0xdc, 0x90, 0xf8, 0x6d,
+	0xae, 0x3d, 0x82, 0xff, 0x15, 0xb5, 0x33, 0xf1, 0xd3, 0x5a, 0xa4, 0x19, 0xba, 0x80, 0xfd, 0x40,
+	0x49, 0x9e, 0x2b, 0xea, 0xd2, 0x1b, 0xdd, 0xaf, 0xac, 0x0f, 0x0c, 0xc6, 0xba, 0xeb, 0x9c, 0xd5,
+	0x02, 0x25, 0xf5, 0xa1, 0xfd, 0x5b, 0x09, 0x2a, 0x4e, 0x92, 0x44, 0x09, 0xfa, 0x1e, 0xf6, 0x82,
+	0x28, 0x14, 0x79, 0xa6, 0xc3, 0xee, 0x97, 0x1f, 0xcc, 0x94, 0x47, 0xe0, 0x28, 0x14, 0x2c, 0x8f,
+	0x41, 0x26, 0xd4, 0x56, 0x22, 0x4d, 0xfd, 0xb9, 0xd8, 0xcc, 0x69, 0x6b, 0x22, 0x0b, 0x6a, 0xa1,
+	0xc8, 0x7c, 0xb9, 0x4c, 0xcd, 0xf2, 0x69, 0xf9, 0xac, 0xd1, 0x6d, 0x5a, 0xc5, 0xf5, 0x58, 0xdb,
+	0xeb, 0xb1, 0x6c, 0x75, 0xcb, 0xb6, 0x4e, 0x6d, 0x09, 0x87, 0xdb, 0x26, 0xd3, 0x38, 0x52, 0xa9,
+	0x40, 0x27, 0x00, 0xba, 0xcb, 0x44, 0xa4, 0xeb, 0x65, 0x96, 0x57, 0x77, 0xc0, 0xea, 0x81, 0x92,
+	0x2c, 0x07, 0xd0, 0x77, 0x50, 0x11, 0xba, 0x9a, 0x5c, 0xb8, 0xd1, 0x6d, 0x7f, 0xbc, 0x6e, 0x56,
+	0x04, 0xb4, 0x4d, 0x38, 0xd2, 0x52, 0x7e, 0xec, 0x4f, 0xe5, 0x52, 0x66, 0x52, 0xa4, 0x9b, 0xc1,
+	0xb6, 0x7f, 0x2f, 0xc1, 0xf1, 0x3b, 0xd4, 0xa6, 0x9c, 0xa7, 0xd0, 0xf0, 0x63, 0xc9, 0xdf, 0x88,
+	0x24, 0xd5, 0x17, 0x56, 0xac, 0x0c, 0xf8, 0xb1, 0x9c, 0x14, 0x08, 0x7a, 0x09, 0x47, 0xe9, 0x3a,
+	0x8e, 0xa3, 0x24, 0x13, 0x21, 0x0f, 0xd4, 0xce, 0x55, 0xaf, 0x50, 0xf9, 0xac, 0xce, 0x9a, 0x3b,
+	0x16, 0xab, 0x6d, 0x50, 0x8a, 0x5a, 0xb0, 0x3f, 0x13, 0x7e, 0xb6, 0x4e, 0x44, 0x31, 0xa8, 0x3a,
+	0xdb, 0xd9, 0xe8, 0x18, 0x6a, 0xd3, 0x28, 0xca, 0xf4, 0x86, 0x16, 0x8b, 0x55, 0xd5, 0x26, 0x09,
+	0x9f, 0xff, 0x51, 0x86, 0xfa, 0xee, 0x2a, 0x50, 0x03, 0x6a, 0x63, 0x7a, 0x45, 0xdd, 0x57, 0xd4,
+	0x78, 0x80, 0xbe, 0x00, 0x93, 0x50, 0xec, 0x5e, 0x0f, 0x6d, 0x8f, 0x5c, 0x0c, 0x1c, 0x8e, 0x29,
+	0xe1, 0x13, 0x87, 0x8d, 0x88, 0x4b, 0x8d, 0x12, 0x7a, 0x0c, 0xff, 0x1f, 0xd3, 0xd1, 0x78, 0x38,
+	0x74, 0x99, 0xe7, 0xf4, 0xf8, 0x25, 0x71, 0x06, 0x3d, 0xe3, 0x61, 0x01, 0xe7, 0x19, 0x38, 0x76,
+	0xa9, 0x67, 0x13, 0xea, 0x30, 0xa3, 0x8c, 0x9e, 0xc1, 0x09, 0xa1, 0x13, 0x7b, 0x40, 0x7a, 0xdc,
+	0xa1, 0x13, 0xc2, 0x5c, 0x7a, 0xed, 0x50, 0x8f, 0x4f, 0x6c, 0x46, 0xec, 0x8b, 0x81, 0x33, 0x32,
+	0xf6, 0xd0, 0x21, 0x00, 0x71, 0xf9, 0xa5, 0x4d, 0x06, 0x63, 0xe6, 0x18, 0x15, 0xd4, 0x04, 0xa3,
+	0xe7, 0x60, 0xb7, 0x47, 0x68, 0x7f, 0x87, 0x56, 0x51, 0x0b, 0x8e, 0xb6, 0x89, 0xa8, 0xe3, 0xbd,
+	0x72, 0xd9, 0x95, 0xd6, 0xb9, 0x24, 0x7d, 0xa3, 0x86, 0x3e, 0x83, 0x47, 0x1e, 0x7b, 0xcd, 0xed,
+	0xbe, 0x4d, 0x28, 0x1f, 0xd8, 0x9e, 0xc3, 0x8c, 0x06, 0x32, 0xa1, 0x39, 0x1c, 0x8c, 0xfb, 0x84,
+	0x72, 0xea, 0x7a, 0xdc, 0x9e, 0xd8, 0x64, 0xa0, 0x15, 0x8d, 0x2e, 0xfa, 0x16, 0xbe, 0x7e, 0x1f,
+	0xc3, 0x07, 0xe4, 0x9a, 0xe8, 0x96, 0xb0, 0x4b, 0xa9, 0x83, 0x3d, 0x32, 0x21, 0xde, 0x6b, 0xe3,
+	0x1c, 0x19, 0x70, 0x40, 0x86, 0xf6, 0xf5, 0xae, 0x16, 0xa1, 0x07, 0x54, 0x68, 0x73, 0x42, 0x3d,
+	0x87, 0x5d, 0xda, 0xd8, 0xd9, 0xb1, 0x33, 0xf4, 0x04, 0x8e, 0xf1, 0x0f, 0x0e, 0xbe, 0x7a, 0x0f,
+	0x39, 0x47, 0x47, 0x77, 0x63, 0x62, 0x43, 0xcc, 0x1d, 0xc6, 0x5c, 0x66, 0xfc, 0x55, 0x42, 0x27,
+	0xff, 0x9a, 0xb9, 0x3d, 0xbc, 0x9b, 0xf9, 0xdf, 0xa5, 0xee, 0xaf, 0x15, 0x28, 0x63, 0x25, 0x51,
+	0x00, 0x55, 0xfd, 0x0c, 0xc3, 0x10, 0x3d, 0xff, 0x84, 0xe7, 0xba, 0xd9, 0xc9, 0xd6, 0x37, 0x9f,
+	0xe4, 0x5b, 0x2c, 0x69, 0xfb, 0x01, 0x12, 0xb0, 0x8f, 0x57, 0x21, 0xbe, 0x11, 0xc1, 0xe2, 0x3e,
+	0x65, 0x8a, 0x5e, 0x7a, 0x62, 0x79, 0x9f, 0x22, 0x33, 0xa8, 0xe3, 0x55, 0x38, 0xca, 0xfc, 0x6c,
+	0x9d, 0xde, 0xa7, 0xce, 0x14, 0x2a, 0x78, 0x15, 0xf6, 0xf1, 0x7d, 0x6a, 0xfc, 0x02, 0x8f, 0xfa,
+	0x22, 0x7b, 0xfb, 0x67, 0x41, 0xe7, 0x1f, 0xcd, 0xf0, 0xee, 0x17, 0xd5, 0x7a, 0xf9, 0xdf, 0x82,
+	0xb6, 0xfa, 0x17, 0xf0, 0xe3, 0xfe, 0xd6, 0x6b, 0x5a, 0xcd, 0xbf, 0xe0, 0xf3, 0x7f, 0x06, 0x00,
+	0x26, 0x53, 0xcf, 0x70, 0x6f, 0x07, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -381,6 +501,10 @@ type CniClient interface {
 	CmdAdd(ctx context.Context, in *CniCmdRequest, opts ...grpc.CallOption) (*CniCmdResponse, error)
 	CmdCheck(ctx context.Context, in *CniCmdRequest, opts ...grpc.CallOption) (*CniCmdResponse, error)
 	CmdDel(ctx context.Context, in *CniCmdRequest, opts ...grpc.CallOption) (*CniCmdResponse, error)
+	CmdStatus(ctx context.Context, in *CniCmdRequest, opts ...grpc.CallOption) (*CniCmdResponse, error)
+	// The valid attachments are read from the network configuration.
+	CmdGC(ctx context.Context, in *CniCmdRequest, opts ...grpc.CallOption) (*CniCmdResponse, error)
+	GetCapabilities(ctx context.Context, in *CniCapabilitiesRequest, opts ...grpc.CallOption) (*CniCapabilitiesResponse, error)
 }
 
 type cniClient struct {
@@ -418,11 +542,42 @@ func (c *cniClient) CmdDel(ctx context.Context, in *CniCmdRequest, opts ...grpc.
 	return out, nil
 }
 
+func (c *cniClient) CmdStatus(ctx context.Context, in *CniCmdRequest, opts ...grpc.CallOption) (*CniCmdResponse, error) {
+	out := new(CniCmdResponse)
+	err := c.cc.Invoke(ctx, "/kuryr.pkg.apis.cni.v1alpha1.Cni/CmdStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cniClient) CmdGC(ctx context.Context, in *CniCmdRequest, opts ...grpc.CallOption) (*CniCmdResponse, error) {
+	out := new(CniCmdResponse)
+	err := c.cc.Invoke(ctx, "/kuryr.pkg.apis.cni.v1alpha1.Cni/CmdGC", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cniClient) GetCapabilities(ctx context.Context, in *CniCapabilitiesRequest, opts ...grpc.CallOption) (*CniCapabilitiesResponse, error) {
+	out := new(CniCapabilitiesResponse)
+	err := c.cc.Invoke(ctx, "/kuryr.pkg.apis.cni.v1alpha1.Cni/GetCapabilities", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CniServer is the server API for Cni service.
 type CniServer interface {
 	CmdAdd(context.Context, *CniCmdRequest) (*CniCmdResponse, error)
 	CmdCheck(context.Context, *CniCmdRequest) (*CniCmdResponse, error)
 	CmdDel(context.Context, *CniCmdRequest) (*CniCmdResponse, error)
+	CmdStatus(context.Context, *CniCmdRequest) (*CniCmdResponse, error)
+	// The valid attachments are read from the network configuration.
+	CmdGC(context.Context, *CniCmdRequest) (*CniCmdResponse, error)
+	GetCapabilities(context.Context, *CniCapabilitiesRequest) (*CniCapabilitiesResponse, error)
 }
 
 // UnimplementedCniServer can be embedded to have forward compatible implementations.
@@ -438,6 +593,15 @@ func (*UnimplementedCniServer) CmdCheck(ctx context.Context, req *CniCmdRequest)
 func (*UnimplementedCniServer) CmdDel(ctx context.Context, req *CniCmdRequest) (*CniCmdResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CmdDel not implemented")
 }
+func (*UnimplementedCniServer) CmdStatus(ctx context.Context, req *CniCmdRequest) (*CniCmdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CmdStatus not implemented")
+}
+func (*UnimplementedCniServer) CmdGC(ctx context.Context, req *CniCmdRequest) (*CniCmdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CmdGC not implemented")
+}
+func (*UnimplementedCniServer) GetCapabilities(ctx context.Context, req *CniCapabilitiesRequest) (*CniCapabilitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCapabilities not implemented")
+}
 
 func RegisterCniServer(s *grpc.Server, srv CniServer) {
 	s.RegisterService(&_Cni_serviceDesc, srv)
@@ -497,6 +661,60 @@ func _Cni_CmdDel_Handler(srv interface{}, ctx context.Context, dec func(interfac
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Cni_CmdStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CniCmdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CniServer).CmdStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kuryr.pkg.apis.cni.v1alpha1.Cni/CmdStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CniServer).CmdStatus(ctx, req.(*CniCmdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cni_CmdGC_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CniCmdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CniServer).CmdGC(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kuryr.pkg.apis.cni.v1alpha1.Cni/CmdGC",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CniServer).CmdGC(ctx, req.(*CniCmdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cni_GetCapabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CniCapabilitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CniServer).GetCapabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kuryr.pkg.apis.cni.v1alpha1.Cni/GetCapabilities",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CniServer).GetCapabilities(ctx, req.(*CniCapabilitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Cni_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "kuryr.pkg.apis.cni.v1alpha1.Cni",
 	HandlerType: (*CniServer)(nil),
@@ -513,6 +731,18 @@ var _Cni_serviceDesc = grpc.ServiceDesc{
 			MethodName: "CmdDel",
 			Handler:    _Cni_CmdDel_Handler,
 		},
+		{
+			MethodName: "CmdStatus",
+			Handler:    _Cni_CmdStatus_Handler,
+		},
+		{
+			MethodName: "CmdGC",
+			Handler:    _Cni_CmdGC_Handler,
+		},
+		{
+			MethodName: "GetCapabilities",
+			Handler:    _Cni_GetCapabilities_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pkg/apis/cni/v1alpha1/cni.proto",
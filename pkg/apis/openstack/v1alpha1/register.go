@@ -35,6 +35,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&KuryrNetworkList{},
 		&KuryrPort{},
 		&KuryrPortList{},
+		&KuryrLoadBalancer{},
+		&KuryrLoadBalancerList{},
 		&KuryrNetworkPolicy{},
 		&KuryrNetworkPolicyList{},
 	)
@@ -70,6 +70,10 @@ type KuryrNetworkStatus struct {
 	PodSubnetId string `json:"podSubnet,omitempty"`
 	PodSubnetPool string `json:"podSubnetPool,omitempty"`
 	PodSubnetCIDR string `json:"podSubnetCIDR,omitempty"`
+	// PodIPv6SubnetId is the optional IPv6 subnet of the pod network, the
+	// pods of dual-stack namespaces get a fixed IP on both subnets.
+	PodIPv6SubnetId string `json:"podIPv6Subnet,omitempty"`
+	PodIPv6SubnetCIDR string `json:"podIPv6SubnetCIDR,omitempty"`
 	PodSgs []string `json:"podSecurityGroups,omitempty"`
 	PodRouterId string `json:"podRouter,omitempty"`
 
@@ -85,8 +89,34 @@ type KuryrPortSpec struct {
 type KuryrPortStatus struct {
 	ProjectId string `json:"projectId"`
 	Vifs []KuryrVif `json:"vifs"`
+	// FloatingIP is set when the pod asked for a floating IP on its default VIF.
+	FloatingIP *FloatingIPInfo `json:"floatingIP,omitempty"`
 }
 
+// List the ways a floating IP can be obtained, they tell how it must be released.
+const (
+	// FloatingIPAllocMethodUser is a floating IP pre-allocated by the user, it is
+	// only disassociated on release.
+	FloatingIPAllocMethodUser = "user"
+	// FloatingIPAllocMethodPool is a floating IP allocated by kuryr from the
+	// external network, it is deleted on release.
+	FloatingIPAllocMethodPool = "pool"
+)
+
+// FloatingIPInfo records a floating IP associated by kuryr with a Neutron port.
+type FloatingIPInfo struct {
+	ID string `json:"id"`
+	Address string `json:"address"`
+	PortID string `json:"portId"`
+	AllocMethod string `json:"allocMethod"`
+}
+
+// List the vnic_types of the Neutron ports of the pods.
+const (
+	VNICTypeNormal = "normal"
+	VNICTypeDirect = "direct"
+)
+
 type KuryrVif struct {
 	IfName string `json:"if_name"`
 	IsDefault bool `json:"default"`
@@ -117,6 +147,14 @@ type VIF struct {
 
 	Qos QosPolicy `json:"qos"`
 
+	// vnic_type of the Neutron port, VNICTypeNormal for a tap plugged into the
+	// OVS bridge or VNICTypeDirect for a SR-IOV VF passed to the pod.
+	VNICType string `json:"vnic_type,omitempty"`
+	// PCI address of the VF of a direct port, set in its binding:profile.
+	PCISlot string `json:"pci_slot,omitempty"`
+	// VLAN of the VF of a direct port on a VLAN network, 0 otherwise.
+	Vlan int `json:"vlan,omitempty"`
+
 	//Name of the registered os_vif plugin.
 	Plugin string `json:"plugin"`
 	BridgeName string `json:"bridge_name"`
@@ -164,6 +202,9 @@ type Subnet struct {
 	Ips     []IP     `json:"ips"`
 	// IP version, either `4' or `6'.
 	IPVersion int `json:"ip_version"`
+	// IPv6 address mode of the subnet, `slaac', `dhcpv6-stateful' or
+	// `dhcpv6-stateless'.
+	IPv6AddressMode string `json:"ipv6_address_mode,omitempty"`
 	Cidr    string   `json:"cidr"`
 	Gateway string   `json:"gateway"`
 	DNS     []string `json:"dns"`
@@ -237,6 +278,61 @@ type SecGroupRule struct {
 	ProjectID string `json:"project_id"`
 }
 
+type KuryrLoadBalancerSpec struct {
+	Type string `json:"type"`
+	LoadBalancerIP string `json:"loadBalancerIP,omitempty"`
+	ProjectId string `json:"projectId,omitempty"`
+	SubnetId string `json:"subnetId"`
+	Ports []LBPort `json:"ports,omitempty"`
+}
+
+type LBPort struct {
+	Name string `json:"name,omitempty"`
+	Protocol string `json:"protocol"`
+	Port int32 `json:"port"`
+}
+
+type KuryrLoadBalancerStatus struct {
+	LoadBalancer LBInfo `json:"loadbalancer,omitempty"`
+	Listeners []LBListener `json:"listeners,omitempty"`
+	Pools []LBPool `json:"pools,omitempty"`
+	Members []LBMember `json:"members,omitempty"`
+	// ServicePubIP is the floating IP associated with the VIP port.
+	ServicePubIP *FloatingIPInfo `json:"servicePubIp,omitempty"`
+}
+
+type LBInfo struct {
+	ID string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+	IP string `json:"ip,omitempty"`
+	PortID string `json:"portId,omitempty"`
+	Provider string `json:"provider,omitempty"`
+}
+
+type LBListener struct {
+	ID string `json:"id"`
+	Name string `json:"name,omitempty"`
+	Protocol string `json:"protocol"`
+	Port int32 `json:"port"`
+}
+
+type LBPool struct {
+	ID string `json:"id"`
+	ListenerID string `json:"listenerId"`
+}
+
+type LBMember struct {
+	ID string `json:"id"`
+	PoolID string `json:"poolId"`
+	IP string `json:"ip"`
+	Port int32 `json:"port"`
+	PodName string `json:"podName,omitempty"`
+	// DrainingSince is set when the endpoint goes away and the member weight
+	// is set to 0, the member is deleted once the pod has exited or the drain
+	// timeout has passed.
+	DrainingSince *metav1.Time `json:"drainingSince,omitempty"`
+}
+
 type KuryrNetworkPolicySpec struct {
 	EgressSgRules string `json:"egressSgRules"`
 	IngressSgRules string `json:"ingressSgRules"`
@@ -325,6 +421,26 @@ type KuryrPortList struct {
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+type KuryrLoadBalancer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec KuryrLoadBalancerSpec `json:"spec"`
+	Status KuryrLoadBalancerStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type KuryrLoadBalancerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []KuryrLoadBalancer `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 type KuryrNetworkPolicy struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
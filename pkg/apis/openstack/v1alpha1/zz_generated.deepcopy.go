@@ -40,6 +40,22 @@ func (in *AddressPair) DeepCopy() *AddressPair {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FloatingIPInfo) DeepCopyInto(out *FloatingIPInfo) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FloatingIPInfo.
+func (in *FloatingIPInfo) DeepCopy() *FloatingIPInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(FloatingIPInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IP) DeepCopyInto(out *IP) {
 	*out = *in
@@ -56,6 +72,127 @@ func (in *IP) DeepCopy() *IP {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KuryrLoadBalancer) DeepCopyInto(out *KuryrLoadBalancer) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KuryrLoadBalancer.
+func (in *KuryrLoadBalancer) DeepCopy() *KuryrLoadBalancer {
+	if in == nil {
+		return nil
+	}
+	out := new(KuryrLoadBalancer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KuryrLoadBalancer) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KuryrLoadBalancerList) DeepCopyInto(out *KuryrLoadBalancerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KuryrLoadBalancer, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KuryrLoadBalancerList.
+func (in *KuryrLoadBalancerList) DeepCopy() *KuryrLoadBalancerList {
+	if in == nil {
+		return nil
+	}
+	out := new(KuryrLoadBalancerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KuryrLoadBalancerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KuryrLoadBalancerSpec) DeepCopyInto(out *KuryrLoadBalancerSpec) {
+	*out = *in
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]LBPort, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KuryrLoadBalancerSpec.
+func (in *KuryrLoadBalancerSpec) DeepCopy() *KuryrLoadBalancerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KuryrLoadBalancerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KuryrLoadBalancerStatus) DeepCopyInto(out *KuryrLoadBalancerStatus) {
+	*out = *in
+	out.LoadBalancer = in.LoadBalancer
+	if in.Listeners != nil {
+		in, out := &in.Listeners, &out.Listeners
+		*out = make([]LBListener, len(*in))
+		copy(*out, *in)
+	}
+	if in.Pools != nil {
+		in, out := &in.Pools, &out.Pools
+		*out = make([]LBPool, len(*in))
+		copy(*out, *in)
+	}
+	if in.Members != nil {
+		in, out := &in.Members, &out.Members
+		*out = make([]LBMember, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ServicePubIP != nil {
+		in, out := &in.ServicePubIP, &out.ServicePubIP
+		*out = new(FloatingIPInfo)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KuryrLoadBalancerStatus.
+func (in *KuryrLoadBalancerStatus) DeepCopy() *KuryrLoadBalancerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KuryrLoadBalancerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KuryrNetwork) DeepCopyInto(out *KuryrNetwork) {
 	*out = *in
@@ -369,6 +506,11 @@ func (in *KuryrPortStatus) DeepCopyInto(out *KuryrPortStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.FloatingIP != nil {
+		in, out := &in.FloatingIP, &out.FloatingIP
+		*out = new(FloatingIPInfo)
+		**out = **in
+	}
 	return
 }
 
@@ -399,6 +541,90 @@ func (in *KuryrVif) DeepCopy() *KuryrVif {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LBInfo) DeepCopyInto(out *LBInfo) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LBInfo.
+func (in *LBInfo) DeepCopy() *LBInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(LBInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LBListener) DeepCopyInto(out *LBListener) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LBListener.
+func (in *LBListener) DeepCopy() *LBListener {
+	if in == nil {
+		return nil
+	}
+	out := new(LBListener)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LBMember) DeepCopyInto(out *LBMember) {
+	*out = *in
+	if in.DrainingSince != nil {
+		in, out := &in.DrainingSince, &out.DrainingSince
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LBMember.
+func (in *LBMember) DeepCopy() *LBMember {
+	if in == nil {
+		return nil
+	}
+	out := new(LBMember)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LBPool) DeepCopyInto(out *LBPool) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LBPool.
+func (in *LBPool) DeepCopy() *LBPool {
+	if in == nil {
+		return nil
+	}
+	out := new(LBPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LBPort) DeepCopyInto(out *LBPort) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LBPort.
+func (in *LBPort) DeepCopy() *LBPort {
+	if in == nil {
+		return nil
+	}
+	out := new(LBPort)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Network) DeepCopyInto(out *Network) {
 	*out = *in
@@ -0,0 +1,142 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+	v1alpha1 "projectkuryr/kuryr/pkg/apis/openstack/v1alpha1"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeKuryrLoadBalancers implements KuryrLoadBalancerInterface
+type FakeKuryrLoadBalancers struct {
+	Fake *FakeOpenstackV1alpha1
+	ns   string
+}
+
+var kuryrloadbalancersResource = schema.GroupVersionResource{Group: "openstack.org", Version: "v1alpha1", Resource: "kuryrloadbalancers"}
+
+var kuryrloadbalancersKind = schema.GroupVersionKind{Group: "openstack.org", Version: "v1alpha1", Kind: "KuryrLoadBalancer"}
+
+// Get takes name of the kuryrLoadBalancer, and returns the corresponding kuryrLoadBalancer object, and an error if there is any.
+func (c *FakeKuryrLoadBalancers) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.KuryrLoadBalancer, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(kuryrloadbalancersResource, c.ns, name), &v1alpha1.KuryrLoadBalancer{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.KuryrLoadBalancer), err
+}
+
+// List takes label and field selectors, and returns the list of KuryrLoadBalancers that match those selectors.
+func (c *FakeKuryrLoadBalancers) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.KuryrLoadBalancerList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(kuryrloadbalancersResource, kuryrloadbalancersKind, c.ns, opts), &v1alpha1.KuryrLoadBalancerList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.KuryrLoadBalancerList{ListMeta: obj.(*v1alpha1.KuryrLoadBalancerList).ListMeta}
+	for _, item := range obj.(*v1alpha1.KuryrLoadBalancerList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested kuryrLoadBalancers.
+func (c *FakeKuryrLoadBalancers) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(kuryrloadbalancersResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a kuryrLoadBalancer and creates it.  Returns the server's representation of the kuryrLoadBalancer, and an error, if there is any.
+func (c *FakeKuryrLoadBalancers) Create(ctx context.Context, kuryrLoadBalancer *v1alpha1.KuryrLoadBalancer, opts v1.CreateOptions) (result *v1alpha1.KuryrLoadBalancer, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(kuryrloadbalancersResource, c.ns, kuryrLoadBalancer), &v1alpha1.KuryrLoadBalancer{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.KuryrLoadBalancer), err
+}
+
+// Update takes the representation of a kuryrLoadBalancer and updates it. Returns the server's representation of the kuryrLoadBalancer, and an error, if there is any.
+func (c *FakeKuryrLoadBalancers) Update(ctx context.Context, kuryrLoadBalancer *v1alpha1.KuryrLoadBalancer, opts v1.UpdateOptions) (result *v1alpha1.KuryrLoadBalancer, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(kuryrloadbalancersResource, c.ns, kuryrLoadBalancer), &v1alpha1.KuryrLoadBalancer{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.KuryrLoadBalancer), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeKuryrLoadBalancers) UpdateStatus(ctx context.Context, kuryrLoadBalancer *v1alpha1.KuryrLoadBalancer, opts v1.UpdateOptions) (*v1alpha1.KuryrLoadBalancer, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(kuryrloadbalancersResource, "status", c.ns, kuryrLoadBalancer), &v1alpha1.KuryrLoadBalancer{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.KuryrLoadBalancer), err
+}
+
+// Delete takes name of the kuryrLoadBalancer and deletes it. Returns an error if one occurs.
+func (c *FakeKuryrLoadBalancers) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(kuryrloadbalancersResource, c.ns, name), &v1alpha1.KuryrLoadBalancer{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeKuryrLoadBalancers) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(kuryrloadbalancersResource, c.ns, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.KuryrLoadBalancerList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched kuryrLoadBalancer.
+func (c *FakeKuryrLoadBalancers) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.KuryrLoadBalancer, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(kuryrloadbalancersResource, c.ns, name, pt, data, subresources...), &v1alpha1.KuryrLoadBalancer{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.KuryrLoadBalancer), err
+}
@@ -29,6 +29,10 @@ type FakeOpenstackV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeOpenstackV1alpha1) KuryrLoadBalancers(namespace string) v1alpha1.KuryrLoadBalancerInterface {
+	return &FakeKuryrLoadBalancers{c, namespace}
+}
+
 func (c *FakeOpenstackV1alpha1) KuryrNetworks(namespace string) v1alpha1.KuryrNetworkInterface {
 	return &FakeKuryrNetworks{c, namespace}
 }
@@ -18,6 +18,8 @@ limitations under the License.
 
 package v1alpha1
 
+type KuryrLoadBalancerExpansion interface{}
+
 type KuryrNetworkExpansion interface{}
 
 type KuryrNetworkPolicyExpansion interface{}
@@ -27,6 +27,7 @@ import (
 
 type OpenstackV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	KuryrLoadBalancersGetter
 	KuryrNetworksGetter
 	KuryrNetworkPoliciesGetter
 	KuryrPortsGetter
@@ -37,6 +38,10 @@ type OpenstackV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *OpenstackV1alpha1Client) KuryrLoadBalancers(namespace string) KuryrLoadBalancerInterface {
+	return newKuryrLoadBalancers(c, namespace)
+}
+
 func (c *OpenstackV1alpha1Client) KuryrNetworks(namespace string) KuryrNetworkInterface {
 	return newKuryrNetworks(c, namespace)
 }
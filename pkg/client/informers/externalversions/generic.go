@@ -53,6 +53,8 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=openstack.org, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithResource("kuryrloadbalancers"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Openstack().V1alpha1().KuryrLoadBalancers().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("kuryrnetworks"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Openstack().V1alpha1().KuryrNetworks().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("kuryrnetworkpolicies"):
@@ -24,6 +24,8 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// KuryrLoadBalancers returns a KuryrLoadBalancerInformer.
+	KuryrLoadBalancers() KuryrLoadBalancerInformer
 	// KuryrNetworks returns a KuryrNetworkInformer.
 	KuryrNetworks() KuryrNetworkInformer
 	// KuryrNetworkPolicies returns a KuryrNetworkPolicyInformer.
@@ -43,6 +45,11 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// KuryrLoadBalancers returns a KuryrLoadBalancerInformer.
+func (v *version) KuryrLoadBalancers() KuryrLoadBalancerInformer {
+	return &kuryrLoadBalancerInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // KuryrNetworks returns a KuryrNetworkInformer.
 func (v *version) KuryrNetworks() KuryrNetworkInformer {
 	return &kuryrNetworkInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
@@ -18,6 +18,14 @@ limitations under the License.
 
 package v1alpha1
 
+// KuryrLoadBalancerListerExpansion allows custom methods to be added to
+// KuryrLoadBalancerLister.
+type KuryrLoadBalancerListerExpansion interface{}
+
+// KuryrLoadBalancerNamespaceListerExpansion allows custom methods to be added to
+// KuryrLoadBalancerNamespaceLister.
+type KuryrLoadBalancerNamespaceListerExpansion interface{}
+
 // KuryrNetworkListerExpansion allows custom methods to be added to
 // KuryrNetworkLister.
 type KuryrNetworkListerExpansion interface{}
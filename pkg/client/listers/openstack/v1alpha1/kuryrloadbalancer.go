@@ -0,0 +1,100 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1alpha1 "projectkuryr/kuryr/pkg/apis/openstack/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// KuryrLoadBalancerLister helps list KuryrLoadBalancers.
+// All objects returned here must be treated as read-only.
+type KuryrLoadBalancerLister interface {
+	// List lists all KuryrLoadBalancers in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha1.KuryrLoadBalancer, err error)
+	// KuryrLoadBalancers returns an object that can list and get KuryrLoadBalancers.
+	KuryrLoadBalancers(namespace string) KuryrLoadBalancerNamespaceLister
+	KuryrLoadBalancerListerExpansion
+}
+
+// kuryrLoadBalancerLister implements the KuryrLoadBalancerLister interface.
+type kuryrLoadBalancerLister struct {
+	indexer cache.Indexer
+}
+
+// NewKuryrLoadBalancerLister returns a new KuryrLoadBalancerLister.
+func NewKuryrLoadBalancerLister(indexer cache.Indexer) KuryrLoadBalancerLister {
+	return &kuryrLoadBalancerLister{indexer: indexer}
+}
+
+// List lists all KuryrLoadBalancers in the indexer.
+func (s *kuryrLoadBalancerLister) List(selector labels.Selector) (ret []*v1alpha1.KuryrLoadBalancer, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.KuryrLoadBalancer))
+	})
+	return ret, err
+}
+
+// KuryrLoadBalancers returns an object that can list and get KuryrLoadBalancers.
+func (s *kuryrLoadBalancerLister) KuryrLoadBalancers(namespace string) KuryrLoadBalancerNamespaceLister {
+	return kuryrLoadBalancerNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// KuryrLoadBalancerNamespaceLister helps list and get KuryrLoadBalancers.
+// All objects returned here must be treated as read-only.
+type KuryrLoadBalancerNamespaceLister interface {
+	// List lists all KuryrLoadBalancers in the indexer for a given namespace.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha1.KuryrLoadBalancer, err error)
+	// Get retrieves the KuryrLoadBalancer from the indexer for a given namespace and name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*v1alpha1.KuryrLoadBalancer, error)
+	KuryrLoadBalancerNamespaceListerExpansion
+}
+
+// kuryrLoadBalancerNamespaceLister implements the KuryrLoadBalancerNamespaceLister
+// interface.
+type kuryrLoadBalancerNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all KuryrLoadBalancers in the indexer for a given namespace.
+func (s kuryrLoadBalancerNamespaceLister) List(selector labels.Selector) (ret []*v1alpha1.KuryrLoadBalancer, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.KuryrLoadBalancer))
+	})
+	return ret, err
+}
+
+// Get retrieves the KuryrLoadBalancer from the indexer for a given namespace and name.
+func (s kuryrLoadBalancerNamespaceLister) Get(name string) (*v1alpha1.KuryrLoadBalancer, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("kuryrloadbalancer"), name)
+	}
+	return obj.(*v1alpha1.KuryrLoadBalancer), nil
+}
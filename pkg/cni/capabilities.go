@@ -0,0 +1,127 @@
+package cni
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cnipb "projectkuryr/kuryr/pkg/apis/cni/v1alpha1"
+)
+
+// Optional features reported by kuryr-agent in its capabilities.
+const (
+	// FeatureCheck is the support of the CNI CHECK command.
+	FeatureCheck = "check"
+	// FeatureStatus is the support of the CNI STATUS command.
+	FeatureStatus = "status"
+	// FeatureGC is the support of the CNI GC command.
+	FeatureGC = "gc"
+	// FeatureChaining is set when kuryr-agent runs kuryr as a chained plugin.
+	FeatureChaining = "chaining"
+)
+
+// BootIDHeader is the gRPC header in which kuryr-agent sends its boot ID with all its responses.
+const BootIDHeader = "kuryr-agent-boot-id"
+
+// capabilitiesCacheFile is where kuryr-cni caches the capabilities of kuryr-agent, a new kuryr-cni
+// process is started for each CNI command.
+var capabilitiesCacheFile = "/var/run/kuryr/cni-capabilities.json"
+
+// Capabilities are the capabilities of kuryr-agent, they are valid until kuryr-agent restarts,
+// i.e. while its boot ID doesn't change.
+type Capabilities struct {
+	BootID               string   `json:"bootID"`
+	APIVersion           string   `json:"apiVersion"`
+	SupportedCNIVersions []string `json:"supportedCNIVersions"`
+	Features             []string `json:"features"`
+	// cached is true when the capabilities were read from capabilitiesCacheFile.
+	cached bool
+}
+
+// legacyCapabilities are the capabilities of the kuryr-agents which predate GetCapabilities. They
+// have no boot ID and send no BootIDHeader, their capabilities are replaced once kuryr-agent is
+// upgraded.
+var legacyCapabilities = Capabilities{Features: []string{FeatureCheck}}
+
+func (c *Capabilities) hasFeature(feature string) bool {
+	for _, f := range c.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+func loadCachedCapabilities() *Capabilities {
+	data, err := ioutil.ReadFile(capabilitiesCacheFile)
+	if err != nil {
+		return nil
+	}
+	capabilities := &Capabilities{}
+	if err := json.Unmarshal(data, capabilities); err != nil {
+		return nil
+	}
+	capabilities.cached = true
+	return capabilities
+}
+
+// saveCachedCapabilities writes the cache atomically, as several kuryr-cni processes may run at
+// the same time. The cache is an optimization, errors are ignored.
+func saveCachedCapabilities(capabilities *Capabilities) {
+	data, err := json.Marshal(capabilities)
+	if err != nil {
+		return
+	}
+	tmpFile, err := ioutil.TempFile(filepath.Dir(capabilitiesCacheFile), filepath.Base(capabilitiesCacheFile))
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmpFile.Name())
+	_, err = tmpFile.Write(data)
+	if closeErr := tmpFile.Close(); err != nil || closeErr != nil {
+		return
+	}
+	os.Rename(tmpFile.Name(), capabilitiesCacheFile)
+}
+
+// invalidateCachedCapabilities is called when the boot ID of kuryr-agent changed, its
+// capabilities are queried again by the next kuryr-cni process.
+func invalidateCachedCapabilities() {
+	os.Remove(capabilitiesCacheFile)
+}
+
+// fetchCapabilities queries the capabilities of kuryr-agent and caches them. legacyCapabilities
+// are returned for a kuryr-agent which doesn't implement GetCapabilities, e.g. during a rolling
+// upgrade.
+func fetchCapabilities(ctx context.Context, client cnipb.CniClient) (*Capabilities, error) {
+	resp, err := client.GetCapabilities(ctx, &cnipb.CniCapabilitiesRequest{})
+	var capabilities Capabilities
+	if status.Code(err) == codes.Unimplemented {
+		capabilities = legacyCapabilities
+	} else if err != nil {
+		return nil, err
+	} else {
+		capabilities = Capabilities{
+			BootID:               resp.BootId,
+			APIVersion:           resp.ApiVersion,
+			SupportedCNIVersions: resp.SupportedCniVersions,
+			Features:             resp.Features,
+		}
+	}
+	saveCachedCapabilities(&capabilities)
+	return &capabilities, nil
+}
+
+// getCapabilities returns the cached capabilities of kuryr-agent, they are queried when the cache
+// is empty.
+func getCapabilities(ctx context.Context, client cnipb.CniClient) (*Capabilities, error) {
+	if capabilities := loadCachedCapabilities(); capabilities != nil {
+		return capabilities, nil
+	}
+	return fetchCapabilities(ctx, client)
+}
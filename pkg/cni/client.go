@@ -2,16 +2,22 @@ package cni
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"github.com/containernetworking/cni/pkg/skel"
 	"github.com/containernetworking/cni/pkg/types"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"net"
 	"os"
 	"projectkuryr/kuryr/pkg/agent/util"
 	cnipb "projectkuryr/kuryr/pkg/apis/cni/v1alpha1"
+	"projectkuryr/kuryr/pkg/utils/tracing"
+	"strings"
+	"time"
 )
 
 type Action int
@@ -20,6 +26,8 @@ const (
 	ActionAdd Action = iota
 	ActionCheck
 	ActionDel
+	ActionStatus
+	ActionGC
 )
 
 // KuryrCNIVersion is the full semantic version (https://semver.org/) of our CNI Protobuf / gRPC service.
@@ -40,10 +48,10 @@ const (
 //         compatibility is not broken). If kuryr-cni does not support this new version, it will
 //         not list the new CNI spec version as supported and there will be no issue. If kuryr-cni
 //         supports it but not the kuryr-agent, the gRPC server will return an UNIMPLEMENTED error
-//         which we can propagate to the runtime. There is no way to handle this last case better
-//         with the current design unless we introduce a different RPC (e.g. Capabilities) early to
-//         query the server for the supported API version. This would also require an additional RPC
-//         for each CNI binary invocation.
+//         which we can propagate to the runtime. To handle this last case better, kuryr-cni queries
+//         the server capabilities with the GetCapabilities RPC and caches them until the server
+//         restarts (the server sends its boot ID with every response), so that an optional
+//         command is skipped instead of failed when the server doesn't support it.
 //       - introduction of a new field to a proto message: highly unlikely because we just send the
 //         CNI input / output as bytes.
 //       - no changes are needed if only the CNI parameters or CNI result format changed. In this
@@ -59,14 +67,18 @@ const (
 // pre-GA releases of a major version, along with that major version release itself) in the
 // server. This is harder to do on the client side (need to fallback to a previous version when
 // getting an UNIMPLEMENTED error).
-const KuryrCNIVersion = "3.0.1"
+const KuryrCNIVersion = "3.2.0"
+
+// RequestIDHeader is the gRPC metadata in which kuryr-cni sends the ID of a CNI request, kuryr-agent
+// logs it with the request so that the logs of both sides can be matched.
+const RequestIDHeader = "kuryr-request-id"
 
 // To allow for testing with a fake client.
 var withClient = rpcClient
 
-func rpcClient(f func(client cnipb.CniClient) error) error {
+func rpcClient(socketPath string, f func(client cnipb.CniClient) error) error {
 	conn, err := grpc.Dial(
-		KuryrCNISocketAddr,
+		socketPath,
 		grpc.WithInsecure(),
 		grpc.WithContextDialer(func(ctx context.Context, addr string) (conn net.Conn, e error) {
 			return util.DialLocalSocket(addr)
@@ -81,8 +93,20 @@ func rpcClient(f func(client cnipb.CniClient) error) error {
 
 // Request requests the kuryr-agent to execute the specified action with the provided arguments via RPC.
 // If successful, it outputs the result to stdout and returns nil. Otherwise types.Error is returned.
+// The request is retried with backoff while the kuryr-agent answers TRY_AGAIN_LATER or cannot be
+// reached, until the deadline of the action.
 func (a Action) Request(arg *skel.CmdArgs) error {
-	return withClient(func(client cnipb.CniClient) error {
+	config, err := loadClientConfig(arg.StdinData)
+	if err != nil {
+		return &types.Error{
+			Code: uint(cnipb.ErrorCode_DECODING_FAILURE),
+			Msg:  err.Error(),
+		}
+	}
+	requestID := newRequestID()
+	logger := config.newLogger()
+	logger.SetPrefix(logger.Prefix() + "request=" + requestID + " ")
+	return withClient(config.SocketPath, func(client cnipb.CniClient) error {
 		cmdRequest := cnipb.CniCmdRequest{
 			CniArgs: &cnipb.CniCmdArgs{
 				ContainerId:          arg.ContainerID,
@@ -93,49 +117,187 @@ func (a Action) Request(arg *skel.CmdArgs) error {
 				Path:                 arg.Path,
 			},
 		}
-		ctx, cancel := context.WithCancel(context.Background())
+		ctx, cancel := context.WithTimeout(context.Background(), config.timeout(a))
 		defer cancel()
+		// The retries of the request keep its ID and its trace context, the spans of kuryr-agent
+		// are in the trace of the Pod.
+		ctx = metadata.AppendToOutgoingContext(ctx, RequestIDHeader, requestID)
+		ctx = tracing.InjectOutgoing(tracing.NewRemoteContext(ctx, podUIDFromArgs(arg.Args)))
 
-		var resp *cnipb.CniCmdResponse
-		var err error
-
-		switch a {
-		case ActionAdd:
-			resp, err = client.CmdAdd(ctx, &cmdRequest)
-		case ActionCheck:
-			resp, err = client.CmdCheck(ctx, &cmdRequest)
-		case ActionDel:
-			resp, err = client.CmdDel(ctx, &cmdRequest)
+		if config.Debug {
+			logger.Printf("Sending %s request for container %s interface %s", a, arg.ContainerID, arg.IfName)
 		}
-
-		// Handle gRPC errors.
-		if status.Code(err) == codes.Unimplemented {
-			return &types.Error{
-				Code:    uint(cnipb.ErrorCode_INCOMPATIBLE_API_VERSION),
-				Msg:     fmt.Sprintf("incompatible CNI API version between client (kuryr-cni) and server (kuryr-agent), client is using version %s", KuryrCNIVersion),
-				Details: fmt.Sprintf("service or method unimplemented by gRPC server: %v", err.Error()),
+		backoff := config.Retry.InitialBackoff.Duration
+		for attempt := 1; ; attempt++ {
+			result, cniErr := a.send(ctx, client, &cmdRequest)
+			if cniErr == nil {
+				if config.Debug {
+					logger.Printf("%s request for container %s succeeded: %s", a, arg.ContainerID, result)
+				}
+				os.Stdout.Write(result)
+				return nil
+			}
+			if cniErr.Code != uint(cnipb.ErrorCode_TRY_AGAIN_LATER) || attempt >= config.Retry.MaxAttempts {
+				logger.Printf("%s request for container %s failed after %d attempt(s): %v", a, arg.ContainerID, attempt, cniErr)
+				return a.finalError(cniErr)
 			}
-		} else if status.Code(err) == codes.Unavailable || status.Code(err) == codes.DeadlineExceeded {
-			// network errors, could be transient.
-			return &types.Error{
-				Code: uint(cnipb.ErrorCode_TRY_AGAIN_LATER),
-				Msg:  err.Error(),
+			logger.Printf("%s request for container %s failed, retrying in %v: %v", a, arg.ContainerID, backoff, cniErr)
+			select {
+			case <-ctx.Done():
+				logger.Printf("%s request for container %s timed out: %v", a, arg.ContainerID, cniErr)
+				return a.finalError(cniErr)
+			case <-time.After(backoff):
 			}
-		} else if err != nil { // all other RPC errors.
-			return &types.Error{
-				Code: uint(cnipb.ErrorCode_UNKNOWN_RPC_ERROR),
-				Msg:  err.Error(),
+			backoff *= 2
+			if backoff > config.Retry.MaxBackoff.Duration {
+				backoff = config.Retry.MaxBackoff.Duration
 			}
 		}
+	})
+}
+
+// podUIDFromArgs returns the K8S_POD_UID of the CNI_ARGS, "" is returned if the runtime doesn't
+// pass it.
+func podUIDFromArgs(args string) string {
+	for _, pair := range strings.Split(args, ";") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) == 2 && kv[0] == "K8S_POD_UID" {
+			return kv[1]
+		}
+	}
+	return ""
+}
+
+// newRequestID returns a random ID for a CNI request.
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+func (a Action) String() string {
+	switch a {
+	case ActionCheck:
+		return "CHECK"
+	case ActionDel:
+		return "DEL"
+	case ActionStatus:
+		return "STATUS"
+	case ActionGC:
+		return "GC"
+	}
+	return "ADD"
+}
+
+// feature returns the optional feature kuryr-agent must support to be sent the action, the action
+// is skipped when kuryr-agent doesn't support it.
+func (a Action) feature() string {
+	switch a {
+	case ActionCheck:
+		return FeatureCheck
+	case ActionStatus:
+		return FeatureStatus
+	case ActionGC:
+		return FeatureGC
+	}
+	return ""
+}
+
+// finalError returns the error reported to the runtime once the retries are exhausted. A
+// kuryr-agent which cannot be reached is reported as not available by STATUS, as mandated by the
+// CNI spec.
+func (a Action) finalError(cniErr *types.Error) *types.Error {
+	if a == ActionStatus && cniErr.Code == uint(cnipb.ErrorCode_TRY_AGAIN_LATER) {
+		return &types.Error{
+			Code:    uint(cnipb.ErrorCode_PLUGIN_NOT_AVAILABLE),
+			Msg:     "kuryr-agent is not available",
+			Details: cniErr.Msg,
+		}
+	}
+	return cniErr
+}
 
-		// Handle errors during CNI execution.
-		if resp.Error != nil {
-			return &types.Error{
-				Code: uint(resp.Error.Code),
-				Msg:  resp.Error.Message,
+// send sends the request to the kuryr-agent once and returns the CNI result.
+func (a Action) send(ctx context.Context, client cnipb.CniClient, cmdRequest *cnipb.CniCmdRequest) ([]byte, *types.Error) {
+	capabilities, err := getCapabilities(ctx, client)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	// An older kuryr-agent is not sent the optional commands it doesn't support, e.g. CHECK, the
+	// cached capabilities are refreshed first in case kuryr-agent was upgraded since. As it
+	// answered, such a kuryr-agent is available for STATUS.
+	if feature := a.feature(); feature != "" {
+		if !capabilities.hasFeature(feature) && capabilities.cached {
+			if capabilities, err = fetchCapabilities(ctx, client); err != nil {
+				return nil, rpcError(err)
 			}
 		}
-		os.Stdout.Write(resp.CniResult)
-		return nil
-	})
-}
\ No newline at end of file
+		if !capabilities.hasFeature(feature) {
+			return nil, nil
+		}
+	}
+
+	var resp *cnipb.CniCmdResponse
+	var header metadata.MD
+
+	switch a {
+	case ActionAdd:
+		resp, err = client.CmdAdd(ctx, cmdRequest, grpc.Header(&header))
+	case ActionCheck:
+		resp, err = client.CmdCheck(ctx, cmdRequest, grpc.Header(&header))
+	case ActionDel:
+		resp, err = client.CmdDel(ctx, cmdRequest, grpc.Header(&header))
+	case ActionStatus:
+		resp, err = client.CmdStatus(ctx, cmdRequest, grpc.Header(&header))
+	case ActionGC:
+		resp, err = client.CmdGC(ctx, cmdRequest, grpc.Header(&header))
+	}
+
+	if err == nil && getBootID(header) != capabilities.BootID {
+		// kuryr-agent restarted, maybe with a new version.
+		invalidateCachedCapabilities()
+	}
+	if err != nil {
+		return nil, rpcError(err)
+	}
+
+	// Handle errors during CNI execution.
+	if resp.Error != nil {
+		return nil, &types.Error{
+			Code: uint(resp.Error.Code),
+			Msg:  resp.Error.Message,
+		}
+	}
+	return resp.CniResult, nil
+}
+
+func getBootID(header metadata.MD) string {
+	if values := header.Get(BootIDHeader); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// rpcError converts the gRPC errors to CNI errors.
+func rpcError(err error) *types.Error {
+	if status.Code(err) == codes.Unimplemented {
+		return &types.Error{
+			Code:    uint(cnipb.ErrorCode_INCOMPATIBLE_API_VERSION),
+			Msg:     fmt.Sprintf("incompatible CNI API version between client (kuryr-cni) and server (kuryr-agent), client is using version %s", KuryrCNIVersion),
+			Details: fmt.Sprintf("service or method unimplemented by gRPC server: %v", err.Error()),
+		}
+	} else if status.Code(err) == codes.Unavailable || status.Code(err) == codes.DeadlineExceeded {
+		// network errors, could be transient.
+		return &types.Error{
+			Code: uint(cnipb.ErrorCode_TRY_AGAIN_LATER),
+			Msg:  err.Error(),
+		}
+	}
+	// all other RPC errors.
+	return &types.Error{
+		Code: uint(cnipb.ErrorCode_UNKNOWN_RPC_ERROR),
+		Msg:  err.Error(),
+	}
+}
@@ -0,0 +1,227 @@
+package cni
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	cnipb "projectkuryr/kuryr/pkg/apis/cni/v1alpha1"
+)
+
+// fakeCniClient is a kuryr-agent with the boot ID and the capabilities, a nil capabilities is an
+// agent which predates GetCapabilities.
+type fakeCniClient struct {
+	bootID       string
+	capabilities *cnipb.CniCapabilitiesResponse
+	calls        map[string]int
+	requestIDs   []string
+	traceParents []string
+	// tryAgainLater is the number of commands answered with TRY_AGAIN_LATER.
+	tryAgainLater int
+}
+
+const testNetConf = `{"cniVersion": "0.4.0", "name": "kuryr", "type": "kuryr-cni"}`
+
+func (c *fakeCniClient) cmd(ctx context.Context, name string, opts []grpc.CallOption) (*cnipb.CniCmdResponse, error) {
+	c.calls[name]++
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		c.requestIDs = append(c.requestIDs, md.Get(RequestIDHeader)...)
+		c.traceParents = append(c.traceParents, md.Get("traceparent")...)
+	}
+	for _, opt := range opts {
+		if header, ok := opt.(grpc.HeaderCallOption); ok && c.bootID != "" {
+			*header.HeaderAddr = metadata.Pairs(BootIDHeader, c.bootID)
+		}
+	}
+	if c.tryAgainLater > 0 {
+		c.tryAgainLater--
+		return &cnipb.CniCmdResponse{Error: &cnipb.Error{Code: cnipb.ErrorCode_TRY_AGAIN_LATER}}, nil
+	}
+	return &cnipb.CniCmdResponse{}, nil
+}
+
+func (c *fakeCniClient) CmdAdd(ctx context.Context, _ *cnipb.CniCmdRequest, opts ...grpc.CallOption) (*cnipb.CniCmdResponse, error) {
+	return c.cmd(ctx, "CmdAdd", opts)
+}
+
+func (c *fakeCniClient) CmdCheck(ctx context.Context, _ *cnipb.CniCmdRequest, opts ...grpc.CallOption) (*cnipb.CniCmdResponse, error) {
+	return c.cmd(ctx, "CmdCheck", opts)
+}
+
+func (c *fakeCniClient) CmdDel(ctx context.Context, _ *cnipb.CniCmdRequest, opts ...grpc.CallOption) (*cnipb.CniCmdResponse, error) {
+	return c.cmd(ctx, "CmdDel", opts)
+}
+
+func (c *fakeCniClient) CmdStatus(ctx context.Context, _ *cnipb.CniCmdRequest, opts ...grpc.CallOption) (*cnipb.CniCmdResponse, error) {
+	return c.cmd(ctx, "CmdStatus", opts)
+}
+
+func (c *fakeCniClient) CmdGC(ctx context.Context, _ *cnipb.CniCmdRequest, opts ...grpc.CallOption) (*cnipb.CniCmdResponse, error) {
+	return c.cmd(ctx, "CmdGC", opts)
+}
+
+func (c *fakeCniClient) GetCapabilities(_ context.Context, _ *cnipb.CniCapabilitiesRequest, _ ...grpc.CallOption) (*cnipb.CniCapabilitiesResponse, error) {
+	c.calls["GetCapabilities"]++
+	if c.capabilities == nil {
+		return nil, status.Error(codes.Unimplemented, "unknown method GetCapabilities")
+	}
+	return c.capabilities, nil
+}
+
+func withFakeClient(t *testing.T, client *fakeCniClient) {
+	dir, err := ioutil.TempDir("", "kuryr-cni")
+	require.NoError(t, err)
+	oldWithClient, oldCacheFile := withClient, capabilitiesCacheFile
+	withClient = func(_ string, f func(client cnipb.CniClient) error) error {
+		return f(client)
+	}
+	capabilitiesCacheFile = filepath.Join(dir, "cni-capabilities.json")
+	t.Cleanup(func() {
+		withClient, capabilitiesCacheFile = oldWithClient, oldCacheFile
+		os.RemoveAll(dir)
+	})
+}
+
+func TestRequestCachesCapabilities(t *testing.T) {
+	client := &fakeCniClient{
+		bootID:       "boot-1",
+		capabilities: &cnipb.CniCapabilitiesResponse{ApiVersion: KuryrCNIVersion, Features: []string{FeatureCheck}, BootId: "boot-1"},
+		calls:        map[string]int{},
+	}
+	withFakeClient(t, client)
+	args := &skel.CmdArgs{ContainerID: "c1", IfName: "eth0", StdinData: []byte(testNetConf)}
+
+	require.NoError(t, ActionAdd.Request(args))
+	require.NoError(t, ActionCheck.Request(args))
+	assert.Equal(t, 1, client.calls["GetCapabilities"])
+	assert.Equal(t, 1, client.calls["CmdCheck"])
+
+	// The agent restarted, its capabilities are queried again.
+	client.bootID = "boot-2"
+	client.capabilities.BootId = "boot-2"
+	require.NoError(t, ActionDel.Request(args))
+	require.NoError(t, ActionDel.Request(args))
+	assert.Equal(t, 2, client.calls["GetCapabilities"])
+	cached := loadCachedCapabilities()
+	require.NotNil(t, cached)
+	assert.Equal(t, "boot-2", cached.BootID)
+}
+
+func TestRequestFallback(t *testing.T) {
+	// An agent without GetCapabilities is still used.
+	client := &fakeCniClient{calls: map[string]int{}}
+	withFakeClient(t, client)
+	args := &skel.CmdArgs{ContainerID: "c1", IfName: "eth0", StdinData: []byte(testNetConf)}
+
+	require.NoError(t, ActionAdd.Request(args))
+	require.NoError(t, ActionCheck.Request(args))
+	assert.Equal(t, 1, client.calls["CmdAdd"])
+	assert.Equal(t, 1, client.calls["CmdCheck"])
+
+	// An optional command is skipped when the agent doesn't support it.
+	client.bootID = "boot-1"
+	client.capabilities = &cnipb.CniCapabilitiesResponse{ApiVersion: KuryrCNIVersion, BootId: "boot-1"}
+	invalidateCachedCapabilities()
+	require.NoError(t, ActionCheck.Request(args))
+	assert.Equal(t, 1, client.calls["CmdCheck"])
+}
+
+func TestRequestRetry(t *testing.T) {
+	client := &fakeCniClient{calls: map[string]int{}, tryAgainLater: 2}
+	withFakeClient(t, client)
+	netConf := `{"cniVersion": "0.4.0", "name": "kuryr", "type": "kuryr-cni", "retry": {"max_attempts": 3, "initial_backoff": "1ms"}}`
+	args := &skel.CmdArgs{
+		ContainerID: "c1",
+		IfName:      "eth0",
+		Args:        "K8S_POD_NAMESPACE=default;K8S_POD_NAME=pod1;K8S_POD_UID=1f6b4c2e-8a3d-4e5f-9b1c-2d3e4f5a6b7c",
+		StdinData:   []byte(netConf),
+	}
+
+	require.NoError(t, ActionAdd.Request(args))
+	assert.Equal(t, 3, client.calls["CmdAdd"])
+	// The retries are sent with the ID and the trace context of the request, in the trace of the Pod.
+	require.Len(t, client.requestIDs, 3)
+	assert.NotEmpty(t, client.requestIDs[0])
+	assert.Equal(t, client.requestIDs[0], client.requestIDs[2])
+	require.Len(t, client.traceParents, 3)
+	assert.Contains(t, client.traceParents[0], "-1f6b4c2e8a3d4e5f9b1c2d3e4f5a6b7c-")
+	assert.Equal(t, client.traceParents[0], client.traceParents[2])
+
+	client.tryAgainLater = 3
+	err := ActionAdd.Request(args)
+	require.Error(t, err)
+	assert.Equal(t, uint(cnipb.ErrorCode_TRY_AGAIN_LATER), err.(*types.Error).Code)
+	assert.Equal(t, 6, client.calls["CmdAdd"])
+}
+
+func TestRequestStatusAndGC(t *testing.T) {
+	// An agent without STATUS and GC support is available and has nothing to collect.
+	client := &fakeCniClient{calls: map[string]int{}}
+	withFakeClient(t, client)
+	args := &skel.CmdArgs{StdinData: []byte(`{"cniVersion": "1.1.0", "name": "kuryr", "type": "kuryr-cni", "retry": {"max_attempts": 2, "initial_backoff": "1ms"}}`)}
+
+	require.NoError(t, ActionStatus.Request(args))
+	require.NoError(t, ActionGC.Request(args))
+	assert.Equal(t, 0, client.calls["CmdStatus"])
+	assert.Equal(t, 0, client.calls["CmdGC"])
+
+	client.bootID = "boot-1"
+	client.capabilities = &cnipb.CniCapabilitiesResponse{ApiVersion: KuryrCNIVersion, Features: []string{FeatureStatus, FeatureGC}, BootId: "boot-1"}
+	invalidateCachedCapabilities()
+	require.NoError(t, ActionStatus.Request(args))
+	require.NoError(t, ActionGC.Request(args))
+	assert.Equal(t, 1, client.calls["CmdStatus"])
+	assert.Equal(t, 1, client.calls["CmdGC"])
+
+	// An agent which keeps answering TRY_AGAIN_LATER is reported as not available.
+	client.tryAgainLater = 2
+	err := ActionStatus.Request(args)
+	require.Error(t, err)
+	assert.Equal(t, uint(cnipb.ErrorCode_PLUGIN_NOT_AVAILABLE), err.(*types.Error).Code)
+}
+
+func TestLoadClientConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuryr-cni")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	kuryrConf := filepath.Join(dir, "kuryr-cni.conf")
+	require.NoError(t, ioutil.WriteFile(kuryrConf, []byte(`
+socket_path: /run/kuryr/cni.sock
+log_file: /var/log/kuryr-cni.log
+timeouts:
+  add: 100s
+  del: 20s
+`), 0644))
+
+	config, err := loadClientConfig([]byte(`{"cniVersion": "0.4.0", "kuryr_conf": "` + kuryrConf + `", "debug": true, "timeouts": {"del": "10s"}}`))
+	require.NoError(t, err)
+	assert.True(t, config.Debug)
+	assert.Equal(t, "/run/kuryr/cni.sock", config.SocketPath)
+	assert.Equal(t, "/var/log/kuryr-cni.log", config.LogFile)
+	assert.Equal(t, 100*time.Second, config.timeout(ActionAdd))
+	assert.Equal(t, 10*time.Second, config.timeout(ActionDel))
+	assert.Equal(t, defaultCheckTimeout, config.timeout(ActionCheck))
+	assert.Equal(t, defaultMaxAttempts, config.Retry.MaxAttempts)
+
+	config, err = loadClientConfig([]byte(testNetConf))
+	require.NoError(t, err)
+	assert.Equal(t, KuryrCNISocketAddr, config.SocketPath)
+
+	config, err = loadClientConfig([]byte(`{"kuryr_conf": "` + filepath.Join(dir, "missing.conf") + `"}`))
+	require.NoError(t, err)
+	assert.Equal(t, defaultAddTimeout, config.timeout(ActionAdd))
+	_, err = loadClientConfig([]byte(`{"timeouts": {"add": "soon"}}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,159 @@
+package cni
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	defaultAddTimeout     = 90 * time.Second
+	defaultCheckTimeout   = 30 * time.Second
+	defaultDelTimeout     = 60 * time.Second
+	defaultStatusTimeout  = 10 * time.Second
+	defaultGCTimeout      = 120 * time.Second
+	defaultMaxAttempts    = 5
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 5 * time.Second
+)
+
+// Duration is a time.Duration written as a string, e.g. "30s", in the configuration of kuryr-cni.
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) set(s string) error {
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = duration
+	return nil
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return d.set(s)
+}
+
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return d.set(s)
+}
+
+// CommandTimeouts are the deadlines of the CNI commands sent to kuryr-agent.
+type CommandTimeouts struct {
+	Add    Duration `json:"add" yaml:"add"`
+	Check  Duration `json:"check" yaml:"check"`
+	Del    Duration `json:"del" yaml:"del"`
+	Status Duration `json:"status" yaml:"status"`
+	GC     Duration `json:"gc" yaml:"gc"`
+}
+
+// RetryPolicy tells how the CNI commands are retried when kuryr-agent answers TRY_AGAIN_LATER or
+// cannot be reached. The backoff doubles after each attempt up to MaxBackoff, the attempts stop
+// when the deadline of the command is exceeded.
+type RetryPolicy struct {
+	MaxAttempts    int      `json:"max_attempts" yaml:"max_attempts"`
+	InitialBackoff Duration `json:"initial_backoff" yaml:"initial_backoff"`
+	MaxBackoff     Duration `json:"max_backoff" yaml:"max_backoff"`
+}
+
+// ClientConfig is the configuration of kuryr-cni. It is read from the network configuration, the
+// options missing there are read from the optional YAML file of kuryr_conf and then take their
+// default value.
+type ClientConfig struct {
+	KuryrConf  string          `json:"kuryr_conf" yaml:"-"`
+	Debug      bool            `json:"debug" yaml:"debug"`
+	LogFile    string          `json:"log_file" yaml:"log_file"`
+	SocketPath string          `json:"socket_path" yaml:"socket_path"`
+	Timeouts   CommandTimeouts `json:"timeouts" yaml:"timeouts"`
+	Retry      RetryPolicy     `json:"retry" yaml:"retry"`
+}
+
+// loadClientConfig reads the configuration of kuryr-cni from the network configuration.
+func loadClientConfig(netConf []byte) (*ClientConfig, error) {
+	config := &ClientConfig{}
+	if err := json.Unmarshal(netConf, config); err != nil {
+		return nil, fmt.Errorf("failed to parse network configuration: %v", err)
+	}
+	if config.KuryrConf != "" {
+		// The file is optional, the defaults are used when it doesn't exist.
+		data, err := ioutil.ReadFile(config.KuryrConf)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read kuryr_conf %s: %v", config.KuryrConf, err)
+		}
+		// The options of the network configuration take precedence over the file ones.
+		fileConfig := &ClientConfig{}
+		if err := yaml.Unmarshal(data, fileConfig); err != nil {
+			return nil, fmt.Errorf("failed to parse kuryr_conf %s: %v", config.KuryrConf, err)
+		}
+		if err := json.Unmarshal(netConf, fileConfig); err != nil {
+			return nil, fmt.Errorf("failed to parse network configuration: %v", err)
+		}
+		config = fileConfig
+	}
+	config.setDefaults()
+	return config, nil
+}
+
+func (c *ClientConfig) setDefaults() {
+	if c.SocketPath == "" {
+		c.SocketPath = KuryrCNISocketAddr
+	}
+	setDefaultDuration(&c.Timeouts.Add, defaultAddTimeout)
+	setDefaultDuration(&c.Timeouts.Check, defaultCheckTimeout)
+	setDefaultDuration(&c.Timeouts.Del, defaultDelTimeout)
+	setDefaultDuration(&c.Timeouts.Status, defaultStatusTimeout)
+	setDefaultDuration(&c.Timeouts.GC, defaultGCTimeout)
+	if c.Retry.MaxAttempts <= 0 {
+		c.Retry.MaxAttempts = defaultMaxAttempts
+	}
+	setDefaultDuration(&c.Retry.InitialBackoff, defaultInitialBackoff)
+	setDefaultDuration(&c.Retry.MaxBackoff, defaultMaxBackoff)
+}
+
+func setDefaultDuration(d *Duration, defaultValue time.Duration) {
+	if d.Duration <= 0 {
+		d.Duration = defaultValue
+	}
+}
+
+// timeout returns the deadline of the command.
+func (c *ClientConfig) timeout(a Action) time.Duration {
+	switch a {
+	case ActionCheck:
+		return c.Timeouts.Check.Duration
+	case ActionDel:
+		return c.Timeouts.Del.Duration
+	case ActionStatus:
+		return c.Timeouts.Status.Duration
+	case ActionGC:
+		return c.Timeouts.GC.Duration
+	}
+	return c.Timeouts.Add.Duration
+}
+
+// newLogger returns the logger of kuryr-cni, the messages are discarded when no log file is
+// configured as the runtime only expects the CNI result from the plugin.
+func (c *ClientConfig) newLogger() *log.Logger {
+	if c.LogFile == "" {
+		return log.New(ioutil.Discard, "", 0)
+	}
+	f, err := os.OpenFile(c.LogFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return log.New(ioutil.Discard, "", 0)
+	}
+	return log.New(f, fmt.Sprintf("kuryr-cni[%d] ", os.Getpid()), log.LstdFlags|log.Lmicroseconds)
+}
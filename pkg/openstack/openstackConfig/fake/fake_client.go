@@ -0,0 +1,783 @@
+// Package fake provides an in-process implementation of
+// openstackConfig.Interface. It keeps Neutron ports and Octavia load
+// balancers in memory, simulates the asynchronous provisioning state machine
+// of Octavia and lets tests inject failures, so that controllers can be unit
+// tested without an OpenStack cloud.
+package fake
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/gophercloud/gophercloud/acceptance/openstack/networking/v2/extensions/mtu"
+	"github.com/gophercloud/gophercloud/acceptance/openstack/networking/v2/extensions/portsbinding"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/loadbalancers"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/monitors"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/pools"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/floatingips"
+	geportsbinding "github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/portsbinding"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
+	"k8s.io/apimachinery/pkg/util/uuid"
+
+	"projectkuryr/kuryr/pkg/openstack/openstackConfig"
+)
+
+// Operation names an OSClient method, it is used to target failure injection
+// and to record the mutations made by the code under test.
+type Operation string
+
+const (
+	OpCreatePort         Operation = "CreatePort"
+	OpGetPort            Operation = "GetPort"
+	OpDeletePort         Operation = "DeletePort"
+	OpGetNetwork         Operation = "GetNetwork"
+	OpGetSubnet          Operation = "GetSubnet"
+	OpCreateFloatingIP   Operation = "CreateFloatingIP"
+	OpGetFloatingIP      Operation = "GetFloatingIP"
+	OpListFloatingIPs    Operation = "ListFloatingIPs"
+	OpUpdateFloatingIP   Operation = "UpdateFloatingIP"
+	OpDeleteFloatingIP   Operation = "DeleteFloatingIP"
+	OpCreateLoadBalancer Operation = "CreateLoadBalancer"
+	OpGetLoadBalancer    Operation = "GetLoadBalancer"
+	OpListLoadBalancers  Operation = "ListLoadBalancers"
+	OpDeleteLoadBalancer Operation = "DeleteLoadBalancer"
+	OpCreateListener     Operation = "CreateListener"
+	OpGetListener        Operation = "GetListener"
+	OpDeleteListener     Operation = "DeleteListener"
+	OpCreatePool         Operation = "CreatePool"
+	OpGetPool            Operation = "GetPool"
+	OpDeletePool         Operation = "DeletePool"
+	OpCreateMember       Operation = "CreateMember"
+	OpUpdateMember       Operation = "UpdateMember"
+	OpListMembers        Operation = "ListMembers"
+	OpDeleteMember       Operation = "DeleteMember"
+	OpCreateMonitor      Operation = "CreateMonitor"
+	OpGetMonitor         Operation = "GetMonitor"
+	OpDeleteMonitor      Operation = "DeleteMonitor"
+)
+
+// Action is a successful mutating call recorded by OSClient, ID is the
+// resource it was made against.
+type Action struct {
+	Op Operation
+	ID string
+}
+
+// OSClient is an in-memory fake of the Neutron and Octavia APIs.
+//
+// Every mutation of a load balancer tree moves the load balancer to a
+// PENDING_* status and the touched object to PENDING_CREATE, PENDING_UPDATE or
+// PENDING_DELETE. Like Octavia, further mutations are rejected with a 409 until
+// the load balancer is ACTIVE again. The pending operation completes after it
+// has been observed PendingPolls times through a Get or List call.
+type OSClient struct {
+	mu sync.Mutex
+
+	// PendingPolls is the number of Get or List calls that observe a load
+	// balancer in a PENDING_* status before the operation completes. Zero
+	// completes every operation synchronously.
+	PendingPolls int
+
+	networks map[string]*mtu.NetworkMTU
+	subnets  map[string]*subnets.Subnet
+	ports    map[string]*portsbinding.PortWithBindingExt
+	// usedIPs is keyed by subnet ID and IP address.
+	usedIPs     map[string]bool
+	floatingIPs map[string]*floatingips.FloatingIP
+
+	lbs       map[string]*lbState
+	listeners map[string]*listeners.Listener
+	pools     map[string]*pools.Pool
+	members   map[string]*pools.Member
+	monitors  map[string]*monitors.Monitor
+	// owner maps listeners, pools, members and monitors to their load balancer.
+	owner map[string]string
+	// status holds the provisioning status of every load balancer object.
+	status map[string]string
+	// failed holds the objects whose pending operation will end in ERROR.
+	failed map[string]bool
+
+	errs     map[Operation][]error
+	provErrs map[Operation]int
+	actions  []Action
+}
+
+type lbState struct {
+	lb    loadbalancers.LoadBalancer
+	polls int
+}
+
+var _ openstackConfig.Interface = &OSClient{}
+
+// NewOSClient returns an empty fake whose pending operations complete on the
+// first poll that observes them.
+func NewOSClient() *OSClient {
+	return &OSClient{
+		PendingPolls: 1,
+		networks:     map[string]*mtu.NetworkMTU{},
+		subnets:      map[string]*subnets.Subnet{},
+		ports:        map[string]*portsbinding.PortWithBindingExt{},
+		usedIPs:      map[string]bool{},
+		floatingIPs:  map[string]*floatingips.FloatingIP{},
+		lbs:          map[string]*lbState{},
+		listeners:    map[string]*listeners.Listener{},
+		pools:        map[string]*pools.Pool{},
+		members:      map[string]*pools.Member{},
+		monitors:     map[string]*monitors.Monitor{},
+		owner:        map[string]string{},
+		status:       map[string]string{},
+		failed:       map[string]bool{},
+		errs:         map[Operation][]error{},
+		provErrs:     map[Operation]int{},
+	}
+}
+
+// AddNetwork seeds a Neutron network.
+func (c *OSClient) AddNetwork(network mtu.NetworkMTU) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.networks[network.ID] = &network
+}
+
+// AddSubnet seeds a Neutron subnet. Ports and load balancer VIPs without a
+// fixed IP get the next free address of the subnet CIDR.
+func (c *OSClient) AddSubnet(subnet subnets.Subnet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subnets[subnet.ID] = &subnet
+	if subnet.GatewayIP != "" {
+		c.usedIPs[subnet.ID+"/"+subnet.GatewayIP] = true
+	}
+	if network, ok := c.networks[subnet.NetworkID]; ok && !containsString(network.Subnets, subnet.ID) {
+		network.Subnets = append(network.Subnets, subnet.ID)
+	}
+}
+
+// SetPortStatus sets the status of a port, e.g. ACTIVE once it is bound by
+// the Neutron agent.
+func (c *OSClient) SetPortStatus(id, status string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if port, ok := c.ports[id]; ok {
+		port.Status = status
+	}
+}
+
+// InjectError makes the next call of op fail with err without changing any
+// state. Errors injected for the same operation are returned in order.
+func (c *OSClient) InjectError(op Operation, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errs[op] = append(c.errs[op], err)
+}
+
+// InjectProvisioningError makes the object created or updated by the next
+// successful call of op end in the ERROR provisioning status. A failed load
+// balancer creation leaves the load balancer in ERROR, a failed child
+// operation only the child.
+func (c *OSClient) InjectProvisioningError(op Operation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.provErrs[op]++
+}
+
+// Settle completes every pending operation immediately.
+func (c *OSClient) Settle() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id := range c.lbs {
+		c.settle(id)
+	}
+}
+
+// Actions returns the mutations made so far, in order.
+func (c *OSClient) Actions() []Action {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Action(nil), c.actions...)
+}
+
+// ClearActions forgets the recorded calls.
+func (c *OSClient) ClearActions() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.actions = nil
+}
+
+// LoadBalancerIDs returns the IDs of the existing load balancers, sorted.
+func (c *OSClient) LoadBalancerIDs() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ids := make([]string, 0, len(c.lbs))
+	for id := range c.lbs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (c *OSClient) CreatePort(opts geportsbinding.CreateOptsExt) (*portsbinding.PortWithBindingExt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpCreatePort); err != nil {
+		return nil, err
+	}
+	body, err := opts.ToPortCreateMap()
+	if err != nil {
+		return nil, err
+	}
+	port := &portsbinding.PortWithBindingExt{}
+	if err := remarshal(body["port"], port); err != nil {
+		return nil, err
+	}
+	if _, ok := c.networks[port.NetworkID]; !ok {
+		return nil, errNotFound("Network", port.NetworkID)
+	}
+	port.ID = string(uuid.NewUUID())
+	if port.MACAddress == "" {
+		port.MACAddress = macFromID(port.ID)
+	}
+	port.Status = "DOWN"
+	if len(port.FixedIPs) == 0 {
+		for _, subnetID := range c.networks[port.NetworkID].Subnets {
+			port.FixedIPs = append(port.FixedIPs, ports.IP{SubnetID: subnetID})
+			break
+		}
+	}
+	if err := c.allocateIPs(port.FixedIPs); err != nil {
+		return nil, err
+	}
+	c.ports[port.ID] = port
+	c.record(OpCreatePort, port.ID)
+	return copyPort(port), nil
+}
+
+func (c *OSClient) GetPort(id string) (*portsbinding.PortWithBindingExt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpGetPort); err != nil {
+		return nil, err
+	}
+	port, ok := c.ports[id]
+	if !ok {
+		return nil, errNotFound("Port", id)
+	}
+	return copyPort(port), nil
+}
+
+func (c *OSClient) DeletePort(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpDeletePort); err != nil {
+		return err
+	}
+	if _, ok := c.ports[id]; !ok {
+		return errNotFound("Port", id)
+	}
+	c.releasePort(id)
+	c.record(OpDeletePort, id)
+	return nil
+}
+
+func (c *OSClient) GetNetwork(id string) (*mtu.NetworkMTU, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpGetNetwork); err != nil {
+		return nil, err
+	}
+	network, ok := c.networks[id]
+	if !ok {
+		return nil, errNotFound("Network", id)
+	}
+	n := *network
+	n.Subnets = append([]string(nil), network.Subnets...)
+	return &n, nil
+}
+
+func (c *OSClient) GetSubnet(id string) (*subnets.Subnet, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpGetSubnet); err != nil {
+		return nil, err
+	}
+	subnet, ok := c.subnets[id]
+	if !ok {
+		return nil, errNotFound("Subnet", id)
+	}
+	s := *subnet
+	return &s, nil
+}
+
+func (c *OSClient) CreateLoadBalancer(opts loadbalancers.CreateOpts) (*loadbalancers.LoadBalancer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpCreateLoadBalancer); err != nil {
+		return nil, err
+	}
+	if _, err := opts.ToLoadBalancerCreateMap(); err != nil {
+		return nil, err
+	}
+	if opts.VipSubnetID == "" {
+		return nil, errBadRequest("vip_subnet_id is required")
+	}
+	subnet, ok := c.subnets[opts.VipSubnetID]
+	if !ok {
+		return nil, errNotFound("Subnet", opts.VipSubnetID)
+	}
+	vip := []ports.IP{{SubnetID: subnet.ID, IPAddress: opts.VipAddress}}
+	if err := c.allocateIPs(vip); err != nil {
+		return nil, err
+	}
+	id := string(uuid.NewUUID())
+	vipPort := &portsbinding.PortWithBindingExt{}
+	vipPort.ID = string(uuid.NewUUID())
+	vipPort.NetworkID = subnet.NetworkID
+	vipPort.MACAddress = macFromID(vipPort.ID)
+	vipPort.FixedIPs = vip
+	vipPort.DeviceID = "lb-" + id
+	vipPort.DeviceOwner = "Octavia"
+	vipPort.Status = "DOWN"
+	c.ports[vipPort.ID] = vipPort
+
+	c.lbs[id] = &lbState{
+		lb: loadbalancers.LoadBalancer{
+			ID:           id,
+			Name:         opts.Name,
+			Description:  opts.Description,
+			ProjectID:    opts.ProjectID,
+			AdminStateUp: opts.AdminStateUp == nil || *opts.AdminStateUp,
+			VipAddress:   vip[0].IPAddress,
+			VipPortID:    vipPort.ID,
+			VipSubnetID:  subnet.ID,
+			VipNetworkID: subnet.NetworkID,
+			Provider:     opts.Provider,
+			Tags:         append([]string(nil), opts.Tags...),
+		},
+		polls: c.PendingPolls,
+	}
+	c.mark(OpCreateLoadBalancer, id, openstackConfig.ProvisioningStatusPendingCreate)
+	c.record(OpCreateLoadBalancer, id)
+	c.finish(id)
+	return c.loadBalancer(id), nil
+}
+
+func (c *OSClient) GetLoadBalancer(id string) (*loadbalancers.LoadBalancer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpGetLoadBalancer); err != nil {
+		return nil, err
+	}
+	if _, ok := c.lbs[id]; !ok {
+		return nil, errNotFound("Load Balancer", id)
+	}
+	lb := c.loadBalancer(id)
+	c.observe(id)
+	return lb, nil
+}
+
+func (c *OSClient) ListLoadBalancers(opts loadbalancers.ListOpts) ([]loadbalancers.LoadBalancer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpListLoadBalancers); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(c.lbs))
+	for id := range c.lbs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var result []loadbalancers.LoadBalancer
+	for _, id := range ids {
+		lb := c.loadBalancer(id)
+		if (opts.ID != "" && opts.ID != lb.ID) ||
+			(opts.Name != "" && opts.Name != lb.Name) ||
+			(opts.ProjectID != "" && opts.ProjectID != lb.ProjectID) ||
+			(opts.VipAddress != "" && opts.VipAddress != lb.VipAddress) ||
+			(opts.VipPortID != "" && opts.VipPortID != lb.VipPortID) {
+			continue
+		}
+		result = append(result, *lb)
+		c.observe(id)
+	}
+	return result, nil
+}
+
+func (c *OSClient) DeleteLoadBalancer(id string, cascade bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpDeleteLoadBalancer); err != nil {
+		return err
+	}
+	st, ok := c.lbs[id]
+	if !ok {
+		return errNotFound("Load Balancer", id)
+	}
+	if s := c.status[id]; s != openstackConfig.ProvisioningStatusActive && s != openstackConfig.ProvisioningStatusError {
+		return errImmutable(id, s)
+	}
+	var children []string
+	for child, lbID := range c.owner {
+		if lbID == id {
+			children = append(children, child)
+		}
+	}
+	if len(children) > 0 && !cascade {
+		return errBadRequest(fmt.Sprintf("Cannot delete Load Balancer %s - it has children", id))
+	}
+	for _, child := range children {
+		c.status[child] = openstackConfig.ProvisioningStatusPendingDelete
+	}
+	c.status[id] = openstackConfig.ProvisioningStatusPendingDelete
+	st.polls = c.PendingPolls
+	c.record(OpDeleteLoadBalancer, id)
+	c.finish(id)
+	return nil
+}
+
+func (c *OSClient) CreateListener(opts listeners.CreateOpts) (*listeners.Listener, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpCreateListener); err != nil {
+		return nil, err
+	}
+	if _, err := opts.ToListenerCreateMap(); err != nil {
+		return nil, err
+	}
+	for _, l := range c.listeners {
+		if c.owner[l.ID] == opts.LoadbalancerID && l.ProtocolPort == opts.ProtocolPort && l.Protocol == string(opts.Protocol) {
+			return nil, errConflict(fmt.Sprintf("Another Listener on this Load Balancer is already using protocol_port %d", opts.ProtocolPort))
+		}
+	}
+	if err := c.lockLoadBalancer(opts.LoadbalancerID); err != nil {
+		return nil, err
+	}
+	listener := &listeners.Listener{
+		ID:            string(uuid.NewUUID()),
+		Name:          opts.Name,
+		Description:   opts.Description,
+		ProjectID:     opts.ProjectID,
+		Protocol:      string(opts.Protocol),
+		ProtocolPort:  opts.ProtocolPort,
+		DefaultPoolID: opts.DefaultPoolID,
+		Loadbalancers: []listeners.LoadBalancerID{{ID: opts.LoadbalancerID}},
+		AdminStateUp:  opts.AdminStateUp == nil || *opts.AdminStateUp,
+		AllowedCIDRs:  append([]string(nil), opts.AllowedCIDRs...),
+	}
+	c.listeners[listener.ID] = listener
+	c.owner[listener.ID] = opts.LoadbalancerID
+	c.mark(OpCreateListener, listener.ID, openstackConfig.ProvisioningStatusPendingCreate)
+	c.record(OpCreateListener, listener.ID)
+	c.finish(opts.LoadbalancerID)
+	return c.listener(listener.ID), nil
+}
+
+func (c *OSClient) GetListener(id string) (*listeners.Listener, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpGetListener); err != nil {
+		return nil, err
+	}
+	if _, ok := c.listeners[id]; !ok {
+		return nil, errNotFound("Listener", id)
+	}
+	listener := c.listener(id)
+	c.observe(c.owner[id])
+	return listener, nil
+}
+
+func (c *OSClient) DeleteListener(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpDeleteListener); err != nil {
+		return err
+	}
+	if _, ok := c.listeners[id]; !ok {
+		return errNotFound("Listener", id)
+	}
+	if err := c.lockLoadBalancer(c.owner[id]); err != nil {
+		return err
+	}
+	c.status[id] = openstackConfig.ProvisioningStatusPendingDelete
+	c.record(OpDeleteListener, id)
+	c.finish(c.owner[id])
+	return nil
+}
+
+func (c *OSClient) CreatePool(opts pools.CreateOpts) (*pools.Pool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpCreatePool); err != nil {
+		return nil, err
+	}
+	if _, err := opts.ToPoolCreateMap(); err != nil {
+		return nil, err
+	}
+	lbID := opts.LoadbalancerID
+	if opts.ListenerID != "" {
+		listener, ok := c.listeners[opts.ListenerID]
+		if !ok {
+			return nil, errNotFound("Listener", opts.ListenerID)
+		}
+		if listener.DefaultPoolID != "" {
+			return nil, errConflict(fmt.Sprintf("Listener %s is already using default pool %s", listener.ID, listener.DefaultPoolID))
+		}
+		lbID = c.owner[listener.ID]
+	}
+	if lbID == "" {
+		return nil, errBadRequest("either loadbalancer_id or listener_id is required")
+	}
+	if err := c.lockLoadBalancer(lbID); err != nil {
+		return nil, err
+	}
+	pool := &pools.Pool{
+		ID:            string(uuid.NewUUID()),
+		Name:          opts.Name,
+		Description:   opts.Description,
+		ProjectID:     opts.ProjectID,
+		LBMethod:      string(opts.LBMethod),
+		Protocol:      string(opts.Protocol),
+		Loadbalancers: []pools.LoadBalancerID{{ID: lbID}},
+		AdminStateUp:  opts.AdminStateUp == nil || *opts.AdminStateUp,
+	}
+	if opts.Persistence != nil {
+		pool.Persistence = *opts.Persistence
+	}
+	if opts.ListenerID != "" {
+		c.listeners[opts.ListenerID].DefaultPoolID = pool.ID
+	}
+	c.pools[pool.ID] = pool
+	c.owner[pool.ID] = lbID
+	c.mark(OpCreatePool, pool.ID, openstackConfig.ProvisioningStatusPendingCreate)
+	c.record(OpCreatePool, pool.ID)
+	c.finish(lbID)
+	return c.pool(pool.ID), nil
+}
+
+func (c *OSClient) GetPool(id string) (*pools.Pool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpGetPool); err != nil {
+		return nil, err
+	}
+	if _, ok := c.pools[id]; !ok {
+		return nil, errNotFound("Pool", id)
+	}
+	pool := c.pool(id)
+	c.observe(c.owner[id])
+	return pool, nil
+}
+
+// DeletePool deletes the pool together with its members and health monitor,
+// as Octavia does.
+func (c *OSClient) DeletePool(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpDeletePool); err != nil {
+		return err
+	}
+	if _, ok := c.pools[id]; !ok {
+		return errNotFound("Pool", id)
+	}
+	if err := c.lockLoadBalancer(c.owner[id]); err != nil {
+		return err
+	}
+	c.status[id] = openstackConfig.ProvisioningStatusPendingDelete
+	for _, m := range c.members {
+		if m.PoolID == id {
+			c.status[m.ID] = openstackConfig.ProvisioningStatusPendingDelete
+		}
+	}
+	for _, hm := range c.monitors {
+		if monitorPool(hm) == id {
+			c.status[hm.ID] = openstackConfig.ProvisioningStatusPendingDelete
+		}
+	}
+	c.record(OpDeletePool, id)
+	c.finish(c.owner[id])
+	return nil
+}
+
+func (c *OSClient) CreateMember(poolID string, opts pools.CreateMemberOpts) (*pools.Member, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpCreateMember); err != nil {
+		return nil, err
+	}
+	if _, err := opts.ToMemberCreateMap(); err != nil {
+		return nil, err
+	}
+	if _, ok := c.pools[poolID]; !ok {
+		return nil, errNotFound("Pool", poolID)
+	}
+	if net.ParseIP(opts.Address) == nil {
+		return nil, errBadRequest(fmt.Sprintf("Invalid input for field/attribute address. Value: '%s'", opts.Address))
+	}
+	for _, m := range c.members {
+		if m.PoolID == poolID && m.Address == opts.Address && m.ProtocolPort == opts.ProtocolPort {
+			return nil, errConflict(fmt.Sprintf("Duplicate member with address %s and port %d", opts.Address, opts.ProtocolPort))
+		}
+	}
+	if err := c.lockLoadBalancer(c.owner[poolID]); err != nil {
+		return nil, err
+	}
+	member := &pools.Member{
+		ID:           string(uuid.NewUUID()),
+		Name:         opts.Name,
+		ProjectID:    opts.ProjectID,
+		PoolID:       poolID,
+		SubnetID:     opts.SubnetID,
+		Address:      opts.Address,
+		ProtocolPort: opts.ProtocolPort,
+		Weight:       1,
+		AdminStateUp: opts.AdminStateUp == nil || *opts.AdminStateUp,
+	}
+	if opts.Weight != nil {
+		member.Weight = *opts.Weight
+	}
+	c.members[member.ID] = member
+	c.owner[member.ID] = c.owner[poolID]
+	c.mark(OpCreateMember, member.ID, openstackConfig.ProvisioningStatusPendingCreate)
+	c.record(OpCreateMember, member.ID)
+	c.finish(c.owner[poolID])
+	return c.member(member.ID), nil
+}
+
+func (c *OSClient) UpdateMember(poolID, memberID string, opts pools.UpdateMemberOpts) (*pools.Member, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpUpdateMember); err != nil {
+		return nil, err
+	}
+	member, ok := c.members[memberID]
+	if !ok || member.PoolID != poolID {
+		return nil, errNotFound("Member", memberID)
+	}
+	if err := c.lockLoadBalancer(c.owner[memberID]); err != nil {
+		return nil, err
+	}
+	if opts.Name != nil {
+		member.Name = *opts.Name
+	}
+	if opts.Weight != nil {
+		member.Weight = *opts.Weight
+	}
+	if opts.AdminStateUp != nil {
+		member.AdminStateUp = *opts.AdminStateUp
+	}
+	c.mark(OpUpdateMember, memberID, openstackConfig.ProvisioningStatusPendingUpdate)
+	c.record(OpUpdateMember, memberID)
+	c.finish(c.owner[memberID])
+	return c.member(memberID), nil
+}
+
+func (c *OSClient) ListMembers(poolID string) ([]pools.Member, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpListMembers); err != nil {
+		return nil, err
+	}
+	if _, ok := c.pools[poolID]; !ok {
+		return nil, errNotFound("Pool", poolID)
+	}
+	members := c.pool(poolID).Members
+	c.observe(c.owner[poolID])
+	return members, nil
+}
+
+func (c *OSClient) DeleteMember(poolID, memberID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpDeleteMember); err != nil {
+		return err
+	}
+	member, ok := c.members[memberID]
+	if !ok || member.PoolID != poolID {
+		return errNotFound("Member", memberID)
+	}
+	if err := c.lockLoadBalancer(c.owner[memberID]); err != nil {
+		return err
+	}
+	c.status[memberID] = openstackConfig.ProvisioningStatusPendingDelete
+	c.record(OpDeleteMember, memberID)
+	c.finish(c.owner[memberID])
+	return nil
+}
+
+func (c *OSClient) CreateMonitor(opts monitors.CreateOpts) (*monitors.Monitor, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpCreateMonitor); err != nil {
+		return nil, err
+	}
+	if _, err := opts.ToMonitorCreateMap(); err != nil {
+		return nil, err
+	}
+	if _, ok := c.pools[opts.PoolID]; !ok {
+		return nil, errNotFound("Pool", opts.PoolID)
+	}
+	for _, hm := range c.monitors {
+		if monitorPool(hm) == opts.PoolID {
+			return nil, errConflict(fmt.Sprintf("This pool already has a health monitor %s", hm.ID))
+		}
+	}
+	if err := c.lockLoadBalancer(c.owner[opts.PoolID]); err != nil {
+		return nil, err
+	}
+	monitor := &monitors.Monitor{
+		ID:             string(uuid.NewUUID()),
+		Name:           opts.Name,
+		ProjectID:      opts.ProjectID,
+		Type:           opts.Type,
+		Delay:          opts.Delay,
+		Timeout:        opts.Timeout,
+		MaxRetries:     opts.MaxRetries,
+		MaxRetriesDown: opts.MaxRetriesDown,
+		HTTPMethod:     opts.HTTPMethod,
+		URLPath:        opts.URLPath,
+		ExpectedCodes:  opts.ExpectedCodes,
+		AdminStateUp:   opts.AdminStateUp == nil || *opts.AdminStateUp,
+		Pools:          []monitors.PoolID{{ID: opts.PoolID}},
+	}
+	c.monitors[monitor.ID] = monitor
+	c.owner[monitor.ID] = c.owner[opts.PoolID]
+	c.mark(OpCreateMonitor, monitor.ID, openstackConfig.ProvisioningStatusPendingCreate)
+	c.record(OpCreateMonitor, monitor.ID)
+	c.finish(c.owner[monitor.ID])
+	return c.monitor(monitor.ID), nil
+}
+
+func (c *OSClient) GetMonitor(id string) (*monitors.Monitor, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpGetMonitor); err != nil {
+		return nil, err
+	}
+	if _, ok := c.monitors[id]; !ok {
+		return nil, errNotFound("Health Monitor", id)
+	}
+	monitor := c.monitor(id)
+	c.observe(c.owner[id])
+	return monitor, nil
+}
+
+func (c *OSClient) DeleteMonitor(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpDeleteMonitor); err != nil {
+		return err
+	}
+	if _, ok := c.monitors[id]; !ok {
+		return errNotFound("Health Monitor", id)
+	}
+	if err := c.lockLoadBalancer(c.owner[id]); err != nil {
+		return err
+	}
+	c.status[id] = openstackConfig.ProvisioningStatusPendingDelete
+	c.record(OpDeleteMonitor, id)
+	c.finish(c.owner[id])
+	return nil
+}
@@ -0,0 +1,236 @@
+package fake
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/acceptance/openstack/networking/v2/extensions/mtu"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/loadbalancers"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/monitors"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/pools"
+	geportsbinding "github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/portsbinding"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"projectkuryr/kuryr/pkg/openstack/openstackConfig"
+)
+
+const (
+	testNetID    = "net-1"
+	testSubnetID = "subnet-1"
+)
+
+func newTestClient(pendingPolls int) *OSClient {
+	c := NewOSClient()
+	c.PendingPolls = pendingPolls
+	network := mtu.NetworkMTU{Network: networks.Network{ID: testNetID}}
+	network.MTU = 1450
+	c.AddNetwork(network)
+	c.AddSubnet(subnets.Subnet{ID: testSubnetID, NetworkID: testNetID, CIDR: "10.0.0.0/29", GatewayIP: "10.0.0.1"})
+	return c
+}
+
+func newActiveLB(t *testing.T, c *OSClient) *loadbalancers.LoadBalancer {
+	lb, err := c.CreateLoadBalancer(loadbalancers.CreateOpts{Name: "default/svc", VipSubnetID: testSubnetID})
+	require.NoError(t, err)
+	c.Settle()
+	return lb
+}
+
+func TestLoadBalancerProvisioning(t *testing.T) {
+	tests := []struct {
+		name         string
+		pendingPolls int
+		injectOp     Operation
+		expected     []string
+	}{
+		{
+			name:         "synchronous",
+			pendingPolls: 0,
+			expected:     []string{"ACTIVE", "ACTIVE"},
+		},
+		{
+			name:         "one poll",
+			pendingPolls: 1,
+			expected:     []string{"PENDING_CREATE", "PENDING_CREATE", "ACTIVE"},
+		},
+		{
+			name:         "three polls",
+			pendingPolls: 3,
+			expected:     []string{"PENDING_CREATE", "PENDING_CREATE", "PENDING_CREATE", "PENDING_CREATE", "ACTIVE"},
+		},
+		{
+			name:         "provisioning error",
+			pendingPolls: 1,
+			injectOp:     OpCreateLoadBalancer,
+			expected:     []string{"PENDING_CREATE", "PENDING_CREATE", "ERROR"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(tt.pendingPolls)
+			if tt.injectOp != "" {
+				c.InjectProvisioningError(tt.injectOp)
+			}
+			lb, err := c.CreateLoadBalancer(loadbalancers.CreateOpts{Name: "default/svc", VipSubnetID: testSubnetID})
+			require.NoError(t, err)
+			assert.Equal(t, "10.0.0.2", lb.VipAddress)
+			statuses := []string{lb.ProvisioningStatus}
+			for i := 1; i < len(tt.expected); i++ {
+				lb, err = c.GetLoadBalancer(lb.ID)
+				require.NoError(t, err)
+				statuses = append(statuses, lb.ProvisioningStatus)
+			}
+			assert.Equal(t, tt.expected, statuses)
+		})
+	}
+}
+
+func TestImmutableLoadBalancer(t *testing.T) {
+	c := newTestClient(1)
+	lb, err := c.CreateLoadBalancer(loadbalancers.CreateOpts{VipSubnetID: testSubnetID})
+	require.NoError(t, err)
+
+	_, err = c.CreateListener(listeners.CreateOpts{LoadbalancerID: lb.ID, Protocol: listeners.ProtocolTCP, ProtocolPort: 80})
+	assert.IsType(t, gophercloud.ErrDefault409{}, err)
+
+	c.Settle()
+	listener, err := c.CreateListener(listeners.CreateOpts{LoadbalancerID: lb.ID, Protocol: listeners.ProtocolTCP, ProtocolPort: 80})
+	require.NoError(t, err)
+	assert.Equal(t, openstackConfig.ProvisioningStatusPendingCreate, listener.ProvisioningStatus)
+	lb, err = c.GetLoadBalancer(lb.ID)
+	require.NoError(t, err)
+	assert.Equal(t, openstackConfig.ProvisioningStatusPendingUpdate, lb.ProvisioningStatus)
+
+	listener, err = c.GetListener(listener.ID)
+	require.NoError(t, err)
+	assert.Equal(t, openstackConfig.ProvisioningStatusActive, listener.ProvisioningStatus)
+}
+
+func TestLoadBalancerChildren(t *testing.T) {
+	c := newTestClient(0)
+	lb := newActiveLB(t, c)
+
+	listener, err := c.CreateListener(listeners.CreateOpts{LoadbalancerID: lb.ID, Protocol: listeners.ProtocolTCP, ProtocolPort: 80})
+	require.NoError(t, err)
+	pool, err := c.CreatePool(pools.CreateOpts{ListenerID: listener.ID, Protocol: pools.ProtocolTCP, LBMethod: pools.LBMethodRoundRobin})
+	require.NoError(t, err)
+	hm, err := c.CreateMonitor(monitors.CreateOpts{PoolID: pool.ID, Type: "TCP", Delay: 5, Timeout: 3, MaxRetries: 3})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		address string
+		port    int
+		err     interface{}
+	}{
+		{name: "first member", address: "10.1.0.5", port: 8080},
+		{name: "second member", address: "10.1.0.6", port: 8080},
+		{name: "duplicate member", address: "10.1.0.5", port: 8080, err: gophercloud.ErrDefault409{}},
+		{name: "invalid address", address: "foo", port: 8080, err: gophercloud.ErrDefault400{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			member, err := c.CreateMember(pool.ID, pools.CreateMemberOpts{Address: tt.address, ProtocolPort: tt.port})
+			if tt.err != nil {
+				assert.IsType(t, tt.err, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 1, member.Weight)
+			assert.Equal(t, openstackConfig.ProvisioningStatusActive, member.ProvisioningStatus)
+		})
+	}
+
+	pool, err = c.GetPool(pool.ID)
+	require.NoError(t, err)
+	assert.Equal(t, hm.ID, pool.MonitorID)
+	assert.Equal(t, []pools.ListenerID{{ID: listener.ID}}, pool.Listeners)
+	assert.Len(t, pool.Members, 2)
+
+	weight := 0
+	member, err := c.UpdateMember(pool.ID, pool.Members[0].ID, pools.UpdateMemberOpts{Weight: &weight})
+	require.NoError(t, err)
+	assert.Equal(t, 0, member.Weight)
+
+	assert.IsType(t, gophercloud.ErrDefault400{}, c.DeleteLoadBalancer(lb.ID, false))
+	require.NoError(t, c.DeletePool(pool.ID))
+	_, err = c.GetMonitor(hm.ID)
+	assert.IsType(t, gophercloud.ErrDefault404{}, err)
+	listener, err = c.GetListener(listener.ID)
+	require.NoError(t, err)
+	assert.Empty(t, listener.DefaultPoolID)
+
+	require.NoError(t, c.DeleteLoadBalancer(lb.ID, true))
+	_, err = c.GetLoadBalancer(lb.ID)
+	assert.IsType(t, gophercloud.ErrDefault404{}, err)
+	_, err = c.GetPort(lb.VipPortID)
+	assert.IsType(t, gophercloud.ErrDefault404{}, err)
+
+	var ops []Operation
+	for _, a := range c.Actions() {
+		ops = append(ops, a.Op)
+	}
+	assert.Equal(t, []Operation{OpCreateLoadBalancer, OpCreateListener, OpCreatePool, OpCreateMonitor,
+		OpCreateMember, OpCreateMember, OpUpdateMember, OpDeletePool, OpDeleteLoadBalancer}, ops)
+}
+
+func TestInjectError(t *testing.T) {
+	c := newTestClient(0)
+	lb := newActiveLB(t, c)
+	injected := errors.New("octavia is down")
+
+	c.InjectError(OpCreateListener, injected)
+	_, err := c.CreateListener(listeners.CreateOpts{LoadbalancerID: lb.ID, Protocol: listeners.ProtocolTCP, ProtocolPort: 80})
+	assert.Equal(t, injected, err)
+	lb, err = c.GetLoadBalancer(lb.ID)
+	require.NoError(t, err)
+	assert.Equal(t, openstackConfig.ProvisioningStatusActive, lb.ProvisioningStatus)
+	assert.Empty(t, lb.Listeners)
+
+	c.InjectProvisioningError(OpCreateListener)
+	listener, err := c.CreateListener(listeners.CreateOpts{LoadbalancerID: lb.ID, Protocol: listeners.ProtocolTCP, ProtocolPort: 80})
+	require.NoError(t, err)
+	assert.Equal(t, openstackConfig.ProvisioningStatusError, listener.ProvisioningStatus)
+	lb, err = c.GetLoadBalancer(lb.ID)
+	require.NoError(t, err)
+	assert.Equal(t, openstackConfig.ProvisioningStatusActive, lb.ProvisioningStatus)
+}
+
+func TestPortAllocation(t *testing.T) {
+	c := newTestClient(0)
+	tests := []struct {
+		name     string
+		fixedIPs []ports.IP
+		expected string
+		err      interface{}
+	}{
+		{name: "next free address", expected: "10.0.0.2"},
+		{name: "fixed address", fixedIPs: []ports.IP{{SubnetID: testSubnetID, IPAddress: "10.0.0.5"}}, expected: "10.0.0.5"},
+		{name: "address in use", fixedIPs: []ports.IP{{SubnetID: testSubnetID, IPAddress: "10.0.0.5"}}, err: gophercloud.ErrDefault409{}},
+		{name: "address out of subnet", fixedIPs: []ports.IP{{SubnetID: testSubnetID, IPAddress: "10.0.1.5"}}, err: gophercloud.ErrDefault400{}},
+		{name: "unknown subnet", fixedIPs: []ports.IP{{SubnetID: "foo"}}, err: gophercloud.ErrDefault404{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := ports.CreateOpts{NetworkID: testNetID}
+			if tt.fixedIPs != nil {
+				opts.FixedIPs = tt.fixedIPs
+			}
+			port, err := c.CreatePort(geportsbinding.CreateOptsExt{CreateOptsBuilder: opts, HostID: "node-1"})
+			if tt.err != nil {
+				assert.IsType(t, tt.err, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "node-1", port.HostID)
+			require.Len(t, port.FixedIPs, 1)
+			assert.Equal(t, tt.expected, port.FixedIPs[0].IPAddress)
+		})
+	}
+}
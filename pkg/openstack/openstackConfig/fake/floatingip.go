@@ -0,0 +1,172 @@
+package fake
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/floatingips"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+// FloatingIPs returns the existing floating IPs sorted by address.
+func (c *OSClient) FloatingIPs() []floatingips.FloatingIP {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var fips []floatingips.FloatingIP
+	for _, fip := range c.floatingIPs {
+		fips = append(fips, *fip)
+	}
+	sort.Slice(fips, func(i, j int) bool { return fips[i].FloatingIP < fips[j].FloatingIP })
+	return fips
+}
+
+func (c *OSClient) CreateFloatingIP(opts floatingips.CreateOpts) (*floatingips.FloatingIP, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpCreateFloatingIP); err != nil {
+		return nil, err
+	}
+	if _, err := opts.ToFloatingIPCreateMap(); err != nil {
+		return nil, err
+	}
+	network, ok := c.networks[opts.FloatingNetworkID]
+	if !ok {
+		return nil, errNotFound("External network", opts.FloatingNetworkID)
+	}
+	if len(network.Subnets) == 0 {
+		return nil, errBadRequest(fmt.Sprintf("External network %s has no subnet", network.ID))
+	}
+	fip := &floatingips.FloatingIP{
+		ID:                string(uuid.NewUUID()),
+		Description:       opts.Description,
+		FloatingNetworkID: network.ID,
+		ProjectID:         opts.ProjectID,
+		Status:            "DOWN",
+	}
+	if opts.PortID != "" {
+		if err := c.checkFloatingIPPort(opts.PortID, ""); err != nil {
+			return nil, err
+		}
+	}
+	subnetID := network.Subnets[0]
+	if opts.SubnetID != "" {
+		subnetID = opts.SubnetID
+	}
+	address := []ports.IP{{SubnetID: subnetID, IPAddress: opts.FloatingIP}}
+	if err := c.allocateIPs(address); err != nil {
+		return nil, err
+	}
+	fip.FloatingIP = address[0].IPAddress
+	c.associate(fip, opts.PortID)
+	c.floatingIPs[fip.ID] = fip
+	c.record(OpCreateFloatingIP, fip.ID)
+	f := *fip
+	return &f, nil
+}
+
+func (c *OSClient) GetFloatingIP(id string) (*floatingips.FloatingIP, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpGetFloatingIP); err != nil {
+		return nil, err
+	}
+	fip, ok := c.floatingIPs[id]
+	if !ok {
+		return nil, errNotFound("Floating IP", id)
+	}
+	f := *fip
+	return &f, nil
+}
+
+func (c *OSClient) ListFloatingIPs(opts floatingips.ListOpts) ([]floatingips.FloatingIP, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpListFloatingIPs); err != nil {
+		return nil, err
+	}
+	var result []floatingips.FloatingIP
+	for _, fip := range c.floatingIPs {
+		if (opts.ID != "" && opts.ID != fip.ID) ||
+			(opts.Description != "" && opts.Description != fip.Description) ||
+			(opts.FloatingNetworkID != "" && opts.FloatingNetworkID != fip.FloatingNetworkID) ||
+			(opts.PortID != "" && opts.PortID != fip.PortID) ||
+			(opts.FloatingIP != "" && opts.FloatingIP != fip.FloatingIP) ||
+			(opts.ProjectID != "" && opts.ProjectID != fip.ProjectID) {
+			continue
+		}
+		result = append(result, *fip)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].FloatingIP < result[j].FloatingIP })
+	return result, nil
+}
+
+func (c *OSClient) UpdateFloatingIP(id string, opts floatingips.UpdateOpts) (*floatingips.FloatingIP, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpUpdateFloatingIP); err != nil {
+		return nil, err
+	}
+	fip, ok := c.floatingIPs[id]
+	if !ok {
+		return nil, errNotFound("Floating IP", id)
+	}
+	if opts.PortID != nil && *opts.PortID != "" {
+		if err := c.checkFloatingIPPort(*opts.PortID, id); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Description != nil {
+		fip.Description = *opts.Description
+	}
+	if opts.PortID != nil {
+		c.associate(fip, *opts.PortID)
+	}
+	c.record(OpUpdateFloatingIP, id)
+	f := *fip
+	return &f, nil
+}
+
+func (c *OSClient) DeleteFloatingIP(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin(OpDeleteFloatingIP); err != nil {
+		return err
+	}
+	fip, ok := c.floatingIPs[id]
+	if !ok {
+		return errNotFound("Floating IP", id)
+	}
+	for _, subnetID := range c.networks[fip.FloatingNetworkID].Subnets {
+		delete(c.usedIPs, subnetID+"/"+fip.FloatingIP)
+	}
+	delete(c.floatingIPs, id)
+	c.record(OpDeleteFloatingIP, id)
+	return nil
+}
+
+// checkFloatingIPPort verifies that portID exists and has no floating IP
+// other than fipID.
+func (c *OSClient) checkFloatingIPPort(portID, fipID string) error {
+	if _, ok := c.ports[portID]; !ok {
+		return errNotFound("Port", portID)
+	}
+	for _, fip := range c.floatingIPs {
+		if fip.PortID == portID && fip.ID != fipID {
+			return errConflict(fmt.Sprintf("Port %s already has floating IP %s associated", portID, fip.FloatingIP))
+		}
+	}
+	return nil
+}
+
+func (c *OSClient) associate(fip *floatingips.FloatingIP, portID string) {
+	fip.PortID = portID
+	fip.FixedIP = ""
+	fip.Status = "DOWN"
+	if port, ok := c.ports[portID]; ok {
+		if len(port.FixedIPs) > 0 {
+			fip.FixedIP = port.FixedIPs[0].IPAddress
+		}
+		fip.Status = "ACTIVE"
+	}
+}
@@ -0,0 +1,385 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/acceptance/openstack/networking/v2/extensions/portsbinding"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/loadbalancers"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/monitors"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/pools"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+
+	"projectkuryr/kuryr/pkg/openstack/openstackConfig"
+)
+
+// All the helpers below must be called with c.mu held.
+
+// begin returns the next error injected for op, if any.
+func (c *OSClient) begin(op Operation) error {
+	errs := c.errs[op]
+	if len(errs) == 0 {
+		return nil
+	}
+	c.errs[op] = errs[1:]
+	return errs[0]
+}
+
+func (c *OSClient) record(op Operation, id string) {
+	c.actions = append(c.actions, Action{Op: op, ID: id})
+}
+
+// mark sets the pending status of an object touched by op and consumes a
+// provisioning error injected for op.
+func (c *OSClient) mark(op Operation, id, status string) {
+	c.status[id] = status
+	if c.provErrs[op] > 0 {
+		c.provErrs[op]--
+		c.failed[id] = true
+	}
+}
+
+// lockLoadBalancer moves an ACTIVE load balancer to PENDING_UPDATE before one
+// of its children is changed.
+func (c *OSClient) lockLoadBalancer(lbID string) error {
+	st, ok := c.lbs[lbID]
+	if !ok {
+		return errNotFound("Load Balancer", lbID)
+	}
+	if s := c.status[lbID]; s != openstackConfig.ProvisioningStatusActive {
+		return errImmutable(lbID, s)
+	}
+	c.status[lbID] = openstackConfig.ProvisioningStatusPendingUpdate
+	st.polls = c.PendingPolls
+	return nil
+}
+
+// finish completes the operation right away when PendingPolls is zero.
+func (c *OSClient) finish(lbID string) {
+	if c.PendingPolls <= 0 {
+		c.settle(lbID)
+	}
+}
+
+// observe counts a poll of a pending load balancer and completes its pending
+// operation once it has been polled PendingPolls times.
+func (c *OSClient) observe(lbID string) {
+	st, ok := c.lbs[lbID]
+	if !ok || !isPending(c.status[lbID]) {
+		return
+	}
+	st.polls--
+	if st.polls <= 0 {
+		c.settle(lbID)
+	}
+}
+
+func (c *OSClient) settle(lbID string) {
+	st, ok := c.lbs[lbID]
+	if !ok {
+		return
+	}
+	st.polls = 0
+	lbStatus := c.status[lbID]
+	if lbStatus == openstackConfig.ProvisioningStatusPendingDelete {
+		for id, owner := range c.owner {
+			if owner == lbID {
+				c.remove(id)
+			}
+		}
+		c.releasePort(st.lb.VipPortID)
+		delete(c.lbs, lbID)
+		delete(c.status, lbID)
+		delete(c.failed, lbID)
+		return
+	}
+	for id, owner := range c.owner {
+		if owner != lbID {
+			continue
+		}
+		switch c.status[id] {
+		case openstackConfig.ProvisioningStatusPendingCreate, openstackConfig.ProvisioningStatusPendingUpdate:
+			c.status[id] = c.outcome(id)
+		case openstackConfig.ProvisioningStatusPendingDelete:
+			c.remove(id)
+		}
+	}
+	if isPending(lbStatus) {
+		c.status[lbID] = c.outcome(lbID)
+	}
+}
+
+func (c *OSClient) outcome(id string) string {
+	if c.failed[id] {
+		delete(c.failed, id)
+		return openstackConfig.ProvisioningStatusError
+	}
+	return openstackConfig.ProvisioningStatusActive
+}
+
+// remove forgets a listener, pool, member or monitor.
+func (c *OSClient) remove(id string) {
+	if _, ok := c.pools[id]; ok {
+		for _, l := range c.listeners {
+			if l.DefaultPoolID == id {
+				l.DefaultPoolID = ""
+			}
+		}
+	}
+	delete(c.listeners, id)
+	delete(c.pools, id)
+	delete(c.members, id)
+	delete(c.monitors, id)
+	delete(c.owner, id)
+	delete(c.status, id)
+	delete(c.failed, id)
+}
+
+func (c *OSClient) loadBalancer(id string) *loadbalancers.LoadBalancer {
+	st := c.lbs[id]
+	lb := st.lb
+	lb.Tags = append([]string(nil), st.lb.Tags...)
+	lb.ProvisioningStatus = c.status[id]
+	lb.OperatingStatus = operatingStatus(lb.ProvisioningStatus)
+	lb.Listeners = nil
+	for _, childID := range c.children(id) {
+		if _, ok := c.listeners[childID]; ok {
+			lb.Listeners = append(lb.Listeners, *c.listener(childID))
+		}
+	}
+	lb.Pools = nil
+	for _, childID := range c.children(id) {
+		if _, ok := c.pools[childID]; ok {
+			lb.Pools = append(lb.Pools, *c.pool(childID))
+		}
+	}
+	return &lb
+}
+
+func (c *OSClient) listener(id string) *listeners.Listener {
+	l := *c.listeners[id]
+	l.Loadbalancers = append([]listeners.LoadBalancerID(nil), l.Loadbalancers...)
+	l.AllowedCIDRs = append([]string(nil), l.AllowedCIDRs...)
+	l.ProvisioningStatus = c.status[id]
+	return &l
+}
+
+func (c *OSClient) pool(id string) *pools.Pool {
+	p := *c.pools[id]
+	p.Loadbalancers = append([]pools.LoadBalancerID(nil), p.Loadbalancers...)
+	p.ProvisioningStatus = c.status[id]
+	p.OperatingStatus = operatingStatus(p.ProvisioningStatus)
+	p.Listeners = nil
+	p.Members = nil
+	p.MonitorID = ""
+	for _, childID := range c.children(c.owner[id]) {
+		if l, ok := c.listeners[childID]; ok && l.DefaultPoolID == id {
+			p.Listeners = append(p.Listeners, pools.ListenerID{ID: childID})
+		}
+		if m, ok := c.members[childID]; ok && m.PoolID == id {
+			p.Members = append(p.Members, *c.member(childID))
+		}
+		if hm, ok := c.monitors[childID]; ok && monitorPool(hm) == id {
+			p.MonitorID = childID
+		}
+	}
+	return &p
+}
+
+func (c *OSClient) member(id string) *pools.Member {
+	m := *c.members[id]
+	m.ProvisioningStatus = c.status[id]
+	m.OperatingStatus = operatingStatus(m.ProvisioningStatus)
+	return &m
+}
+
+func (c *OSClient) monitor(id string) *monitors.Monitor {
+	hm := *c.monitors[id]
+	hm.Pools = append([]monitors.PoolID(nil), hm.Pools...)
+	hm.ProvisioningStatus = c.status[id]
+	hm.OperatingStatus = operatingStatus(hm.ProvisioningStatus)
+	return &hm
+}
+
+// children returns the sorted IDs of the objects owned by a load balancer.
+func (c *OSClient) children(lbID string) []string {
+	var ids []string
+	for id, owner := range c.owner {
+		if owner == lbID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// allocateIPs reserves the requested addresses and fills in the missing ones
+// with the next free address of their subnet.
+func (c *OSClient) allocateIPs(ips []ports.IP) error {
+	var reserved []string
+	rollback := func() {
+		for _, key := range reserved {
+			delete(c.usedIPs, key)
+		}
+	}
+	for i := range ips {
+		subnet, ok := c.subnets[ips[i].SubnetID]
+		if !ok {
+			rollback()
+			return errNotFound("Subnet", ips[i].SubnetID)
+		}
+		_, cidr, err := net.ParseCIDR(subnet.CIDR)
+		if err != nil {
+			rollback()
+			return errBadRequest(fmt.Sprintf("Subnet %s has an invalid CIDR %q", subnet.ID, subnet.CIDR))
+		}
+		if ips[i].IPAddress == "" {
+			ip := nextFreeIP(cidr, func(ip net.IP) bool { return c.usedIPs[subnet.ID+"/"+ip.String()] })
+			if ip == nil {
+				rollback()
+				return errConflict(fmt.Sprintf("No more IP addresses available on subnet %s", subnet.ID))
+			}
+			ips[i].IPAddress = ip.String()
+		} else {
+			ip := net.ParseIP(ips[i].IPAddress)
+			if ip == nil || !cidr.Contains(ip) {
+				rollback()
+				return errBadRequest(fmt.Sprintf("IP address %s is not a valid IP for subnet %s", ips[i].IPAddress, subnet.ID))
+			}
+			if c.usedIPs[subnet.ID+"/"+ip.String()] {
+				rollback()
+				return errConflict(fmt.Sprintf("IP address %s already allocated in subnet %s", ips[i].IPAddress, subnet.ID))
+			}
+			ips[i].IPAddress = ip.String()
+		}
+		key := subnet.ID + "/" + ips[i].IPAddress
+		c.usedIPs[key] = true
+		reserved = append(reserved, key)
+	}
+	return nil
+}
+
+func (c *OSClient) releasePort(id string) {
+	port, ok := c.ports[id]
+	if !ok {
+		return
+	}
+	for _, ip := range port.FixedIPs {
+		delete(c.usedIPs, ip.SubnetID+"/"+ip.IPAddress)
+	}
+	// Like Neutron, deleting a port disassociates its floating IPs.
+	for _, fip := range c.floatingIPs {
+		if fip.PortID == id {
+			fip.PortID = ""
+			fip.FixedIP = ""
+			fip.Status = "DOWN"
+		}
+	}
+	delete(c.ports, id)
+}
+
+// nextFreeIP returns the lowest address of cidr, excluding the network and
+// the last address, for which used returns false.
+func nextFreeIP(cidr *net.IPNet, used func(net.IP) bool) net.IP {
+	ip := nextIP(cidr.IP)
+	for cidr.Contains(ip) && cidr.Contains(nextIP(ip)) {
+		if !used(ip) {
+			return ip
+		}
+		ip = nextIP(ip)
+	}
+	return nil
+}
+
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+func isPending(status string) bool {
+	switch status {
+	case openstackConfig.ProvisioningStatusPendingCreate,
+		openstackConfig.ProvisioningStatusPendingUpdate,
+		openstackConfig.ProvisioningStatusPendingDelete:
+		return true
+	}
+	return false
+}
+
+func operatingStatus(provisioningStatus string) string {
+	switch provisioningStatus {
+	case openstackConfig.ProvisioningStatusActive:
+		return "ONLINE"
+	case openstackConfig.ProvisioningStatusError:
+		return "ERROR"
+	}
+	return "OFFLINE"
+}
+
+func monitorPool(hm *monitors.Monitor) string {
+	if len(hm.Pools) == 0 {
+		return ""
+	}
+	return hm.Pools[0].ID
+}
+
+func copyPort(port *portsbinding.PortWithBindingExt) *portsbinding.PortWithBindingExt {
+	p := *port
+	p.FixedIPs = append([]ports.IP(nil), port.FixedIPs...)
+	p.SecurityGroups = append([]string(nil), port.SecurityGroups...)
+	return &p
+}
+
+// macFromID derives a stable Neutron-like MAC address from a resource ID.
+func macFromID(id string) string {
+	return fmt.Sprintf("fa:16:3e:%s:%s:%s", id[0:2], id[2:4], id[4:6])
+}
+
+func remarshal(in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func containsString(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func unexpectedResponse(code int, msg string) gophercloud.ErrUnexpectedResponseCode {
+	return gophercloud.ErrUnexpectedResponseCode{Actual: code, Body: []byte(msg)}
+}
+
+func errNotFound(kind, id string) error {
+	return gophercloud.ErrDefault404{ErrUnexpectedResponseCode: unexpectedResponse(http.StatusNotFound, fmt.Sprintf("%s %s could not be found.", kind, id))}
+}
+
+func errConflict(msg string) error {
+	return gophercloud.ErrDefault409{ErrUnexpectedResponseCode: unexpectedResponse(http.StatusConflict, msg)}
+}
+
+func errBadRequest(msg string) error {
+	return gophercloud.ErrDefault400{ErrUnexpectedResponseCode: unexpectedResponse(http.StatusBadRequest, msg)}
+}
+
+func errImmutable(lbID, status string) error {
+	return errConflict(fmt.Sprintf("Load Balancer %s is immutable and cannot be updated, provisioning status is %s.", lbID, status))
+}
@@ -0,0 +1,40 @@
+package openstackConfig
+
+import (
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/floatingips"
+	"k8s.io/klog"
+)
+
+func (c *OSClient) CreateFloatingIP(opts floatingips.CreateOpts) (*floatingips.FloatingIP, error) {
+	fip, err := floatingips.Create(c.netClient, opts).Extract()
+	if err != nil {
+		klog.Errorf("Create floating IP Failed. opts: %+v, Error: %v", opts, err)
+	}
+	return fip, err
+}
+
+func (c *OSClient) GetFloatingIP(id string) (*floatingips.FloatingIP, error) {
+	return floatingips.Get(c.netClient, id).Extract()
+}
+
+func (c *OSClient) ListFloatingIPs(opts floatingips.ListOpts) ([]floatingips.FloatingIP, error) {
+	allPages, err := floatingips.List(c.netClient, opts).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	return floatingips.ExtractFloatingIPs(allPages)
+}
+
+// UpdateFloatingIP associates the floating IP with opts.PortID, an empty
+// PortID disassociates it.
+func (c *OSClient) UpdateFloatingIP(id string, opts floatingips.UpdateOpts) (*floatingips.FloatingIP, error) {
+	fip, err := floatingips.Update(c.netClient, id, opts).Extract()
+	if err != nil {
+		klog.Errorf("Update floating IP %s Failed. opts: %+v, Error: %v", id, opts, err)
+	}
+	return fip, err
+}
+
+func (c *OSClient) DeleteFloatingIP(id string) error {
+	return floatingips.Delete(c.netClient, id).ExtractErr()
+}
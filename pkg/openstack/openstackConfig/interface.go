@@ -3,6 +3,11 @@ package openstackConfig
 import (
 	"github.com/gophercloud/gophercloud/acceptance/openstack/networking/v2/extensions/mtu"
 	"github.com/gophercloud/gophercloud/acceptance/openstack/networking/v2/extensions/portsbinding"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/loadbalancers"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/monitors"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/pools"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/floatingips"
 	geportsbinding "github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/portsbinding"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
 )
@@ -14,4 +19,35 @@ type Interface interface {
 
 	GetNetwork(id string) (*mtu.NetworkMTU, error)
 	GetSubnet(id string) (*subnets.Subnet, error)
+
+	CreateFloatingIP(opts floatingips.CreateOpts) (*floatingips.FloatingIP, error)
+	GetFloatingIP(id string) (*floatingips.FloatingIP, error)
+	ListFloatingIPs(opts floatingips.ListOpts) ([]floatingips.FloatingIP, error)
+	UpdateFloatingIP(id string, opts floatingips.UpdateOpts) (*floatingips.FloatingIP, error)
+	DeleteFloatingIP(id string) error
+
+	// Octavia load balancer API. Every mutating call moves the parent load
+	// balancer to PENDING_UPDATE, callers must wait for it to become ACTIVE
+	// again before issuing the next mutation.
+	CreateLoadBalancer(opts loadbalancers.CreateOpts) (*loadbalancers.LoadBalancer, error)
+	GetLoadBalancer(id string) (*loadbalancers.LoadBalancer, error)
+	ListLoadBalancers(opts loadbalancers.ListOpts) ([]loadbalancers.LoadBalancer, error)
+	DeleteLoadBalancer(id string, cascade bool) error
+
+	CreateListener(opts listeners.CreateOpts) (*listeners.Listener, error)
+	GetListener(id string) (*listeners.Listener, error)
+	DeleteListener(id string) error
+
+	CreatePool(opts pools.CreateOpts) (*pools.Pool, error)
+	GetPool(id string) (*pools.Pool, error)
+	DeletePool(id string) error
+
+	CreateMember(poolID string, opts pools.CreateMemberOpts) (*pools.Member, error)
+	UpdateMember(poolID, memberID string, opts pools.UpdateMemberOpts) (*pools.Member, error)
+	ListMembers(poolID string) ([]pools.Member, error)
+	DeleteMember(poolID, memberID string) error
+
+	CreateMonitor(opts monitors.CreateOpts) (*monitors.Monitor, error)
+	GetMonitor(id string) (*monitors.Monitor, error)
+	DeleteMonitor(id string) error
 }
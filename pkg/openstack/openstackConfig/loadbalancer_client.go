@@ -0,0 +1,171 @@
+package openstackConfig
+
+import (
+	"errors"
+
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/loadbalancers"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/monitors"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/pools"
+	"k8s.io/klog"
+)
+
+// Octavia provisioning statuses shared by load balancers and their children.
+const (
+	ProvisioningStatusActive        = "ACTIVE"
+	ProvisioningStatusPendingCreate = "PENDING_CREATE"
+	ProvisioningStatusPendingUpdate = "PENDING_UPDATE"
+	ProvisioningStatusPendingDelete = "PENDING_DELETE"
+	ProvisioningStatusError         = "ERROR"
+)
+
+// ErrLoadBalancerUnavailable is returned by the Octavia methods when no
+// load-balancer endpoint was found in the service catalog.
+var ErrLoadBalancerUnavailable = errors.New("octavia load-balancer endpoint is not available")
+
+func (c *OSClient) CreateLoadBalancer(opts loadbalancers.CreateOpts) (*loadbalancers.LoadBalancer, error) {
+	if c.lbClient == nil {
+		return nil, ErrLoadBalancerUnavailable
+	}
+	lb, err := loadbalancers.Create(c.lbClient, opts).Extract()
+	if err != nil {
+		klog.Errorf("Create loadbalancer Failed. opts: %+v, Error: %v", opts, err)
+	}
+	return lb, err
+}
+
+func (c *OSClient) GetLoadBalancer(id string) (*loadbalancers.LoadBalancer, error) {
+	if c.lbClient == nil {
+		return nil, ErrLoadBalancerUnavailable
+	}
+	return loadbalancers.Get(c.lbClient, id).Extract()
+}
+
+func (c *OSClient) ListLoadBalancers(opts loadbalancers.ListOpts) ([]loadbalancers.LoadBalancer, error) {
+	if c.lbClient == nil {
+		return nil, ErrLoadBalancerUnavailable
+	}
+	allPages, err := loadbalancers.List(c.lbClient, opts).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	return loadbalancers.ExtractLoadBalancers(allPages)
+}
+
+func (c *OSClient) DeleteLoadBalancer(id string, cascade bool) error {
+	if c.lbClient == nil {
+		return ErrLoadBalancerUnavailable
+	}
+	return loadbalancers.Delete(c.lbClient, id, loadbalancers.DeleteOpts{Cascade: cascade}).ExtractErr()
+}
+
+func (c *OSClient) CreateListener(opts listeners.CreateOpts) (*listeners.Listener, error) {
+	if c.lbClient == nil {
+		return nil, ErrLoadBalancerUnavailable
+	}
+	listener, err := listeners.Create(c.lbClient, opts).Extract()
+	if err != nil {
+		klog.Errorf("Create listener Failed. opts: %+v, Error: %v", opts, err)
+	}
+	return listener, err
+}
+
+func (c *OSClient) GetListener(id string) (*listeners.Listener, error) {
+	if c.lbClient == nil {
+		return nil, ErrLoadBalancerUnavailable
+	}
+	return listeners.Get(c.lbClient, id).Extract()
+}
+
+func (c *OSClient) DeleteListener(id string) error {
+	if c.lbClient == nil {
+		return ErrLoadBalancerUnavailable
+	}
+	return listeners.Delete(c.lbClient, id).ExtractErr()
+}
+
+func (c *OSClient) CreatePool(opts pools.CreateOpts) (*pools.Pool, error) {
+	if c.lbClient == nil {
+		return nil, ErrLoadBalancerUnavailable
+	}
+	pool, err := pools.Create(c.lbClient, opts).Extract()
+	if err != nil {
+		klog.Errorf("Create pool Failed. opts: %+v, Error: %v", opts, err)
+	}
+	return pool, err
+}
+
+func (c *OSClient) GetPool(id string) (*pools.Pool, error) {
+	if c.lbClient == nil {
+		return nil, ErrLoadBalancerUnavailable
+	}
+	return pools.Get(c.lbClient, id).Extract()
+}
+
+func (c *OSClient) DeletePool(id string) error {
+	if c.lbClient == nil {
+		return ErrLoadBalancerUnavailable
+	}
+	return pools.Delete(c.lbClient, id).ExtractErr()
+}
+
+func (c *OSClient) CreateMember(poolID string, opts pools.CreateMemberOpts) (*pools.Member, error) {
+	if c.lbClient == nil {
+		return nil, ErrLoadBalancerUnavailable
+	}
+	member, err := pools.CreateMember(c.lbClient, poolID, opts).Extract()
+	if err != nil {
+		klog.Errorf("Create member in pool %s Failed. opts: %+v, Error: %v", poolID, opts, err)
+	}
+	return member, err
+}
+
+func (c *OSClient) UpdateMember(poolID, memberID string, opts pools.UpdateMemberOpts) (*pools.Member, error) {
+	if c.lbClient == nil {
+		return nil, ErrLoadBalancerUnavailable
+	}
+	return pools.UpdateMember(c.lbClient, poolID, memberID, opts).Extract()
+}
+
+func (c *OSClient) ListMembers(poolID string) ([]pools.Member, error) {
+	if c.lbClient == nil {
+		return nil, ErrLoadBalancerUnavailable
+	}
+	allPages, err := pools.ListMembers(c.lbClient, poolID, pools.ListMembersOpts{}).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	return pools.ExtractMembers(allPages)
+}
+
+func (c *OSClient) DeleteMember(poolID, memberID string) error {
+	if c.lbClient == nil {
+		return ErrLoadBalancerUnavailable
+	}
+	return pools.DeleteMember(c.lbClient, poolID, memberID).ExtractErr()
+}
+
+func (c *OSClient) CreateMonitor(opts monitors.CreateOpts) (*monitors.Monitor, error) {
+	if c.lbClient == nil {
+		return nil, ErrLoadBalancerUnavailable
+	}
+	monitor, err := monitors.Create(c.lbClient, opts).Extract()
+	if err != nil {
+		klog.Errorf("Create health monitor Failed. opts: %+v, Error: %v", opts, err)
+	}
+	return monitor, err
+}
+
+func (c *OSClient) GetMonitor(id string) (*monitors.Monitor, error) {
+	if c.lbClient == nil {
+		return nil, ErrLoadBalancerUnavailable
+	}
+	return monitors.Get(c.lbClient, id).Extract()
+}
+
+func (c *OSClient) DeleteMonitor(id string) error {
+	if c.lbClient == nil {
+		return ErrLoadBalancerUnavailable
+	}
+	return monitors.Delete(c.lbClient, id).ExtractErr()
+}
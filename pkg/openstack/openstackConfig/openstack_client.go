@@ -18,6 +18,8 @@ type OSClient struct {
 	region string
 	providerClient *gophercloud.ProviderClient
 	netClient *gophercloud.ServiceClient
+	// lbClient is nil when the cloud has no load-balancer endpoint.
+	lbClient *gophercloud.ServiceClient
 }
 
 //def setup_openstacksdk():
@@ -34,10 +36,18 @@ func NewOSClient(opts *gophercloud.AuthOptions, region string) (*OSClient, error
 		klog.Errorf("NewNetworkV2 Error: %v!\n\n" , err)
 		return nil, err
 	}
+	// Octavia is optional, pods networking keeps working without it.
+	lbClient, err := openstack.NewLoadBalancerV2(providerClient, gophercloud.EndpointOpts{Region: region})
+	if err != nil {
+		klog.Warningf("NewLoadBalancerV2 Error: %v, LoadBalancer Services will not be supported", err)
+		lbClient = nil
+		err = nil
+	}
 	osClient := &OSClient{
 		providerClient: providerClient,
 		region: region,
 		netClient: netClient,
+		lbClient: lbClient,
 	}
 
 	klog.Infof("netClient: %+v\n\n" , netClient.Endpoint)
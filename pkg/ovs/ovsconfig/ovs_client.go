@@ -30,7 +30,9 @@ type OVSPortData struct {
 	IFName      string
 	OFPort      int32
 	ExternalIDs map[string]string
-	Options     map[string]string
+	// Interface external IDs.
+	IFExternalIDs map[string]string
+	Options       map[string]string
 }
 
 const (
@@ -351,7 +353,7 @@ func (br *OVSBridge) CreateInternalPort(name string, ofPortRequest int32, extern
 	if ofPortRequest < 0 || ofPortRequest > ofPortRequestMax {
 		return "", newInvalidArgumentsError(fmt.Sprint("invalid ofPortRequest value: ", ofPortRequest))
 	}
-	return br.createPort(name, name, "internal", ofPortRequest, externalIDs, nil)
+	return br.createPort(name, name, "internal", ofPortRequest, externalIDs, nil, nil)
 }
 
 // CreateTunnelPort creates a tunnel port with the specified name and type on
@@ -422,7 +424,7 @@ func (br *OVSBridge) createTunnelPort(
 		options["csum"] = "true"
 	}
 
-	return br.createPort(name, name, string(tunnelType), ofPortRequest, externalIDs, options)
+	return br.createPort(name, name, string(tunnelType), ofPortRequest, externalIDs, nil, options)
 }
 
 // GetInterfaceOptions returns the options of the provided interface.
@@ -444,6 +446,28 @@ func (br *OVSBridge) GetInterfaceOptions(name string) (map[string]string, Error)
 	return buildMapFromOVSDBMap(optionsRes), nil
 }
 
+// GetInterfaceExternalIDs returns the external IDs of the provided interface.
+func (br *OVSBridge) GetInterfaceExternalIDs(name string) (map[string]string, Error) {
+	tx := br.ovsdb.Transaction(openvSwitchSchema)
+	tx.Select(dbtransaction.Select{
+		Table:   "Interface",
+		Where:   [][]interface{}{{"name", "==", name}},
+		Columns: []string{"external_ids"},
+	})
+
+	res, err, temporary := tx.Commit()
+	if err != nil {
+		klog.Error("Transaction failed: ", err)
+		return nil, NewTransactionError(err, temporary)
+	}
+
+	if len(res[0].Rows) == 0 {
+		return nil, newInvalidArgumentsError(fmt.Sprintf("interface %s not found", name))
+	}
+	externalIDsRes := res[0].Rows[0].(map[string]interface{})["external_ids"].([]interface{})
+	return buildMapFromOVSDBMap(externalIDsRes), nil
+}
+
 // SetInterfaceOptions sets the specified options of the provided interface.
 func (br *OVSBridge) SetInterfaceOptions(name string, options map[string]interface{}) Error {
 	tx := br.ovsdb.Transaction(openvSwitchSchema)
@@ -494,7 +518,7 @@ func ParseTunnelInterfaceOptions(portData *OVSPortData) (net.IP, net.IP, string,
 
 // CreateUplinkPort creates uplink port.
 func (br *OVSBridge) CreateUplinkPort(name string, ofPortRequest int32, externalIDs map[string]interface{}) (string, Error) {
-	return br.createPort(name, name, "", ofPortRequest, externalIDs, nil)
+	return br.createPort(name, name, "", ofPortRequest, externalIDs, nil, nil)
 }
 
 // CreatePort creates a port with the specified name on the bridge, and connects
@@ -502,16 +526,26 @@ func (br *OVSBridge) CreateUplinkPort(name string, ofPortRequest int32, external
 // If externalIDs is not empty, the map key/value pairs will be set to the
 // port's external_ids.
 func (br *OVSBridge) CreatePort(name, ifDev string, externalIDs map[string]interface{}) (string, Error) {
-	return br.createPort(name, ifDev, "", 0, externalIDs, nil)
+	return br.createPort(name, ifDev, "", 0, externalIDs, nil, nil)
 }
 
-func (br *OVSBridge) createPort(name, ifName, ifType string, ofPortRequest int32, externalIDs, options map[string]interface{}) (string, Error) {
+// CreatePortExt creates a port like CreatePort, externalIDs are set on the
+// port and ifExternalIDs on its interface.
+func (br *OVSBridge) CreatePortExt(name, ifDev string, externalIDs, ifExternalIDs map[string]interface{}) (string, Error) {
+	return br.createPort(name, ifDev, "", 0, externalIDs, ifExternalIDs, nil)
+}
+
+func (br *OVSBridge) createPort(name, ifName, ifType string, ofPortRequest int32, externalIDs, ifExternalIDs, options map[string]interface{}) (string, Error) {
 	var externalIDMap []interface{}
+	var ifExternalIDMap []interface{}
 	var optionMap []interface{}
 
 	if externalIDs != nil {
 		externalIDMap = helpers.MakeOVSDBMap(externalIDs)
 	}
+	if ifExternalIDs != nil {
+		ifExternalIDMap = helpers.MakeOVSDBMap(ifExternalIDs)
+	}
 	if options != nil {
 		optionMap = helpers.MakeOVSDBMap(options)
 	}
@@ -522,6 +556,7 @@ func (br *OVSBridge) createPort(name, ifName, ifType string, ofPortRequest int32
 		Name:          ifName,
 		Type:          ifType,
 		OFPortRequest: ofPortRequest,
+		ExternalIDs:   ifExternalIDMap,
 		Options:       optionMap,
 	}
 	ifNamedUUID := tx.Insert(dbtransaction.Insert{
@@ -619,6 +654,7 @@ func buildMapFromOVSDBMap(data []interface{}) map[string]string {
 func buildPortDataCommon(port, intf map[string]interface{}, portData *OVSPortData) {
 	portData.Name = port["name"].(string)
 	portData.ExternalIDs = buildMapFromOVSDBMap(port["external_ids"].([]interface{}))
+	portData.IFExternalIDs = buildMapFromOVSDBMap(intf["external_ids"].([]interface{}))
 	portData.Options = buildMapFromOVSDBMap(intf["options"].([]interface{}))
 	portData.IFType = intf["type"].(string)
 	if ofPort, ok := intf["ofport"].(float64); ok {
@@ -641,7 +677,7 @@ func (br *OVSBridge) GetPortData(portUUID, ifName string) (*OVSPortData, Error)
 	})
 	tx.Select(dbtransaction.Select{
 		Table:   "Interface",
-		Columns: []string{"_uuid", "type", "ofport", "options"},
+		Columns: []string{"_uuid", "type", "ofport", "external_ids", "options"},
 		Where:   [][]interface{}{{"name", "==", ifName}},
 	})
 
@@ -694,7 +730,7 @@ func (br *OVSBridge) GetPortList() ([]OVSPortData, Error) {
 	})
 	tx.Select(dbtransaction.Select{
 		Table:   "Interface",
-		Columns: []string{"_uuid", "type", "name", "ofport", "options"},
+		Columns: []string{"_uuid", "type", "name", "ofport", "external_ids", "options"},
 	})
 
 	res, err, temporary := tx.Commit()
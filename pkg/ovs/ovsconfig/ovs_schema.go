@@ -16,6 +16,7 @@ type Interface struct {
 	Name          string        `json:"name"`
 	Type          string        `json:"type,omitempty"`
 	OFPortRequest int32         `json:"ofport_request,omitempty"`
+	ExternalIDs   []interface{} `json:"external_ids,omitempty"`
 	Options       []interface{} `json:"options,omitempty"`
 }
 
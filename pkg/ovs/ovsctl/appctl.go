@@ -103,6 +103,10 @@ func (c *ovsCtlClient) Trace(req *TracingRequest) (string, error) {
 		}
 	}
 
+	if ipKey != "" && req.Flow != "" {
+		ipKey += ","
+	}
+
 	// "ip" or IP protocol must be set before "nw_ttl", "nw_src", "nw_dst", and
 	// "tp_port". For IPv6 packet, "ipv6" is required as a precondition.
 	flow := inPort + dlSrc + dlDst + ipKey + req.Flow + "," + nwTTL + nwSrc + nwDst
@@ -0,0 +1,70 @@
+package proxy
+
+import (
+	"net"
+	"strconv"
+)
+
+// BaseEndpointInfo contains base information that defines an endpoint.
+// This could be used directly by proxier while processing endpoints,
+// or can be used for constructing a more specific EndpointInfo struct
+// defined by the proxier if needed.
+type BaseEndpointInfo struct {
+	Endpoint string // TODO: should be an endpointString type
+	// IsLocal indicates whether the endpoint is running in same host as kube-proxy.
+	IsLocal  bool
+	Topology map[string]string
+}
+
+var _ Endpoint = &BaseEndpointInfo{}
+
+// NewBaseEndpointInfo returns the endpoint ip:port, an IPv6 address is
+// enclosed in brackets.
+func NewBaseEndpointInfo(ip string, port int, isLocal bool, topology map[string]string) *BaseEndpointInfo {
+	return &BaseEndpointInfo{
+		Endpoint: net.JoinHostPort(ip, strconv.Itoa(port)),
+		IsLocal:  isLocal,
+		Topology: topology,
+	}
+}
+
+// String is part of proxy.Endpoint interface.
+func (info *BaseEndpointInfo) String() string {
+	return info.Endpoint
+}
+
+// GetIsLocal is part of proxy.Endpoint interface.
+func (info *BaseEndpointInfo) GetIsLocal() bool {
+	return info.IsLocal
+}
+
+// GetTopology returns the topology information of the endpoint.
+func (info *BaseEndpointInfo) GetTopology() map[string]string {
+	return info.Topology
+}
+
+// IP returns just the IP part of the endpoint, it's a part of proxy.Endpoint interface.
+func (info *BaseEndpointInfo) IP() string {
+	host, _, err := net.SplitHostPort(info.Endpoint)
+	if err != nil {
+		return ""
+	}
+	if net.ParseIP(host) == nil {
+		return ""
+	}
+	return host
+}
+
+// Port returns just the Port part of the endpoint.
+func (info *BaseEndpointInfo) Port() (int, error) {
+	_, port, err := net.SplitHostPort(info.Endpoint)
+	if err != nil {
+		return -1, err
+	}
+	return strconv.Atoi(port)
+}
+
+// Equal is part of proxy.Endpoint interface.
+func (info *BaseEndpointInfo) Equal(other Endpoint) bool {
+	return info.String() == other.String() && info.GetIsLocal() == other.GetIsLocal()
+}
@@ -1,16 +1,323 @@
 package proxy
 
 import (
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
+
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
-	"sync"
+	"k8s.io/klog"
+	utilnet "k8s.io/utils/net"
 )
 
+// BaseServiceInfo contains base information that defines a service.
+// This could be used directly by proxier while processing services,
+// or can be used for constructing a more specific ServiceInfo struct
+// defined by the proxier if needed.
+type BaseServiceInfo struct {
+	clusterIP                net.IP
+	port                     int
+	protocol                 corev1.Protocol
+	nodePort                 int
+	loadBalancerStatus       corev1.LoadBalancerStatus
+	sessionAffinityType      corev1.ServiceAffinity
+	stickyMaxAgeSeconds      int
+	externalIPs              []string
+	loadBalancerSourceRanges []string
+	healthCheckNodePort      int
+	onlyNodeLocalEndpoints   bool
+	topologyKeys             []string
+}
+
+var _ ServicePort = &BaseServiceInfo{}
+
+// String is part of ServicePort interface.
+func (info *BaseServiceInfo) String() string {
+	return fmt.Sprintf("%s:%d/%s", info.clusterIP, info.port, info.protocol)
+}
+
+// ClusterIP is part of ServicePort interface.
+func (info *BaseServiceInfo) ClusterIP() net.IP {
+	return info.clusterIP
+}
+
+// Port is part of ServicePort interface.
+func (info *BaseServiceInfo) Port() int {
+	return info.port
+}
+
+// SessionAffinityType is part of the ServicePort interface.
+func (info *BaseServiceInfo) SessionAffinityType() corev1.ServiceAffinity {
+	return info.sessionAffinityType
+}
+
+// StickyMaxAgeSeconds is part of the ServicePort interface
+func (info *BaseServiceInfo) StickyMaxAgeSeconds() int {
+	return info.stickyMaxAgeSeconds
+}
+
+// Protocol is part of ServicePort interface.
+func (info *BaseServiceInfo) Protocol() corev1.Protocol {
+	return info.protocol
+}
+
+// LoadBalancerSourceRanges is part of ServicePort interface
+func (info *BaseServiceInfo) LoadBalancerSourceRanges() []string {
+	return info.loadBalancerSourceRanges
+}
+
+// HealthCheckNodePort is part of ServicePort interface.
+func (info *BaseServiceInfo) HealthCheckNodePort() int {
+	return info.healthCheckNodePort
+}
+
+// NodePort is part of the ServicePort interface.
+func (info *BaseServiceInfo) NodePort() int {
+	return info.nodePort
+}
+
+// ExternalIPStrings is part of ServicePort interface.
+func (info *BaseServiceInfo) ExternalIPStrings() []string {
+	return info.externalIPs
+}
+
+// LoadBalancerIPStrings is part of ServicePort interface.
+func (info *BaseServiceInfo) LoadBalancerIPStrings() []string {
+	var ips []string
+	for _, ing := range info.loadBalancerStatus.Ingress {
+		ips = append(ips, ing.IP)
+	}
+	return ips
+}
+
+// OnlyNodeLocalEndpoints is part of ServicePort interface.
+func (info *BaseServiceInfo) OnlyNodeLocalEndpoints() bool {
+	return info.onlyNodeLocalEndpoints
+}
+
+// TopologyKeys is part of ServicePort interface.
+func (info *BaseServiceInfo) TopologyKeys() []string {
+	return info.topologyKeys
+}
+
+// requestsOnlyLocalTraffic checks if service requests OnlyLocal traffic.
+func requestsOnlyLocalTraffic(service *corev1.Service) bool {
+	if service.Spec.Type != corev1.ServiceTypeLoadBalancer &&
+		service.Spec.Type != corev1.ServiceTypeNodePort {
+		return false
+	}
+	return service.Spec.ExternalTrafficPolicy == corev1.ServiceExternalTrafficPolicyTypeLocal
+}
+
+func (sct *ServiceChangeTracker) newBaseServiceInfo(port *corev1.ServicePort, service *corev1.Service) *BaseServiceInfo {
+	onlyNodeLocalEndpoints := requestsOnlyLocalTraffic(service)
+	var stickyMaxAgeSeconds int
+	if service.Spec.SessionAffinity == corev1.ServiceAffinityClientIP {
+		stickyMaxAgeSeconds = int(corev1.DefaultClientIPServiceAffinitySeconds)
+		// Kube-apiserver side guarantees SessionAffinityConfig won't be nil when session affinity type is ClientIP
+		if cfg := service.Spec.SessionAffinityConfig; cfg != nil && cfg.ClientIP != nil && cfg.ClientIP.TimeoutSeconds != nil {
+			stickyMaxAgeSeconds = int(*cfg.ClientIP.TimeoutSeconds)
+		}
+	}
+	info := &BaseServiceInfo{
+		clusterIP:              net.ParseIP(service.Spec.ClusterIP),
+		port:                   int(port.Port),
+		protocol:               port.Protocol,
+		nodePort:               int(port.NodePort),
+		sessionAffinityType:    service.Spec.SessionAffinity,
+		stickyMaxAgeSeconds:    stickyMaxAgeSeconds,
+		onlyNodeLocalEndpoints: onlyNodeLocalEndpoints,
+		topologyKeys:           service.Spec.TopologyKeys,
+	}
+
+	// Only keep the addresses of the IP family handled by the tracker.
+	isIPv6 := sct.ipFamily == corev1.IPv6Protocol
+	for _, ip := range service.Spec.ExternalIPs {
+		if utilnet.IsIPv6String(ip) == isIPv6 {
+			info.externalIPs = append(info.externalIPs, ip)
+		}
+	}
+	for _, cidr := range service.Spec.LoadBalancerSourceRanges {
+		if utilnet.IsIPv6CIDRString(cidr) == isIPv6 {
+			info.loadBalancerSourceRanges = append(info.loadBalancerSourceRanges, cidr)
+		}
+	}
+	for _, ing := range service.Status.LoadBalancer.Ingress {
+		if ing.IP != "" && utilnet.IsIPv6String(ing.IP) == isIPv6 {
+			info.loadBalancerStatus.Ingress = append(info.loadBalancerStatus.Ingress, ing)
+		}
+	}
+
+	if onlyNodeLocalEndpoints {
+		p := service.Spec.HealthCheckNodePort
+		if p == 0 {
+			klog.Errorf("Service %s/%s has no healthcheck nodeport", service.Namespace, service.Name)
+		} else {
+			info.healthCheckNodePort = int(p)
+		}
+	}
+
+	return info
+}
+
+type makeServicePortFunc func(*corev1.ServicePort, *corev1.Service, *BaseServiceInfo) ServicePort
+
+// NewServiceChangeTracker initializes a ServiceChangeTracker. makeServiceInfo
+// may be nil, the BaseServiceInfo is then used as the ServicePort.
+func NewServiceChangeTracker(makeServiceInfo makeServicePortFunc, ipFamily corev1.IPFamily, recorder record.EventRecorder) *ServiceChangeTracker {
+	return &ServiceChangeTracker{
+		items:           make(map[types.NamespacedName]*serviceChange),
+		makeServiceInfo: makeServiceInfo,
+		ipFamily:        ipFamily,
+		recorder:        recorder,
+	}
+}
+
+// Update updates given service's change map based on the <previous, current> service pair.  It returns true if items changed,
+// otherwise return false.  Update can be used to add/update/delete items of ServiceChangeMap.  For example,
+// Add item
+//   - pass <nil, service> as the <previous, current> pair.
+//
+// Update item
+//   - pass <oldService, service> as the <previous, current> pair.
+//
+// Delete item
+//   - pass <service, nil> as the <previous, current> pair.
+func (sct *ServiceChangeTracker) Update(previous, current *corev1.Service) bool {
+	svc := current
+	if svc == nil {
+		svc = previous
+	}
+	// previous == nil && current == nil is unexpected, we should return false directly.
+	if svc == nil {
+		return false
+	}
+	namespacedName := types.NamespacedName{Namespace: svc.Namespace, Name: svc.Name}
+
+	sct.lock.Lock()
+	defer sct.lock.Unlock()
+
+	change, exists := sct.items[namespacedName]
+	if !exists {
+		change = &serviceChange{}
+		change.previous = sct.serviceToServiceMap(previous)
+		sct.items[namespacedName] = change
+	}
+	change.current = sct.serviceToServiceMap(current)
+	// if change.previous equal to change.current, it means no change
+	if reflect.DeepEqual(change.previous, change.current) {
+		delete(sct.items, namespacedName)
+	}
+	klog.V(2).Infof("Service %s updated: %d ports", namespacedName, len(change.current))
+	return len(sct.items) > 0
+}
+
+// ShouldSkipService checks if a given service should skip proxying
+func ShouldSkipService(svcName types.NamespacedName, service *corev1.Service) bool {
+	// if ClusterIP is "None" or empty, skip proxying
+	if service.Spec.ClusterIP == corev1.ClusterIPNone || service.Spec.ClusterIP == "" {
+		klog.V(3).Infof("Skipping service %s due to clusterIP = %q", svcName, service.Spec.ClusterIP)
+		return true
+	}
+	// Even if ClusterIP is set, ServiceTypeExternalName services don't get proxied
+	if service.Spec.Type == corev1.ServiceTypeExternalName {
+		klog.V(3).Infof("Skipping service %s due to Type=ExternalName", svcName)
+		return true
+	}
+	return false
+}
+
+// serviceToServiceMap translates a single Service object to a ServiceMap.
+//
+// NOTE: service object should NOT be modified.
+func (sct *ServiceChangeTracker) serviceToServiceMap(service *corev1.Service) ServiceMap {
+	if service == nil {
+		return nil
+	}
+	svcName := types.NamespacedName{Namespace: service.Namespace, Name: service.Name}
+	if ShouldSkipService(svcName, service) {
+		return nil
+	}
+
+	if utilnet.IsIPv6String(service.Spec.ClusterIP) != (sct.ipFamily == corev1.IPv6Protocol) {
+		if sct.recorder != nil {
+			sct.recorder.Eventf(service, corev1.EventTypeWarning, "KubeProxyIncorrectIPVersion",
+				"%s in spec.clusterIP has incorrect IP version (service %s/%s).", service.Spec.ClusterIP, service.Namespace, service.Name)
+		}
+		return nil
+	}
+
+	serviceMap := make(ServiceMap)
+	for i := range service.Spec.Ports {
+		servicePort := &service.Spec.Ports[i]
+		svcPortName := ServicePortName{NamespacedName: svcName, Port: servicePort.Name, Protocol: servicePort.Protocol}
+		baseSvcInfo := sct.newBaseServiceInfo(servicePort, service)
+		if sct.makeServiceInfo != nil {
+			serviceMap[svcPortName] = sct.makeServiceInfo(servicePort, service, baseSvcInfo)
+		} else {
+			serviceMap[svcPortName] = baseSvcInfo
+		}
+	}
+	return serviceMap
+}
+
+// Update updates ServiceMap base on the given changes.
+func (sm ServiceMap) Update(changes *ServiceChangeTracker) {
+	changes.lock.Lock()
+	defer changes.lock.Unlock()
+	for _, change := range changes.items {
+		sm.unmerge(change.previous)
+		sm.merge(change.current)
+		if changes.processServiceMapChange != nil {
+			changes.processServiceMapChange(change.previous, change.current)
+		}
+	}
+	// clear changes after applying them to ServiceMap.
+	changes.items = make(map[types.NamespacedName]*serviceChange)
+}
+
+// merge adds other ServiceMap's elements to current ServiceMap.
+func (sm ServiceMap) merge(other ServiceMap) {
+	for svcPortName, info := range other {
+		_, exists := sm[svcPortName]
+		if !exists {
+			klog.V(1).Infof("Adding new service port %q at %s", svcPortName, info.String())
+		} else {
+			klog.V(1).Infof("Updating existing service port %q at %s", svcPortName, info.String())
+		}
+		sm[svcPortName] = info
+	}
+}
+
+// unmerge deletes all other ServiceMap's elements from current ServiceMap.
+func (sm ServiceMap) unmerge(other ServiceMap) {
+	for svcPortName := range other {
+		if _, exists := sm[svcPortName]; exists {
+			klog.V(1).Infof("Removing service port %q", svcPortName)
+			delete(sm, svcPortName)
+		} else {
+			klog.Errorf("Service port %q doesn't exists", svcPortName)
+		}
+	}
+}
+
 // ServiceMap maps a service to its ServicePort.
 type ServiceMap map[ServicePortName]ServicePort
 
-
+// HealthCheckNodePorts returns the health check node port of the Services
+// with externalTrafficPolicy Local, keyed by Service.
+func (sm ServiceMap) HealthCheckNodePorts() map[types.NamespacedName]uint16 {
+	ports := make(map[types.NamespacedName]uint16)
+	for svcPortName, info := range sm {
+		if info.HealthCheckNodePort() != 0 {
+			ports[svcPortName.NamespacedName] = uint16(info.HealthCheckNodePort())
+		}
+	}
+	return ports
+}
 
 // serviceChange contains all changes to services that happened since proxy rules were synced.  For a single object,
 // changes are accumulated, i.e. previous is state from before applying the changes,
@@ -28,7 +335,7 @@ type ServiceChangeTracker struct {
 	// items maps a service to its serviceChange.
 	items map[types.NamespacedName]*serviceChange
 	// makeServiceInfo allows proxier to inject customized information when processing service.
-	//makeServiceInfo         makeServicePortFunc
+	makeServiceInfo         makeServicePortFunc
 	processServiceMapChange processServiceMapChangeFunc
 	ipFamily                corev1.IPFamily
 
@@ -38,4 +345,3 @@ type ServiceChangeTracker struct {
 // This handler is invoked by the apply function on every change. This function should not modify the
 // ServiceMap's but just use the changes for any Proxier specific cleanup.
 type processServiceMapChangeFunc func(previous, current ServiceMap)
-
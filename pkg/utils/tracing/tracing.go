@@ -0,0 +1,187 @@
+// Package tracing records the OpenTelemetry spans of the stages of the networking of a Pod, e.g. the
+// creation of its Neutron port by kuryr-controller and the CNI ADD of kuryr-agent. The spans without
+// parent use the UID of the Pod as trace ID, so that the spans of kuryr-controller and kuryr-agent are
+// in the same trace. kuryr-cni sends the trace context of a CNI request to kuryr-agent in the gRPC
+// metadata, the spans of the request are its children. The spans are only recorded once Init is
+// called, the default OpenTelemetry tracer provider discards them.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const tracerName = "projectkuryr/kuryr"
+
+// propagator carries the trace context in the W3C traceparent header.
+var propagator = propagation.TraceContext{}
+
+// Span is a stage of the networking of a Pod.
+type Span interface {
+	// SetAttribute sets an attribute of the span, e.g. the ID of the VIF.
+	SetAttribute(key string, value interface{})
+	// End ends the span, err is the error of the stage when it failed.
+	End(err error)
+}
+
+// Init exports the spans of the component to the Jaeger collector, the default endpoint of the
+// exporter is used when collectorEndpoint is empty. The returned function flushes the spans.
+func Init(serviceName, collectorEndpoint string) (func(context.Context) error, error) {
+	var endpointOptions []jaeger.CollectorEndpointOption
+	if collectorEndpoint != "" {
+		endpointOptions = append(endpointOptions, jaeger.WithEndpoint(collectorEndpoint))
+	}
+	exporter, err := jaeger.New(jaeger.WithCollectorEndpoint(endpointOptions...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the Jaeger exporter: %v", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithIDGenerator(podIDGenerator{}),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+type podUIDKey struct{}
+
+// Start starts the span of a stage in the trace of the Pod UID, as a child of the span of ctx if
+// any.
+func Start(ctx context.Context, podUID, name string) (context.Context, Span) {
+	if podUID != "" {
+		ctx = context.WithValue(ctx, podUIDKey{}, podUID)
+	}
+	ctx, span := otel.Tracer(tracerName).Start(ctx, name)
+	if podUID != "" {
+		span.SetAttributes(attribute.String("pod.uid", podUID))
+	}
+	return ctx, otelSpan{span}
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s otelSpan) SetAttribute(key string, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		s.span.SetAttributes(attribute.String(key, v))
+	case int:
+		s.span.SetAttributes(attribute.Int(key, v))
+	case bool:
+		s.span.SetAttributes(attribute.Bool(key, v))
+	default:
+		s.span.SetAttributes(attribute.String(key, fmt.Sprint(v)))
+	}
+}
+
+func (s otelSpan) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
+
+// podIDGenerator generates the IDs of the spans, the trace ID of a span without parent is the UID of
+// its Pod when known.
+type podIDGenerator struct{}
+
+func (g podIDGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	podUID, _ := ctx.Value(podUIDKey{}).(string)
+	traceID, ok := podTraceID(podUID)
+	if !ok {
+		rand.Read(traceID[:])
+	}
+	return traceID, g.NewSpanID(ctx, traceID)
+}
+
+func (podIDGenerator) NewSpanID(_ context.Context, _ trace.TraceID) trace.SpanID {
+	var spanID trace.SpanID
+	rand.Read(spanID[:])
+	return spanID
+}
+
+// podTraceID returns the trace ID of the Pod UID, false is returned if the UID is not a UUID.
+func podTraceID(podUID string) (trace.TraceID, bool) {
+	var traceID trace.TraceID
+	b, err := hex.DecodeString(strings.Replace(podUID, "-", "", -1))
+	if err != nil || len(b) != len(traceID) {
+		return traceID, false
+	}
+	copy(traceID[:], b)
+	return traceID, true
+}
+
+// NewRemoteContext returns ctx with the context of a new span in the trace of the Pod UID. It is used
+// by kuryr-cni, which doesn't record spans, to start the trace of a CNI request.
+func NewRemoteContext(ctx context.Context, podUID string) context.Context {
+	var g podIDGenerator
+	traceID, spanID := g.NewIDs(context.WithValue(ctx, podUIDKey{}, podUID))
+	return trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}))
+}
+
+// metadataCarrier reads and writes the trace context in gRPC metadata.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// InjectOutgoing adds the trace context of ctx to the outgoing gRPC metadata of ctx.
+func InjectOutgoing(ctx context.Context) context.Context {
+	md := metadata.MD{}
+	propagator.Inject(ctx, metadataCarrier(md))
+	for key, values := range md {
+		for _, value := range values {
+			ctx = metadata.AppendToOutgoingContext(ctx, key, value)
+		}
+	}
+	return ctx
+}
+
+// UnaryServerInterceptor sets the trace context of the incoming gRPC metadata as the parent of the
+// spans of the request.
+func UnaryServerInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = propagator.Extract(ctx, metadataCarrier(md))
+	}
+	return handler(ctx, req)
+}
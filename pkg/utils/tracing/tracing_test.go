@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const testPodUID = "1f6b4c2e-8a3d-4e5f-9b1c-2d3e4f5a6b7c"
+
+// withSpanRecorder records the spans of the test with a tracer provider like the one of Init.
+func withSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	oldProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(recorder),
+		sdktrace.WithIDGenerator(podIDGenerator{}),
+	))
+	t.Cleanup(func() { otel.SetTracerProvider(oldProvider) })
+	return recorder
+}
+
+func TestStart(t *testing.T) {
+	recorder := withSpanRecorder(t)
+	podTrace, ok := podTraceID(testPodUID)
+	require.True(t, ok)
+
+	ctx, span := Start(context.Background(), testPodUID, "controller.newKuryrPort")
+	_, child := Start(ctx, "", "controller.createPort")
+	child.SetAttribute("vif", "port-1")
+	child.End(nil)
+	span.End(assert.AnError)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	createPort, newKuryrPort := spans[0], spans[1]
+	assert.Equal(t, podTrace, newKuryrPort.SpanContext().TraceID())
+	assert.Equal(t, podTrace, createPort.SpanContext().TraceID())
+	assert.Equal(t, newKuryrPort.SpanContext().SpanID(), createPort.Parent().SpanID())
+	assert.Equal(t, "Error", newKuryrPort.Status().Code.String())
+
+	// A span without the UID of its Pod is in a new trace.
+	_, span = Start(context.Background(), "", "cni.status")
+	span.End(nil)
+	spans = recorder.Ended()
+	require.Len(t, spans, 3)
+	assert.True(t, spans[2].SpanContext().TraceID().IsValid())
+	assert.NotEqual(t, podTrace, spans[2].SpanContext().TraceID())
+}
+
+func TestPropagation(t *testing.T) {
+	recorder := withSpanRecorder(t)
+	podTrace, _ := podTraceID(testPodUID)
+
+	// kuryr-cni sends the trace context of the request in the gRPC metadata.
+	clientCtx := NewRemoteContext(context.Background(), testPodUID)
+	remote := trace.SpanContextFromContext(clientCtx)
+	assert.Equal(t, podTrace, remote.TraceID())
+	outgoing, _ := metadata.FromOutgoingContext(InjectOutgoing(clientCtx))
+	require.NotEmpty(t, outgoing.Get("traceparent"))
+
+	// The spans of kuryr-agent are children of the span of kuryr-cni.
+	serverCtx := metadata.NewIncomingContext(context.Background(), outgoing)
+	_, err := UnaryServerInterceptor(serverCtx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		_, span := Start(ctx, testPodUID, "cni.add")
+		span.End(nil)
+		return nil, nil
+	})
+	require.NoError(t, err)
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, podTrace, spans[0].SpanContext().TraceID())
+	assert.Equal(t, remote.SpanID(), spans[0].Parent().SpanID())
+	assert.True(t, spans[0].Parent().IsRemote())
+}
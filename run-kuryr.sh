@@ -34,6 +34,7 @@ openstack:
 
     podSubnet : ${OPENSTACK_KURYR_POD_SUBNETID}
 #   podSubnetPool : ${OPENSTACK_KURYR_POD_SUBNET_POOL}
+#   podIPv6Subnet : ${OPENSTACK_KURYR_POD_IPV6_SUBNETID}
     podRouter : ${OPENSTACK_KURYR_ROUTER_ID}
     podSecurityGroups :
     - ${OPENSTACK_PROJECT_SG_ID}
@@ -75,6 +76,7 @@ mock_kubelet_invoke_cni(){
   "name": "kuryr",
   "type": "kuryr-cni",
   "kuryr_conf": "/etc/kuryr/xxx.conf",
+  "log_file": "/var/log/kuryr-cni.log",
   "debug": true
 }
 EOF